@@ -14,8 +14,15 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"log"
+	"net"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"portfolio-be/internal/api"
 	"portfolio-be/internal/config"
@@ -59,12 +66,74 @@ func main() {
 	}
 
 	// Setup router
-	router := api.SetupRouter(db, s3Service, cfg)
+	router, lifecycle := api.SetupRouter(db, s3Service, cfg)
 
-	// Start server
-	address := cfg.Host + ":" + cfg.Port
-	log.Printf("Server starting on http://%s", address)
-	if err := http.ListenAndServe(":"+cfg.Port, router); err != nil {
+	server := &http.Server{
+		Addr:              ":" + cfg.Port,
+		Handler:           router,
+		ReadHeaderTimeout: cfg.ServerConfig.ReadHeaderTimeout,
+		ReadTimeout:       cfg.ServerConfig.ReadTimeout,
+		WriteTimeout:      cfg.ServerConfig.WriteTimeout,
+		IdleTimeout:       cfg.ServerConfig.IdleTimeout,
+	}
+
+	// Listen before reporting ready so a taken port fails startup
+	listener, err := net.Listen("tcp", server.Addr)
+	if err != nil {
 		log.Fatal("Failed to start server:", err)
 	}
+
+	// Start the scheduled jobs, task workers and analytics recorder only once the port is
+	// taken, so that a failed startup runs none of them
+	lifecycle.Start()
+
+	serverErr := make(chan error, 1)
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serverErr <- err
+		}
+	}()
+	lifecycle.SetReady(true)
+	log.Printf("Server starting on http://%s", cfg.Host+":"+cfg.Port)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	exitCode := 0
+	select {
+	case <-ctx.Done():
+		// A second signal exits immediately
+		stop()
+		log.Println("Shutdown signal received, draining...")
+		// Report unready and give load balancers time to stop routing here
+		lifecycle.SetReady(false)
+		time.Sleep(cfg.ServerConfig.DrainDelay)
+	case err := <-serverErr:
+		stop()
+		log.Printf("Server failed: %v", err)
+		lifecycle.SetReady(false)
+		exitCode = 1
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ServerConfig.ShutdownTimeout)
+
+	// Drain in-flight requests, then stop the scheduler and background workers,
+	// then close the database they write to
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Failed to drain in-flight requests: %v", err)
+		server.Close()
+		exitCode = 1
+	}
+	if err := lifecycle.Shutdown(shutdownCtx); err != nil {
+		exitCode = 1
+	}
+	if sqlDB, err := db.DB(); err == nil {
+		if err := sqlDB.Close(); err != nil {
+			log.Printf("Failed to close database: %v", err)
+			exitCode = 1
+		}
+	}
+
+	cancel()
+
+	log.Println("Server stopped")
+	os.Exit(exitCode)
 }
@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"portfolio-be/internal/services"
+	"portfolio-be/pkg/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AnalyticsHandler struct {
+	service *services.AnalyticsService
+}
+
+func NewAnalyticsHandler(service *services.AnalyticsService) *AnalyticsHandler {
+	return &AnalyticsHandler{service: service}
+}
+
+// GetResourceAnalytics godoc
+// @Summary Get resource analytics
+// @Description Get daily views and downloads of a resource with its top referrers and countries.
+// @Description Recent events may take up to the flush interval to appear.
+// @Tags resources
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Resource ID"
+// @Param from query string false "First day (YYYY-MM-DD), defaults to 29 days before to"
+// @Param to query string false "Last day (YYYY-MM-DD), defaults to today (UTC)"
+// @Success 200 {object} utils.Response{data=models.ResourceAnalytics}
+// @Failure 400 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Router /admin/resources/{id}/analytics [get]
+func (h *AnalyticsHandler) GetResourceAnalytics(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid resource ID", err)
+		return
+	}
+
+	report, err := h.service.GetResourceAnalytics(uint(id), c.Query("from"), c.Query("to"))
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrResourceNotFound):
+			utils.NotFoundResponse(c, "Resource not found")
+		case errors.Is(err, services.ErrInvalidAnalyticsRange):
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid date range", err)
+		default:
+			utils.InternalErrorResponse(c, err)
+		}
+		return
+	}
+
+	utils.SuccessResponse(c, "Resource analytics retrieved successfully", report)
+}
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"portfolio-be/internal/services"
+	"portfolio-be/pkg/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ArchiveHandler struct {
+	service *services.ArchiveService
+}
+
+func NewArchiveHandler(service *services.ArchiveService) *ArchiveHandler {
+	return &ArchiveHandler{service: service}
+}
+
+// ImportArchive godoc
+// @Summary Upload a ZIP archive
+// @Description Store every file of a ZIP archive as an upload with the same validation and quota rules as
+// @Description single uploads, optionally creating a resource per file with a shared category and tags.
+// @Description Returns the outcome of every entry.
+// @Tags upload
+// @Accept multipart/form-data
+// @Produce json
+// @Security BearerAuth
+// @Param file formData file true "ZIP archive"
+// @Param create_resources formData bool false "Create a resource for every uploaded file" default(false)
+// @Param category formData string false "Category of the created resources"
+// @Param tags formData string false "Comma-separated tags of the created resources"
+// @Param is_public formData bool false "Whether the created resources are public" default(true)
+// @Success 200 {object} utils.Response{data=models.ArchiveImportReport}
+// @Failure 400 {object} utils.Response
+// @Failure 401 {object} utils.Response
+// @Failure 403 {object} utils.Response
+// @Failure 413 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Router /admin/uploads/archive [post]
+func (h *ArchiveHandler) ImportArchive(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.service.MaxRequestSize())
+	if err := c.Request.ParseMultipartForm(32 << 20); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			utils.ErrorResponse(c, http.StatusRequestEntityTooLarge, "Archive too large", err)
+			return
+		}
+		utils.ErrorResponse(c, http.StatusBadRequest, "Failed to parse multipart form", err)
+		return
+	}
+
+	file, header, err := c.Request.FormFile("file")
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "No file provided", err)
+		return
+	}
+	defer file.Close()
+
+	opts := services.ArchiveImportOptions{
+		CreateResources: c.PostForm("create_resources") == "true",
+		Category:        c.PostForm("category"),
+		Tags:            c.PostForm("tags"),
+		IsPublic:        true,
+	}
+	if value := c.PostForm("is_public"); value != "" {
+		isPublic, err := strconv.ParseBool(value)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid is_public value", err)
+			return
+		}
+		opts.IsPublic = isPublic
+	}
+
+	report, err := h.service.ImportArchive(file, header.Size, opts, c.GetUint("user_id"))
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidArchive):
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid ZIP archive", err)
+		case errors.Is(err, services.ErrArchiveTooLarge):
+			utils.ErrorResponse(c, http.StatusRequestEntityTooLarge, "Archive too large", err)
+		default:
+			utils.InternalErrorResponse(c, err)
+		}
+		return
+	}
+
+	utils.SuccessResponse(c, "Archive imported", report)
+}
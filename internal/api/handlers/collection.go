@@ -0,0 +1,331 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"portfolio-be/internal/models"
+	"portfolio-be/internal/services"
+	"portfolio-be/pkg/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CollectionHandler struct {
+	service *services.CollectionService
+}
+
+func NewCollectionHandler(service *services.CollectionService) *CollectionHandler {
+	return &CollectionHandler{service: service}
+}
+
+// GetCollections godoc
+// @Summary Get all collections
+// @Description Get all resource collections with their ordered items
+// @Tags collections
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} utils.Response{data=[]models.CollectionResponse}
+// @Failure 500 {object} utils.Response
+// @Router /admin/collections [get]
+func (h *CollectionHandler) GetCollections(c *gin.Context) {
+	collections, err := h.service.GetAllCollections()
+	if err != nil {
+		utils.InternalErrorResponse(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, "Collections retrieved successfully", collections)
+}
+
+// GetCollection godoc
+// @Summary Get collection by ID
+// @Description Get a collection with its ordered items
+// @Tags collections
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Collection ID"
+// @Success 200 {object} utils.Response{data=models.CollectionResponse}
+// @Failure 400 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Router /admin/collections/{id} [get]
+func (h *CollectionHandler) GetCollection(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid collection ID", err)
+		return
+	}
+
+	collection, err := h.service.GetCollectionByID(uint(id))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, "Collection retrieved successfully", collection)
+}
+
+// CreateCollection godoc
+// @Summary Create a collection
+// @Description Create a resource collection, optionally attached to a project as a gallery
+// @Tags collections
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param collection body models.CollectionRequest true "Collection data"
+// @Success 201 {object} utils.Response{data=models.CollectionResponse}
+// @Failure 400 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Router /admin/collections [post]
+func (h *CollectionHandler) CreateCollection(c *gin.Context) {
+	var req models.CollectionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request payload", err)
+		return
+	}
+
+	collection, err := h.service.CreateCollection(&req)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	utils.CreatedResponse(c, "Collection created successfully", collection)
+}
+
+// UpdateCollection godoc
+// @Summary Update a collection
+// @Description Update a collection's details and project attachment
+// @Tags collections
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Collection ID"
+// @Param collection body models.CollectionRequest true "Collection data"
+// @Success 200 {object} utils.Response{data=models.CollectionResponse}
+// @Failure 400 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Router /admin/collections/{id} [put]
+func (h *CollectionHandler) UpdateCollection(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid collection ID", err)
+		return
+	}
+
+	var req models.CollectionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request payload", err)
+		return
+	}
+
+	collection, err := h.service.UpdateCollection(uint(id), &req)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, "Collection updated successfully", collection)
+}
+
+// DeleteCollection godoc
+// @Summary Delete a collection
+// @Description Delete a collection; its resources are kept
+// @Tags collections
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Collection ID"
+// @Success 200 {object} utils.Response
+// @Failure 400 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Router /admin/collections/{id} [delete]
+func (h *CollectionHandler) DeleteCollection(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid collection ID", err)
+		return
+	}
+
+	if err := h.service.DeleteCollection(uint(id)); err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, "Collection deleted successfully", nil)
+}
+
+// AddCollectionItem godoc
+// @Summary Add a resource to a collection
+// @Description Add a resource with an optional caption, appended or inserted at a position
+// @Tags collections
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Collection ID"
+// @Param item body models.CollectionItemRequest true "Collection item data"
+// @Success 201 {object} utils.Response{data=models.CollectionResponse}
+// @Failure 400 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Failure 409 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Router /admin/collections/{id}/items [post]
+func (h *CollectionHandler) AddCollectionItem(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid collection ID", err)
+		return
+	}
+
+	var req models.CollectionItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request payload", err)
+		return
+	}
+
+	collection, err := h.service.AddItem(uint(id), &req)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	utils.CreatedResponse(c, "Collection item added successfully", collection)
+}
+
+// UpdateCollectionItem godoc
+// @Summary Update a collection item
+// @Description Update the caption of a resource within a collection
+// @Tags collections
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Collection ID"
+// @Param itemId path int true "Collection item ID"
+// @Param item body models.CollectionItemUpdateRequest true "Collection item data"
+// @Success 200 {object} utils.Response{data=models.CollectionResponse}
+// @Failure 400 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Router /admin/collections/{id}/items/{itemId} [put]
+func (h *CollectionHandler) UpdateCollectionItem(c *gin.Context) {
+	id, itemID, ok := h.parseItemIDs(c)
+	if !ok {
+		return
+	}
+
+	var req models.CollectionItemUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request payload", err)
+		return
+	}
+
+	collection, err := h.service.UpdateItem(id, itemID, &req)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, "Collection item updated successfully", collection)
+}
+
+// RemoveCollectionItem godoc
+// @Summary Remove a resource from a collection
+// @Description Remove an item from a collection; the resource itself is kept
+// @Tags collections
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Collection ID"
+// @Param itemId path int true "Collection item ID"
+// @Success 200 {object} utils.Response
+// @Failure 400 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Router /admin/collections/{id}/items/{itemId} [delete]
+func (h *CollectionHandler) RemoveCollectionItem(c *gin.Context) {
+	id, itemID, ok := h.parseItemIDs(c)
+	if !ok {
+		return
+	}
+
+	if err := h.service.RemoveItem(id, itemID); err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, "Collection item removed successfully", nil)
+}
+
+// ReorderCollectionItems godoc
+// @Summary Reorder collection items
+// @Description Set the order of a collection's items; item_ids must list every item exactly once
+// @Tags collections
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Collection ID"
+// @Param request body models.CollectionReorderRequest true "Item IDs in their new order"
+// @Success 200 {object} utils.Response{data=models.CollectionResponse}
+// @Failure 400 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Router /admin/collections/{id}/items/order [put]
+func (h *CollectionHandler) ReorderCollectionItems(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid collection ID", err)
+		return
+	}
+
+	var req models.CollectionReorderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request payload", err)
+		return
+	}
+
+	collection, err := h.service.ReorderItems(uint(id), req.ItemIDs)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, "Collection reordered successfully", collection)
+}
+
+func (h *CollectionHandler) parseItemIDs(c *gin.Context) (uint, uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid collection ID", err)
+		return 0, 0, false
+	}
+
+	itemID, err := strconv.ParseUint(c.Param("itemId"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid collection item ID", err)
+		return 0, 0, false
+	}
+
+	return uint(id), uint(itemID), true
+}
+
+func (h *CollectionHandler) handleError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrCollectionNotFound):
+		utils.NotFoundResponse(c, "Collection not found")
+	case errors.Is(err, services.ErrResourceNotFound):
+		utils.NotFoundResponse(c, "Resource not found")
+	case errors.Is(err, services.ErrCollectionProjectNotFound):
+		utils.ErrorResponse(c, http.StatusBadRequest, "Project not found", err)
+	case errors.Is(err, services.ErrCollectionItemExists):
+		utils.ErrorResponse(c, http.StatusConflict, "Resource is already in the collection", err)
+	case errors.Is(err, services.ErrInvalidReorder):
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid item order", err)
+	default:
+		utils.InternalErrorResponse(c, err)
+	}
+}
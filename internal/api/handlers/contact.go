@@ -1,7 +1,9 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
+	"portfolio-be/internal/api/middleware"
 	"portfolio-be/internal/models"
 	"portfolio-be/internal/services"
 	"portfolio-be/pkg/utils"
@@ -20,15 +22,28 @@ func NewContactHandler(contactService *services.ContactService) *ContactHandler
 	}
 }
 
+// GetFormToken godoc
+// @Summary Get a contact form token
+// @Description Get the signed token the contact form must submit, issued when the form is shown. Submissions sent sooner than min_fill_seconds later are treated as spam.
+// @Tags contact
+// @Accept json
+// @Produce json
+// @Success 200 {object} utils.Response{data=models.ContactFormToken}
+// @Router /api/contacts/form-token [get]
+func (h *ContactHandler) GetFormToken(c *gin.Context) {
+	utils.SuccessResponse(c, "Form token issued successfully", h.contactService.IssueFormToken())
+}
+
 // CreateContact godoc
 // @Summary Create a new contact message
-// @Description Submit a contact form message
+// @Description Submit a contact form message with the token from /api/contacts/form-token. Messages are rate limited per IP and email address, and messages that look like spam are held for review.
 // @Tags contact
 // @Accept json
 // @Produce json
 // @Param contact body models.ContactRequest true "Contact message data"
-// @Success 201 {object} utils.Response{data=models.ContactResponse}
+// @Success 201 {object} utils.Response{data=models.ContactReceipt}
 // @Failure 400 {object} utils.Response
+// @Failure 429 {object} utils.Response
 // @Failure 500 {object} utils.Response
 // @Router /api/contacts [post]
 func (h *ContactHandler) CreateContact(c *gin.Context) {
@@ -38,22 +53,29 @@ func (h *ContactHandler) CreateContact(c *gin.Context) {
 		return
 	}
 
-	contact, err := h.contactService.CreateContact(&req)
+	contact, err := h.contactService.CreateContact(c.Request.Context(), &req, c.ClientIP())
 	if err != nil {
-		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to create contact", err)
+		switch {
+		case errors.Is(err, services.ErrInvalidFormToken):
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid or expired form, please reload the page", err)
+		case errors.Is(err, services.ErrCaptchaFailed):
+			utils.ErrorResponse(c, http.StatusBadRequest, "CAPTCHA verification failed", err)
+		case errors.Is(err, services.ErrContactRateLimited):
+			utils.ErrorResponse(c, http.StatusTooManyRequests, "Too many messages, please try again later", err)
+		default:
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to create contact", err)
+		}
 		return
 	}
 
-	response := models.ContactResponse{
+	// Spam is acknowledged like any message so bots cannot tell they were caught
+	response := models.ContactReceipt{
 		ID:        contact.ID,
 		Name:      contact.Name,
 		Email:     contact.Email,
 		Subject:   contact.Subject,
 		Message:   contact.Message,
-		Status:    contact.Status,
-		IsActive:  contact.IsActive,
 		CreatedAt: contact.CreatedAt,
-		UpdatedAt: contact.UpdatedAt,
 	}
 
 	utils.CreatedResponse(c, "Contact message sent successfully", response)
@@ -61,14 +83,22 @@ func (h *ContactHandler) CreateContact(c *gin.Context) {
 
 // GetContacts godoc
 // @Summary Get all contact messages (Admin only)
-// @Description Get all contact messages with pagination
+// @Description Search and filter the contact inbox with pagination. Spam is only listed when asked for by status, and archived messages only with archived=true.
 // @Tags admin
 // @Accept json
 // @Produce json
 // @Security BearerAuth
 // @Param page query int false "Page number" default(1)
 // @Param limit query int false "Items per page" default(10)
-// @Param status query string false "Filter by status" Enums(unread,read,replied)
+// @Param search query string false "Words that must all appear in the name, email, subject or message"
+// @Param status query string false "Filter by status" Enums(unread,read,replied,spam)
+// @Param label query int false "Filter by label ID"
+// @Param assignee query string false "Filter by assignee: a user ID, me or none"
+// @Param archived query bool false "List archived messages instead of the inbox"
+// @Param created_from query string false "Created at or after (RFC3339 or YYYY-MM-DD)"
+// @Param created_to query string false "Created before (RFC3339, or YYYY-MM-DD to include that day)"
+// @Param sort query string false "Sort by created_at or status" default(created_at)
+// @Param order query string false "Sort order (asc or desc); defaults to newest first, or unread first by status"
 // @Success 200 {object} utils.PaginatedResponse{data=[]models.ContactResponse}
 // @Failure 400 {object} utils.Response
 // @Failure 401 {object} utils.Response
@@ -78,7 +108,6 @@ func (h *ContactHandler) CreateContact(c *gin.Context) {
 func (h *ContactHandler) GetContacts(c *gin.Context) {
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
-	status := c.Query("status")
 
 	if page < 1 {
 		page = 1
@@ -87,18 +116,59 @@ func (h *ContactHandler) GetContacts(c *gin.Context) {
 		limit = 10
 	}
 
-	var contacts []models.ContactResponse
-	var total int64
-	var err error
+	filter := models.ContactFilter{
+		Search:    c.Query("search"),
+		Status:    c.Query("status"),
+		Archived:  c.Query("archived") == "true",
+		SortBy:    c.Query("sort"),
+		SortOrder: c.Query("order"),
+	}
 
-	if status != "" {
-		contacts, total, err = h.contactService.GetContactsByStatus(status, page, limit)
-	} else {
-		contacts, total, err = h.contactService.GetAllContacts(page, limit)
+	var err error
+	if value := c.Query("label"); value != "" {
+		id, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid label ID", err)
+			return
+		}
+		labelID := uint(id)
+		filter.LabelID = &labelID
+	}
+	switch value := c.Query("assignee"); value {
+	case "":
+	case "none":
+		filter.Unassigned = true
+	case "me":
+		userID := c.GetUint("user_id")
+		filter.AssigneeID = &userID
+	default:
+		id, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid assignee", errors.New("assignee must be a user ID, me or none"))
+			return
+		}
+		userID := uint(id)
+		filter.AssigneeID = &userID
+	}
+	if filter.CreatedFrom, err = parseDateQuery(c.Query("created_from"), false); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid created_from date", err)
+		return
+	}
+	if filter.CreatedTo, err = parseDateQuery(c.Query("created_to"), true); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid created_to date", err)
+		return
 	}
 
+	contacts, total, err := h.contactService.ListContacts(filter, c.GetUint("user_id"), limit, (page-1)*limit)
 	if err != nil {
-		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get contacts", err)
+		switch {
+		case errors.Is(err, services.ErrInvalidContactSort):
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid sort field", err)
+		case errors.Is(err, services.ErrInvalidContactStatus):
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid status", err)
+		default:
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get contacts", err)
+		}
 		return
 	}
 
@@ -109,22 +179,18 @@ func (h *ContactHandler) GetContacts(c *gin.Context) {
 		TotalPages: int((total + int64(limit) - 1) / int64(limit)),
 	}
 
-	c.JSON(http.StatusOK, utils.PaginatedResponse{
-		Success:    true,
-		Message:    "Contacts retrieved successfully",
-		Data:       contacts,
-		Pagination: pagination,
-	})
+	utils.PaginatedSuccessResponse(c, "Contacts retrieved successfully", contacts, pagination)
 }
 
 // GetContact godoc
 // @Summary Get a contact message by ID (Admin only)
-// @Description Get a specific contact message by its ID
+// @Description Get a specific contact message by its ID with who read it and the thread of replies sent to it. Viewing changes nothing unless mark_read=true, which records that you read it and needs the contacts:update permission.
 // @Tags admin
 // @Accept json
 // @Produce json
 // @Security BearerAuth
 // @Param id path int true "Contact ID"
+// @Param mark_read query bool false "Mark the message read by you"
 // @Success 200 {object} utils.Response{data=models.ContactResponse}
 // @Failure 400 {object} utils.Response
 // @Failure 401 {object} utils.Response
@@ -140,15 +206,24 @@ func (h *ContactHandler) GetContact(c *gin.Context) {
 		return
 	}
 
-	contact, err := h.contactService.GetContactByID(uint(id))
+	userID := c.GetUint("user_id")
+	if c.Query("mark_read") == "true" {
+		if !c.GetBool(middleware.PermissionContextKey("contacts", "update")) {
+			utils.ErrorResponse(c, http.StatusForbidden, "Insufficient permissions", errors.New("marking contacts read needs the contacts:update permission"))
+			return
+		}
+		if err := h.contactService.MarkAsRead(uint(id), userID); err != nil {
+			writeContactError(c, err)
+			return
+		}
+	}
+
+	contact, err := h.contactService.GetContactByID(uint(id), userID)
 	if err != nil {
-		utils.ErrorResponse(c, http.StatusNotFound, "Contact not found", err)
+		writeContactError(c, err)
 		return
 	}
 
-	// Mark as read when viewed
-	h.contactService.MarkAsRead(uint(id))
-
 	utils.SuccessResponse(c, "Contact retrieved successfully", contact)
 }
 
@@ -224,18 +299,30 @@ func (h *ContactHandler) DeleteContact(c *gin.Context) {
 
 // GetUnreadCount godoc
 // @Summary Get unread contact count (Admin only)
-// @Description Get the number of unread contact messages
+// @Description Get the number of inbox messages nobody has read, or with scope=me the number you have not read
 // @Tags admin
 // @Accept json
 // @Produce json
 // @Security BearerAuth
+// @Param scope query string false "Whose unread messages to count" Enums(all, me) default(all)
 // @Success 200 {object} utils.Response{data=map[string]int64}
+// @Failure 400 {object} utils.Response
 // @Failure 401 {object} utils.Response
 // @Failure 403 {object} utils.Response
 // @Failure 500 {object} utils.Response
 // @Router /admin/contacts/unread-count [get]
 func (h *ContactHandler) GetUnreadCount(c *gin.Context) {
-	count, err := h.contactService.GetUnreadCount()
+	var count int64
+	var err error
+	switch c.DefaultQuery("scope", "all") {
+	case "all":
+		count, err = h.contactService.GetUnreadCount()
+	case "me":
+		count, err = h.contactService.GetUnreadCountForUser(c.GetUint("user_id"))
+	default:
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid scope", errors.New("scope must be all or me"))
+		return
+	}
 	if err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get unread count", err)
 		return
@@ -248,7 +335,7 @@ func (h *ContactHandler) GetUnreadCount(c *gin.Context) {
 
 // MarkAsRead godoc
 // @Summary Mark contact as read (Admin only)
-// @Description Mark a contact message as read
+// @Description Record that you read a contact message and move it from unread to read
 // @Tags admin
 // @Accept json
 // @Produce json
@@ -269,10 +356,281 @@ func (h *ContactHandler) MarkAsRead(c *gin.Context) {
 		return
 	}
 
-	if err := h.contactService.MarkAsRead(uint(id)); err != nil {
-		utils.ErrorResponse(c, http.StatusNotFound, "Contact not found", err)
+	if err := h.contactService.MarkAsRead(uint(id), c.GetUint("user_id")); err != nil {
+		writeContactError(c, err)
 		return
 	}
 
 	utils.SuccessResponse(c, "Contact marked as read", nil)
 }
+
+// MarkAsUnread godoc
+// @Summary Mark contact as unread (Admin only)
+// @Description Forget that you read a contact message and move it from read back to unread
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Contact ID"
+// @Success 200 {object} utils.Response
+// @Failure 400 {object} utils.Response
+// @Failure 401 {object} utils.Response
+// @Failure 403 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Router /admin/contacts/{id}/mark-unread [patch]
+func (h *ContactHandler) MarkAsUnread(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid contact ID", err)
+		return
+	}
+
+	if err := h.contactService.MarkAsUnread(uint(id), c.GetUint("user_id")); err != nil {
+		writeContactError(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, "Contact marked as unread", nil)
+}
+
+// AssignContact godoc
+// @Summary Assign a contact message (Admin only)
+// @Description Assign a contact message to a user, or unassign it with a null user_id
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Contact ID"
+// @Param assignment body models.ContactAssignRequest true "Assignee"
+// @Success 200 {object} utils.Response{data=models.ContactResponse}
+// @Failure 400 {object} utils.Response
+// @Failure 401 {object} utils.Response
+// @Failure 403 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Router /admin/contacts/{id}/assignee [put]
+func (h *ContactHandler) AssignContact(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid contact ID", err)
+		return
+	}
+
+	var req models.ContactAssignRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationErrorResponse(c, err)
+		return
+	}
+
+	contact, err := h.contactService.AssignContact(uint(id), req.UserID, c.GetUint("user_id"))
+	if err != nil {
+		writeContactError(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, "Contact assigned successfully", contact)
+}
+
+// BulkUpdateContacts godoc
+// @Summary Update many contact messages (Admin only)
+// @Description Mark read or unread (for you), archive, unarchive, label or unlabel many contact messages at once. Label and unlabel need label_id. Returns how many messages changed.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param bulk body models.ContactBulkRequest true "Contacts and action"
+// @Success 200 {object} utils.Response{data=models.ContactBulkResult}
+// @Failure 400 {object} utils.Response
+// @Failure 401 {object} utils.Response
+// @Failure 403 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Router /admin/contacts/bulk [post]
+func (h *ContactHandler) BulkUpdateContacts(c *gin.Context) {
+	var req models.ContactBulkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationErrorResponse(c, err)
+		return
+	}
+
+	result, err := h.contactService.BulkUpdate(&req, c.GetUint("user_id"))
+	if err != nil {
+		writeContactError(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, "Contacts updated successfully", result)
+}
+
+// BulkDeleteContacts godoc
+// @Summary Delete many contact messages (Admin only)
+// @Description Delete many contact messages at once. Returns how many were deleted.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param bulk body models.ContactBulkDeleteRequest true "Contacts to delete"
+// @Success 200 {object} utils.Response{data=models.ContactBulkResult}
+// @Failure 400 {object} utils.Response
+// @Failure 401 {object} utils.Response
+// @Failure 403 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Router /admin/contacts/bulk-delete [post]
+func (h *ContactHandler) BulkDeleteContacts(c *gin.Context) {
+	var req models.ContactBulkDeleteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationErrorResponse(c, err)
+		return
+	}
+
+	result, err := h.contactService.BulkDelete(req.IDs)
+	if err != nil {
+		writeContactError(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, "Contacts deleted successfully", result)
+}
+
+// GetContactLabels godoc
+// @Summary Get contact labels (Admin only)
+// @Description Get the labels admins can put on contact messages, by name
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} utils.Response{data=[]models.ContactLabel}
+// @Failure 401 {object} utils.Response
+// @Failure 403 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Router /admin/contacts/labels [get]
+func (h *ContactHandler) GetContactLabels(c *gin.Context) {
+	labels, err := h.contactService.GetLabels()
+	if err != nil {
+		utils.InternalErrorResponse(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, "Contact labels retrieved successfully", labels)
+}
+
+// CreateContactLabel godoc
+// @Summary Create a contact label (Admin only)
+// @Description Create a label for contact messages; names are unique regardless of case
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param label body models.ContactLabelRequest true "Label data"
+// @Success 201 {object} utils.Response{data=models.ContactLabel}
+// @Failure 400 {object} utils.Response
+// @Failure 401 {object} utils.Response
+// @Failure 403 {object} utils.Response
+// @Failure 409 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Router /admin/contacts/labels [post]
+func (h *ContactHandler) CreateContactLabel(c *gin.Context) {
+	var req models.ContactLabelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationErrorResponse(c, err)
+		return
+	}
+
+	label, err := h.contactService.CreateLabel(&req)
+	if err != nil {
+		writeContactError(c, err)
+		return
+	}
+
+	utils.CreatedResponse(c, "Contact label created successfully", label)
+}
+
+// UpdateContactLabel godoc
+// @Summary Update a contact label (Admin only)
+// @Description Rename or recolor a contact label
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Label ID"
+// @Param label body models.ContactLabelRequest true "Label data"
+// @Success 200 {object} utils.Response{data=models.ContactLabel}
+// @Failure 400 {object} utils.Response
+// @Failure 401 {object} utils.Response
+// @Failure 403 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Failure 409 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Router /admin/contacts/labels/{id} [put]
+func (h *ContactHandler) UpdateContactLabel(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid label ID", err)
+		return
+	}
+
+	var req models.ContactLabelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationErrorResponse(c, err)
+		return
+	}
+
+	label, err := h.contactService.UpdateLabel(uint(id), &req)
+	if err != nil {
+		writeContactError(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, "Contact label updated successfully", label)
+}
+
+// DeleteContactLabel godoc
+// @Summary Delete a contact label (Admin only)
+// @Description Delete a contact label and remove it from every message
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Label ID"
+// @Success 200 {object} utils.Response
+// @Failure 400 {object} utils.Response
+// @Failure 401 {object} utils.Response
+// @Failure 403 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Router /admin/contacts/labels/{id} [delete]
+func (h *ContactHandler) DeleteContactLabel(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid label ID", err)
+		return
+	}
+
+	if err := h.contactService.DeleteLabel(uint(id)); err != nil {
+		writeContactError(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, "Contact label deleted successfully", nil)
+}
+
+// writeContactError maps contact inbox errors to HTTP responses
+func writeContactError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrContactNotFound):
+		utils.NotFoundResponse(c, "Contact not found")
+	case errors.Is(err, services.ErrContactLabelNotFound):
+		utils.NotFoundResponse(c, "Contact label not found")
+	case errors.Is(err, services.ErrAssigneeNotFound):
+		utils.ErrorResponse(c, http.StatusBadRequest, "Assignee not found", err)
+	case errors.Is(err, services.ErrContactLabelRequired):
+		utils.ErrorResponse(c, http.StatusBadRequest, "Label is required", err)
+	case errors.Is(err, services.ErrInvalidLabelColor):
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid label color", err)
+	case errors.Is(err, services.ErrContactLabelExists):
+		utils.ErrorResponse(c, http.StatusConflict, "Contact label name is taken", err)
+	default:
+		utils.InternalErrorResponse(c, err)
+	}
+}
@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+
+	"portfolio-be/internal/models"
+	"portfolio-be/internal/services"
+	"portfolio-be/pkg/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ContactPrivacyHandler struct {
+	service *services.ContactPrivacyService
+}
+
+func NewContactPrivacyHandler(service *services.ContactPrivacyService) *ContactPrivacyHandler {
+	return &ContactPrivacyHandler{service: service}
+}
+
+// ExportContacts godoc
+// @Summary Export the contact data of a person (Admin only)
+// @Description Export everything stored about the sender of contact messages for a data-subject access request: every message sent with the email address, including deleted ones, with its IP address, labels and the replies sent to it. The address is matched ignoring case.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param email query string true "Email address of the person"
+// @Success 200 {object} utils.Response{data=models.ContactExport}
+// @Failure 400 {object} utils.Response
+// @Failure 401 {object} utils.Response
+// @Failure 403 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Router /admin/contacts/export [get]
+func (h *ContactPrivacyHandler) ExportContacts(c *gin.Context) {
+	export, err := h.service.Export(c.Query("email"))
+	if err != nil {
+		writeContactPrivacyError(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, "Contact data exported successfully", export)
+}
+
+// EraseContacts godoc
+// @Summary Erase the contact data of a person (Admin only)
+// @Description Permanently delete every message sent with an email address, including deleted ones, with the replies sent to them, their read receipts and labels. The erasure is recorded in the audit trail with a hash of the address, even when nothing was stored.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param email query string true "Email address of the person"
+// @Success 200 {object} utils.Response{data=models.ContactErasure}
+// @Failure 400 {object} utils.Response
+// @Failure 401 {object} utils.Response
+// @Failure 403 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Router /admin/contacts/erase [post]
+func (h *ContactPrivacyHandler) EraseContacts(c *gin.Context) {
+	erasure, err := h.service.Erase(c.Query("email"), c.GetUint("user_id"), c.GetString("username"))
+	if err != nil {
+		writeContactPrivacyError(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, "Contact data erased successfully", erasure)
+}
+
+// GetErasures godoc
+// @Summary Get the contact erasure audit trail (Admin only)
+// @Description Get the erasures of contact personal data, newest first: those requested by an admin and those made by the retention job
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param reason query string false "Filter by reason" Enums(request, retention)
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(20)
+// @Success 200 {object} utils.PaginatedResponse{data=[]models.ContactErasure}
+// @Failure 400 {object} utils.Response
+// @Failure 401 {object} utils.Response
+// @Failure 403 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Router /admin/contacts/erasures [get]
+func (h *ContactPrivacyHandler) GetErasures(c *gin.Context) {
+	reason := c.Query("reason")
+	switch reason {
+	case "", models.ContactErasureRequest, models.ContactErasureRetention:
+	default:
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid reason", errors.New("reason must be request or retention"))
+		return
+	}
+
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	erasures, total, err := h.service.GetErasures(reason, limit, (page-1)*limit)
+	if err != nil {
+		utils.InternalErrorResponse(c, err)
+		return
+	}
+
+	pagination := utils.Pagination{
+		Page:       page,
+		Limit:      limit,
+		TotalItems: total,
+		TotalPages: int(math.Ceil(float64(total) / float64(limit))),
+	}
+
+	utils.PaginatedSuccessResponse(c, "Erasures retrieved successfully", erasures, pagination)
+}
+
+// writeContactPrivacyError maps export and erasure errors to HTTP responses
+func writeContactPrivacyError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidErasureEmail):
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid email", err)
+	default:
+		utils.InternalErrorResponse(c, err)
+	}
+}
@@ -0,0 +1,233 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"portfolio-be/internal/models"
+	"portfolio-be/internal/services"
+	"portfolio-be/pkg/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ContactReplyHandler struct {
+	service *services.ContactReplyService
+}
+
+func NewContactReplyHandler(service *services.ContactReplyService) *ContactReplyHandler {
+	return &ContactReplyHandler{service: service}
+}
+
+// CreateReply godoc
+// @Summary Reply to a contact message (Admin only)
+// @Description Email a reply to the sender of a contact message and mark the message replied. The reply is delivered in the background; its status moves from queued to sent, or to failed once retries run out. Give template_id to send a canned reply, optionally overriding its body or subject. Messages anonymized by the retention policy cannot be replied to.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Contact ID"
+// @Param reply body models.ContactReplyRequest true "Reply data"
+// @Success 201 {object} utils.Response{data=models.ContactReply}
+// @Failure 400 {object} utils.Response
+// @Failure 401 {object} utils.Response
+// @Failure 403 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Failure 409 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Router /admin/contacts/{id}/replies [post]
+func (h *ContactReplyHandler) CreateReply(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid contact ID", err)
+		return
+	}
+
+	var req models.ContactReplyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationErrorResponse(c, err)
+		return
+	}
+
+	reply, err := h.service.Reply(uint(id), c.GetUint("user_id"), c.GetString("username"), &req)
+	if err != nil {
+		writeContactReplyError(c, err)
+		return
+	}
+
+	utils.CreatedResponse(c, "Reply queued for delivery", reply)
+}
+
+// GetReplyTemplates godoc
+// @Summary Get canned replies (Admin only)
+// @Description Get the canned reply templates by name
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} utils.Response{data=[]models.ReplyTemplate}
+// @Failure 401 {object} utils.Response
+// @Failure 403 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Router /admin/contacts/reply-templates [get]
+func (h *ContactReplyHandler) GetReplyTemplates(c *gin.Context) {
+	templates, err := h.service.GetTemplates()
+	if err != nil {
+		utils.InternalErrorResponse(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, "Reply templates retrieved successfully", templates)
+}
+
+// GetReplyTemplate godoc
+// @Summary Get a canned reply (Admin only)
+// @Description Get a canned reply template by its ID
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Template ID"
+// @Success 200 {object} utils.Response{data=models.ReplyTemplate}
+// @Failure 400 {object} utils.Response
+// @Failure 401 {object} utils.Response
+// @Failure 403 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Router /admin/contacts/reply-templates/{id} [get]
+func (h *ContactReplyHandler) GetReplyTemplate(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid template ID", err)
+		return
+	}
+
+	template, err := h.service.GetTemplate(uint(id))
+	if err != nil {
+		writeContactReplyError(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, "Reply template retrieved successfully", template)
+}
+
+// CreateReplyTemplate godoc
+// @Summary Create a canned reply (Admin only)
+// @Description Create a canned reply template. Subject and body are Go templates that can use {{.Name}}, {{.Email}}, {{.Subject}}, {{.SiteName}} and {{.Author}}.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param template body models.ReplyTemplateRequest true "Template data"
+// @Success 201 {object} utils.Response{data=models.ReplyTemplate}
+// @Failure 400 {object} utils.Response
+// @Failure 401 {object} utils.Response
+// @Failure 403 {object} utils.Response
+// @Failure 409 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Router /admin/contacts/reply-templates [post]
+func (h *ContactReplyHandler) CreateReplyTemplate(c *gin.Context) {
+	var req models.ReplyTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationErrorResponse(c, err)
+		return
+	}
+
+	template, err := h.service.CreateTemplate(&req)
+	if err != nil {
+		writeContactReplyError(c, err)
+		return
+	}
+
+	utils.CreatedResponse(c, "Reply template created successfully", template)
+}
+
+// UpdateReplyTemplate godoc
+// @Summary Update a canned reply (Admin only)
+// @Description Replace the name, subject and body of a canned reply template
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Template ID"
+// @Param template body models.ReplyTemplateRequest true "Template data"
+// @Success 200 {object} utils.Response{data=models.ReplyTemplate}
+// @Failure 400 {object} utils.Response
+// @Failure 401 {object} utils.Response
+// @Failure 403 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Failure 409 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Router /admin/contacts/reply-templates/{id} [put]
+func (h *ContactReplyHandler) UpdateReplyTemplate(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid template ID", err)
+		return
+	}
+
+	var req models.ReplyTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationErrorResponse(c, err)
+		return
+	}
+
+	template, err := h.service.UpdateTemplate(uint(id), &req)
+	if err != nil {
+		writeContactReplyError(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, "Reply template updated successfully", template)
+}
+
+// DeleteReplyTemplate godoc
+// @Summary Delete a canned reply (Admin only)
+// @Description Delete a canned reply template; replies already sent with it are kept
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Template ID"
+// @Success 200 {object} utils.Response
+// @Failure 400 {object} utils.Response
+// @Failure 401 {object} utils.Response
+// @Failure 403 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Router /admin/contacts/reply-templates/{id} [delete]
+func (h *ContactReplyHandler) DeleteReplyTemplate(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid template ID", err)
+		return
+	}
+
+	if err := h.service.DeleteTemplate(uint(id)); err != nil {
+		writeContactReplyError(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, "Reply template deleted successfully", nil)
+}
+
+// writeContactReplyError maps reply and template errors to HTTP responses
+func writeContactReplyError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrContactNotFound):
+		utils.NotFoundResponse(c, "Contact not found")
+	case errors.Is(err, services.ErrReplyTemplateNotFound):
+		utils.NotFoundResponse(c, "Reply template not found")
+	case errors.Is(err, services.ErrEmptyReply):
+		utils.ErrorResponse(c, http.StatusBadRequest, "Reply is empty", err)
+	case errors.Is(err, services.ErrInvalidReplyTemplate):
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid reply template", err)
+	case errors.Is(err, services.ErrContactAnonymized):
+		utils.ErrorResponse(c, http.StatusConflict, "Contact message was anonymized", err)
+	case errors.Is(err, services.ErrReplyTemplateExists):
+		utils.ErrorResponse(c, http.StatusConflict, "Reply template name is taken", err)
+	default:
+		utils.InternalErrorResponse(c, err)
+	}
+}
@@ -76,7 +76,7 @@ func (h *ContentHandler) GetContent(c *gin.Context) {
 
 // GetAllContents godoc
 // @Summary Get all contents
-// @Description Get a list of all content items with optional filtering
+// @Description Get a list of all content items with optional filtering; the filters given all apply
 // @Tags content
 // @Accept json
 // @Produce json
@@ -84,6 +84,7 @@ func (h *ContentHandler) GetContent(c *gin.Context) {
 // @Param limit query int false "Items per page" default(10)
 // @Param category query string false "Filter by category"
 // @Param status query string false "Filter by status"
+// @Param tag query string false "Filter by tag name or slug"
 // @Success 200 {object} utils.PaginatedResponse
 // @Failure 500 {object} utils.Response
 // @Router /api/v1/contents [get]
@@ -93,6 +94,7 @@ func (h *ContentHandler) GetAllContents(c *gin.Context) {
 	limitStr := c.DefaultQuery("limit", "10")
 	category := c.Query("category")
 	status := c.Query("status")
+	tag := c.Query("tag")
 
 	page, err := strconv.Atoi(pageStr)
 	if err != nil || page < 1 {
@@ -106,14 +108,8 @@ func (h *ContentHandler) GetAllContents(c *gin.Context) {
 
 	offset := (page - 1) * limit
 
-	contents, err := h.service.GetAllContent(limit, offset, category, status)
-	if err != nil {
-		utils.InternalErrorResponse(c, err)
-		return
-	}
-
-	// Get total count for pagination
-	totalCount, err := h.service.GetContentCount()
+	filter := models.ContentFilter{Category: category, Status: status, Tag: tag}
+	contents, totalCount, err := h.service.GetAllContent(filter, limit, offset)
 	if err != nil {
 		utils.InternalErrorResponse(c, err)
 		return
@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"portfolio-be/internal/services"
+	"portfolio-be/pkg/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CronHandler struct {
+	service *services.CronService
+}
+
+func NewCronHandler(service *services.CronService) *CronHandler {
+	return &CronHandler{service: service}
+}
+
+// CleanupExpiredUploads godoc
+// @Summary Clean up expired uploads
+// @Description Delete uploads that have been expired or inactive past the grace period and are not used by any resource
+// @Tags upload
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param dry_run query bool false "Report what would be deleted without deleting anything" default(false)
+// @Success 200 {object} utils.Response{data=models.CleanupReport}
+// @Failure 409 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Router /admin/uploads/expired/cleanup [post]
+func (h *CronHandler) CleanupExpiredUploads(c *gin.Context) {
+	dryRun := c.Query("dry_run") == "true"
+
+	report, err := h.service.RunCleanupNow(dryRun)
+	if err != nil {
+		writeJobError(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, "Expired upload cleanup completed successfully", report)
+}
+
+// RescanUploads godoc
+// @Summary Scan quarantined uploads
+// @Description Scan uploads whose virus scan is pending or failed, and clean uploads due for a periodic rescan
+// @Tags upload
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} utils.Response{data=models.ScanReport}
+// @Failure 409 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Router /admin/uploads/scan [post]
+func (h *CronHandler) RescanUploads(c *gin.Context) {
+	report, err := h.service.RunRescanNow()
+	if err != nil {
+		writeJobError(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, "Upload scan completed successfully", report)
+}
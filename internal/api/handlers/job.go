@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+
+	"portfolio-be/internal/models"
+	"portfolio-be/internal/services"
+	"portfolio-be/pkg/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type JobHandler struct {
+	scheduler *services.Scheduler
+}
+
+func NewJobHandler(scheduler *services.Scheduler) *JobHandler {
+	return &JobHandler{scheduler: scheduler}
+}
+
+// GetJobs godoc
+// @Summary Get scheduled jobs
+// @Description Get every scheduled job with its cron schedule, pause state, next run and last run
+// @Tags jobs
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} utils.Response{data=[]models.JobStatus}
+// @Router /admin/jobs [get]
+func (h *JobHandler) GetJobs(c *gin.Context) {
+	utils.SuccessResponse(c, "Jobs retrieved successfully", h.scheduler.List())
+}
+
+// GetJobRuns godoc
+// @Summary Get job run history
+// @Description Get the runs of a scheduled job, newest first
+// @Tags jobs
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param name path string true "Job name"
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(20)
+// @Success 200 {object} utils.PaginatedResponse{data=[]models.JobRun}
+// @Failure 404 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Router /admin/jobs/{name}/runs [get]
+func (h *JobHandler) GetJobRuns(c *gin.Context) {
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	runs, total, err := h.scheduler.History(c.Param("name"), limit, (page-1)*limit)
+	if err != nil {
+		writeJobError(c, err)
+		return
+	}
+
+	pagination := utils.Pagination{
+		Page:       page,
+		Limit:      limit,
+		TotalItems: total,
+		TotalPages: int(math.Ceil(float64(total) / float64(limit))),
+	}
+
+	utils.PaginatedSuccessResponse(c, "Job runs retrieved successfully", runs, pagination)
+}
+
+// RunJob godoc
+// @Summary Run a job now
+// @Description Start a run of a scheduled job in the background, even when the job is paused
+// @Tags jobs
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param name path string true "Job name"
+// @Success 200 {object} utils.Response{data=models.JobRun}
+// @Failure 404 {object} utils.Response
+// @Failure 409 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Router /admin/jobs/{name}/run [post]
+func (h *JobHandler) RunJob(c *gin.Context) {
+	run, err := h.scheduler.Trigger(c.Param("name"), models.JobTriggerManual)
+	if err != nil {
+		writeJobError(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, "Job started successfully", run)
+}
+
+// PauseJob godoc
+// @Summary Pause a job
+// @Description Stop a job from running on its schedule. The pause survives restarts.
+// @Tags jobs
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param name path string true "Job name"
+// @Success 200 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Router /admin/jobs/{name}/pause [post]
+func (h *JobHandler) PauseJob(c *gin.Context) {
+	if err := h.scheduler.Pause(c.Param("name")); err != nil {
+		writeJobError(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, "Job paused successfully", nil)
+}
+
+// ResumeJob godoc
+// @Summary Resume a job
+// @Description Put a paused job back on its schedule
+// @Tags jobs
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param name path string true "Job name"
+// @Success 200 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Router /admin/jobs/{name}/resume [post]
+func (h *JobHandler) ResumeJob(c *gin.Context) {
+	if err := h.scheduler.Resume(c.Param("name")); err != nil {
+		writeJobError(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, "Job resumed successfully", nil)
+}
+
+// writeJobError maps scheduler errors to HTTP responses
+func writeJobError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrJobNotFound):
+		utils.NotFoundResponse(c, "Job not found")
+	case errors.Is(err, services.ErrJobRunning), errors.Is(err, services.ErrJobLeased):
+		utils.ErrorResponse(c, http.StatusConflict, "Job is already running", err)
+	default:
+		utils.InternalErrorResponse(c, err)
+	}
+}
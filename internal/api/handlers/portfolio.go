@@ -15,6 +15,7 @@ type PortfolioHandler struct {
 	technologyService  services.TechnologyService
 	projectService     services.ProjectService
 	testimonialService services.TestimonialService
+	collectionService  *services.CollectionService
 }
 
 func NewPortfolioHandler(
@@ -23,6 +24,7 @@ func NewPortfolioHandler(
 	technologyService services.TechnologyService,
 	projectService services.ProjectService,
 	testimonialService services.TestimonialService,
+	collectionService *services.CollectionService,
 ) *PortfolioHandler {
 	return &PortfolioHandler{
 		experienceService:  experienceService,
@@ -30,6 +32,7 @@ func NewPortfolioHandler(
 		technologyService:  technologyService,
 		projectService:     projectService,
 		testimonialService: testimonialService,
+		collectionService:  collectionService,
 	}
 }
 
@@ -44,7 +47,7 @@ type PortfolioData struct {
 
 // GetPortfolioData
 // @Summary Get complete portfolio data
-// @Description Get all portfolio data in one request (services, technologies, experiences, testimonials, projects)
+// @Description Get all portfolio data in one request (services, technologies, experiences, testimonials, projects with galleries)
 // @Tags portfolio
 // @Accept json
 // @Produce json
@@ -104,9 +107,12 @@ func (h *PortfolioHandler) GetPortfolioData(c *gin.Context) {
 		}
 	}()
 
-	// Fetch projects
+	// Fetch projects with their galleries
 	go func() {
 		projects, err := h.projectService.GetActiveProjects()
+		if err == nil {
+			err = h.collectionService.AttachGalleries(projects)
+		}
 		if err != nil {
 			errorChan <- err
 			projectChan <- nil
@@ -27,11 +27,19 @@ func NewProjectHandler(projectService services.ProjectService) *ProjectHandler {
 // @Tags projects
 // @Accept json
 // @Produce json
+// @Param tag query string false "Filter by tag name or slug"
 // @Success 200 {object} utils.Response{data=[]models.ProjectResponse}
 // @Failure 500 {object} utils.Response
 // @Router /api/projects [get]
 func (h *ProjectHandler) GetProjects(c *gin.Context) {
-	projects, err := h.projectService.GetActiveProjects()
+	var projects []models.ProjectResponse
+	var err error
+
+	if tag := c.Query("tag"); tag != "" {
+		projects, err = h.projectService.GetActiveProjectsByTag(tag)
+	} else {
+		projects, err = h.projectService.GetActiveProjects()
+	}
 	if err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get projects", err)
 		return
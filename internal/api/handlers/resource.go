@@ -1,24 +1,73 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
 	"math"
+	"mime"
 	"net/http"
+	"portfolio-be/internal/api/middleware"
 	"portfolio-be/internal/models"
 	"portfolio-be/internal/services"
 	"portfolio-be/pkg/utils"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
 type ResourceHandler struct {
-	service *services.ResourceService
+	service    *services.ResourceService
+	publicOnly bool
+	// writeTimeout is how long each write of streamed content may take; zero leaves
+	// the server's deadline in place
+	writeTimeout time.Duration
 }
 
 func NewResourceHandler(service *services.ResourceService) *ResourceHandler {
 	return &ResourceHandler{service: service}
 }
 
+// NewPublicResourceHandler creates a handler for public endpoints, which hide private
+// and inactive resources from listings and only serve private resources to users
+// with uploads:read. Streamed content gets writeTimeout for each write instead of the
+// server's WriteTimeout for the whole response, so that long downloads are not cut off.
+func NewPublicResourceHandler(service *services.ResourceService, writeTimeout time.Duration) *ResourceHandler {
+	return &ResourceHandler{service: service, publicOnly: true, writeTimeout: writeTimeout}
+}
+
+// canReadPrivate reports whether the caller may access private resources
+func (h *ResourceHandler) canReadPrivate(c *gin.Context) bool {
+	return !h.publicOnly || c.GetBool(middleware.PermissionContextKey("uploads", "read"))
+}
+
+// getAccessibleResource loads a resource the caller may access, writing the error response otherwise
+func (h *ResourceHandler) getAccessibleResource(c *gin.Context, id uint) (*models.ResourceResponse, bool) {
+	resource, err := h.service.GetAccessibleResource(id, h.canReadPrivate(c))
+	if err != nil {
+		writeAccessError(c, err)
+		return nil, false
+	}
+	return resource, true
+}
+
+// writeAccessError writes the error response for a resource the caller may not access or download
+func writeAccessError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrResourcePrivate) && c.GetUint("user_id") == 0:
+		utils.ErrorResponse(c, http.StatusUnauthorized, "Authentication required to access this resource", err)
+	case errors.Is(err, services.ErrResourcePrivate):
+		utils.ErrorResponse(c, http.StatusForbidden, "Insufficient permissions to access this resource", err)
+	case errors.Is(err, services.ErrUploadQuarantined):
+		utils.ErrorResponse(c, http.StatusLocked, "File is quarantined until its virus scan is clean", err)
+	default:
+		utils.NotFoundResponse(c, "Resource not found")
+	}
+}
+
 // CreateResource godoc
 // @Summary Create a new resource
 // @Description Create a new resource with upload reference
@@ -69,18 +118,29 @@ func (h *ResourceHandler) GetResource(c *gin.Context) {
 		return
 	}
 
-	resource, err := h.service.GetResourceByID(uint(id))
-	if err != nil {
-		utils.NotFoundResponse(c, "Resource not found")
+	if !h.publicOnly {
+		resource, err := h.service.GetResourceByID(uint(id))
+		if err != nil {
+			utils.NotFoundResponse(c, "Resource not found")
+			return
+		}
+
+		utils.SuccessResponse(c, "Resource retrieved successfully", resource)
 		return
 	}
 
+	resource, ok := h.getAccessibleResource(c, uint(id))
+	if !ok {
+		return
+	}
+	h.service.LogAccess(resource.ID, resource.IsPublic, "view", c.GetUint("user_id"), c.ClientIP(), c.Request.Referer())
+
 	utils.SuccessResponse(c, "Resource retrieved successfully", resource)
 }
 
 // GetAllResources godoc
 // @Summary Get all resources
-// @Description Get a list of all resource records
+// @Description Get a list of resource records; all filters can be combined
 // @Tags resources
 // @Accept json
 // @Produce json
@@ -90,17 +150,22 @@ func (h *ResourceHandler) GetResource(c *gin.Context) {
 // @Param category query string false "Filter by category"
 // @Param public query bool false "Filter public resources only"
 // @Param search query string false "Search in name, description, or tags"
+// @Param tag query string false "Filter by tag name or slug"
+// @Param created_from query string false "Created at or after (RFC3339 or YYYY-MM-DD)"
+// @Param created_to query string false "Created before (RFC3339, or YYYY-MM-DD to include that day)"
+// @Param content_type query string false "Upload content type, e.g. image/png or image/*"
+// @Param min_size query int false "Minimum file size in bytes"
+// @Param max_size query int false "Maximum file size in bytes"
+// @Param sort query string false "Sort by name, created_at, view_count or download_count" default(created_at)
+// @Param order query string false "Sort order (asc or desc)" default(desc)
 // @Success 200 {object} utils.PaginatedResponse
+// @Failure 400 {object} utils.Response
 // @Failure 500 {object} utils.Response
 // @Router /api/v1/resources [get]
 func (h *ResourceHandler) GetAllResources(c *gin.Context) {
 	// Parse query parameters
 	pageStr := c.DefaultQuery("page", "1")
 	limitStr := c.DefaultQuery("limit", "10")
-	resourceType := c.Query("type")
-	category := c.Query("category")
-	publicOnly := c.Query("public") == "true"
-	search := c.Query("search")
 
 	page, err := strconv.Atoi(pageStr)
 	if err != nil || page < 1 {
@@ -114,43 +179,92 @@ func (h *ResourceHandler) GetAllResources(c *gin.Context) {
 
 	offset := (page - 1) * limit
 
-	var resources []models.ResourceResponse
+	filter := models.ResourceFilter{
+		Type:        models.ResourceType(c.Query("type")),
+		Category:    c.Query("category"),
+		Tag:         c.Query("tag"),
+		Search:      c.Query("search"),
+		PublicOnly:  h.publicOnly || c.Query("public") == "true",
+		ContentType: c.Query("content_type"),
+		SortBy:      c.Query("sort"),
+		SortOrder:   c.Query("order"),
+	}
 
-	// Handle different query types
-	switch {
-	case search != "":
-		resources, err = h.service.SearchResources(search, limit, offset)
-	case publicOnly:
-		resources, err = h.service.GetPublicResources(limit, offset)
-	case resourceType != "":
-		resources, err = h.service.GetResourcesByType(models.ResourceType(resourceType), limit, offset)
-	case category != "":
-		resources, err = h.service.GetResourcesByCategory(category, limit, offset)
-	default:
-		resources, err = h.service.GetAllResources(limit, offset)
+	if filter.CreatedFrom, err = parseDateQuery(c.Query("created_from"), false); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid created_from date", err)
+		return
+	}
+	if filter.CreatedTo, err = parseDateQuery(c.Query("created_to"), true); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid created_to date", err)
+		return
+	}
+	if filter.MinSize, err = parseSizeQuery(c.Query("min_size")); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid min_size", err)
+		return
+	}
+	if filter.MaxSize, err = parseSizeQuery(c.Query("max_size")); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid max_size", err)
+		return
 	}
 
+	resources, total, err := h.service.ListResources(filter, limit, offset)
 	if err != nil {
+		if errors.Is(err, services.ErrInvalidResourceSort) {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid sort field", err)
+			return
+		}
 		utils.InternalErrorResponse(c, err)
 		return
 	}
 
-	// For simplicity, calculate total pages based on returned results
-	totalPages := int(math.Ceil(float64(len(resources)) / float64(limit)))
-	if len(resources) == limit {
-		totalPages = page + 1 // Assume there might be more pages
-	}
-
 	pagination := utils.Pagination{
 		Page:       page,
 		Limit:      limit,
-		TotalItems: int64(len(resources)),
-		TotalPages: totalPages,
+		TotalItems: total,
+		TotalPages: int(math.Ceil(float64(total) / float64(limit))),
 	}
 
 	utils.PaginatedSuccessResponse(c, "Resources retrieved successfully", resources, pagination)
 }
 
+// parseDateQuery parses an RFC3339 timestamp or a YYYY-MM-DD date. An empty value yields
+// nil. With endOfDay, a bare date is moved to the start of the following day so that
+// exclusive upper bounds include the whole day.
+func parseDateQuery(value string, endOfDay bool) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return &t, nil
+	}
+
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return nil, err
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1)
+	}
+	return &t, nil
+}
+
+// parseSizeQuery parses a non-negative byte count; an empty value yields 0
+func parseSizeQuery(value string) (int64, error) {
+	if value == "" {
+		return 0, nil
+	}
+
+	size, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	if size < 0 {
+		return 0, errors.New("size must not be negative")
+	}
+	return size, nil
+}
+
 // UpdateResource godoc
 // @Summary Update a resource
 // @Description Update an existing resource record
@@ -225,13 +339,18 @@ func (h *ResourceHandler) DeleteResource(c *gin.Context) {
 
 // DownloadResource godoc
 // @Summary Download a resource
-// @Description Download a resource file and increment download count
+// @Description Get a download URL for a resource and increment download count. Private resources
+// @Description require a user with uploads:read and return a short-lived presigned URL.
 // @Tags resources
 // @Accept json
 // @Produce json
+// @Security BearerAuth
 // @Param id path int true "Resource ID"
 // @Success 200 {object} utils.Response
+// @Failure 401 {object} utils.Response
+// @Failure 403 {object} utils.Response
 // @Failure 404 {object} utils.Response
+// @Failure 423 {object} utils.Response
 // @Failure 500 {object} utils.Response
 // @Router /api/v1/resources/{id}/download [post]
 func (h *ResourceHandler) DownloadResource(c *gin.Context) {
@@ -242,14 +361,12 @@ func (h *ResourceHandler) DownloadResource(c *gin.Context) {
 		return
 	}
 
-	resource, err := h.service.GetResourceByID(uint(id))
+	resource, err := h.service.GetDownloadableResource(uint(id), h.canReadPrivate(c))
 	if err != nil {
-		utils.NotFoundResponse(c, "Resource not found")
+		writeAccessError(c, err)
 		return
 	}
-
-	// Increment download count
-	go h.service.IncrementDownloadCount(uint(id))
+	h.service.LogAccess(resource.ID, resource.IsPublic, "download", c.GetUint("user_id"), c.ClientIP(), c.Request.Referer())
 
 	// Return resource with download URL
 	utils.SuccessResponse(c, "Resource download URL retrieved", map[string]interface{}{
@@ -258,6 +375,147 @@ func (h *ResourceHandler) DownloadResource(c *gin.Context) {
 	})
 }
 
+// GetResourceContent godoc
+// @Summary Stream resource content
+// @Description Stream a resource file through the API with support for Range, If-None-Match and
+// @Description If-Modified-Since. Private resources require a user with uploads:read.
+// @Tags resources
+// @Produce octet-stream
+// @Security BearerAuth
+// @Param id path int true "Resource ID"
+// @Param inline query bool false "Display inline instead of as an attachment" default(false)
+// @Param Range header string false "Byte range to return, e.g. bytes=0-1023"
+// @Param If-None-Match header string false "Return 304 if the ETag matches"
+// @Param If-Modified-Since header string false "Return 304 if the file has not changed since"
+// @Success 200 {file} binary
+// @Success 206 {file} binary
+// @Success 304 "Not modified"
+// @Failure 401 {object} utils.Response
+// @Failure 403 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Failure 416 {object} utils.Response
+// @Failure 423 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Router /api/resources/{id}/content [get]
+// @Router /api/resources/{id}/content [head]
+// @Router /api/v1/resources/{id}/content [get]
+// @Router /api/v1/resources/{id}/content [head]
+func (h *ResourceHandler) GetResourceContent(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid resource ID", err)
+		return
+	}
+
+	req := services.ObjectRequest{
+		Range:       c.GetHeader("Range"),
+		IfNoneMatch: c.GetHeader("If-None-Match"),
+	}
+	if since, err := http.ParseTime(c.GetHeader("If-Modified-Since")); err == nil && req.IfNoneMatch == "" {
+		// If-None-Match takes precedence over If-Modified-Since (RFC 9110)
+		req.IfModifiedSince = &since
+	}
+
+	resource, stream, err := h.service.OpenResourceContent(uint(id), h.canReadPrivate(c), req)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrResourcePrivate) && c.GetUint("user_id") == 0:
+			utils.ErrorResponse(c, http.StatusUnauthorized, "Authentication required to access this resource", err)
+		case errors.Is(err, services.ErrResourcePrivate):
+			utils.ErrorResponse(c, http.StatusForbidden, "Insufficient permissions to access this resource", err)
+		case errors.Is(err, services.ErrResourceNotFound):
+			utils.NotFoundResponse(c, "Resource not found")
+		case errors.Is(err, services.ErrUploadQuarantined):
+			utils.ErrorResponse(c, http.StatusLocked, "File is quarantined until its virus scan is clean", err)
+		case errors.Is(err, services.ErrInvalidRange) && resource != nil:
+			c.Header("Content-Range", "bytes */"+strconv.FormatInt(resource.Upload.FileSize, 10))
+			utils.ErrorResponse(c, http.StatusRequestedRangeNotSatisfiable, "Requested range not satisfiable", err)
+		default:
+			utils.InternalErrorResponse(c, err)
+		}
+		return
+	}
+
+	if stream.NotModified {
+		if stream.ETag != "" {
+			c.Header("ETag", stream.ETag)
+		}
+		c.Status(http.StatusNotModified)
+		return
+	}
+	defer stream.Body.Close()
+
+	disposition := "attachment"
+	if c.Query("inline") == "true" {
+		disposition = "inline"
+	}
+
+	contentType := stream.ContentType
+	if contentType == "" {
+		contentType = resource.Upload.ContentType
+	}
+
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Length", strconv.FormatInt(stream.ContentLength, 10))
+	c.Header("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": resource.Upload.OriginalName}))
+	c.Header("Accept-Ranges", "bytes")
+	if stream.ETag != "" {
+		c.Header("ETag", stream.ETag)
+	}
+	if stream.LastModified != nil {
+		c.Header("Last-Modified", stream.LastModified.UTC().Format(http.TimeFormat))
+	}
+
+	status := http.StatusOK
+	if stream.ContentRange != "" {
+		c.Header("Content-Range", stream.ContentRange)
+		status = http.StatusPartialContent
+	}
+
+	// Count a download once per file rather than once per range request
+	if status == http.StatusOK || strings.HasPrefix(req.Range, "bytes=0-") {
+		h.service.LogAccess(resource.ID, resource.IsPublic, "content", c.GetUint("user_id"), c.ClientIP(), c.Request.Referer())
+	}
+
+	c.Status(status)
+	if c.Request.Method == http.MethodHead {
+		return
+	}
+	if err := h.copyContent(c, stream.Body); err != nil {
+		log.Printf("Failed to stream resource %d: %v", resource.ID, err)
+	}
+}
+
+// copyContent streams body to the client, moving the write deadline forward before
+// each write so that slow clients are served while stalled ones are still dropped
+func (h *ResourceHandler) copyContent(c *gin.Context, body io.Reader) error {
+	if h.writeTimeout <= 0 {
+		_, err := io.Copy(c.Writer, body)
+		return err
+	}
+
+	controller := http.NewResponseController(c.Writer)
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := body.Read(buf)
+		if n > 0 {
+			if err := controller.SetWriteDeadline(time.Now().Add(h.writeTimeout)); err != nil {
+				return fmt.Errorf("failed to extend write deadline: %w", err)
+			}
+			if _, err := c.Writer.Write(buf[:n]); err != nil {
+				return err
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
 // GetResourceStats godoc
 // @Summary Get resource statistics
 // @Description Get statistics about resources
@@ -287,11 +545,11 @@ func (h *ResourceHandler) GetResourceStats(c *gin.Context) {
 // @Failure 500 {object} utils.Response
 // @Router /admin/resources/refresh-urls [post]
 func (h *ResourceHandler) RefreshExpiredURLs(c *gin.Context) {
-	err := h.service.RefreshExpiredURLs()
+	refreshed, err := h.service.RefreshExpiredURLs(c.Request.Context())
 	if err != nil {
 		utils.InternalErrorResponse(c, err)
 		return
 	}
 
-	utils.SuccessResponse(c, "URLs refreshed successfully", nil)
+	utils.SuccessResponse(c, "URLs refreshed successfully", gin.H{"refreshed": refreshed})
 }
@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"portfolio-be/internal/services"
+	"portfolio-be/pkg/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TagHandler struct {
+	service *services.TagService
+}
+
+func NewTagHandler(service *services.TagService) *TagHandler {
+	return &TagHandler{service: service}
+}
+
+// GetTags godoc
+// @Summary Get all tags
+// @Description Get tags with their usage counts, most used first. Only public, active resources that scanned clean, published content and active projects are counted.
+// @Tags tags
+// @Accept json
+// @Produce json
+// @Param type query string false "Only count tags used by this entity type (resource, content or project)"
+// @Success 200 {object} utils.Response{data=[]models.TagUsage}
+// @Failure 400 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Router /api/tags [get]
+func (h *TagHandler) GetTags(c *gin.Context) {
+	h.getTags(c, true)
+}
+
+// GetAllTags godoc
+// @Summary Get all tags with unfiltered counts (Admin only)
+// @Description Get tags with their usage counts, most used first, counting private, inactive and unpublished entities too
+// @Tags tags
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param type query string false "Only count tags used by this entity type (resource, content or project)"
+// @Success 200 {object} utils.Response{data=[]models.TagUsage}
+// @Failure 400 {object} utils.Response
+// @Failure 401 {object} utils.Response
+// @Failure 403 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Router /admin/tags [get]
+func (h *TagHandler) GetAllTags(c *gin.Context) {
+	h.getTags(c, false)
+}
+
+func (h *TagHandler) getTags(c *gin.Context, publicOnly bool) {
+	tags, err := h.service.GetTags(c.Query("type"), publicOnly)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidTagType) {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid tag type", err)
+			return
+		}
+		utils.InternalErrorResponse(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, "Tags retrieved successfully", tags)
+}
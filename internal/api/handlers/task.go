@@ -0,0 +1,209 @@
+package handlers
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+
+	"portfolio-be/internal/models"
+	"portfolio-be/internal/services"
+	"portfolio-be/pkg/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TaskHandler struct {
+	queue *services.TaskQueue
+}
+
+func NewTaskHandler(queue *services.TaskQueue) *TaskHandler {
+	return &TaskHandler{queue: queue}
+}
+
+// taskStatsResponse is the body of the task stats endpoint
+type taskStatsResponse struct {
+	Counts *models.TaskStats `json:"counts"`
+	Kinds  []string          `json:"kinds" example:"media.job,s3.delete"`
+}
+
+// GetTasks godoc
+// @Summary Get background tasks
+// @Description Get queued, running, succeeded and dead-lettered background tasks, newest first
+// @Tags tasks
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param status query string false "Filter by status" Enums(pending, running, succeeded, dead)
+// @Param kind query string false "Filter by task kind"
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(20)
+// @Success 200 {object} utils.PaginatedResponse{data=[]models.Task}
+// @Failure 400 {object} utils.Response
+// @Failure 401 {object} utils.Response
+// @Failure 403 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Router /admin/tasks [get]
+func (h *TaskHandler) GetTasks(c *gin.Context) {
+	status := c.Query("status")
+	switch status {
+	case "", models.TaskPending, models.TaskRunning, models.TaskSucceeded, models.TaskDead:
+	default:
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid status", errors.New("status must be pending, running, succeeded or dead"))
+		return
+	}
+
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	tasks, total, err := h.queue.List(status, c.Query("kind"), limit, (page-1)*limit)
+	if err != nil {
+		utils.InternalErrorResponse(c, err)
+		return
+	}
+
+	pagination := utils.Pagination{
+		Page:       page,
+		Limit:      limit,
+		TotalItems: total,
+		TotalPages: int(math.Ceil(float64(total) / float64(limit))),
+	}
+
+	utils.PaginatedSuccessResponse(c, "Tasks retrieved successfully", tasks, pagination)
+}
+
+// GetTaskStats godoc
+// @Summary Get task queue stats
+// @Description Count background tasks by status and list the task kinds this instance handles
+// @Tags tasks
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} utils.Response{data=taskStatsResponse}
+// @Failure 401 {object} utils.Response
+// @Failure 403 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Router /admin/tasks/stats [get]
+func (h *TaskHandler) GetTaskStats(c *gin.Context) {
+	stats, err := h.queue.Stats()
+	if err != nil {
+		utils.InternalErrorResponse(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, "Task stats retrieved successfully", taskStatsResponse{Counts: stats, Kinds: h.queue.Kinds()})
+}
+
+// GetTask godoc
+// @Summary Get a background task
+// @Description Get a background task with its payload, attempts and last error
+// @Tags tasks
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Task ID"
+// @Success 200 {object} utils.Response{data=models.Task}
+// @Failure 400 {object} utils.Response
+// @Failure 401 {object} utils.Response
+// @Failure 403 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Router /admin/tasks/{id} [get]
+func (h *TaskHandler) GetTask(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid task ID", err)
+		return
+	}
+
+	task, err := h.queue.Get(uint(id))
+	if err != nil {
+		writeTaskError(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, "Task retrieved successfully", task)
+}
+
+// RetryTask godoc
+// @Summary Retry a dead task
+// @Description Put a dead-lettered task back in the queue with a fresh set of attempts
+// @Tags tasks
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Task ID"
+// @Success 200 {object} utils.Response{data=models.Task}
+// @Failure 400 {object} utils.Response
+// @Failure 401 {object} utils.Response
+// @Failure 403 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Failure 409 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Router /admin/tasks/{id}/retry [post]
+func (h *TaskHandler) RetryTask(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid task ID", err)
+		return
+	}
+
+	task, err := h.queue.Retry(uint(id))
+	if err != nil {
+		writeTaskError(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, "Task queued for retry", task)
+}
+
+// DeleteTask godoc
+// @Summary Delete a task
+// @Description Delete a task that is not running, such as a dead task that should not be retried
+// @Tags tasks
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Task ID"
+// @Success 200 {object} utils.Response
+// @Failure 400 {object} utils.Response
+// @Failure 401 {object} utils.Response
+// @Failure 403 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Failure 409 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Router /admin/tasks/{id} [delete]
+func (h *TaskHandler) DeleteTask(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid task ID", err)
+		return
+	}
+
+	if err := h.queue.Delete(uint(id)); err != nil {
+		writeTaskError(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, "Task deleted successfully", nil)
+}
+
+// writeTaskError maps task queue errors to HTTP responses
+func writeTaskError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrTaskNotFound):
+		utils.NotFoundResponse(c, "Task not found")
+	case errors.Is(err, services.ErrTaskNotDead):
+		utils.ErrorResponse(c, http.StatusConflict, "Task is not dead-lettered", err)
+	case errors.Is(err, services.ErrTaskRunning):
+		utils.ErrorResponse(c, http.StatusConflict, "Task is running", err)
+	default:
+		utils.InternalErrorResponse(c, err)
+	}
+}
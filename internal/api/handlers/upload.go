@@ -1,23 +1,38 @@
 package handlers
 
 import (
+	"errors"
 	"math"
 	"net/http"
+	"portfolio-be/internal/api/middleware"
 	"portfolio-be/internal/services"
 	"portfolio-be/pkg/utils"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
 type UploadHandler struct {
-	service *services.UploadService
+	service    *services.UploadService
+	publicOnly bool
 }
 
 func NewUploadHandler(service *services.UploadService) *UploadHandler {
 	return &UploadHandler{service: service}
 }
 
+// NewPublicUploadHandler creates a handler for public endpoints, which only list
+// uploads shown on the public site unless the user has uploads:read
+func NewPublicUploadHandler(service *services.UploadService) *UploadHandler {
+	return &UploadHandler{service: service, publicOnly: true}
+}
+
+// listsPublicOnly reports whether the caller is limited to uploads shown on the public site
+func (h *UploadHandler) listsPublicOnly(c *gin.Context) bool {
+	return h.publicOnly && !c.GetBool(middleware.PermissionContextKey("uploads", "read"))
+}
+
 // UploadFile godoc
 // @Summary Upload a file
 // @Description Upload a file to S3 and save record to database
@@ -27,6 +42,9 @@ func NewUploadHandler(service *services.UploadService) *UploadHandler {
 // @Param file formData file true "File to upload"
 // @Success 201 {object} utils.Response
 // @Failure 400 {object} utils.Response
+// @Failure 403 {object} utils.Response
+// @Failure 413 {object} utils.Response
+// @Failure 415 {object} utils.Response
 // @Failure 500 {object} utils.Response
 // @Router /api/v1/uploads [post]
 func (h *UploadHandler) UploadFile(c *gin.Context) {
@@ -44,33 +62,48 @@ func (h *UploadHandler) UploadFile(c *gin.Context) {
 	}
 	defer file.Close()
 
-	// Check file size (max 10MB)
-	if header.Size > 10*1024*1024 {
-		utils.ErrorResponse(c, http.StatusBadRequest, "File size exceeds 10MB limit", nil)
-		return
-	}
-
-	// Upload file
-	upload, err := h.service.UploadFile(file, header)
+	// Upload file; size and type limits come from the user's upload quota
+	upload, err := h.service.UploadFile(file, header, c.GetUint("user_id"))
 	if err != nil {
-		utils.InternalErrorResponse(c, err)
+		writeUploadError(c, err)
 		return
 	}
 
 	utils.CreatedResponse(c, "File uploaded successfully", upload)
 }
 
+// writeUploadError writes the error response for a rejected or failed upload
+func writeUploadError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrFileTypeNotAllowed), errors.Is(err, services.ErrFileClassNotAllowed):
+		utils.ErrorResponse(c, http.StatusUnsupportedMediaType, "File type not allowed", err)
+	case errors.Is(err, services.ErrFileTooLarge):
+		utils.ErrorResponse(c, http.StatusRequestEntityTooLarge, "File too large", err)
+	case errors.Is(err, services.ErrImageTooLarge):
+		utils.ErrorResponse(c, http.StatusRequestEntityTooLarge, "Image dimensions too large", err)
+	case errors.Is(err, services.ErrQuotaExceeded):
+		utils.ErrorResponse(c, http.StatusForbidden, "Upload quota exceeded", err)
+	default:
+		utils.InternalErrorResponse(c, err)
+	}
+}
+
 // GetUpload godoc
 // @Summary Get upload by ID
-// @Description Get a single upload record by its ID
+// @Description Get a single upload record by its ID. Public endpoints only return clean uploads behind public, active resources unless the user has uploads:read.
 // @Tags upload
 // @Accept json
 // @Produce json
+// @Security BearerAuth
 // @Param id path int true "Upload ID"
 // @Success 200 {object} utils.Response
+// @Failure 401 {object} utils.Response
+// @Failure 403 {object} utils.Response
 // @Failure 404 {object} utils.Response
 // @Failure 500 {object} utils.Response
+// @Router /api/uploads/{id} [get]
 // @Router /api/v1/uploads/{id} [get]
+// @Router /admin/uploads/{id} [get]
 func (h *UploadHandler) GetUpload(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := strconv.ParseUint(idStr, 10, 32)
@@ -79,7 +112,7 @@ func (h *UploadHandler) GetUpload(c *gin.Context) {
 		return
 	}
 
-	upload, err := h.service.GetUploadByID(uint(id))
+	upload, err := h.service.GetUploadByID(uint(id), h.listsPublicOnly(c))
 	if err != nil {
 		utils.NotFoundResponse(c, "Upload not found")
 		return
@@ -90,15 +123,20 @@ func (h *UploadHandler) GetUpload(c *gin.Context) {
 
 // GetAllUploads godoc
 // @Summary Get all uploads
-// @Description Get a list of all upload records
+// @Description Get a list of upload records. Public endpoints only list clean uploads behind public, active resources unless the user has uploads:read.
 // @Tags upload
 // @Accept json
 // @Produce json
+// @Security BearerAuth
 // @Param page query int false "Page number" default(1)
 // @Param limit query int false "Items per page" default(10)
 // @Success 200 {object} utils.PaginatedResponse
+// @Failure 401 {object} utils.Response
+// @Failure 403 {object} utils.Response
 // @Failure 500 {object} utils.Response
+// @Router /api/uploads [get]
 // @Router /api/v1/uploads [get]
+// @Router /admin/uploads [get]
 func (h *UploadHandler) GetAllUploads(c *gin.Context) {
 	// Parse query parameters
 	pageStr := c.DefaultQuery("page", "1")
@@ -116,7 +154,7 @@ func (h *UploadHandler) GetAllUploads(c *gin.Context) {
 
 	offset := (page - 1) * limit
 
-	uploads, totalCount, err := h.service.GetUploadsWithCount(limit, offset)
+	uploads, totalCount, err := h.service.GetUploadsWithCount(h.listsPublicOnly(c), limit, offset)
 	if err != nil {
 		utils.InternalErrorResponse(c, err)
 		return
@@ -137,15 +175,20 @@ func (h *UploadHandler) GetAllUploads(c *gin.Context) {
 
 // GetAllUploadsWithSummary godoc
 // @Summary Get all uploads with summary
-// @Description Get a list of all upload records with summary statistics
+// @Description Get a list of upload records with summary statistics. Public endpoints only cover clean uploads behind public, active resources unless the user has uploads:read.
 // @Tags upload
 // @Accept json
 // @Produce json
+// @Security BearerAuth
 // @Param page query int false "Page number" default(1)
 // @Param limit query int false "Items per page" default(12)
 // @Success 200 {object} utils.Response
+// @Failure 401 {object} utils.Response
+// @Failure 403 {object} utils.Response
 // @Failure 500 {object} utils.Response
+// @Router /api/uploads/summary [get]
 // @Router /api/v1/uploads/summary [get]
+// @Router /admin/uploads/summary [get]
 func (h *UploadHandler) GetAllUploadsWithSummary(c *gin.Context) {
 	// Parse query parameters
 	pageStr := c.DefaultQuery("page", "1")
@@ -163,7 +206,7 @@ func (h *UploadHandler) GetAllUploadsWithSummary(c *gin.Context) {
 
 	offset := (page - 1) * limit
 
-	result, err := h.service.GetAllUploadsWithSummary(limit, offset)
+	result, err := h.service.GetAllUploadsWithSummary(h.listsPublicOnly(c), limit, offset)
 	if err != nil {
 		utils.InternalErrorResponse(c, err)
 		return
@@ -197,8 +240,10 @@ func (h *UploadHandler) GetAllUploadsWithSummary(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param id path int true "Upload ID"
+// @Param cascade query bool false "Also delete referencing resources and clear referencing URL fields"
 // @Success 200 {object} utils.Response
 // @Failure 404 {object} utils.Response
+// @Failure 409 {object} utils.Response
 // @Failure 500 {object} utils.Response
 // @Router /api/v1/uploads/{id} [delete]
 func (h *UploadHandler) DeleteUpload(c *gin.Context) {
@@ -209,9 +254,13 @@ func (h *UploadHandler) DeleteUpload(c *gin.Context) {
 		return
 	}
 
-	err = h.service.DeleteUpload(uint(id))
+	cascade := c.Query("cascade") == "true"
+
+	err = h.service.DeleteUpload(uint(id), cascade)
 	if err != nil {
-		if err.Error() == "record not found" {
+		if errors.Is(err, services.ErrUploadInUse) {
+			utils.ErrorResponse(c, http.StatusConflict, "Upload is still referenced", err)
+		} else if err.Error() == "record not found" {
 			utils.NotFoundResponse(c, "Upload not found")
 		} else {
 			utils.InternalErrorResponse(c, err)
@@ -221,3 +270,119 @@ func (h *UploadHandler) DeleteUpload(c *gin.Context) {
 
 	utils.SuccessResponse(c, "Upload deleted successfully", nil)
 }
+
+// GetDeduplicationReport godoc
+// @Summary Get upload deduplication report
+// @Description Get how much storage is saved by uploads sharing identical content
+// @Tags upload
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} utils.Response{data=models.DeduplicationReport}
+// @Failure 500 {object} utils.Response
+// @Router /admin/uploads/dedup-report [get]
+func (h *UploadHandler) GetDeduplicationReport(c *gin.Context) {
+	report, err := h.service.GetDeduplicationReport()
+	if err != nil {
+		utils.InternalErrorResponse(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, "Deduplication report retrieved successfully", report)
+}
+
+// GetUploadReferences godoc
+// @Summary Get upload references
+// @Description Get the resources and entities that reference an upload
+// @Tags upload
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Upload ID"
+// @Success 200 {object} utils.Response{data=[]models.UploadReference}
+// @Failure 400 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Router /admin/uploads/{id}/references [get]
+func (h *UploadHandler) GetUploadReferences(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid upload ID", err)
+		return
+	}
+
+	refs, err := h.service.GetUploadReferences(uint(id))
+	if err != nil {
+		utils.NotFoundResponse(c, "Upload not found")
+		return
+	}
+
+	utils.SuccessResponse(c, "Upload references retrieved successfully", refs)
+}
+
+// FindOrphans godoc
+// @Summary Find orphaned uploads
+// @Description Report uploads that nothing references and S3 objects without an upload record
+// @Tags upload
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param min_age_hours query int false "Ignore uploads and objects newer than this many hours" default(24)
+// @Success 200 {object} utils.Response{data=models.OrphanReport}
+// @Failure 500 {object} utils.Response
+// @Router /admin/uploads/orphans [get]
+func (h *UploadHandler) FindOrphans(c *gin.Context) {
+	h.orphans(c, false)
+}
+
+// CleanupOrphans godoc
+// @Summary Clean up orphaned uploads
+// @Description Delete uploads that nothing references and S3 objects without an upload record
+// @Tags upload
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param min_age_hours query int false "Ignore uploads and objects newer than this many hours" default(24)
+// @Success 200 {object} utils.Response{data=models.OrphanReport}
+// @Failure 500 {object} utils.Response
+// @Router /admin/uploads/orphans/cleanup [post]
+func (h *UploadHandler) CleanupOrphans(c *gin.Context) {
+	h.orphans(c, true)
+}
+
+func (h *UploadHandler) orphans(c *gin.Context, clean bool) {
+	minAgeHours, err := strconv.Atoi(c.DefaultQuery("min_age_hours", "24"))
+	if err != nil || minAgeHours < 0 {
+		minAgeHours = 24
+	}
+
+	report, err := h.service.FindOrphans(time.Duration(minAgeHours)*time.Hour, clean)
+	if err != nil {
+		utils.InternalErrorResponse(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, "Orphan report generated successfully", report)
+}
+
+// RebuildReferences godoc
+// @Summary Rebuild upload reference index
+// @Description Rescan resources, projects, testimonials, experiences and contents for upload references
+// @Tags upload
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Router /admin/uploads/references/rebuild [post]
+func (h *UploadHandler) RebuildReferences(c *gin.Context) {
+	count, err := h.service.RebuildReferences()
+	if err != nil {
+		utils.InternalErrorResponse(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, "Upload references rebuilt successfully", map[string]int{
+		"references": count,
+	})
+}
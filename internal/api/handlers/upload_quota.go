@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"portfolio-be/internal/models"
+	"portfolio-be/internal/services"
+	"portfolio-be/pkg/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type UploadQuotaHandler struct {
+	service *services.UploadQuotaService
+}
+
+func NewUploadQuotaHandler(service *services.UploadQuotaService) *UploadQuotaHandler {
+	return &UploadQuotaHandler{service: service}
+}
+
+// GetUsage godoc
+// @Summary Get upload usage
+// @Description Get a user's upload statistics against their effective quota. Defaults to the current user.
+// @Tags upload
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param user_id query int false "User ID"
+// @Success 200 {object} utils.Response{data=models.UploadUsage}
+// @Failure 400 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Router /admin/uploads/usage [get]
+func (h *UploadQuotaHandler) GetUsage(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	if value := c.Query("user_id"); value != "" {
+		id, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid user ID", err)
+			return
+		}
+		userID = uint(id)
+	}
+
+	usage, err := h.service.GetUsage(userID)
+	if err != nil {
+		if errors.Is(err, services.ErrQuotaUserNotFound) {
+			utils.NotFoundResponse(c, "User not found")
+			return
+		}
+		utils.InternalErrorResponse(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, "Upload usage retrieved successfully", usage)
+}
+
+// GetQuotas godoc
+// @Summary Get upload quotas
+// @Description Get all role and user upload quotas
+// @Tags upload
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} utils.Response{data=[]models.UploadQuotaResponse}
+// @Failure 500 {object} utils.Response
+// @Router /admin/uploads/quotas [get]
+func (h *UploadQuotaHandler) GetQuotas(c *gin.Context) {
+	quotas, err := h.service.GetQuotas()
+	if err != nil {
+		utils.InternalErrorResponse(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, "Upload quotas retrieved successfully", quotas)
+}
+
+// SaveQuota godoc
+// @Summary Set an upload quota
+// @Description Create or replace the upload quota of a role or a user. Zero limits and empty classes mean unlimited.
+// @Tags upload
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param quota body models.UploadQuotaRequest true "Quota data"
+// @Success 200 {object} utils.Response{data=models.UploadQuotaResponse}
+// @Failure 400 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Router /admin/uploads/quotas [put]
+func (h *UploadQuotaHandler) SaveQuota(c *gin.Context) {
+	var req models.UploadQuotaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationErrorResponse(c, err)
+		return
+	}
+
+	quota, err := h.service.SaveQuota(&req)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidQuota) {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid quota", err)
+			return
+		}
+		utils.InternalErrorResponse(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, "Upload quota saved successfully", quota)
+}
+
+// DeleteQuota godoc
+// @Summary Delete an upload quota
+// @Description Delete a role or user upload quota
+// @Tags upload
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Quota ID"
+// @Success 200 {object} utils.Response
+// @Failure 400 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Router /admin/uploads/quotas/{id} [delete]
+func (h *UploadQuotaHandler) DeleteQuota(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid quota ID", err)
+		return
+	}
+
+	if err := h.service.DeleteQuota(uint(id)); err != nil {
+		if errors.Is(err, services.ErrQuotaNotFound) {
+			utils.NotFoundResponse(c, "Quota not found")
+			return
+		}
+		utils.InternalErrorResponse(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, "Upload quota deleted successfully", nil)
+}
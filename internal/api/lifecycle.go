@@ -0,0 +1,98 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Lifecycle tracks whether the server is ready for traffic, starts the background
+// workers wired by SetupRouter and stops the background services
+type Lifecycle struct {
+	db       *gorm.DB
+	ready    atomic.Bool
+	starters []starter
+	stoppers []stopper
+}
+
+// starter is a background worker started by Start
+type starter struct {
+	name  string
+	start func()
+}
+
+// stopper is a background service stopped during shutdown
+type stopper struct {
+	name string
+	stop func(ctx context.Context) error
+}
+
+// onStart registers a background worker; workers start in registration order
+func (l *Lifecycle) onStart(name string, start func()) {
+	l.starters = append(l.starters, starter{name: name, start: start})
+}
+
+// Start starts the background workers
+func (l *Lifecycle) Start() {
+	for _, s := range l.starters {
+		log.Printf("Starting %s", s.name)
+		s.start()
+	}
+}
+
+// onShutdown registers a background service; services stop in registration order
+func (l *Lifecycle) onShutdown(name string, stop func(ctx context.Context) error) {
+	l.stoppers = append(l.stoppers, stopper{name: name, stop: stop})
+}
+
+// SetReady flips the readiness reported by /ready
+func (l *Lifecycle) SetReady(ready bool) {
+	l.ready.Store(ready)
+}
+
+// Shutdown stops the background services in order, sharing the deadline of ctx
+func (l *Lifecycle) Shutdown(ctx context.Context) error {
+	var errs []error
+	for _, s := range l.stoppers {
+		if err := s.stop(ctx); err != nil {
+			log.Printf("Failed to stop %s: %v", s.name, err)
+			errs = append(errs, fmt.Errorf("%s: %w", s.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// readiness reports 503 while the server starts, drains or cannot reach the database,
+// so load balancers stop sending it traffic
+func (l *Lifecycle) readiness(c *gin.Context) {
+	if !l.ready.Load() {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"status":  "unavailable",
+			"message": "Server is starting or shutting down",
+		})
+		return
+	}
+
+	sqlDB, err := l.db.DB()
+	if err == nil {
+		err = sqlDB.PingContext(c.Request.Context())
+	}
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"status":  "unavailable",
+			"message": "Database is unreachable",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "ready",
+		"message": "Portfolio Backend API is ready",
+	})
+}
@@ -42,6 +42,23 @@ func AuthMiddleware(jwtService *services.JWTService) gin.HandlerFunc {
 	}
 }
 
+// OptionalAuthMiddleware sets user info in context when a valid Bearer token is
+// present, and lets anonymous requests through otherwise
+func OptionalAuthMiddleware(jwtService *services.JWTService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		parts := strings.SplitN(c.GetHeader("Authorization"), " ", 2)
+		if len(parts) == 2 && parts[0] == "Bearer" {
+			if claims, err := jwtService.ValidateToken(parts[1]); err == nil {
+				c.Set("user_id", claims.UserID)
+				c.Set("username", claims.Username)
+				c.Set("role", claims.Role)
+			}
+		}
+
+		c.Next()
+	}
+}
+
 func AdminMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		role, exists := c.Get("role")
@@ -68,6 +68,26 @@ func (m *PermissionMiddleware) RequirePermission(resource, action string) gin.Ha
 	}
 }
 
+// PermissionContextKey returns the context key set by MarkPermission
+func PermissionContextKey(resource, action string) string {
+	return fmt.Sprintf("permission:%s:%s", resource, action)
+}
+
+// MarkPermission never rejects a request; it records in the context whether the
+// optionally authenticated user has the given permission
+func (m *PermissionMiddleware) MarkPermission(resource, action string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if id, ok := c.Get("user_id"); ok {
+			if userID, ok := id.(uint); ok {
+				hasPermission, err := m.checkUserPermission(userID, resource, action)
+				c.Set(PermissionContextKey(resource, action), err == nil && hasPermission)
+			}
+		}
+
+		c.Next()
+	}
+}
+
 // RequireAnyPermission checks if the authenticated user has any of the specified permissions
 func (m *PermissionMiddleware) RequireAnyPermission(permissions []string) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -212,7 +232,7 @@ func (m *PermissionMiddleware) GetUserPermissions(userID uint) ([]string, error)
 	// Admin role has all permissions
 	if user.Role == "admin" || (user.UserRole != nil && user.UserRole.Name == "admin") {
 		// Return all possible permissions for admin
-		resources := []string{"users", "roles", "permissions", "projects", "technologies", "experiences", "testimonials", "contacts", "services", "uploads"}
+		resources := []string{"users", "roles", "permissions", "projects", "technologies", "experiences", "testimonials", "contacts", "services", "uploads", "jobs", "tasks"}
 		actions := []string{"create", "read", "update", "delete"}
 
 		for _, resource := range resources {
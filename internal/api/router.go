@@ -1,6 +1,8 @@
 package api
 
 import (
+	"fmt"
+	"log"
 	"net/http"
 	"portfolio-be/internal/api/handlers"
 	"portfolio-be/internal/api/middleware"
@@ -14,14 +16,33 @@ import (
 	"gorm.io/gorm"
 )
 
-func SetupRouter(db *gorm.DB, s3Service *services.S3Service, cfg *config.Config) *gin.Engine {
-	// Set Gin mode
-	gin.SetMode(gin.ReleaseMode)
-
+// newEngine creates the Gin engine with the global middleware. Client IPs are taken from
+// X-Forwarded-For and X-Real-IP only on requests from the configured trusted proxies;
+// with none configured, the address of the connection is used, so that clients cannot
+// spoof their IP to get around the rate limits.
+func newEngine(cfg config.ServerConfig) (*gin.Engine, error) {
 	router := gin.New()
+	if err := router.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		return nil, fmt.Errorf("invalid trusted proxies: %w", err)
+	}
 	router.Use(gin.Logger())
 	router.Use(gin.Recovery())
 	router.Use(middleware.CORSMiddleware())
+	return router, nil
+}
+
+// SetupRouter wires repositories, services and routes. The returned lifecycle reports
+// readiness and stops the background services.
+func SetupRouter(db *gorm.DB, s3Service *services.S3Service, cfg *config.Config) (*gin.Engine, *Lifecycle) {
+	// Set Gin mode
+	gin.SetMode(gin.ReleaseMode)
+
+	router, err := newEngine(cfg.ServerConfig)
+	if err != nil {
+		log.Fatalf("Failed to create router: %v", err)
+	}
+
+	lifecycle := &Lifecycle{db: db}
 
 	// Health check endpoint
 	router.GET("/health", func(c *gin.Context) {
@@ -31,6 +52,9 @@ func SetupRouter(db *gorm.DB, s3Service *services.S3Service, cfg *config.Config)
 		})
 	})
 
+	// Readiness endpoint, unavailable while starting and draining
+	router.GET("/ready", lifecycle.readiness)
+
 	// Swagger documentation endpoint
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
@@ -45,45 +69,109 @@ func SetupRouter(db *gorm.DB, s3Service *services.S3Service, cfg *config.Config)
 	testimonialRepo := repository.NewTestimonialRepository(db)
 	userRepo := repository.NewUserRepository(db)
 	contactRepo := repository.NewContactRepository(db)
+	contactReplyRepo := repository.NewContactReplyRepository(db)
+	contactLabelRepo := repository.NewContactLabelRepository(db)
+	contactErasureRepo := repository.NewContactErasureRepository(db)
+	replyTemplateRepo := repository.NewReplyTemplateRepository(db)
 	roleRepo := repository.NewRoleRepository(db)
 	permissionRepo := repository.NewPermissionRepository(db)
+	uploadReferenceRepo := repository.NewUploadReferenceRepository(db)
+	collectionRepo := repository.NewCollectionRepository(db)
+	tagRepo := repository.NewTagRepository(db)
+	analyticsRepo := repository.NewAnalyticsRepository(db)
+	uploadQuotaRepo := repository.NewUploadQuotaRepository(db)
+	jobRepo := repository.NewJobRepository(db)
+	taskRepo := repository.NewTaskRepository(db)
+	dataMigrationRepo := repository.NewDataMigrationRepository(db)
+
+	// Initialize the task queue; its workers are started by lifecycle.Start
+	taskQueue := services.NewTaskQueue(taskRepo, cfg.TaskConfig, cfg.JobsConfig.InstanceID)
+	s3Service.RegisterTasks(taskQueue)
 
 	// Initialize services
-	contentService := services.NewContentService(contentRepo)
-	uploadService := services.NewUploadService(uploadRepo, s3Service)
-	resourceService := services.NewResourceService(resourceRepo, uploadRepo, s3Service)
-	experienceService := services.NewExperienceService(experienceRepo)
+	uploadReferenceService := services.NewUploadReferenceService(uploadReferenceRepo, uploadRepo, resourceRepo)
+	tagService := services.NewTagService(tagRepo)
+	analyticsService := services.NewAnalyticsService(analyticsRepo, resourceRepo, cfg.AnalyticsConfig)
+	contentService := services.NewContentService(contentRepo, uploadReferenceService, tagService)
+	mediaProber := services.NewMediaProber(cfg.MediaConfig.FFprobePath)
+	mediaJobs := services.NewMediaJobRunner(cfg.MediaConfig, uploadRepo, s3Service, taskQueue)
+	scanner := services.NewScanner(cfg.ScanConfig)
+	uploadQuotaService := services.NewUploadQuotaService(uploadQuotaRepo, uploadRepo, userRepo, cfg.QuotaConfig)
+	uploadService := services.NewUploadService(uploadRepo, s3Service, uploadReferenceService, mediaProber, mediaJobs, scanner, uploadQuotaService, cfg.ImageConfig)
+	resourceService := services.NewResourceService(resourceRepo, uploadRepo, s3Service, uploadService, uploadReferenceService, tagService, analyticsService)
+	archiveService := services.NewArchiveService(uploadService, resourceService, cfg.ArchiveConfig)
+	collectionService := services.NewCollectionService(collectionRepo, resourceRepo, projectRepo, resourceService)
+	experienceService := services.NewExperienceService(experienceRepo, uploadReferenceService)
 	serviceService := services.NewServiceService(serviceRepo)
 	technologyService := services.NewTechnologyService(technologyRepo)
-	projectService := services.NewProjectService(projectRepo)
-	testimonialService := services.NewTestimonialService(testimonialRepo)
+	projectService := services.NewProjectService(projectRepo, uploadReferenceService, tagService)
+	testimonialService := services.NewTestimonialService(testimonialRepo, uploadReferenceService)
 	jwtService := services.NewJWTService(cfg.JWTConfig.SecretKey, cfg.JWTConfig.Issuer)
 	authService := services.NewAuthService(userRepo, jwtService)
-	contactService := services.NewContactService(contactRepo)
+	mailer, err := services.NewMailer(cfg.MailConfig)
+	if err != nil {
+		log.Fatalf("Failed to initialize mailer: %v", err)
+	}
+	mailService := services.NewMailService(mailer, taskQueue, cfg.MailConfig)
+	contactService := services.NewContactService(contactRepo, contactReplyRepo, contactLabelRepo, userRepo, cfg.ContactConfig, services.NewSpamScorer(cfg.ContactConfig), services.NewCaptchaVerifier(cfg.ContactConfig), mailService)
+	contactReplyService := services.NewContactReplyService(contactRepo, contactReplyRepo, replyTemplateRepo, mailService, taskQueue)
+	contactPrivacyService := services.NewContactPrivacyService(contactRepo, contactReplyRepo, contactErasureRepo, taskQueue, mailService, cfg.ContactConfig)
 	roleService := services.NewRoleService(roleRepo, permissionRepo, userRepo)
 	permissionService := services.NewPermissionService(permissionRepo)
 
+	// One-time data migrations, recorded in the database so that they run once rather
+	// than on every start of every instance
+	dataMigrations := services.NewDataMigrationService(dataMigrationRepo, cfg.JobsConfig.InstanceID)
+	// Rewrite upload URLs stored on entities to the public base URL, again whenever it changes
+	dataMigrations.Register("upload-urls:"+s3Service.GetFileURL(""), uploadService.NormalizeURLs)
+	// Index upload references held by rows written before the index existed
+	dataMigrations.Register("upload-references", uploadReferenceService.Rebuild)
+	// Parse tag strings of rows written before tags were normalized
+	dataMigrations.Register("tags", tagService.Backfill)
+	// Spell out symbols in the slugs of tags saved before, e.g. "C++" apart from "C"
+	dataMigrations.Register("tag-slugs", tagService.Reslug)
+	// Without a scanner, clear the pending scan status AutoMigrate gave existing uploads
+	// rather than quarantining them; a real scanner scans them on its startup run
+	if !uploadService.ScanningEnabled() {
+		dataMigrations.Register("upload-scan-status", uploadService.MarkUnscannedClean)
+	}
+	dataMigrations.Run()
+
 	// Initialize middleware
 	permissionMiddleware := middleware.NewPermissionMiddleware(userRepo)
 
 	// Initialize Cron Service
-	cronService := services.NewCronService(resourceService, uploadService)
-	// Start cron service in background
-	go cronService.Start()
+	scheduler := services.NewScheduler(jobRepo, cfg.JobsConfig)
+	cronService := services.NewCronService(scheduler, resourceService, uploadService, contactPrivacyService, cfg.CleanupConfig, cfg.ScanConfig, cfg.JobsConfig)
+	if err := cronService.Register(); err != nil {
+		log.Fatalf("Failed to register scheduled jobs: %v", err)
+	}
 
 	// Initialize handlers
 	contentHandler := handlers.NewContentHandler(contentService)
 	uploadHandler := handlers.NewUploadHandler(uploadService)
+	publicUploadHandler := handlers.NewPublicUploadHandler(uploadService)
+	cronHandler := handlers.NewCronHandler(cronService)
+	jobHandler := handlers.NewJobHandler(scheduler)
+	taskHandler := handlers.NewTaskHandler(taskQueue)
 	resourceHandler := handlers.NewResourceHandler(resourceService)
+	publicResourceHandler := handlers.NewPublicResourceHandler(resourceService, cfg.ServerConfig.WriteTimeout)
+	collectionHandler := handlers.NewCollectionHandler(collectionService)
+	tagHandler := handlers.NewTagHandler(tagService)
+	analyticsHandler := handlers.NewAnalyticsHandler(analyticsService)
+	uploadQuotaHandler := handlers.NewUploadQuotaHandler(uploadQuotaService)
+	archiveHandler := handlers.NewArchiveHandler(archiveService)
 	experienceHandler := handlers.NewExperienceHandler(experienceService)
 	serviceHandler := handlers.NewServiceHandler(serviceService)
 	technologyHandler := handlers.NewTechnologyHandler(technologyService)
 	projectHandler := handlers.NewProjectHandler(projectService)
 	testimonialHandler := handlers.NewTestimonialHandler(testimonialService)
-	portfolioHandler := handlers.NewPortfolioHandler(experienceService, serviceService, technologyService, projectService, testimonialService)
+	portfolioHandler := handlers.NewPortfolioHandler(experienceService, serviceService, technologyService, projectService, testimonialService, collectionService)
 	authHandler := handlers.NewAuthHandler(authService, permissionMiddleware)
 	userHandler := handlers.NewUserHandler(userRepo)
 	contactHandler := handlers.NewContactHandler(contactService)
+	contactReplyHandler := handlers.NewContactReplyHandler(contactReplyService)
+	contactPrivacyHandler := handlers.NewContactPrivacyHandler(contactPrivacyService)
 	statsHandler := handlers.NewStatsHandler(projectService, experienceService, technologyService, serviceService, testimonialService, contactService)
 	adminOrderHandler := handlers.NewAdminOrderHandler(projectService, experienceService, technologyService, serviceService, testimonialService)
 	roleHandler := handlers.NewRoleHandler(roleService)
@@ -174,15 +262,48 @@ func SetupRouter(db *gorm.DB, s3Service *services.S3Service, cfg *config.Config)
 
 		// Contact management
 		admin.GET("/contacts", permissionMiddleware.RequirePermission("contacts", "read"), contactHandler.GetContacts)
-		admin.GET("/contacts/:id", permissionMiddleware.RequirePermission("contacts", "read"), contactHandler.GetContact)
+		admin.GET("/contacts/:id", permissionMiddleware.RequirePermission("contacts", "read"), permissionMiddleware.MarkPermission("contacts", "update"), contactHandler.GetContact)
 		admin.PUT("/contacts/:id", permissionMiddleware.RequirePermission("contacts", "update"), contactHandler.UpdateContact)
 		admin.DELETE("/contacts/:id", permissionMiddleware.RequirePermission("contacts", "delete"), contactHandler.DeleteContact)
 		admin.GET("/contacts/unread-count", permissionMiddleware.RequirePermission("contacts", "read"), contactHandler.GetUnreadCount)
 		admin.PATCH("/contacts/:id/mark-read", permissionMiddleware.RequirePermission("contacts", "update"), contactHandler.MarkAsRead)
-
-		// Upload management
+		admin.PATCH("/contacts/:id/mark-unread", permissionMiddleware.RequirePermission("contacts", "update"), contactHandler.MarkAsUnread)
+		admin.PUT("/contacts/:id/assignee", permissionMiddleware.RequirePermission("contacts", "update"), contactHandler.AssignContact)
+		admin.POST("/contacts/bulk", permissionMiddleware.RequirePermission("contacts", "update"), contactHandler.BulkUpdateContacts)
+		admin.POST("/contacts/bulk-delete", permissionMiddleware.RequirePermission("contacts", "delete"), contactHandler.BulkDeleteContacts)
+		admin.GET("/contacts/labels", permissionMiddleware.RequirePermission("contacts", "read"), contactHandler.GetContactLabels)
+		admin.POST("/contacts/labels", permissionMiddleware.RequirePermission("contacts", "create"), contactHandler.CreateContactLabel)
+		admin.PUT("/contacts/labels/:id", permissionMiddleware.RequirePermission("contacts", "update"), contactHandler.UpdateContactLabel)
+		admin.DELETE("/contacts/labels/:id", permissionMiddleware.RequirePermission("contacts", "delete"), contactHandler.DeleteContactLabel)
+		admin.GET("/contacts/export", permissionMiddleware.RequirePermission("contacts", "read"), contactPrivacyHandler.ExportContacts)
+		admin.POST("/contacts/erase", permissionMiddleware.RequirePermission("contacts", "delete"), contactPrivacyHandler.EraseContacts)
+		admin.GET("/contacts/erasures", permissionMiddleware.RequirePermission("contacts", "read"), contactPrivacyHandler.GetErasures)
+		admin.POST("/contacts/:id/replies", permissionMiddleware.RequirePermission("contacts", "update"), contactReplyHandler.CreateReply)
+		admin.GET("/contacts/reply-templates", permissionMiddleware.RequirePermission("contacts", "read"), contactReplyHandler.GetReplyTemplates)
+		admin.POST("/contacts/reply-templates", permissionMiddleware.RequirePermission("contacts", "create"), contactReplyHandler.CreateReplyTemplate)
+		admin.GET("/contacts/reply-templates/:id", permissionMiddleware.RequirePermission("contacts", "read"), contactReplyHandler.GetReplyTemplate)
+		admin.PUT("/contacts/reply-templates/:id", permissionMiddleware.RequirePermission("contacts", "update"), contactReplyHandler.UpdateReplyTemplate)
+		admin.DELETE("/contacts/reply-templates/:id", permissionMiddleware.RequirePermission("contacts", "delete"), contactReplyHandler.DeleteReplyTemplate)
+
+		// Upload management; unlike the public listings these include every file, also
+		// those behind private and inactive resources
+		admin.GET("/uploads", permissionMiddleware.RequirePermission("uploads", "read"), uploadHandler.GetAllUploads)
+		admin.GET("/uploads/summary", permissionMiddleware.RequirePermission("uploads", "read"), uploadHandler.GetAllUploadsWithSummary)
+		admin.GET("/uploads/:id", permissionMiddleware.RequirePermission("uploads", "read"), uploadHandler.GetUpload)
 		admin.POST("/uploads", permissionMiddleware.RequirePermission("uploads", "create"), uploadHandler.UploadFile)
+		admin.POST("/uploads/archive", permissionMiddleware.RequirePermission("uploads", "create"), archiveHandler.ImportArchive)
 		admin.DELETE("/uploads/:id", permissionMiddleware.RequirePermission("uploads", "delete"), uploadHandler.DeleteUpload)
+		admin.GET("/uploads/dedup-report", permissionMiddleware.RequirePermission("uploads", "read"), uploadHandler.GetDeduplicationReport)
+		admin.GET("/uploads/:id/references", permissionMiddleware.RequirePermission("uploads", "read"), uploadHandler.GetUploadReferences)
+		admin.POST("/uploads/references/rebuild", permissionMiddleware.RequirePermission("uploads", "update"), uploadHandler.RebuildReferences)
+		admin.GET("/uploads/orphans", permissionMiddleware.RequirePermission("uploads", "read"), uploadHandler.FindOrphans)
+		admin.POST("/uploads/orphans/cleanup", permissionMiddleware.RequirePermission("uploads", "delete"), uploadHandler.CleanupOrphans)
+		admin.POST("/uploads/expired/cleanup", permissionMiddleware.RequirePermission("uploads", "delete"), cronHandler.CleanupExpiredUploads)
+		admin.POST("/uploads/scan", permissionMiddleware.RequirePermission("uploads", "update"), cronHandler.RescanUploads)
+		admin.GET("/uploads/usage", permissionMiddleware.RequirePermission("uploads", "read"), uploadQuotaHandler.GetUsage)
+		admin.GET("/uploads/quotas", permissionMiddleware.RequirePermission("uploads", "read"), uploadQuotaHandler.GetQuotas)
+		admin.PUT("/uploads/quotas", permissionMiddleware.RequirePermission("users", "update"), uploadQuotaHandler.SaveQuota)
+		admin.DELETE("/uploads/quotas/:id", permissionMiddleware.RequirePermission("users", "update"), uploadQuotaHandler.DeleteQuota)
 
 		// Resource management
 		admin.POST("/resources", permissionMiddleware.RequirePermission("uploads", "create"), resourceHandler.CreateResource)
@@ -191,8 +312,20 @@ func SetupRouter(db *gorm.DB, s3Service *services.S3Service, cfg *config.Config)
 		admin.PUT("/resources/:id", permissionMiddleware.RequirePermission("uploads", "update"), resourceHandler.UpdateResource)
 		admin.DELETE("/resources/:id", permissionMiddleware.RequirePermission("uploads", "delete"), resourceHandler.DeleteResource)
 		admin.GET("/resources/stats", permissionMiddleware.RequirePermission("uploads", "read"), resourceHandler.GetResourceStats)
+		admin.GET("/resources/:id/analytics", permissionMiddleware.RequirePermission("uploads", "read"), analyticsHandler.GetResourceAnalytics)
 		admin.POST("/resources/refresh-urls", permissionMiddleware.RequirePermission("uploads", "update"), resourceHandler.RefreshExpiredURLs)
 
+		// Collection management
+		admin.GET("/collections", permissionMiddleware.RequirePermission("uploads", "read"), collectionHandler.GetCollections)
+		admin.POST("/collections", permissionMiddleware.RequirePermission("uploads", "create"), collectionHandler.CreateCollection)
+		admin.GET("/collections/:id", permissionMiddleware.RequirePermission("uploads", "read"), collectionHandler.GetCollection)
+		admin.PUT("/collections/:id", permissionMiddleware.RequirePermission("uploads", "update"), collectionHandler.UpdateCollection)
+		admin.DELETE("/collections/:id", permissionMiddleware.RequirePermission("uploads", "delete"), collectionHandler.DeleteCollection)
+		admin.POST("/collections/:id/items", permissionMiddleware.RequirePermission("uploads", "update"), collectionHandler.AddCollectionItem)
+		admin.PUT("/collections/:id/items/order", permissionMiddleware.RequirePermission("uploads", "update"), collectionHandler.ReorderCollectionItems)
+		admin.PUT("/collections/:id/items/:itemId", permissionMiddleware.RequirePermission("uploads", "update"), collectionHandler.UpdateCollectionItem)
+		admin.DELETE("/collections/:id/items/:itemId", permissionMiddleware.RequirePermission("uploads", "update"), collectionHandler.RemoveCollectionItem)
+
 		// Order management
 		admin.PUT("/projects/order", permissionMiddleware.RequirePermission("projects", "update"), adminOrderHandler.UpdateProjectsOrder)
 		admin.PUT("/experiences/order", permissionMiddleware.RequirePermission("experiences", "update"), adminOrderHandler.UpdateExperiencesOrder)
@@ -200,6 +333,23 @@ func SetupRouter(db *gorm.DB, s3Service *services.S3Service, cfg *config.Config)
 		admin.PUT("/services/order", permissionMiddleware.RequirePermission("services", "update"), adminOrderHandler.UpdateServicesOrder)
 		admin.PUT("/testimonials/order", permissionMiddleware.RequirePermission("testimonials", "update"), adminOrderHandler.UpdateTestimonialsOrder)
 
+		// Scheduled jobs
+		admin.GET("/jobs", permissionMiddleware.RequirePermission("jobs", "read"), jobHandler.GetJobs)
+		admin.GET("/jobs/:name/runs", permissionMiddleware.RequirePermission("jobs", "read"), jobHandler.GetJobRuns)
+		admin.POST("/jobs/:name/run", permissionMiddleware.RequirePermission("jobs", "update"), jobHandler.RunJob)
+		admin.POST("/jobs/:name/pause", permissionMiddleware.RequirePermission("jobs", "update"), jobHandler.PauseJob)
+		admin.POST("/jobs/:name/resume", permissionMiddleware.RequirePermission("jobs", "update"), jobHandler.ResumeJob)
+
+		// Background task queue
+		admin.GET("/tasks", permissionMiddleware.RequirePermission("tasks", "read"), taskHandler.GetTasks)
+		admin.GET("/tasks/stats", permissionMiddleware.RequirePermission("tasks", "read"), taskHandler.GetTaskStats)
+		admin.GET("/tasks/:id", permissionMiddleware.RequirePermission("tasks", "read"), taskHandler.GetTask)
+		admin.POST("/tasks/:id/retry", permissionMiddleware.RequirePermission("tasks", "update"), taskHandler.RetryTask)
+		admin.DELETE("/tasks/:id", permissionMiddleware.RequirePermission("tasks", "delete"), taskHandler.DeleteTask)
+
+		// Tags with counts that include private and unpublished entities
+		admin.GET("/tags", permissionMiddleware.RequireAnyPermission([]string{"uploads:read", "contents:read", "projects:read"}), tagHandler.GetAllTags)
+
 		// Stats (read-only for most admins)
 		admin.GET("/stats", permissionMiddleware.RequireAnyPermission([]string{"projects:read", "experiences:read", "technologies:read", "services:read", "testimonials:read", "contacts:read"}), statsHandler.GetCounts)
 	}
@@ -231,22 +381,38 @@ func SetupRouter(db *gorm.DB, s3Service *services.S3Service, cfg *config.Config)
 		api.GET("/testimonials", testimonialHandler.GetTestimonials)
 		api.GET("/testimonials/:id", testimonialHandler.GetTestimonial)
 
+		// Tag routes
+		api.GET("/tags", tagHandler.GetTags)
+
 		// Contact routes (for submitting contact forms)
+		api.GET("/contacts/form-token", contactHandler.GetFormToken)
 		api.POST("/contacts", contactHandler.CreateContact)
 
 		// Stats routes
 		api.GET("/stats/counts", statsHandler.GetCounts)
 
-		// Upload routes
-		api.GET("/uploads", uploadHandler.GetAllUploads)
-		api.GET("/uploads/:id", uploadHandler.GetUpload)
-		api.GET("/uploads/summary", uploadHandler.GetAllUploadsWithSummary)
-
-		// Resource routes (public read-only)
-		api.GET("/resources", resourceHandler.GetAllResources)
-		api.GET("/resources/:id", resourceHandler.GetResource)
-		api.POST("/resources/:id/download", resourceHandler.DownloadResource)
-		api.GET("/resources/stats", resourceHandler.GetResourceStats)
+		// Upload routes (public read-only, uploads not shown on the site need uploads:read)
+		publicUploads := api.Group("/uploads")
+		publicUploads.Use(middleware.OptionalAuthMiddleware(jwtService))
+		publicUploads.Use(permissionMiddleware.MarkPermission("uploads", "read"))
+		{
+			publicUploads.GET("", publicUploadHandler.GetAllUploads)
+			publicUploads.GET("/summary", publicUploadHandler.GetAllUploadsWithSummary)
+			publicUploads.GET("/:id", publicUploadHandler.GetUpload)
+		}
+
+		// Resource routes (public read-only, private resources need uploads:read)
+		publicResources := api.Group("/resources")
+		publicResources.Use(middleware.OptionalAuthMiddleware(jwtService))
+		publicResources.Use(permissionMiddleware.MarkPermission("uploads", "read"))
+		{
+			publicResources.GET("", publicResourceHandler.GetAllResources)
+			publicResources.GET("/:id", publicResourceHandler.GetResource)
+			publicResources.POST("/:id/download", publicResourceHandler.DownloadResource)
+			publicResources.GET("/:id/content", publicResourceHandler.GetResourceContent)
+			publicResources.HEAD("/:id/content", publicResourceHandler.GetResourceContent)
+			publicResources.GET("/stats", publicResourceHandler.GetResourceStats)
+		}
 	}
 
 	// Legacy API v1 routes (kept for backward compatibility, read-only)
@@ -261,18 +427,24 @@ func SetupRouter(db *gorm.DB, s3Service *services.S3Service, cfg *config.Config)
 
 		// Upload routes
 		uploads := v1.Group("/uploads")
+		uploads.Use(middleware.OptionalAuthMiddleware(jwtService))
+		uploads.Use(permissionMiddleware.MarkPermission("uploads", "read"))
 		{
-			uploads.GET("", uploadHandler.GetAllUploads)
-			uploads.GET("/:id", uploadHandler.GetUpload)
-			uploads.GET("/summary", uploadHandler.GetAllUploadsWithSummary)
+			uploads.GET("", publicUploadHandler.GetAllUploads)
+			uploads.GET("/summary", publicUploadHandler.GetAllUploadsWithSummary)
+			uploads.GET("/:id", publicUploadHandler.GetUpload)
 		}
 
 		// Resource routes
 		resources := v1.Group("/resources")
+		resources.Use(middleware.OptionalAuthMiddleware(jwtService))
+		resources.Use(permissionMiddleware.MarkPermission("uploads", "read"))
 		{
-			resources.GET("", resourceHandler.GetAllResources)
-			resources.GET("/:id", resourceHandler.GetResource)
-			resources.POST("/:id/download", resourceHandler.DownloadResource)
+			resources.GET("", publicResourceHandler.GetAllResources)
+			resources.GET("/:id", publicResourceHandler.GetResource)
+			resources.POST("/:id/download", publicResourceHandler.DownloadResource)
+			resources.GET("/:id/content", publicResourceHandler.GetResourceContent)
+			resources.HEAD("/:id/content", publicResourceHandler.GetResourceContent)
 		}
 
 		// Experience routes
@@ -317,5 +489,18 @@ func SetupRouter(db *gorm.DB, s3Service *services.S3Service, cfg *config.Config)
 		}
 	}
 
-	return router
+	// Stop the scheduler first so no job starts new work, then drain the workers
+	lifecycle.onShutdown("scheduled jobs", cronService.Stop)
+	lifecycle.onShutdown("analytics", analyticsService.Close)
+	lifecycle.onShutdown("task workers", taskQueue.Close)
+
+	lifecycle.onStart("analytics", analyticsService.Start)
+	lifecycle.onStart("task workers", taskQueue.Start)
+	lifecycle.onStart("scheduled jobs", func() {
+		if err := cronService.Start(); err != nil {
+			log.Fatalf("Failed to start cron service: %v", err)
+		}
+	})
+
+	return router, lifecycle
 }
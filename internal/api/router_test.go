@@ -0,0 +1,479 @@
+package api
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"portfolio-be/internal/api/handlers"
+	"portfolio-be/internal/api/middleware"
+	"portfolio-be/internal/config"
+	"portfolio-be/internal/models"
+	"portfolio-be/internal/repository"
+	"portfolio-be/internal/services"
+	"portfolio-be/internal/testutil"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// newContactTestRouter serves the contact form on an engine trusting the given proxies
+func newContactTestRouter(t *testing.T, trustedProxies []string) (*gin.Engine, *services.ContactService, *gorm.DB) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	db := testutil.OpenDB(t, filepath.Join(t.TempDir(), "contacts.db"))
+
+	contactConfig := config.ContactConfig{
+		FormSecret:        "test-secret",
+		FormTokenTTL:      time.Hour,
+		RateLimitWindow:   time.Hour,
+		RateLimitPerIP:    2,
+		RateLimitPerEmail: 100,
+		SpamThreshold:     5,
+		MaxLinks:          2,
+	}
+	tasks := services.NewTaskQueue(repository.NewTaskRepository(db), config.TaskConfig{}, "test")
+	mail := services.NewMailService(services.NoopMailer{}, tasks, config.MailConfig{SiteName: "Test"})
+	service := services.NewContactService(repository.NewContactRepository(db), repository.NewContactReplyRepository(db),
+		repository.NewContactLabelRepository(db), repository.NewUserRepository(db), contactConfig,
+		services.NewSpamScorer(contactConfig), services.NoopCaptcha{}, mail)
+
+	router, err := newEngine(config.ServerConfig{TrustedProxies: trustedProxies})
+	if err != nil {
+		t.Fatalf("failed to create router: %v", err)
+	}
+	router.POST("/api/contacts", handlers.NewContactHandler(service).CreateContact)
+	return router, service, db
+}
+
+// postContact submits the contact form from a client at 192.0.2.1 claiming to forward for forwardedFor
+func postContact(t *testing.T, router *gin.Engine, service *services.ContactService, email, forwardedFor string) int {
+	t.Helper()
+	body, _ := json.Marshal(models.ContactRequest{
+		Name:      "Jane Doe",
+		Email:     email,
+		Message:   "Hello there",
+		FormToken: service.IssueFormToken().Token,
+	})
+	req := httptest.NewRequest(http.MethodPost, "/api/contacts", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Forwarded-For", forwardedFor)
+	req.Header.Set("X-Real-IP", forwardedFor)
+	req.RemoteAddr = "192.0.2.1:40000"
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	return recorder.Code
+}
+
+func TestSpoofedForwardedForDoesNotResetContactRateLimit(t *testing.T) {
+	router, service, db := newContactTestRouter(t, nil)
+
+	for i := 0; i < 2; i++ {
+		if code := postContact(t, router, service, fmt.Sprintf("jane%d@example.com", i), fmt.Sprintf("198.51.100.%d", i+1)); code != http.StatusCreated {
+			t.Fatalf("expected message %d to be accepted, got %d", i+1, code)
+		}
+	}
+	if code := postContact(t, router, service, "jane9@example.com", "198.51.100.9"); code != http.StatusTooManyRequests {
+		t.Fatalf("expected a new forged X-Forwarded-For not to reset the per-IP limit, got %d", code)
+	}
+
+	var ips []string
+	if err := db.Model(&models.Contact{}).Distinct().Pluck("ip_address", &ips).Error; err != nil {
+		t.Fatalf("failed to get stored IPs: %v", err)
+	}
+	if len(ips) != 1 || ips[0] != "192.0.2.1" {
+		t.Errorf("expected only the connection address to be stored, got %v", ips)
+	}
+}
+
+func TestTrustedProxyForwardsClientIP(t *testing.T) {
+	router, service, db := newContactTestRouter(t, []string{"192.0.2.0/24"})
+
+	for i := 0; i < 3; i++ {
+		if code := postContact(t, router, service, fmt.Sprintf("jane%d@example.com", i), fmt.Sprintf("198.51.100.%d", i+1)); code != http.StatusCreated {
+			t.Fatalf("expected message %d from a distinct client behind the proxy to be accepted, got %d", i+1, code)
+		}
+	}
+
+	var contact models.Contact
+	if err := db.Order("id ASC").First(&contact).Error; err != nil {
+		t.Fatalf("failed to get contact: %v", err)
+	}
+	if contact.IPAddress != "198.51.100.1" {
+		t.Errorf("expected the forwarded client IP, got %q", contact.IPAddress)
+	}
+}
+
+// fakeS3Bucket serves objects of an in-memory bucket to S3Service, with Range and
+// conditional GETs handled by http.ServeContent
+type fakeS3Bucket struct {
+	objects  map[string][]byte
+	modified time.Time
+	// delay slows objects down to 32 KiB per delay
+	delay time.Duration
+}
+
+// slowReader reads at most 32 KiB at a time, pausing before each read
+type slowReader struct {
+	io.ReadSeeker
+	delay time.Duration
+}
+
+func (r slowReader) Read(p []byte) (int, error) {
+	time.Sleep(r.delay)
+	if len(p) > 32*1024 {
+		p = p[:32*1024]
+	}
+	return r.ReadSeeker.Read(p)
+}
+
+func (f *fakeS3Bucket) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// Path-style requests: /<bucket> or /<bucket>/<key>
+	_, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	data, ok := f.objects[key]
+	switch {
+	case key == "":
+		// HeadBucket and PutBucketCors
+		w.WriteHeader(http.StatusOK)
+	case !ok:
+		w.WriteHeader(http.StatusNotFound)
+	default:
+		sum := md5.Sum(data)
+		w.Header().Set("ETag", `"`+hex.EncodeToString(sum[:])+`"`)
+		w.Header().Set("Content-Type", "text/plain")
+		http.ServeContent(w, r, key, f.modified, slowReader{bytes.NewReader(data), f.delay})
+	}
+}
+
+// newContentTestRouter serves the public resource content endpoint for one public
+// resource whose file holds content
+func newContentTestRouter(t *testing.T, content []byte, writeTimeout time.Duration) (*gin.Engine, string, *fakeS3Bucket) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	bucket := &fakeS3Bucket{
+		objects:  map[string][]byte{"uploads/notes.txt": content},
+		modified: time.Now().UTC().Add(-time.Hour).Truncate(time.Second),
+	}
+	server := httptest.NewServer(bucket)
+	t.Cleanup(server.Close)
+	s3Service, err := services.NewS3Service(config.S3Config{
+		Endpoint:        server.URL,
+		Region:          "us-east-1",
+		Bucket:          "test",
+		AccessKeyID:     "test",
+		SecretAccessKey: "test",
+		ForcePathStyle:  true,
+		PresignExpiry:   time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("failed to create S3 service: %v", err)
+	}
+
+	db := testutil.OpenDB(t, filepath.Join(t.TempDir(), "resources.db"))
+	uploadRepo := repository.NewUploadRepository(db)
+	resourceRepo := repository.NewResourceRepository(db)
+	upload := &models.Upload{FileName: "notes.txt", OriginalName: "meeting notes.txt", S3Key: "uploads/notes.txt", S3Bucket: "test",
+		FileSize: int64(len(content)), ContentType: "text/plain", IsActive: true, ScanStatus: models.UploadScanClean}
+	if err := uploadRepo.Create(upload); err != nil {
+		t.Fatalf("failed to create upload: %v", err)
+	}
+	resource := &models.Resource{Name: "Notes", Type: "document", UploadID: upload.ID, IsPublic: true, IsActive: true}
+	if err := resourceRepo.Create(resource); err != nil {
+		t.Fatalf("failed to create resource: %v", err)
+	}
+
+	analytics := services.NewAnalyticsService(repository.NewAnalyticsRepository(db), resourceRepo, config.AnalyticsConfig{})
+	service := services.NewResourceService(resourceRepo, uploadRepo, s3Service, nil, nil, nil, analytics)
+
+	router, err := newEngine(config.ServerConfig{})
+	if err != nil {
+		t.Fatalf("failed to create router: %v", err)
+	}
+	handler := handlers.NewPublicResourceHandler(service, writeTimeout)
+	router.GET("/api/resources/:id/content", handler.GetResourceContent)
+	router.HEAD("/api/resources/:id/content", handler.GetResourceContent)
+	return router, fmt.Sprintf("/api/resources/%d/content", resource.ID), bucket
+}
+
+func TestResourceContentRangeAndConditionalRequests(t *testing.T) {
+	content := []byte("hello world")
+	router, path, bucket := newContentTestRouter(t, content, 0)
+	sum := md5.Sum(content)
+	etag := `"` + hex.EncodeToString(sum[:]) + `"`
+	lastModified := bucket.modified.Format(http.TimeFormat)
+
+	tests := []struct {
+		name       string
+		method     string
+		headers    map[string]string
+		wantStatus int
+		wantBody   string
+		wantHeader map[string]string
+	}{
+		{
+			name:       "full file",
+			method:     http.MethodGet,
+			wantStatus: http.StatusOK,
+			wantBody:   "hello world",
+			wantHeader: map[string]string{
+				"ETag":                etag,
+				"Accept-Ranges":       "bytes",
+				"Content-Length":      "11",
+				"Last-Modified":       lastModified,
+				"Content-Disposition": `attachment; filename="meeting notes.txt"`,
+			},
+		},
+		{
+			name:       "range",
+			method:     http.MethodGet,
+			headers:    map[string]string{"Range": "bytes=6-10"},
+			wantStatus: http.StatusPartialContent,
+			wantBody:   "world",
+			wantHeader: map[string]string{"Content-Range": "bytes 6-10/11", "Content-Length": "5"},
+		},
+		{
+			name:       "unsatisfiable range",
+			method:     http.MethodGet,
+			headers:    map[string]string{"Range": "bytes=100-200"},
+			wantStatus: http.StatusRequestedRangeNotSatisfiable,
+			wantHeader: map[string]string{"Content-Range": "bytes */11"},
+		},
+		{
+			name:       "matching etag",
+			method:     http.MethodGet,
+			headers:    map[string]string{"If-None-Match": etag},
+			wantStatus: http.StatusNotModified,
+			wantHeader: map[string]string{"ETag": etag},
+		},
+		{
+			name:       "etag list",
+			method:     http.MethodGet,
+			headers:    map[string]string{"If-None-Match": `"stale", ` + etag},
+			wantStatus: http.StatusNotModified,
+			wantHeader: map[string]string{"ETag": etag},
+		},
+		{
+			name:       "any etag",
+			method:     http.MethodGet,
+			headers:    map[string]string{"If-None-Match": "*"},
+			wantStatus: http.StatusNotModified,
+			wantHeader: map[string]string{"ETag": etag},
+		},
+		{
+			name:       "stale etag",
+			method:     http.MethodGet,
+			headers:    map[string]string{"If-None-Match": `"stale"`},
+			wantStatus: http.StatusOK,
+			wantBody:   "hello world",
+		},
+		{
+			name:       "not modified since",
+			method:     http.MethodGet,
+			headers:    map[string]string{"If-Modified-Since": lastModified},
+			wantStatus: http.StatusNotModified,
+		},
+		{
+			name:       "modified since",
+			method:     http.MethodGet,
+			headers:    map[string]string{"If-Modified-Since": bucket.modified.Add(-time.Hour).Format(http.TimeFormat)},
+			wantStatus: http.StatusOK,
+			wantBody:   "hello world",
+		},
+		{
+			name:       "stale etag wins over if-modified-since",
+			method:     http.MethodGet,
+			headers:    map[string]string{"If-None-Match": `"stale"`, "If-Modified-Since": lastModified},
+			wantStatus: http.StatusOK,
+			wantBody:   "hello world",
+		},
+		{
+			name:       "head",
+			method:     http.MethodHead,
+			wantStatus: http.StatusOK,
+			wantHeader: map[string]string{"ETag": etag, "Content-Length": "11"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, path, nil)
+			for name, value := range tt.headers {
+				req.Header.Set(name, value)
+			}
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, req)
+
+			if recorder.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.wantStatus, recorder.Code, recorder.Body.String())
+			}
+			if tt.wantStatus != http.StatusRequestedRangeNotSatisfiable && recorder.Body.String() != tt.wantBody {
+				t.Errorf("expected body %q, got %q", tt.wantBody, recorder.Body.String())
+			}
+			for name, value := range tt.wantHeader {
+				if got := recorder.Header().Get(name); got != value {
+					t.Errorf("expected %s %q, got %q", name, value, got)
+				}
+			}
+		})
+	}
+}
+
+func TestResourceContentOutlastsServerWriteTimeout(t *testing.T) {
+	// 256 KiB sent in 32 KiB pieces over 600ms, three times the server's WriteTimeout
+	content := bytes.Repeat([]byte("0123456789abcdef"), 16*1024)
+	router, path, bucket := newContentTestRouter(t, content, 200*time.Millisecond)
+	bucket.delay = 75 * time.Millisecond
+
+	server := httptest.NewUnstartedServer(router)
+	server.Config.WriteTimeout = 200 * time.Millisecond
+	server.Start()
+	t.Cleanup(server.Close)
+
+	resp, err := http.Get(server.URL + path)
+	if err != nil {
+		t.Fatalf("failed to request content: %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil || !bytes.Equal(body, content) {
+		t.Fatalf("expected the whole file, got %d of %d bytes: %v", len(body), len(content), err)
+	}
+}
+
+func TestPublicUploadListingsHideUploadsNotShownOnTheSite(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	db := testutil.OpenDB(t, filepath.Join(t.TempDir(), "uploads.db"))
+	uploadRepo := repository.NewUploadRepository(db)
+	resourceRepo := repository.NewResourceRepository(db)
+	server := httptest.NewServer(&fakeS3Bucket{objects: map[string][]byte{}})
+	t.Cleanup(server.Close)
+	s3Service, err := services.NewS3Service(config.S3Config{Endpoint: server.URL, Region: "us-east-1", Bucket: "test",
+		AccessKeyID: "test", SecretAccessKey: "test", ForcePathStyle: true, PresignExpiry: time.Hour})
+	if err != nil {
+		t.Fatalf("failed to create S3 service: %v", err)
+	}
+	service := services.NewUploadService(uploadRepo, s3Service, nil, nil, nil, services.NoopScanner{}, nil, config.ImageConfig{})
+
+	ids := map[string]uint{}
+	for _, file := range []struct {
+		name       string
+		scanStatus string
+		public     bool
+		active     bool
+		resource   bool
+	}{
+		{"shown", models.UploadScanClean, true, true, true},
+		{"private", models.UploadScanClean, false, true, true},
+		{"inactive", models.UploadScanClean, true, false, true},
+		{"pending", models.UploadScanPending, true, true, true},
+		{"unused", models.UploadScanClean, false, false, false},
+	} {
+		upload := &models.Upload{FileName: file.name + ".txt", OriginalName: file.name + ".txt", S3Key: "uploads/" + file.name + ".txt",
+			FileSize: 10, ContentType: "text/plain", IsActive: true, ScanStatus: file.scanStatus}
+		if err := uploadRepo.Create(upload); err != nil {
+			t.Fatalf("failed to create upload: %v", err)
+		}
+		ids[file.name] = upload.ID
+		if !file.resource {
+			continue
+		}
+		resource := &models.Resource{Name: file.name, Type: models.ResourceTypeDocument, UploadID: upload.ID}
+		if err := resourceRepo.Create(resource); err != nil {
+			t.Fatalf("failed to create resource: %v", err)
+		}
+		db.Model(resource).Updates(map[string]interface{}{"is_public": file.public, "is_active": file.active})
+	}
+
+	router, err := newEngine(config.ServerConfig{})
+	if err != nil {
+		t.Fatalf("failed to create router: %v", err)
+	}
+	uploads := router.Group("/api/uploads")
+	uploads.Use(func(c *gin.Context) {
+		// Stands in for MarkPermission on a request from a user with uploads:read
+		if c.GetHeader("Authorization") != "" {
+			c.Set(middleware.PermissionContextKey("uploads", "read"), true)
+		}
+	})
+	handler := handlers.NewPublicUploadHandler(service)
+	uploads.GET("", handler.GetAllUploads)
+	uploads.GET("/summary", handler.GetAllUploadsWithSummary)
+	uploads.GET("/:id", handler.GetUpload)
+
+	get := func(path string, canRead bool) (int, []byte) {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if canRead {
+			req.Header.Set("Authorization", "Bearer test")
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec.Code, rec.Body.Bytes()
+	}
+	listed := func(canRead bool) (names []string, total int64) {
+		t.Helper()
+		code, body := get("/api/uploads", canRead)
+		var list struct {
+			Data []struct {
+				FileName string `json:"file_name"`
+			} `json:"data"`
+			Pagination struct {
+				TotalItems int64 `json:"total_items"`
+			} `json:"pagination"`
+		}
+		if code != http.StatusOK || json.Unmarshal(body, &list) != nil {
+			t.Fatalf("expected the upload list, got %d: %s", code, body)
+		}
+		for _, upload := range list.Data {
+			names = append(names, upload.FileName)
+		}
+		return names, list.Pagination.TotalItems
+	}
+
+	if names, total := listed(false); len(names) != 1 || names[0] != "shown.txt" || total != 1 {
+		t.Errorf("expected anonymous callers to see only shown.txt, got %v (total %d)", names, total)
+	}
+	if names, total := listed(true); len(names) != 5 || total != 5 {
+		t.Errorf("expected users with uploads:read to see every upload, got %v (total %d)", names, total)
+	}
+
+	code, body := get("/api/uploads/summary", false)
+	var summary struct {
+		Data struct {
+			Uploads []models.UploadResponse `json:"data"`
+			Summary models.UploadSummary    `json:"summary"`
+		} `json:"data"`
+	}
+	if code != http.StatusOK || json.Unmarshal(body, &summary) != nil {
+		t.Fatalf("expected the upload summary, got %d: %s", code, body)
+	}
+	if len(summary.Data.Uploads) != 1 || summary.Data.Summary.TotalFiles != 1 {
+		t.Errorf("expected the public summary to cover one upload, got %+v", summary.Data)
+	}
+
+	for name, id := range ids {
+		path := fmt.Sprintf("/api/uploads/%d", id)
+		want := http.StatusNotFound
+		if name == "shown" {
+			want = http.StatusOK
+		}
+		if code, _ := get(path, false); code != want {
+			t.Errorf("expected %d for anonymous request of %s, got %d", want, name, code)
+		}
+		if code, _ := get(path, true); code != http.StatusOK {
+			t.Errorf("expected 200 for %s with uploads:read, got %d", name, code)
+		}
+	}
+}
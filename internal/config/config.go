@@ -6,6 +6,9 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
@@ -141,6 +144,15 @@ func Load() *Config {
 		Port:        getEnv("PORT", "5303"),
 		Host:        getEnv("HOST", "localhost"),
 		DatabaseURL: getSecretOrEnv(secretData, "database_url", "DATABASE_URL", "portfolio.db"),
+		ServerConfig: ServerConfig{
+			ReadHeaderTimeout: time.Duration(getEnvPositiveInt("SERVER_READ_HEADER_TIMEOUT_SECONDS", 10)) * time.Second,
+			ReadTimeout:       time.Duration(getEnvPositiveInt("SERVER_READ_TIMEOUT_SECONDS", 300)) * time.Second,
+			WriteTimeout:      time.Duration(getEnvPositiveInt("SERVER_WRITE_TIMEOUT_SECONDS", 300)) * time.Second,
+			IdleTimeout:       time.Duration(getEnvPositiveInt("SERVER_IDLE_TIMEOUT_SECONDS", 120)) * time.Second,
+			DrainDelay:        time.Duration(getEnvInt("SHUTDOWN_DRAIN_DELAY_SECONDS", 5)) * time.Second,
+			ShutdownTimeout:   time.Duration(getEnvPositiveInt("SHUTDOWN_TIMEOUT_SECONDS", 30)) * time.Second,
+			TrustedProxies:    getEnvList("TRUSTED_PROXIES"),
+		},
 		S3Config: S3Config{
 			Endpoint:        getSecretOrEnv(secretData, "s3_endpoint", "S3_ENDPOINT", defaultS3Endpoint),
 			Region:          getSecretOrEnv(secretData, "s3_region", "S3_REGION", "us-east-1"),
@@ -148,6 +160,8 @@ func Load() *Config {
 			AccessKeyID:     getSecretOrEnv(secretData, "s3_access_key_id", "S3_ACCESS_KEY_ID", defaultS3AccessKey),
 			SecretAccessKey: getSecretOrEnv(secretData, "s3_secret_access_key", "S3_SECRET_ACCESS_KEY", defaultS3SecretKey),
 			ForcePathStyle:  true,
+			PublicBaseURL:   getEnv("S3_PUBLIC_BASE_URL", ""),
+			PresignExpiry:   time.Duration(getEnvPositiveInt("S3_PRESIGN_EXPIRY_MINUTES", 60)) * time.Minute,
 		},
 		JWTConfig: JWTConfig{
 			SecretKey: getSecretOrEnv(secretData, "jwt_secret_key", "JWT_SECRET_KEY", defaultJWTSecret),
@@ -158,6 +172,98 @@ func Load() *Config {
 			Region:     region,
 			UseSecrets: useSecrets,
 		},
+		CleanupConfig: CleanupConfig{
+			GracePeriod: time.Duration(getEnvInt("UPLOAD_CLEANUP_GRACE_DAYS", 30)) * 24 * time.Hour,
+			BatchSize:   getEnvPositiveInt("UPLOAD_CLEANUP_BATCH_SIZE", 100),
+		},
+		ImageConfig: ImageConfig{
+			MaxPixels: int64(getEnvInt("IMAGE_MAX_PIXELS", 50_000_000)),
+		},
+		AnalyticsConfig: AnalyticsConfig{
+			BufferSize:    getEnvPositiveInt("ANALYTICS_BUFFER_SIZE", 10000),
+			BatchSize:     getEnvPositiveInt("ANALYTICS_BATCH_SIZE", 500),
+			FlushInterval: time.Duration(getEnvPositiveInt("ANALYTICS_FLUSH_SECONDS", 30)) * time.Second,
+			GeoIPPath:     getEnv("GEOIP_DB_PATH", ""),
+		},
+		MediaConfig: MediaConfig{
+			FFprobePath: getEnv("FFPROBE_PATH", "ffprobe"),
+			FFmpegPath:  getEnv("FFMPEG_PATH", "ffmpeg"),
+			Workers:     getEnvPositiveInt("MEDIA_WORKERS", 2),
+			JobTimeout:  time.Duration(getEnvPositiveInt("MEDIA_JOB_TIMEOUT_MINUTES", 30)) * time.Minute,
+		},
+		ScanConfig: ScanConfig{
+			Scanner:      getEnv("UPLOAD_SCANNER", "none"),
+			ClamdAddress: getEnv("CLAMD_ADDRESS", "localhost:3310"),
+			Timeout:      time.Duration(getEnvPositiveInt("SCAN_TIMEOUT_SECONDS", 60)) * time.Second,
+			BatchSize:    getEnvPositiveInt("SCAN_BATCH_SIZE", 100),
+			RescanAfter:  time.Duration(getEnvInt("SCAN_RESCAN_DAYS", 0)) * 24 * time.Hour,
+		},
+		QuotaConfig: QuotaConfig{
+			MaxTotalBytes:  int64(getEnvInt("UPLOAD_QUOTA_MB", 0)) << 20,
+			MaxFiles:       int64(getEnvInt("UPLOAD_QUOTA_FILES", 0)),
+			MaxFileSize:    int64(getEnvInt("UPLOAD_MAX_FILE_MB", 10)) << 20,
+			AllowedClasses: getEnvList("UPLOAD_ALLOWED_CLASSES"),
+		},
+		ArchiveConfig: ArchiveConfig{
+			MaxArchiveSize:      int64(getEnvPositiveInt("UPLOAD_ARCHIVE_MAX_MB", 200)) << 20,
+			MaxEntries:          getEnvPositiveInt("UPLOAD_ARCHIVE_MAX_ENTRIES", 500),
+			MaxUncompressedSize: int64(getEnvPositiveInt("UPLOAD_ARCHIVE_MAX_UNCOMPRESSED_MB", 1024)) << 20,
+			MaxCompressionRatio: int64(getEnvPositiveInt("UPLOAD_ARCHIVE_MAX_RATIO", 100)),
+		},
+		JobsConfig: JobsConfig{
+			URLRefreshSchedule:       getEnv("JOB_URL_REFRESH_SCHEDULE", "0 * * * *"),
+			UploadCleanupSchedule:    getEnv("JOB_UPLOAD_CLEANUP_SCHEDULE", "30 3 * * *"),
+			UploadScanSchedule:       getEnv("JOB_UPLOAD_SCAN_SCHEDULE", "15 * * * *"),
+			ContactRetentionSchedule: getEnv("JOB_CONTACT_RETENTION_SCHEDULE", "45 3 * * *"),
+			HistoryRetention:         time.Duration(getEnvInt("JOB_HISTORY_DAYS", 30)) * 24 * time.Hour,
+			InstanceID:               getEnv("INSTANCE_ID", defaultInstanceID()),
+			LeaseTTL:                 time.Duration(getEnvPositiveInt("JOB_LEASE_TTL_SECONDS", 60)) * time.Second,
+		},
+		ContactConfig: ContactConfig{
+			FormSecret:        getSecretOrEnv(secretData, "contact_form_secret", "CONTACT_FORM_SECRET", ""),
+			MinFillTime:       time.Duration(getEnvInt("CONTACT_MIN_FILL_SECONDS", 3)) * time.Second,
+			FormTokenTTL:      time.Duration(getEnvPositiveInt("CONTACT_FORM_TOKEN_HOURS", 24)) * time.Hour,
+			RateLimitWindow:   time.Duration(getEnvPositiveInt("CONTACT_RATE_LIMIT_WINDOW_MINUTES", 60)) * time.Minute,
+			RateLimitPerIP:    getEnvPositiveInt("CONTACT_RATE_LIMIT_PER_IP", 5),
+			RateLimitPerEmail: getEnvPositiveInt("CONTACT_RATE_LIMIT_PER_EMAIL", 3),
+			SpamThreshold:     getEnvPositiveInt("CONTACT_SPAM_THRESHOLD", 5),
+			SpamKeywords:      getEnvList("CONTACT_SPAM_KEYWORDS"),
+			MaxLinks:          getEnvInt("CONTACT_MAX_LINKS", 2),
+			Captcha:           getEnv("CONTACT_CAPTCHA", "none"),
+			CaptchaSecret:     getSecretOrEnv(secretData, "contact_captcha_secret", "CONTACT_CAPTCHA_SECRET", ""),
+			CaptchaTimeout:    time.Duration(getEnvPositiveInt("CONTACT_CAPTCHA_TIMEOUT_SECONDS", 10)) * time.Second,
+			NotifyEmails:      getEnvList("CONTACT_NOTIFY_EMAILS"),
+			AutoReply:         getEnv("CONTACT_AUTO_REPLY", "false") == "true",
+			Retention:         time.Duration(getEnvInt("CONTACT_RETENTION_DAYS", 0)) * 24 * time.Hour,
+			RetentionAction:   getEnv("CONTACT_RETENTION_ACTION", "anonymize"),
+		},
+		MailConfig: MailConfig{
+			Driver:       getEnv("MAIL_DRIVER", "none"),
+			From:         getEnv("MAIL_FROM", "Portfolio <noreply@localhost>"),
+			SMTPHost:     getEnv("SMTP_HOST", "localhost"),
+			SMTPPort:     getEnvPositiveInt("SMTP_PORT", 587),
+			SMTPUsername: getEnv("SMTP_USERNAME", ""),
+			SMTPPassword: getSecretOrEnv(secretData, "smtp_password", "SMTP_PASSWORD", ""),
+			SMTPTLS:      getEnv("SMTP_TLS", "starttls"),
+			Timeout:      time.Duration(getEnvPositiveInt("SMTP_TIMEOUT_SECONDS", 30)) * time.Second,
+			DropDir:      getEnv("MAIL_DROP_DIR", "mail"),
+			SiteName:     getEnv("MAIL_SITE_NAME", "Portfolio"),
+			AdminURL:     strings.TrimSuffix(getEnv("ADMIN_URL", ""), "/"),
+		},
+		TaskConfig: TaskConfig{
+			Workers:           getEnvPositiveInt("TASK_WORKERS", 4),
+			PollInterval:      time.Duration(getEnvPositiveInt("TASK_POLL_SECONDS", 2)) * time.Second,
+			VisibilityTimeout: time.Duration(getEnvPositiveInt("TASK_VISIBILITY_TIMEOUT_SECONDS", 300)) * time.Second,
+			MaxAttempts:       getEnvPositiveInt("TASK_MAX_ATTEMPTS", 5),
+			BackoffBase:       time.Duration(getEnvPositiveInt("TASK_BACKOFF_SECONDS", 10)) * time.Second,
+			BackoffMax:        time.Duration(getEnvPositiveInt("TASK_BACKOFF_MAX_SECONDS", 3600)) * time.Second,
+			Retention:         time.Duration(getEnvInt("TASK_RETENTION_DAYS", 7)) * 24 * time.Hour,
+		},
+	}
+
+	// Form tokens are signed with the JWT secret unless a dedicated secret is set
+	if config.ContactConfig.FormSecret == "" {
+		config.ContactConfig.FormSecret = config.JWTConfig.SecretKey
 	}
 
 	// Validate critical S3 configuration
@@ -175,6 +281,68 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
+// getEnvInt returns the integer value of an environment variable, or defaultValue when
+// it is unset or not a number. Zero is kept, as it disables the limits that allow it;
+// negative values are rejected.
+func getEnvInt(key string, defaultValue int) int {
+	value, ok := lookupEnvInt(key)
+	if !ok {
+		return defaultValue
+	}
+	if value < 0 {
+		log.Fatalf("Invalid configuration: %s must not be negative, got %d", key, value)
+	}
+	return value
+}
+
+// getEnvPositiveInt is getEnvInt for settings that must be positive, such as timeouts,
+// sizes and worker counts
+func getEnvPositiveInt(key string, defaultValue int) int {
+	value, ok := lookupEnvInt(key)
+	if !ok {
+		return defaultValue
+	}
+	if value <= 0 {
+		log.Fatalf("Invalid configuration: %s must be positive, got %d", key, value)
+	}
+	return value
+}
+
+// lookupEnvInt parses an integer environment variable, reporting whether it is set to a
+// number
+func lookupEnvInt(key string) (int, bool) {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return 0, false
+	}
+	value, err := strconv.Atoi(strings.TrimSpace(raw))
+	if err != nil {
+		log.Printf("Ignoring %s: %q is not a number", key, raw)
+		return 0, false
+	}
+	return value, true
+}
+
+// getEnvList returns the comma-separated values of an environment variable
+func getEnvList(key string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// defaultInstanceID identifies the process by host name and process id
+func defaultInstanceID() string {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "instance"
+	}
+	return fmt.Sprintf("%s-%d", host, os.Getpid())
+}
+
 func getSecretOrEnv(secretData *SecretData, secretKey, envKey, defaultValue string) string {
 	if secretData != nil {
 		switch secretKey {
@@ -206,6 +374,18 @@ func getSecretOrEnv(secretData *SecretData, secretKey, envKey, defaultValue stri
 			if secretData.JWTSecretKey != "" {
 				return secretData.JWTSecretKey
 			}
+		case "contact_form_secret":
+			if secretData.ContactFormSecret != "" {
+				return secretData.ContactFormSecret
+			}
+		case "contact_captcha_secret":
+			if secretData.CaptchaSecret != "" {
+				return secretData.CaptchaSecret
+			}
+		case "smtp_password":
+			if secretData.SMTPPassword != "" {
+				return secretData.SMTPPassword
+			}
 		}
 	}
 	// Fallback to environment variable or default
@@ -9,4 +9,7 @@ type SecretData struct {
 	S3Bucket          string `json:"s3_bucket"`
 	S3AccessKeyID     string `json:"s3_access_key_id"`
 	S3SecretAccessKey string `json:"s3_secret_access_key"`
+	ContactFormSecret string `json:"contact_form_secret"`
+	CaptchaSecret     string `json:"contact_captcha_secret"`
+	SMTPPassword      string `json:"smtp_password"`
 }
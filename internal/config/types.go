@@ -1,13 +1,199 @@
 package config
 
+import "time"
+
 // Config represents the application configuration
 type Config struct {
 	Port                 string
 	Host                 string
 	DatabaseURL          string
+	ServerConfig         ServerConfig
 	S3Config             S3Config
 	JWTConfig            JWTConfig
 	SecretsManagerConfig SecretsManagerConfig
+	CleanupConfig        CleanupConfig
+	AnalyticsConfig      AnalyticsConfig
+	MediaConfig          MediaConfig
+	ScanConfig           ScanConfig
+	QuotaConfig          QuotaConfig
+	ArchiveConfig        ArchiveConfig
+	JobsConfig           JobsConfig
+	TaskConfig           TaskConfig
+	ContactConfig        ContactConfig
+	MailConfig           MailConfig
+	ImageConfig          ImageConfig
+}
+
+// ServerConfig holds HTTP server timeouts and graceful shutdown configuration
+type ServerConfig struct {
+	ReadHeaderTimeout time.Duration
+	// ReadTimeout and WriteTimeout bound whole requests and responses, including
+	// large uploads. Streamed resource content gets WriteTimeout for each write
+	// instead, so that downloads are not cut off however long they take.
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+	IdleTimeout  time.Duration
+	// DrainDelay is how long /ready reports unavailable before the server stops
+	// accepting connections, so load balancers can take the instance out of rotation;
+	// zero stops at once
+	DrainDelay time.Duration
+	// ShutdownTimeout bounds draining in-flight requests and stopping background services
+	ShutdownTimeout time.Duration
+	// TrustedProxies are the IPs and CIDRs of the reverse proxies whose X-Forwarded-For
+	// and X-Real-IP headers are believed; by default none are
+	TrustedProxies []string
+}
+
+// JobsConfig holds the cron expressions of scheduled jobs. Expressions have five
+// fields (minute hour day-of-month month day-of-week) or are a descriptor such as
+// @daily or "@every 30m".
+type JobsConfig struct {
+	URLRefreshSchedule    string
+	UploadCleanupSchedule string
+	UploadScanSchedule    string
+	// ContactRetentionSchedule runs the contact retention policy
+	ContactRetentionSchedule string
+	// HistoryRetention is how long job run history is kept; zero keeps it forever
+	HistoryRetention time.Duration
+	// InstanceID identifies this replica in job leases and run history
+	InstanceID string
+	// LeaseTTL is how long a job lease lasts without renewal; a replica that dies
+	// mid-run blocks the job for at most this long
+	LeaseTTL time.Duration
+}
+
+// ContactConfig holds contact form spam protection configuration
+type ContactConfig struct {
+	// FormSecret signs the form tokens that prove when the form was shown
+	FormSecret string
+	// MinFillTime is how long a person needs at least to fill in the form; faster
+	// submissions are flagged as spam. Zero disables the check.
+	MinFillTime  time.Duration
+	FormTokenTTL time.Duration
+	// RateLimitWindow is the window the per-IP and per-email limits count messages in
+	RateLimitWindow   time.Duration
+	RateLimitPerIP    int
+	RateLimitPerEmail int
+	// SpamThreshold is the spam score at which a message is flagged as spam
+	SpamThreshold int
+	SpamKeywords  []string
+	// MaxLinks is how many links a message may contain before it scores as spam
+	MaxLinks int
+	// Captcha is "turnstile", "hcaptcha", "stub" (accepts any token but "fail") or
+	// "none" to disable CAPTCHA verification
+	Captcha        string
+	CaptchaSecret  string
+	CaptchaTimeout time.Duration
+	// NotifyEmails receive a notification for every new message that is not spam
+	NotifyEmails []string
+	// AutoReply sends senders a fixed acknowledgement of their message, unless it scored
+	// half the spam threshold or more
+	AutoReply bool
+	// Retention is how long contact messages keep personal data; zero keeps it forever
+	Retention time.Duration
+	// RetentionAction is "anonymize" to erase the sender and content of older messages
+	// or "delete" to delete them
+	RetentionAction string
+}
+
+// MailConfig holds outgoing email configuration
+type MailConfig struct {
+	// Driver is "smtp", "file" to write .eml files to DropDir for development, or "none"
+	// to log and discard email
+	Driver string
+	// From is the sender address, optionally with a display name
+	From         string
+	SMTPHost     string
+	SMTPPort     int
+	SMTPUsername string
+	SMTPPassword string
+	// SMTPTLS is "starttls", "tls" for implicit TLS, or "none"
+	SMTPTLS string
+	Timeout time.Duration
+	DropDir string
+	// SiteName and AdminURL are used in email templates; AdminURL links notifications
+	// to the admin interface when set
+	SiteName string
+	AdminURL string
+}
+
+// TaskConfig holds background task queue configuration
+type TaskConfig struct {
+	Workers      int
+	PollInterval time.Duration
+	// VisibilityTimeout is how long a claimed task stays locked to its worker; a task
+	// whose worker dies is picked up again once it expires
+	VisibilityTimeout time.Duration
+	MaxAttempts       int
+	// BackoffBase is the delay before the first retry; it doubles with every attempt
+	// up to BackoffMax
+	BackoffBase time.Duration
+	BackoffMax  time.Duration
+	// Retention is how long succeeded tasks are kept; dead tasks are kept until retried
+	// or deleted
+	Retention time.Duration
+}
+
+// ArchiveConfig holds the limits of ZIP archive imports. The ratio and uncompressed
+// limits guard against zip bombs.
+type ArchiveConfig struct {
+	MaxArchiveSize      int64
+	MaxEntries          int
+	MaxUncompressedSize int64
+	// MaxCompressionRatio bounds the uncompressed to compressed size of each entry
+	MaxCompressionRatio int64
+}
+
+// QuotaConfig holds the upload quota applied to users without a role or user quota.
+// Zero limits and empty classes mean unlimited.
+type QuotaConfig struct {
+	MaxTotalBytes  int64
+	MaxFiles       int64
+	MaxFileSize    int64
+	AllowedClasses []string
+}
+
+// ScanConfig holds upload virus scanning configuration
+type ScanConfig struct {
+	// Scanner is "clamd", "stub" (flags the EICAR test file) or "none" to mark uploads clean unscanned
+	Scanner      string
+	ClamdAddress string
+	Timeout      time.Duration
+	// BatchSize is how many uploads are loaded at a time; each run works through all
+	// uploads due for a scan
+	BatchSize int
+	// RescanAfter re-checks clean uploads scanned longer ago than this with the latest
+	// signatures; zero disables periodic rescans
+	RescanAfter time.Duration
+}
+
+// MediaConfig holds audio/video probing and processing configuration. ffprobe and
+// ffmpeg are optional; without them only MP4/QuickTime and WAV files are probed
+// and no poster frames or transcodes are produced.
+type MediaConfig struct {
+	FFprobePath string
+	FFmpegPath  string
+	// Workers bounds how many ffmpeg processes run at once
+	Workers    int
+	JobTimeout time.Duration
+}
+
+// AnalyticsConfig holds resource analytics recording configuration
+type AnalyticsConfig struct {
+	// BufferSize is how many events may wait to be aggregated before new ones are dropped
+	BufferSize int
+	// BatchSize is how many events are aggregated before a flush is forced
+	BatchSize     int
+	FlushInterval time.Duration
+	// GeoIPPath points to an optional IP-to-country CSV (start_ip,end_ip,country_code).
+	// Countries are not recorded when it is empty or missing.
+	GeoIPPath string
+}
+
+// CleanupConfig holds expired upload cleanup configuration
+type CleanupConfig struct {
+	GracePeriod time.Duration
+	BatchSize   int
 }
 
 // SecretsManagerConfig holds AWS Secrets Manager configuration
@@ -25,6 +211,11 @@ type S3Config struct {
 	AccessKeyID     string
 	SecretAccessKey string
 	ForcePathStyle  bool
+	// PublicBaseURL is the public or CDN origin serving the bucket, e.g. https://media.example.com/bucket.
+	// When empty, public URLs are built from Endpoint and Bucket.
+	PublicBaseURL string
+	// PresignExpiry is how long presigned URLs for private files stay valid
+	PresignExpiry time.Duration
 }
 
 // JWTConfig holds JWT configuration
@@ -32,3 +223,10 @@ type JWTConfig struct {
 	SecretKey string
 	Issuer    string
 }
+
+// ImageConfig holds the limits of uploaded images
+type ImageConfig struct {
+	// MaxPixels bounds the width times height of decoded images, checked from the image
+	// header so that small files declaring huge dimensions are never decoded
+	MaxPixels int64
+}
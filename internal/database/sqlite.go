@@ -2,6 +2,7 @@ package database
 
 import (
 	"portfolio-be/internal/models"
+	"strings"
 
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
@@ -10,6 +11,16 @@ import (
 )
 
 func InitSQLite(databaseURL string) (*gorm.DB, error) {
+	// Wait for locks instead of failing with SQLITE_BUSY when another connection or
+	// replica is writing
+	if !strings.Contains(databaseURL, "busy_timeout") {
+		separator := "?"
+		if strings.Contains(databaseURL, "?") {
+			separator = "&"
+		}
+		databaseURL += separator + "_pragma=busy_timeout(5000)"
+	}
+
 	// Use the pure Go SQLite driver by specifying the driver name
 	db, err := gorm.Open(sqlite.Dialector{
 		DriverName: "sqlite",
@@ -25,7 +36,7 @@ func InitSQLite(databaseURL string) (*gorm.DB, error) {
 }
 
 func Migrate(db *gorm.DB) error {
-	return db.AutoMigrate(
+	err := db.AutoMigrate(
 		&models.User{},
 		&models.Role{},
 		&models.Permission{},
@@ -33,13 +44,43 @@ func Migrate(db *gorm.DB) error {
 		&models.Content{},
 		&models.Upload{},
 		&models.Resource{},
+		&models.UploadReference{},
+		&models.ResourceDailyStat{},
+		&models.UploadQuota{},
+		&models.JobRun{},
+		&models.JobState{},
+		&models.JobLease{},
+		&models.Task{},
+		&models.DataMigration{},
 		&models.Experience{},
 		&models.Service{},
 		&models.Technology{},
 		&models.Project{},
+		&models.Collection{},
+		&models.CollectionItem{},
+		&models.Tag{},
+		&models.Tagging{},
 		&models.Testimonial{},
 		&models.Contact{},
+		&models.ContactRead{},
+		&models.ContactReply{},
+		&models.ReplyTemplate{},
+		&models.ContactErasure{},
 	)
+	if err != nil {
+		return err
+	}
+
+	return normalizeUploadURLs(db)
+}
+
+// normalizeUploadURLs drops the persisted upload URL column; URLs are now computed at
+// read time from the S3 key
+func normalizeUploadURLs(db *gorm.DB) error {
+	if !db.Migrator().HasColumn(&models.Upload{}, "url") {
+		return nil
+	}
+	return db.Exec("ALTER TABLE uploads DROP COLUMN url").Error
 }
 
 // IsEmpty checks if the database has been seeded with initial data
@@ -269,7 +310,7 @@ func Seed(db *gorm.DB) error {
 
 // seedPermissions creates default permissions
 func seedPermissions(db *gorm.DB) error {
-	resources := []string{"users", "roles", "permissions", "projects", "technologies", "experiences", "testimonials", "contacts", "services", "uploads"}
+	resources := []string{"users", "roles", "permissions", "projects", "technologies", "experiences", "testimonials", "contacts", "services", "uploads", "jobs", "tasks"}
 	actions := []string{"create", "read", "update", "delete"}
 
 	for _, resource := range resources {
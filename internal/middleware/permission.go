@@ -212,7 +212,7 @@ func (m *PermissionMiddleware) GetUserPermissions(userID uint) ([]string, error)
 	// Admin role has all permissions
 	if user.Role == "admin" || (user.UserRole != nil && user.UserRole.Name == "admin") {
 		// Return all possible permissions for admin
-		resources := []string{"users", "roles", "permissions", "projects", "technologies", "experiences", "testimonials", "contacts", "services", "uploads"}
+		resources := []string{"users", "roles", "permissions", "projects", "technologies", "experiences", "testimonials", "contacts", "services", "uploads", "jobs", "tasks"}
 		actions := []string{"create", "read", "update", "delete"}
 
 		for _, resource := range resources {
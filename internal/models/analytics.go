@@ -0,0 +1,57 @@
+package models
+
+import "time"
+
+// Analytics dimensions of resource daily stats
+const (
+	AnalyticsDimensionTotal    = "total"
+	AnalyticsDimensionReferrer = "referrer"
+	AnalyticsDimensionCountry  = "country"
+)
+
+// Analytics event actions
+const (
+	AnalyticsActionView     = "view"
+	AnalyticsActionDownload = "download"
+)
+
+// ResourceDailyStat aggregates the views and downloads of a resource for one UTC day.
+// Rows with the total dimension hold the day's totals; referrer and country rows
+// break them down by referrer domain and country code.
+type ResourceDailyStat struct {
+	ID         uint      `json:"id" gorm:"primarykey" example:"1"`
+	ResourceID uint      `json:"resource_id" gorm:"not null;uniqueIndex:idx_resource_daily_stats_key" example:"1"`
+	Day        string    `json:"day" gorm:"size:10;not null;uniqueIndex:idx_resource_daily_stats_key" example:"2024-01-31"`
+	Dimension  string    `json:"dimension" gorm:"not null;uniqueIndex:idx_resource_daily_stats_key" example:"referrer"`
+	Value      string    `json:"value" gorm:"not null;default:'';uniqueIndex:idx_resource_daily_stats_key" example:"google.com"`
+	Views      int64     `json:"views" gorm:"default:0" example:"12"`
+	Downloads  int64     `json:"downloads" gorm:"default:0" example:"3"`
+	CreatedAt  time.Time `json:"created_at" example:"2024-01-31T00:00:00Z"`
+	UpdatedAt  time.Time `json:"updated_at" example:"2024-01-31T23:59:00Z"`
+}
+
+// AnalyticsPoint is one day of a resource analytics time series
+type AnalyticsPoint struct {
+	Day       string `json:"day" example:"2024-01-31"`
+	Views     int64  `json:"views" example:"12"`
+	Downloads int64  `json:"downloads" example:"3"`
+}
+
+// AnalyticsBreakdown is the share of views and downloads of one referrer or country
+type AnalyticsBreakdown struct {
+	Value     string `json:"value" example:"google.com"`
+	Views     int64  `json:"views" example:"8"`
+	Downloads int64  `json:"downloads" example:"1"`
+}
+
+// ResourceAnalytics is the analytics report of a resource over a range of days
+type ResourceAnalytics struct {
+	ResourceID     uint                 `json:"resource_id" example:"1"`
+	From           string               `json:"from" example:"2024-01-01"`
+	To             string               `json:"to" example:"2024-01-31"`
+	TotalViews     int64                `json:"total_views" example:"120"`
+	TotalDownloads int64                `json:"total_downloads" example:"30"`
+	Series         []AnalyticsPoint     `json:"series"`
+	Referrers      []AnalyticsBreakdown `json:"referrers"`
+	Countries      []AnalyticsBreakdown `json:"countries"`
+}
@@ -0,0 +1,26 @@
+package models
+
+// Statuses of an entry in an archive import
+const (
+	ArchiveEntryUploaded = "uploaded"
+	ArchiveEntrySkipped  = "skipped"
+	ArchiveEntryFailed   = "failed"
+)
+
+// ArchiveEntryResult is the outcome of importing one archive entry
+type ArchiveEntryResult struct {
+	Name       string          `json:"name" example:"gallery/photo-1.jpg"`
+	Status     string          `json:"status" example:"uploaded"`
+	Error      string          `json:"error,omitempty" example:"file type is not allowed: application/zip"`
+	Upload     *UploadResponse `json:"upload,omitempty"`
+	ResourceID *uint           `json:"resource_id,omitempty" example:"12"`
+}
+
+// ArchiveImportReport summarizes a ZIP archive import
+type ArchiveImportReport struct {
+	Entries  int                  `json:"entries" example:"24"`
+	Uploaded int                  `json:"uploaded" example:"22"`
+	Skipped  int                  `json:"skipped" example:"1"`
+	Failed   int                  `json:"failed" example:"1"`
+	Results  []ArchiveEntryResult `json:"results"`
+}
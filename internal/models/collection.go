@@ -0,0 +1,77 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Collection represents an ordered set of resources, such as a project's screenshot gallery
+type Collection struct {
+	ID          uint             `json:"id" gorm:"primarykey" example:"1"`
+	Name        string           `json:"name" gorm:"not null" example:"Car Rent screenshots"`
+	Description string           `json:"description" gorm:"type:text" example:"Screens from the booking flow"`
+	ProjectID   *uint            `json:"project_id" gorm:"index" example:"1"`
+	IsActive    bool             `json:"is_active" gorm:"default:true" example:"true"`
+	Items       []CollectionItem `json:"items" gorm:"foreignKey:CollectionID"`
+	CreatedAt   time.Time        `json:"created_at" example:"2023-01-01T00:00:00Z"`
+	UpdatedAt   time.Time        `json:"updated_at" example:"2023-01-01T00:00:00Z"`
+	DeletedAt   gorm.DeletedAt   `json:"-" gorm:"index"`
+}
+
+// CollectionItem places a resource in a collection at a position with an optional caption
+type CollectionItem struct {
+	ID           uint      `json:"id" gorm:"primarykey" example:"1"`
+	CollectionID uint      `json:"collection_id" gorm:"not null;uniqueIndex:idx_collection_items_resource" example:"1"`
+	ResourceID   uint      `json:"resource_id" gorm:"not null;uniqueIndex:idx_collection_items_resource;index" example:"1"`
+	Resource     Resource  `json:"resource" gorm:"foreignKey:ResourceID"`
+	Position     int       `json:"position" gorm:"not null;default:0" example:"0"`
+	Caption      string    `json:"caption" example:"Booking confirmation screen"`
+	CreatedAt    time.Time `json:"created_at" example:"2023-01-01T00:00:00Z"`
+	UpdatedAt    time.Time `json:"updated_at" example:"2023-01-01T00:00:00Z"`
+}
+
+// CollectionRequest represents the request payload for creating/updating a collection
+type CollectionRequest struct {
+	Name        string `json:"name" binding:"required" example:"Car Rent screenshots"`
+	Description string `json:"description" example:"Screens from the booking flow"`
+	ProjectID   *uint  `json:"project_id" example:"1"`
+	IsActive    *bool  `json:"is_active" example:"true"`
+}
+
+// CollectionItemRequest represents the request payload for adding a resource to a collection
+type CollectionItemRequest struct {
+	ResourceID uint   `json:"resource_id" binding:"required" example:"1"`
+	Caption    string `json:"caption" example:"Booking confirmation screen"`
+	Position   *int   `json:"position" example:"0"`
+}
+
+// CollectionItemUpdateRequest represents the request payload for updating a collection item
+type CollectionItemUpdateRequest struct {
+	Caption *string `json:"caption,omitempty" example:"Updated caption"`
+}
+
+// CollectionReorderRequest lists the collection's item IDs in their new order
+type CollectionReorderRequest struct {
+	ItemIDs []uint `json:"item_ids" binding:"required" example:"3,1,2"`
+}
+
+// CollectionItemResponse represents a resource within a collection
+type CollectionItemResponse struct {
+	ID       uint             `json:"id" example:"1"`
+	Position int              `json:"position" example:"0"`
+	Caption  string           `json:"caption" example:"Booking confirmation screen"`
+	Resource ResourceResponse `json:"resource"`
+}
+
+// CollectionResponse represents the response payload for collection operations
+type CollectionResponse struct {
+	ID          uint                     `json:"id" example:"1"`
+	Name        string                   `json:"name" example:"Car Rent screenshots"`
+	Description string                   `json:"description" example:"Screens from the booking flow"`
+	ProjectID   *uint                    `json:"project_id" example:"1"`
+	IsActive    bool                     `json:"is_active" example:"true"`
+	Items       []CollectionItemResponse `json:"items"`
+	CreatedAt   time.Time                `json:"created_at" example:"2023-01-01T00:00:00Z"`
+	UpdatedAt   time.Time                `json:"updated_at" example:"2023-01-01T00:00:00Z"`
+}
@@ -6,17 +6,34 @@ import (
 	"gorm.io/gorm"
 )
 
+// Contact statuses. Spam holds messages the spam checks flagged, kept out of the inbox
+// until an admin reviews them.
+const (
+	ContactStatusUnread  = "unread"
+	ContactStatusRead    = "read"
+	ContactStatusReplied = "replied"
+	ContactStatusSpam    = "spam"
+)
+
 type Contact struct {
-	ID        uint           `json:"id" gorm:"primarykey" example:"1"`
-	Name      string         `json:"name" gorm:"not null" example:"John Doe"`
-	Email     string         `json:"email" gorm:"not null" example:"john@example.com"`
-	Subject   string         `json:"subject" example:"Project Inquiry"`
-	Message   string         `json:"message" gorm:"type:text;not null" example:"I would like to discuss a potential project."`
-	Status    string         `json:"status" gorm:"default:unread" example:"unread"` // unread, read, replied
-	IsActive  bool           `json:"is_active" gorm:"default:true" example:"true"`
-	CreatedAt time.Time      `json:"created_at" example:"2023-01-01T00:00:00Z"`
-	UpdatedAt time.Time      `json:"updated_at" example:"2023-01-01T00:00:00Z"`
-	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+	ID          uint       `json:"id" gorm:"primarykey" example:"1"`
+	Name        string     `json:"name" gorm:"not null" example:"John Doe"`
+	Email       string     `json:"email" gorm:"not null" example:"john@example.com"`
+	Subject     string     `json:"subject" example:"Project Inquiry"`
+	Message     string     `json:"message" gorm:"type:text;not null" example:"I would like to discuss a potential project."`
+	Status      string     `json:"status" gorm:"default:unread;index" example:"unread"` // unread, read, replied, spam
+	IsActive    bool       `json:"is_active" gorm:"default:true" example:"true"`
+	IPAddress   string     `json:"-" gorm:"index"` // Submitter's address, used for rate limiting
+	SpamScore   int        `json:"spam_score" example:"0"`
+	SpamReasons string     `json:"spam_reasons,omitempty" example:""`              // Comma-separated reasons the spam checks gave
+	AssigneeID  *uint      `json:"assignee_id,omitempty" gorm:"index" example:"2"` // Admin user handling the message
+	ArchivedAt  *time.Time `json:"archived_at,omitempty" gorm:"index"`             // Archived messages leave the inbox
+	// AnonymizedAt is when the retention policy erased the sender and content of the message
+	AnonymizedAt *time.Time     `json:"anonymized_at,omitempty" gorm:"index"`
+	Labels       []ContactLabel `json:"labels,omitempty" gorm:"many2many:contact_label_links;"`
+	CreatedAt    time.Time      `json:"created_at" gorm:"index" example:"2023-01-01T00:00:00Z"`
+	UpdatedAt    time.Time      `json:"updated_at" example:"2023-01-01T00:00:00Z"`
+	DeletedAt    gorm.DeletedAt `json:"-" gorm:"index"`
 }
 
 type ContactRequest struct {
@@ -24,18 +41,73 @@ type ContactRequest struct {
 	Email   string `json:"email" binding:"required,email" example:"john@example.com"`
 	Subject string `json:"subject" example:"Project Inquiry"`
 	Message string `json:"message" binding:"required" example:"I would like to discuss a potential project."`
+	// Website is a honeypot field hidden from people; bots that fill it in are flagged as spam
+	Website string `json:"website" example:""`
+	// FormToken is the signed token from GET /api/contacts/form-token, issued when the form is shown
+	FormToken string `json:"form_token" binding:"required" example:"1700000000000.3f2a..."`
+	// CaptchaToken is the response of the configured CAPTCHA widget, when one is enabled
+	CaptchaToken string `json:"captcha_token,omitempty" example:""`
 }
 
-type ContactResponse struct {
+// ContactFormToken is issued when the contact form is shown. The form must send it back
+// no sooner than MinFillSeconds later.
+type ContactFormToken struct {
+	Token          string    `json:"token" example:"1700000000000.3f2a..."`
+	IssuedAt       time.Time `json:"issued_at" example:"2023-01-01T00:00:00Z"`
+	ExpiresAt      time.Time `json:"expires_at" example:"2023-01-02T00:00:00Z"`
+	MinFillSeconds int       `json:"min_fill_seconds" example:"3"`
+	// CaptchaProvider names the CAPTCHA widget the form must show, if any
+	CaptchaProvider string `json:"captcha_provider,omitempty" example:"turnstile"`
+}
+
+// ContactReceipt confirms a submission to the sender without revealing how the spam
+// checks judged it
+type ContactReceipt struct {
 	ID        uint      `json:"id" example:"1"`
 	Name      string    `json:"name" example:"John Doe"`
 	Email     string    `json:"email" example:"john@example.com"`
 	Subject   string    `json:"subject" example:"Project Inquiry"`
 	Message   string    `json:"message" example:"I would like to discuss a potential project."`
-	Status    string    `json:"status" example:"unread"`
-	IsActive  bool      `json:"is_active" example:"true"`
 	CreatedAt time.Time `json:"created_at" example:"2023-01-01T00:00:00Z"`
-	UpdatedAt time.Time `json:"updated_at" example:"2023-01-01T00:00:00Z"`
+}
+
+type ContactResponse struct {
+	ID           uint           `json:"id" example:"1"`
+	Name         string         `json:"name" example:"John Doe"`
+	Email        string         `json:"email" example:"john@example.com"`
+	Subject      string         `json:"subject" example:"Project Inquiry"`
+	Message      string         `json:"message" example:"I would like to discuss a potential project."`
+	Status       string         `json:"status" example:"unread"`
+	IsActive     bool           `json:"is_active" example:"true"`
+	SpamScore    int            `json:"spam_score" example:"0"`
+	SpamReasons  []string       `json:"spam_reasons,omitempty"`
+	AssigneeID   *uint          `json:"assignee_id,omitempty" example:"2"`
+	ArchivedAt   *time.Time     `json:"archived_at,omitempty"`
+	AnonymizedAt *time.Time     `json:"anonymized_at,omitempty"`
+	Labels       []ContactLabel `json:"labels"`
+	CreatedAt    time.Time      `json:"created_at" example:"2023-01-01T00:00:00Z"`
+	UpdatedAt    time.Time      `json:"updated_at" example:"2023-01-01T00:00:00Z"`
+	// ReadAt is when the requesting admin last marked the message read
+	ReadAt *time.Time `json:"read_at,omitempty" example:"2023-01-01T00:10:00Z"`
+	// ReadBy lists who read the message, when a single contact is requested
+	ReadBy []ContactReadReceipt `json:"read_by,omitempty"`
+	// Replies is the thread of replies, oldest first, when a single contact is requested
+	Replies []ContactReply `json:"replies,omitempty"`
+}
+
+// ContactRead records that an admin read a contact message. Marking the message unread
+// removes the admin's record.
+type ContactRead struct {
+	ContactID uint      `json:"contact_id" gorm:"primaryKey;autoIncrement:false" example:"1"`
+	UserID    uint      `json:"user_id" gorm:"primaryKey;autoIncrement:false;index" example:"2"`
+	ReadAt    time.Time `json:"read_at" gorm:"not null" example:"2023-01-01T00:10:00Z"`
+}
+
+// ContactReadReceipt is who read a contact message and when
+type ContactReadReceipt struct {
+	UserID   uint      `json:"user_id" example:"2"`
+	Username string    `json:"username" example:"admin"`
+	ReadAt   time.Time `json:"read_at" example:"2023-01-01T00:10:00Z"`
 }
 
 type ContactUpdateRequest struct {
@@ -46,3 +118,72 @@ type ContactUpdateRequest struct {
 	Status   *string `json:"status,omitempty" example:"read"`
 	IsActive *bool   `json:"is_active,omitempty" example:"true"`
 }
+
+// ContactLabel is an admin-defined label for sorting the inbox
+type ContactLabel struct {
+	ID        uint      `json:"id" gorm:"primarykey" example:"1"`
+	Name      string    `json:"name" gorm:"not null;uniqueIndex" example:"Job offer"`
+	Color     string    `json:"color" example:"#3b82f6"`
+	CreatedAt time.Time `json:"created_at" example:"2023-01-01T00:00:00Z"`
+	UpdatedAt time.Time `json:"updated_at" example:"2023-01-01T00:00:00Z"`
+}
+
+// ContactLabelRequest creates or updates a contact label
+type ContactLabelRequest struct {
+	Name  string `json:"name" binding:"required" example:"Job offer"`
+	Color string `json:"color" example:"#3b82f6"`
+}
+
+// ContactFilter combines the filters and sort order of the contact inbox. Zero values
+// are ignored.
+type ContactFilter struct {
+	// Search matches every word against the name, email, subject or message
+	Search string
+	// Status lists a single status; without it, spam is left out
+	Status  string
+	LabelID *uint
+	// AssigneeID lists the messages of one user; Unassigned lists those of nobody
+	AssigneeID *uint
+	Unassigned bool
+	// Archived lists archived messages instead of the inbox
+	Archived    bool
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time
+	// SortBy is created_at or status
+	SortBy    string
+	SortOrder string
+}
+
+// Bulk contact actions
+const (
+	ContactBulkMarkRead   = "mark_read"
+	ContactBulkMarkUnread = "mark_unread"
+	ContactBulkArchive    = "archive"
+	ContactBulkUnarchive  = "unarchive"
+	ContactBulkLabel      = "label"
+	ContactBulkUnlabel    = "unlabel"
+)
+
+// ContactBulkRequest applies an action to many contacts. Label and unlabel need label_id;
+// mark_read and mark_unread apply to the requesting admin.
+type ContactBulkRequest struct {
+	IDs     []uint `json:"ids" binding:"required,min=1,max=500" example:"1,2,3"`
+	Action  string `json:"action" binding:"required,oneof=mark_read mark_unread archive unarchive label unlabel" example:"archive"`
+	LabelID *uint  `json:"label_id,omitempty" example:"1"`
+}
+
+// ContactBulkDeleteRequest deletes many contacts
+type ContactBulkDeleteRequest struct {
+	IDs []uint `json:"ids" binding:"required,min=1,max=500" example:"1,2,3"`
+}
+
+// ContactBulkResult reports how many contacts a bulk action changed
+type ContactBulkResult struct {
+	Action   string `json:"action" example:"archive"`
+	Affected int64  `json:"affected" example:"3"`
+}
+
+// ContactAssignRequest assigns a contact to a user; a null user_id unassigns it
+type ContactAssignRequest struct {
+	UserID *uint `json:"user_id" example:"2"`
+}
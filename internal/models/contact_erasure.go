@@ -0,0 +1,61 @@
+package models
+
+import "time"
+
+// How personal data is erased from contact messages. Anonymizing keeps the message
+// without its content and sender so that counts and statistics stay intact; deleting
+// removes it with its replies, read receipts and labels.
+const (
+	ContactErasureAnonymize = "anonymize"
+	ContactErasureDelete    = "delete"
+)
+
+// Why personal data was erased
+const (
+	ContactErasureRequest   = "request"   // An admin erased the data of one person
+	ContactErasureRetention = "retention" // The retention job erased messages past the retention period
+)
+
+// ContactErasure is an audit record of an erasure of contact personal data. It keeps a
+// hash of the email address rather than the address itself, so that an erasure can be
+// confirmed to the person who asked for it without storing their address again.
+type ContactErasure struct {
+	ID     uint   `json:"id" gorm:"primarykey" example:"1"`
+	Action string `json:"action" gorm:"not null" example:"delete"`        // anonymize, delete
+	Reason string `json:"reason" gorm:"not null;index" example:"request"` // request, retention
+	// EmailHash is the hex SHA-256 of the lowercased email address of a request
+	EmailHash string `json:"email_hash,omitempty" gorm:"index" example:"973dfe463ec85785f5f95af5ba3906eedb2d931c24e69824a89ea65dba4e813b"`
+	Contacts  int64  `json:"contacts" example:"3"` // Messages erased
+	Replies   int64  `json:"replies" example:"1"`  // Replies erased with them
+	Tasks     int64  `json:"tasks" example:"2"`    // Email tasks about them deleted with them
+	// ActorID and Actor are the admin who erased the data; empty for the retention job
+	ActorID   *uint     `json:"actor_id,omitempty" gorm:"index" example:"1"`
+	Actor     string    `json:"actor,omitempty" example:"admin"`
+	CreatedAt time.Time `json:"created_at" gorm:"index" example:"2023-01-01T00:00:00Z"`
+}
+
+// ContactExport is everything stored about the sender of contact messages, for a
+// data-subject access request
+type ContactExport struct {
+	Email      string                 `json:"email" example:"john@example.com"`
+	ExportedAt time.Time              `json:"exported_at" example:"2023-01-01T00:00:00Z"`
+	Messages   []ContactExportMessage `json:"messages"`
+}
+
+// ContactExportMessage is a contact message in a data-subject export, with the replies
+// sent to it
+type ContactExportMessage struct {
+	ID        uint           `json:"id" example:"1"`
+	Name      string         `json:"name" example:"John Doe"`
+	Email     string         `json:"email" example:"john@example.com"`
+	Subject   string         `json:"subject" example:"Project Inquiry"`
+	Message   string         `json:"message" example:"I would like to discuss a potential project."`
+	Status    string         `json:"status" example:"replied"`
+	IPAddress string         `json:"ip_address,omitempty" example:"203.0.113.7"`
+	Labels    []string       `json:"labels"`
+	Replies   []ContactReply `json:"replies"`
+	CreatedAt time.Time      `json:"created_at" example:"2023-01-01T00:00:00Z"`
+	UpdatedAt time.Time      `json:"updated_at" example:"2023-01-01T00:00:00Z"`
+	// DeletedAt is set for messages an admin deleted from the inbox but that are still stored
+	DeletedAt *time.Time `json:"deleted_at,omitempty" example:"2023-01-02T00:00:00Z"`
+}
@@ -0,0 +1,56 @@
+package models
+
+import "time"
+
+// Delivery statuses of a contact reply. A queued reply with an error is being retried.
+const (
+	ContactReplyQueued = "queued"
+	ContactReplySent   = "sent"
+	ContactReplyFailed = "failed"
+)
+
+// ContactReply is an email an admin sent in answer to a contact message
+type ContactReply struct {
+	ID        uint   `json:"id" gorm:"primarykey" example:"1"`
+	ContactID uint   `json:"contact_id" gorm:"not null;index" example:"1"`
+	AuthorID  uint   `json:"author_id" gorm:"index" example:"1"`
+	Author    string `json:"author" example:"admin"` // Username of the author when the reply was sent
+	Subject   string `json:"subject" example:"Re: Project Inquiry"`
+	Body      string `json:"body" gorm:"type:text;not null" example:"Thanks for reaching out, I am available next week."`
+	Status    string `json:"status" gorm:"default:queued;index" example:"sent"` // queued, sent, failed
+	// LastError is the error of the last failed delivery attempt
+	LastError string `json:"last_error,omitempty" example:""`
+	// TaskID is the background task delivering the reply
+	TaskID    *uint      `json:"task_id,omitempty" example:"12"`
+	MessageID string     `json:"message_id,omitempty" example:"<1700000000.ab12@example.com>"`
+	SentAt    *time.Time `json:"sent_at,omitempty" example:"2023-01-01T00:05:00Z"`
+	CreatedAt time.Time  `json:"created_at" example:"2023-01-01T00:00:00Z"`
+	UpdatedAt time.Time  `json:"updated_at" example:"2023-01-01T00:05:00Z"`
+}
+
+// ContactReplyRequest sends a reply to a contact message. The body may be left out when
+// a template is given, in which case the rendered template is sent.
+type ContactReplyRequest struct {
+	// Subject defaults to the template subject, or "Re: " and the message subject
+	Subject    string `json:"subject" example:"Re: Project Inquiry"`
+	Body       string `json:"body" example:"Thanks for reaching out, I am available next week."`
+	TemplateID *uint  `json:"template_id,omitempty" example:"1"`
+}
+
+// ReplyTemplate is a canned reply. Subject and body are Go text templates that can use
+// {{.Name}}, {{.Email}}, {{.Subject}}, {{.SiteName}} and {{.Author}}.
+type ReplyTemplate struct {
+	ID        uint      `json:"id" gorm:"primarykey" example:"1"`
+	Name      string    `json:"name" gorm:"not null;uniqueIndex" example:"Thanks, will reply soon"`
+	Subject   string    `json:"subject" example:"Re: {{.Subject}}"`
+	Body      string    `json:"body" gorm:"type:text;not null" example:"Hi {{.Name}}, thanks for your message."`
+	CreatedAt time.Time `json:"created_at" example:"2023-01-01T00:00:00Z"`
+	UpdatedAt time.Time `json:"updated_at" example:"2023-01-01T00:00:00Z"`
+}
+
+// ReplyTemplateRequest creates or updates a canned reply
+type ReplyTemplateRequest struct {
+	Name    string `json:"name" binding:"required" example:"Thanks, will reply soon"`
+	Subject string `json:"subject" example:"Re: {{.Subject}}"`
+	Body    string `json:"body" binding:"required" example:"Hi {{.Name}}, thanks for your message."`
+}
@@ -6,6 +6,16 @@ import (
 	"gorm.io/gorm"
 )
 
+// ContentStatusPublished is the status of content shown on the public site
+const ContentStatusPublished = "published"
+
+// ContentFilter narrows a content listing; the filters that are set all apply
+type ContentFilter struct {
+	Category string
+	Status   string
+	Tag      string
+}
+
 // Content represents a content item in the system
 type Content struct {
 	ID          uint           `json:"id" gorm:"primarykey" example:"1"`
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// DataMigration records a one-time data migration, such as the backfill of a new index,
+// so that it runs once across restarts and replicas. FinishedAt is unset while the
+// migration runs.
+type DataMigration struct {
+	Name       string     `json:"name" gorm:"primarykey" example:"upload-references"`
+	Instance   string     `json:"instance" example:"api-1-4821"`
+	StartedAt  time.Time  `json:"started_at" example:"2023-01-01T00:00:00Z"`
+	FinishedAt *time.Time `json:"finished_at" example:"2023-01-01T00:00:05Z"`
+	Items      int        `json:"items" example:"12"`
+}
@@ -0,0 +1,67 @@
+package models
+
+import "time"
+
+// Job run statuses
+const (
+	JobRunRunning     = "running"
+	JobRunSucceeded   = "succeeded"
+	JobRunFailed      = "failed"
+	JobRunInterrupted = "interrupted"
+)
+
+// Job run triggers
+const (
+	JobTriggerSchedule = "schedule"
+	JobTriggerManual   = "manual"
+	JobTriggerStartup  = "startup"
+)
+
+// JobRun records one run of a scheduled job
+type JobRun struct {
+	ID             uint       `json:"id" gorm:"primarykey" example:"1"`
+	JobName        string     `json:"job_name" gorm:"not null;index:idx_job_runs_job_started" example:"url-refresh"`
+	Trigger        string     `json:"trigger" gorm:"not null" example:"schedule"`
+	Instance       string     `json:"instance" example:"api-1-4821"`
+	FencingToken   int64      `json:"fencing_token" example:"42"`
+	Status         string     `json:"status" gorm:"not null;index" example:"succeeded"`
+	StartedAt      time.Time  `json:"started_at" gorm:"not null;index:idx_job_runs_job_started" example:"2023-01-01T00:00:00Z"`
+	FinishedAt     *time.Time `json:"finished_at" example:"2023-01-01T00:00:05Z"`
+	Duration       string     `json:"duration,omitempty" gorm:"-" example:"5s"`
+	ItemsProcessed int64      `json:"items_processed" example:"12"`
+	Error          string     `json:"error,omitempty" example:""`
+}
+
+// JobState persists the pause state of a job across restarts
+type JobState struct {
+	Name      string    `json:"name" gorm:"primarykey" example:"url-refresh"`
+	Paused    bool      `json:"paused" example:"false"`
+	UpdatedAt time.Time `json:"updated_at" example:"2023-01-01T00:00:00Z"`
+}
+
+// JobLease grants one instance the right to run a job until it expires. The token
+// grows with every acquisition, so a holder whose lease expired is fenced out of
+// renewing it, and its job out of further writes, once another instance has taken
+// over. LastSlot is the schedule time of
+// the last scheduled run, so that each tick of the schedule runs once across instances.
+type JobLease struct {
+	Name      string     `json:"name" gorm:"primarykey" example:"url-refresh"`
+	Holder    string     `json:"holder" gorm:"not null" example:"api-1-4821"`
+	Token     int64      `json:"token" gorm:"not null" example:"42"`
+	ExpiresAt time.Time  `json:"expires_at" gorm:"not null" example:"2023-01-01T00:01:00Z"`
+	LastSlot  *time.Time `json:"last_slot" example:"2023-01-01T00:00:00Z"`
+	UpdatedAt time.Time  `json:"updated_at" example:"2023-01-01T00:00:00Z"`
+}
+
+// JobStatus describes a registered job in API responses. Instance names the holder of
+// the job's lease while it runs.
+type JobStatus struct {
+	Name        string     `json:"name" example:"url-refresh"`
+	Description string     `json:"description" example:"Extend the expiry of uploads used by resources"`
+	Schedule    string     `json:"schedule" example:"0 * * * *"`
+	Paused      bool       `json:"paused" example:"false"`
+	Running     bool       `json:"running" example:"false"`
+	Instance    string     `json:"instance,omitempty" example:"api-1-4821"`
+	NextRun     *time.Time `json:"next_run,omitempty" example:"2023-01-01T01:00:00Z"`
+	LastRun     *JobRun    `json:"last_run,omitempty"`
+}
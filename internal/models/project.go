@@ -43,17 +43,18 @@ type ProjectRequest struct {
 
 // ProjectResponse represents the response payload for project operations
 type ProjectResponse struct {
-	ID             uint         `json:"id" example:"1"`
-	Name           string       `json:"name" example:"Car Rent"`
-	Description    string       `json:"description" example:"Web-based platform for car rentals"`
-	Tags           []ProjectTag `json:"tags"`
-	Image          string       `json:"image" example:"carrent.png"`
-	SourceCodeLink string       `json:"source_code_link" example:"https://github.com/example"`
-	LiveDemoLink   string       `json:"live_demo_link" example:"https://example.com"`
-	Order          int          `json:"order" example:"1"`
-	IsActive       bool         `json:"is_active" example:"true"`
-	CreatedAt      time.Time    `json:"created_at" example:"2023-01-01T00:00:00Z"`
-	UpdatedAt      time.Time    `json:"updated_at" example:"2023-01-01T00:00:00Z"`
+	ID             uint                 `json:"id" example:"1"`
+	Name           string               `json:"name" example:"Car Rent"`
+	Description    string               `json:"description" example:"Web-based platform for car rentals"`
+	Tags           []ProjectTag         `json:"tags"`
+	Image          string               `json:"image" example:"carrent.png"`
+	SourceCodeLink string               `json:"source_code_link" example:"https://github.com/example"`
+	LiveDemoLink   string               `json:"live_demo_link" example:"https://example.com"`
+	Order          int                  `json:"order" example:"1"`
+	IsActive       bool                 `json:"is_active" example:"true"`
+	Galleries      []CollectionResponse `json:"galleries,omitempty"`
+	CreatedAt      time.Time            `json:"created_at" example:"2023-01-01T00:00:00Z"`
+	UpdatedAt      time.Time            `json:"updated_at" example:"2023-01-01T00:00:00Z"`
 }
 
 func (p *Project) ToResponse() ProjectResponse {
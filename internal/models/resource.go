@@ -80,20 +80,34 @@ type ResourceUpdateRequest struct {
 	IsActive    *bool         `json:"is_active,omitempty" example:"true"`
 }
 
-func (r *Resource) ToResponse() ResourceResponse {
-	tags := []string{}
-	if r.Tags != "" {
-		// Simple split by comma - you might want to use a more sophisticated parser
-		tags = append(tags, r.Tags)
-	}
+// ResourceFilter combines the filters and sort order of a resource listing. Zero
+// values are ignored.
+type ResourceFilter struct {
+	Type        ResourceType
+	Category    string
+	Tag         string
+	Search      string
+	PublicOnly  bool
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time
+	// ContentType matches the upload MIME type exactly, or by prefix when it ends
+	// with "/" or "/*", e.g. "image/*"
+	ContentType string
+	MinSize     int64
+	MaxSize     int64
+	// SortBy is one of name, created_at, view_count or download_count
+	SortBy    string
+	SortOrder string
+}
 
+func (r *Resource) ToResponse() ResourceResponse {
 	return ResourceResponse{
 		ID:            r.ID,
 		Name:          r.Name,
 		Description:   r.Description,
 		Type:          r.Type,
 		Category:      r.Category,
-		Tags:          tags,
+		Tags:          ParseTags(r.Tags),
 		Upload:        r.Upload.ToResponse(),
 		Alt:           r.Alt,
 		IsPublic:      r.IsPublic,
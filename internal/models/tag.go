@@ -0,0 +1,116 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Tag is a normalized label shared by resources, contents and projects
+type Tag struct {
+	ID        uint      `json:"id" gorm:"primarykey" example:"1"`
+	Name      string    `json:"name" gorm:"not null" example:"golang"`
+	Slug      string    `json:"slug" gorm:"not null;uniqueIndex" example:"golang"`
+	CreatedAt time.Time `json:"created_at" example:"2023-01-01T00:00:00Z"`
+	UpdatedAt time.Time `json:"updated_at" example:"2023-01-01T00:00:00Z"`
+}
+
+// Tagging links a tag to a resource, content or project. EntityType uses the
+// ReferenceEntity* constants.
+type Tagging struct {
+	ID         uint      `json:"id" gorm:"primarykey" example:"1"`
+	TagID      uint      `json:"tag_id" gorm:"not null;index;uniqueIndex:idx_taggings_entity_tag" example:"1"`
+	EntityType string    `json:"entity_type" gorm:"not null;uniqueIndex:idx_taggings_entity_tag" example:"resource"`
+	EntityID   uint      `json:"entity_id" gorm:"not null;uniqueIndex:idx_taggings_entity_tag" example:"1"`
+	CreatedAt  time.Time `json:"created_at" example:"2023-01-01T00:00:00Z"`
+}
+
+// TagUsage represents a tag with the number of entities using it
+type TagUsage struct {
+	ID        uint   `json:"id" example:"1"`
+	Name      string `json:"name" example:"golang"`
+	Slug      string `json:"slug" example:"golang"`
+	Count     int64  `json:"count" example:"7"`
+	Resources int64  `json:"resources" example:"3"`
+	Contents  int64  `json:"contents" example:"2"`
+	Projects  int64  `json:"projects" example:"2"`
+}
+
+// NormalizeTagName trims a tag, collapses inner whitespace and lowercases it
+func NormalizeTagName(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), " "))
+}
+
+// tagSymbols spells out the symbols that tell tags apart, so that "C++", "C#" and "C"
+// get different slugs
+var tagSymbols = map[rune]string{'+': "plus", '#': "sharp"}
+
+// TagSlug returns the URL-safe slug of a tag name, e.g. "Node.js API" becomes
+// "node-js-api", "C++" "c-plus-plus" and ".NET" "dot-net". A name of other symbols
+// only, such as an emoji, gets a slug from its hash. Blank names get no slug.
+func TagSlug(name string) string {
+	var words []string
+	var word strings.Builder
+	flush := func() {
+		if word.Len() > 0 {
+			words = append(words, word.String())
+			word.Reset()
+		}
+	}
+
+	runes := []rune(strings.ToLower(name))
+	for i, r := range runes {
+		switch {
+		case isTagWordRune(r):
+			word.WriteRune(r)
+		case tagSymbols[r] != "":
+			flush()
+			words = append(words, tagSymbols[r])
+		case r == '.' && len(words) == 0 && word.Len() == 0 && i+1 < len(runes) && isTagWordRune(runes[i+1]):
+			// A leading dot is part of the name, as in ".NET"
+			words = append(words, "dot")
+		default:
+			flush()
+		}
+	}
+	flush()
+
+	if len(words) == 0 {
+		name = NormalizeTagName(name)
+		if name == "" {
+			return ""
+		}
+		sum := sha256.Sum256([]byte(name))
+		return "tag-" + hex.EncodeToString(sum[:4])
+	}
+	return strings.Join(words, "-")
+}
+
+// isTagWordRune reports whether r is kept as is in a tag slug
+func isTagWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// ParseTags splits a comma separated tag string into normalized names, dropping
+// empty entries and entries that share a slug with an earlier one
+func ParseTags(tags string) []string {
+	return NormalizeTags(strings.Split(tags, ","))
+}
+
+// NormalizeTags normalizes tag names, dropping empty entries and duplicate slugs
+func NormalizeTags(tags []string) []string {
+	names := []string{}
+	seen := make(map[string]bool)
+	for _, tag := range tags {
+		name := NormalizeTagName(tag)
+		slug := TagSlug(name)
+		if slug == "" || seen[slug] {
+			continue
+		}
+		seen[slug] = true
+		names = append(names, name)
+	}
+	return names
+}
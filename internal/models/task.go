@@ -0,0 +1,44 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Task statuses
+const (
+	TaskPending   = "pending"
+	TaskRunning   = "running"
+	TaskSucceeded = "succeeded"
+	TaskDead      = "dead"
+)
+
+// Task is a unit of background work in the task queue. A worker claims a due task by
+// locking it until LockedUntil; a task whose lock expires without the worker reporting
+// back is claimed again, so handlers must be safe to run more than once. Failed tasks
+// are retried at RunAt until MaxAttempts is reached, then dead-lettered. Ref names the
+// record a task is about, so that its tasks can be deleted along with the record.
+type Task struct {
+	ID          uint            `json:"id" gorm:"primarykey" example:"1"`
+	Kind        string          `json:"kind" gorm:"not null;index" example:"s3.delete"`
+	Ref         string          `json:"ref,omitempty" gorm:"index" example:"contact-12"`
+	Payload     json.RawMessage `json:"payload" gorm:"type:text" swaggertype:"object"`
+	Status      string          `json:"status" gorm:"not null;index:idx_tasks_status_run_at" example:"pending"`
+	Attempts    int             `json:"attempts" example:"1"`
+	MaxAttempts int             `json:"max_attempts" example:"5"`
+	RunAt       time.Time       `json:"run_at" gorm:"not null;index:idx_tasks_status_run_at" example:"2023-01-01T00:00:00Z"`
+	LockedBy    string          `json:"locked_by,omitempty" example:"api-1-4821-2"`
+	LockedUntil *time.Time      `json:"locked_until,omitempty" example:"2023-01-01T00:05:00Z"`
+	LastError   string          `json:"last_error,omitempty" example:""`
+	CreatedAt   time.Time       `json:"created_at" example:"2023-01-01T00:00:00Z"`
+	UpdatedAt   time.Time       `json:"updated_at" example:"2023-01-01T00:00:00Z"`
+	FinishedAt  *time.Time      `json:"finished_at,omitempty" example:"2023-01-01T00:00:01Z"`
+}
+
+// TaskStats counts the tasks in each status
+type TaskStats struct {
+	Pending   int64 `json:"pending" example:"3"`
+	Running   int64 `json:"running" example:"1"`
+	Succeeded int64 `json:"succeeded" example:"120"`
+	Dead      int64 `json:"dead" example:"0"`
+}
@@ -6,50 +6,108 @@ import (
 	"gorm.io/gorm"
 )
 
+// Virus scan states of an upload. Only clean uploads are served.
+const (
+	UploadScanPending  = "pending"
+	UploadScanClean    = "clean"
+	UploadScanInfected = "infected"
+	UploadScanFailed   = "failed"
+)
+
 // Upload represents a file upload record in the system
 type Upload struct {
-	ID           uint           `json:"id" gorm:"primarykey" example:"1"`
-	FileName     string         `json:"file_name" gorm:"not null" example:"image_123456.jpg"`
-	OriginalName string         `json:"original_name" gorm:"not null" example:"my-image.jpg"`
-	FileSize     int64          `json:"file_size" example:"1024000"`
-	ContentType  string         `json:"content_type" example:"image/jpeg"`
-	S3Key        string         `json:"s3_key" gorm:"not null;unique" example:"uploads/2023/01/01/image_123456.jpg"`
-	S3Bucket     string         `json:"s3_bucket" gorm:"not null" example:"my-portfolio-bucket"`
-	URL          string         `json:"url" gorm:"not null" example:"https://my-portfolio-bucket.s3.amazonaws.com/uploads/2023/01/01/image_123456.jpg"`
-	ExpiresAt    *time.Time     `json:"expires_at" gorm:"index" example:"2024-01-01T00:00:00Z"`
-	IsActive     bool           `json:"is_active" gorm:"default:true" example:"true"`
-	CreatedAt    time.Time      `json:"created_at" example:"2023-01-01T00:00:00Z"`
-	UpdatedAt    time.Time      `json:"updated_at" example:"2023-01-01T00:00:00Z"`
-	DeletedAt    gorm.DeletedAt `json:"-" gorm:"index"`
+	ID            uint           `json:"id" gorm:"primarykey" example:"1"`
+	FileName      string         `json:"file_name" gorm:"not null" example:"image_123456.jpg"`
+	OriginalName  string         `json:"original_name" gorm:"not null" example:"my-image.jpg"`
+	FileSize      int64          `json:"file_size" example:"1024000"`
+	ContentType   string         `json:"content_type" example:"image/jpeg"`
+	S3Key         string         `json:"s3_key" gorm:"not null;index" example:"uploads/2023/01/01/image_123456.jpg"`
+	S3Bucket      string         `json:"s3_bucket" gorm:"not null" example:"my-portfolio-bucket"`
+	URL           string         `json:"url" gorm:"-" example:"https://my-portfolio-bucket.s3.amazonaws.com/uploads/2023/01/01/image_123456.jpg"` // Computed at read time from S3Key
+	ExpiresAt     *time.Time     `json:"expires_at" gorm:"index" example:"2024-01-01T00:00:00Z"`
+	IsActive      bool           `json:"is_active" gorm:"default:true" example:"true"`
+	ContentHash   string         `json:"content_hash" gorm:"index" example:"9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"`
+	Width         int            `json:"width" example:"1920"`
+	Height        int            `json:"height" example:"1080"`
+	DominantColor string         `json:"dominant_color" example:"#3a5f7d"`
+	BlurHash      string         `json:"blur_hash" example:"LEHV6nWB2yk8pyo0adR*.7kCMdnj"`
+	Duration      float64        `json:"duration" example:"12.5"` // Seconds, for audio and video
+	VideoCodec    string         `json:"video_codec" example:"h264"`
+	AudioCodec    string         `json:"audio_codec" example:"aac"`
+	PosterKey     string         `json:"-" gorm:"index"`                    // Poster frame extracted from a video
+	TranscodedKey string         `json:"-" gorm:"index"`                    // Browser-playable MP4 of a video in another format
+	PosterURL     string         `json:"poster_url,omitempty" gorm:"-"`     // Computed at read time from PosterKey
+	TranscodedURL string         `json:"transcoded_url,omitempty" gorm:"-"` // Computed at read time from TranscodedKey
+	ScanStatus    string         `json:"scan_status" gorm:"default:pending;index" example:"clean"`
+	ScanSignature string         `json:"scan_signature" example:"Eicar-Test-Signature"` // Malware found by the last scan
+	ScannedAt     *time.Time     `json:"scanned_at" example:"2023-01-01T00:00:00Z"`
+	UploadedBy    *uint          `json:"uploaded_by" gorm:"index" example:"1"` // User who uploaded the file, counted against their quota
+	CreatedAt     time.Time      `json:"created_at" example:"2023-01-01T00:00:00Z"`
+	UpdatedAt     time.Time      `json:"updated_at" example:"2023-01-01T00:00:00Z"`
+	DeletedAt     gorm.DeletedAt `json:"-" gorm:"index"`
 }
 
 // UploadResponse represents the response payload for upload operations
 type UploadResponse struct {
-	ID           uint       `json:"id" example:"1"`
-	FileName     string     `json:"file_name" example:"image_123456.jpg"`
-	OriginalName string     `json:"original_name" example:"my-image.jpg"`
-	FileSize     int64      `json:"file_size" example:"1024000"`
-	ContentType  string     `json:"content_type" example:"image/jpeg"`
-	URL          string     `json:"url" example:"https://my-portfolio-bucket.s3.amazonaws.com/uploads/2023/01/01/image_123456.jpg"`
-	ExpiresAt    *time.Time `json:"expires_at" example:"2024-01-01T00:00:00Z"`
-	IsActive     bool       `json:"is_active" example:"true"`
-	CreatedAt    time.Time  `json:"created_at" example:"2023-01-01T00:00:00Z"`
+	ID            uint       `json:"id" example:"1"`
+	FileName      string     `json:"file_name" example:"image_123456.jpg"`
+	OriginalName  string     `json:"original_name" example:"my-image.jpg"`
+	FileSize      int64      `json:"file_size" example:"1024000"`
+	ContentType   string     `json:"content_type" example:"image/jpeg"`
+	URL           string     `json:"url" example:"https://my-portfolio-bucket.s3.amazonaws.com/uploads/2023/01/01/image_123456.jpg"`
+	ExpiresAt     *time.Time `json:"expires_at" example:"2024-01-01T00:00:00Z"`
+	IsActive      bool       `json:"is_active" example:"true"`
+	ContentHash   string     `json:"content_hash" example:"9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"`
+	Width         int        `json:"width" example:"1920"`
+	Height        int        `json:"height" example:"1080"`
+	DominantColor string     `json:"dominant_color" example:"#3a5f7d"`
+	BlurHash      string     `json:"blur_hash" example:"LEHV6nWB2yk8pyo0adR*.7kCMdnj"`
+	Duration      float64    `json:"duration,omitempty" example:"12.5"`
+	VideoCodec    string     `json:"video_codec,omitempty" example:"h264"`
+	AudioCodec    string     `json:"audio_codec,omitempty" example:"aac"`
+	PosterURL     string     `json:"poster_url,omitempty" example:"https://my-portfolio-bucket.s3.amazonaws.com/uploads/poster.jpg"`
+	TranscodedURL string     `json:"transcoded_url,omitempty" example:"https://my-portfolio-bucket.s3.amazonaws.com/uploads/video.mp4"`
+	ScanStatus    string     `json:"scan_status" example:"clean"`
+	ScanSignature string     `json:"scan_signature,omitempty" example:"Eicar-Test-Signature"`
+	ScannedAt     *time.Time `json:"scanned_at,omitempty" example:"2023-01-01T00:00:00Z"`
+	UploadedBy    *uint      `json:"uploaded_by,omitempty" example:"1"`
+	CreatedAt     time.Time  `json:"created_at" example:"2023-01-01T00:00:00Z"`
 }
 
 func (u *Upload) ToResponse() UploadResponse {
 	return UploadResponse{
-		ID:           u.ID,
-		FileName:     u.FileName,
-		OriginalName: u.OriginalName,
-		FileSize:     u.FileSize,
-		ContentType:  u.ContentType,
-		URL:          u.URL,
-		ExpiresAt:    u.ExpiresAt,
-		IsActive:     u.IsActive,
-		CreatedAt:    u.CreatedAt,
+		ID:            u.ID,
+		FileName:      u.FileName,
+		OriginalName:  u.OriginalName,
+		FileSize:      u.FileSize,
+		ContentType:   u.ContentType,
+		URL:           u.URL,
+		ExpiresAt:     u.ExpiresAt,
+		IsActive:      u.IsActive,
+		ContentHash:   u.ContentHash,
+		Width:         u.Width,
+		Height:        u.Height,
+		DominantColor: u.DominantColor,
+		BlurHash:      u.BlurHash,
+		Duration:      u.Duration,
+		VideoCodec:    u.VideoCodec,
+		AudioCodec:    u.AudioCodec,
+		PosterURL:     u.PosterURL,
+		TranscodedURL: u.TranscodedURL,
+		ScanStatus:    u.ScanStatus,
+		ScanSignature: u.ScanSignature,
+		ScannedAt:     u.ScannedAt,
+		UploadedBy:    u.UploadedBy,
+		CreatedAt:     u.CreatedAt,
 	}
 }
 
+// IsQuarantined reports whether an upload must not be served because its virus
+// scan is pending, failed or found malware
+func (u *Upload) IsQuarantined() bool {
+	return u.ScanStatus != UploadScanClean
+}
+
 // UploadSummary represents upload statistics
 type UploadSummary struct {
 	TotalFiles         int64  `json:"total_files" example:"150"`
@@ -58,7 +116,20 @@ type UploadSummary struct {
 	Images             int64  `json:"images" example:"80"`
 	Documents          int64  `json:"documents" example:"30"`
 	Videos             int64  `json:"videos" example:"20"`
-	Others             int64  `json:"others" example:"20"`
+	Audio              int64  `json:"audio" example:"5"`
+	Others             int64  `json:"others" example:"15"`
+}
+
+// DeduplicationReport represents storage savings from content-addressed deduplication
+type DeduplicationReport struct {
+	TotalUploads        int64  `json:"total_uploads" example:"150"`
+	UniqueObjects       int64  `json:"unique_objects" example:"120"`
+	DuplicateUploads    int64  `json:"duplicate_uploads" example:"30"`
+	LogicalSize         int64  `json:"logical_size" example:"62914560"`
+	StoredSize          int64  `json:"stored_size" example:"52428800"`
+	SavedSize           int64  `json:"saved_size" example:"10485760"`
+	SavedSizeFormatted  string `json:"saved_size_formatted" example:"10.0 MB"`
+	StoredSizeFormatted string `json:"stored_size_formatted" example:"50.0 MB"`
 }
 
 // UploadListResponse represents the response for upload list with summary
@@ -66,3 +137,30 @@ type UploadListResponse struct {
 	Uploads []UploadResponse `json:"uploads"`
 	Summary UploadSummary    `json:"summary"`
 }
+
+// CleanupReport summarizes a run of the expired upload cleanup job
+type CleanupReport struct {
+	DryRun              bool      `json:"dry_run" example:"true"`
+	GracePeriod         string    `json:"grace_period" example:"720h0m0s"`
+	StartedAt           time.Time `json:"started_at" example:"2023-01-01T00:00:00Z"`
+	FinishedAt          time.Time `json:"finished_at" example:"2023-01-01T00:00:05Z"`
+	Batches             int       `json:"batches" example:"1"`
+	Scanned             int       `json:"scanned" example:"12"`
+	Deleted             int       `json:"deleted" example:"10"`
+	Skipped             int       `json:"skipped" example:"1"`
+	Failed              int       `json:"failed" example:"1"`
+	BytesFreed          int64     `json:"bytes_freed" example:"2048000"`
+	BytesFreedFormatted string    `json:"bytes_freed_formatted" example:"2.0 MB"`
+	Errors              []string  `json:"errors,omitempty"`
+}
+
+// ScanReport summarizes a run of the upload virus rescan job
+type ScanReport struct {
+	StartedAt  time.Time `json:"started_at" example:"2023-01-01T00:00:00Z"`
+	FinishedAt time.Time `json:"finished_at" example:"2023-01-01T00:00:05Z"`
+	Scanned    int       `json:"scanned" example:"12"`
+	Clean      int       `json:"clean" example:"10"`
+	Infected   int       `json:"infected" example:"1"`
+	Failed     int       `json:"failed" example:"1"`
+	Errors     []string  `json:"errors,omitempty"`
+}
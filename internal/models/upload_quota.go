@@ -0,0 +1,122 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// MIME classes that upload quotas can allow
+const (
+	MimeClassImage    = "image"
+	MimeClassVideo    = "video"
+	MimeClassAudio    = "audio"
+	MimeClassDocument = "document"
+	MimeClassOther    = "other"
+)
+
+// MimeClasses lists every MIME class
+var MimeClasses = []string{MimeClassImage, MimeClassVideo, MimeClassAudio, MimeClassDocument, MimeClassOther}
+
+// Sources of an effective upload quota
+const (
+	QuotaSourceUser    = "user"
+	QuotaSourceRole    = "role"
+	QuotaSourceDefault = "default"
+)
+
+// MimeClass returns the class of a content type, matching the upload summary categories
+func MimeClass(contentType string) string {
+	switch {
+	case strings.HasPrefix(contentType, "image/"):
+		return MimeClassImage
+	case strings.HasPrefix(contentType, "video/"):
+		return MimeClassVideo
+	case strings.HasPrefix(contentType, "audio/"):
+		return MimeClassAudio
+	case contentType == "application/pdf", contentType == "text/plain", strings.Contains(contentType, "word"):
+		return MimeClassDocument
+	default:
+		return MimeClassOther
+	}
+}
+
+// UploadQuota limits the uploads of a role or of a single user. A user quota replaces
+// the quota of the user's role. Zero limits and empty classes mean unlimited.
+type UploadQuota struct {
+	ID             uint      `json:"id" gorm:"primarykey" example:"1"`
+	Role           string    `json:"role" gorm:"index" example:"editor"`
+	UserID         *uint     `json:"user_id" gorm:"index" example:"2"`
+	MaxTotalBytes  int64     `json:"max_total_bytes" example:"1073741824"`
+	MaxFiles       int64     `json:"max_files" example:"500"`
+	MaxFileSize    int64     `json:"max_file_size" example:"10485760"`
+	AllowedClasses string    `json:"-"` // Comma-separated MIME classes
+	CreatedAt      time.Time `json:"created_at" example:"2023-01-01T00:00:00Z"`
+	UpdatedAt      time.Time `json:"updated_at" example:"2023-01-01T00:00:00Z"`
+}
+
+// UploadQuotaRequest creates or replaces the quota of a role or user; exactly one of
+// role and user_id must be set
+type UploadQuotaRequest struct {
+	Role           string   `json:"role" example:"editor"`
+	UserID         *uint    `json:"user_id" example:"2"`
+	MaxTotalBytes  int64    `json:"max_total_bytes" binding:"min=0" example:"1073741824"`
+	MaxFiles       int64    `json:"max_files" binding:"min=0" example:"500"`
+	MaxFileSize    int64    `json:"max_file_size" binding:"min=0" example:"10485760"`
+	AllowedClasses []string `json:"allowed_classes" example:"image,document"`
+}
+
+// UploadQuotaResponse represents an upload quota in API responses
+type UploadQuotaResponse struct {
+	ID             uint      `json:"id" example:"1"`
+	Role           string    `json:"role,omitempty" example:"editor"`
+	UserID         *uint     `json:"user_id,omitempty" example:"2"`
+	MaxTotalBytes  int64     `json:"max_total_bytes" example:"1073741824"`
+	MaxFiles       int64     `json:"max_files" example:"500"`
+	MaxFileSize    int64     `json:"max_file_size" example:"10485760"`
+	AllowedClasses []string  `json:"allowed_classes" example:"image,document"`
+	UpdatedAt      time.Time `json:"updated_at" example:"2023-01-01T00:00:00Z"`
+}
+
+func (q *UploadQuota) ToResponse() UploadQuotaResponse {
+	return UploadQuotaResponse{
+		ID:             q.ID,
+		Role:           q.Role,
+		UserID:         q.UserID,
+		MaxTotalBytes:  q.MaxTotalBytes,
+		MaxFiles:       q.MaxFiles,
+		MaxFileSize:    q.MaxFileSize,
+		AllowedClasses: q.Classes(),
+		UpdatedAt:      q.UpdatedAt,
+	}
+}
+
+// Classes returns the allowed MIME classes, empty when every class is allowed
+func (q *UploadQuota) Classes() []string {
+	classes := []string{}
+	for _, class := range strings.Split(q.AllowedClasses, ",") {
+		if class = strings.TrimSpace(class); class != "" {
+			classes = append(classes, class)
+		}
+	}
+	return classes
+}
+
+// EffectiveQuota is the quota that applies to a user and where it comes from
+type EffectiveQuota struct {
+	Source         string   `json:"source" example:"role"`
+	MaxTotalBytes  int64    `json:"max_total_bytes" example:"1073741824"`
+	MaxFiles       int64    `json:"max_files" example:"500"`
+	MaxFileSize    int64    `json:"max_file_size" example:"10485760"`
+	AllowedClasses []string `json:"allowed_classes" example:"image,document"`
+}
+
+// UploadUsage is a user's upload usage against their quota
+type UploadUsage struct {
+	UserID uint           `json:"user_id" example:"2"`
+	Role   string         `json:"role" example:"editor"`
+	Quota  EffectiveQuota `json:"quota"`
+	Usage  UploadSummary  `json:"usage"`
+	// Remaining limits are omitted when unlimited
+	RemainingBytes *int64 `json:"remaining_bytes,omitempty" example:"1063741824"`
+	RemainingFiles *int64 `json:"remaining_files,omitempty" example:"480"`
+}
@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+// Entity types that can reference an upload
+const (
+	ReferenceEntityResource    = "resource"
+	ReferenceEntityProject     = "project"
+	ReferenceEntityTestimonial = "testimonial"
+	ReferenceEntityExperience  = "experience"
+	ReferenceEntityContent     = "content"
+)
+
+// UploadReference records that an entity field points at an upload
+type UploadReference struct {
+	ID         uint      `json:"id" gorm:"primarykey" example:"1"`
+	UploadID   uint      `json:"upload_id" gorm:"not null;index" example:"1"`
+	EntityType string    `json:"entity_type" gorm:"not null;index:idx_upload_references_entity" example:"project"`
+	EntityID   uint      `json:"entity_id" gorm:"not null;index:idx_upload_references_entity" example:"3"`
+	Field      string    `json:"field" gorm:"not null" example:"image"`
+	CreatedAt  time.Time `json:"created_at" example:"2023-01-01T00:00:00Z"`
+}
+
+// StorageObject represents an object stored in S3
+type StorageObject struct {
+	Key          string    `json:"key" example:"uploads/0b6f1c2e-4a57-4d43-9b0e-2b8a4a0d6b1e.jpg"`
+	Size         int64     `json:"size" example:"1024000"`
+	LastModified time.Time `json:"last_modified" example:"2023-01-01T00:00:00Z"`
+}
+
+// OrphanReport lists uploads and S3 objects that nothing references
+type OrphanReport struct {
+	UnreferencedUploads []UploadResponse `json:"unreferenced_uploads"`
+	OrphanObjects       []StorageObject  `json:"orphan_objects"`
+	TotalSize           int64            `json:"total_size" example:"2048000"`
+	TotalSizeFormatted  string           `json:"total_size_formatted" example:"2.0 MB"`
+	Cleaned             bool             `json:"cleaned" example:"false"`
+	Errors              []string         `json:"errors,omitempty"`
+}
@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"portfolio-be/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ResourceCounts are lifetime view and download increments of a resource
+type ResourceCounts struct {
+	Views     int64
+	Downloads int64
+}
+
+type AnalyticsRepository struct {
+	db *gorm.DB
+}
+
+func NewAnalyticsRepository(db *gorm.DB) *AnalyticsRepository {
+	return &AnalyticsRepository{db: db}
+}
+
+// Apply adds aggregated daily stats and lifetime resource counters in one transaction
+func (r *AnalyticsRepository) Apply(stats []models.ResourceDailyStat, counts map[uint]ResourceCounts) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if len(stats) > 0 {
+			err := tx.Clauses(clause.OnConflict{
+				Columns: []clause.Column{{Name: "resource_id"}, {Name: "day"}, {Name: "dimension"}, {Name: "value"}},
+				DoUpdates: clause.Assignments(map[string]interface{}{
+					"views":      gorm.Expr("resource_daily_stats.views + excluded.views"),
+					"downloads":  gorm.Expr("resource_daily_stats.downloads + excluded.downloads"),
+					"updated_at": gorm.Expr("excluded.updated_at"),
+				}),
+			}).CreateInBatches(stats, 100).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		for resourceID, c := range counts {
+			err := tx.Model(&models.Resource{}).Where("id = ?", resourceID).Updates(map[string]interface{}{
+				"view_count":     gorm.Expr("view_count + ?", c.Views),
+				"download_count": gorm.Expr("download_count + ?", c.Downloads),
+			}).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// GetDailyTotals returns the per-day totals of a resource between two days, inclusive
+func (r *AnalyticsRepository) GetDailyTotals(resourceID uint, from, to string) ([]models.ResourceDailyStat, error) {
+	var stats []models.ResourceDailyStat
+	err := r.db.Where("resource_id = ? AND dimension = ? AND day BETWEEN ? AND ?", resourceID, models.AnalyticsDimensionTotal, from, to).
+		Order("day ASC").Find(&stats).Error
+	return stats, err
+}
+
+// GetBreakdown sums the views and downloads of a resource per value of a dimension
+// between two days, inclusive, most viewed first
+func (r *AnalyticsRepository) GetBreakdown(resourceID uint, dimension, from, to string, limit int) ([]models.AnalyticsBreakdown, error) {
+	var breakdown []models.AnalyticsBreakdown
+	err := r.db.Model(&models.ResourceDailyStat{}).
+		Select("value, SUM(views) AS views, SUM(downloads) AS downloads").
+		Where("resource_id = ? AND dimension = ? AND day BETWEEN ? AND ?", resourceID, dimension, from, to).
+		Group("value").
+		Order("views DESC, downloads DESC, value ASC").
+		Limit(limit).
+		Scan(&breakdown).Error
+	return breakdown, err
+}
@@ -0,0 +1,137 @@
+package repository
+
+import (
+	"portfolio-be/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type CollectionRepository struct {
+	db *gorm.DB
+}
+
+func NewCollectionRepository(db *gorm.DB) *CollectionRepository {
+	return &CollectionRepository{db: db}
+}
+
+// preloadItems loads collection items in position order with their resources and uploads
+func preloadItems(db *gorm.DB) *gorm.DB {
+	return db.Preload("Items", func(db *gorm.DB) *gorm.DB {
+		return db.Order("position ASC, id ASC")
+	}).Preload("Items.Resource").Preload("Items.Resource.Upload")
+}
+
+// Transaction runs fn with a repository bound to a single database transaction
+func (r *CollectionRepository) Transaction(fn func(repo *CollectionRepository) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return fn(&CollectionRepository{db: tx})
+	})
+}
+
+func (r *CollectionRepository) Create(collection *models.Collection) error {
+	return r.db.Create(collection).Error
+}
+
+// Exists reports whether a collection exists
+func (r *CollectionRepository) Exists(id uint) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.Collection{}).Where("id = ?", id).Count(&count).Error
+	return count > 0, err
+}
+
+func (r *CollectionRepository) GetByID(id uint) (*models.Collection, error) {
+	var collection models.Collection
+	err := preloadItems(r.db).First(&collection, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &collection, nil
+}
+
+func (r *CollectionRepository) GetAll() ([]models.Collection, error) {
+	var collections []models.Collection
+	err := preloadItems(r.db).Order("name ASC").Find(&collections).Error
+	return collections, err
+}
+
+// GetActiveByProjectIDs returns the active collections attached to any of the given projects
+func (r *CollectionRepository) GetActiveByProjectIDs(projectIDs []uint) ([]models.Collection, error) {
+	var collections []models.Collection
+	err := preloadItems(r.db).
+		Where("project_id IN ? AND is_active = ?", projectIDs, true).
+		Order("id ASC").Find(&collections).Error
+	return collections, err
+}
+
+func (r *CollectionRepository) Update(collection *models.Collection) error {
+	return r.db.Omit("Items").Save(collection).Error
+}
+
+// Delete removes a collection along with its item memberships
+func (r *CollectionRepository) Delete(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("collection_id = ?", id).Delete(&models.CollectionItem{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.Collection{}, id).Error
+	})
+}
+
+func (r *CollectionRepository) CreateItem(item *models.CollectionItem) error {
+	return r.db.Omit("Resource").Create(item).Error
+}
+
+// HasResource reports whether a resource is already a member of a collection
+func (r *CollectionRepository) HasResource(collectionID, resourceID uint) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.CollectionItem{}).
+		Where("collection_id = ? AND resource_id = ?", collectionID, resourceID).Count(&count).Error
+	return count > 0, err
+}
+
+func (r *CollectionRepository) GetItem(collectionID, itemID uint) (*models.CollectionItem, error) {
+	var item models.CollectionItem
+	err := r.db.Where("collection_id = ?", collectionID).First(&item, itemID).Error
+	if err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+func (r *CollectionRepository) UpdateItem(item *models.CollectionItem) error {
+	return r.db.Omit("Resource").Save(item).Error
+}
+
+func (r *CollectionRepository) DeleteItem(collectionID, itemID uint) error {
+	return r.db.Where("collection_id = ?", collectionID).Delete(&models.CollectionItem{}, itemID).Error
+}
+
+// ShiftPositions moves every item at or after position one slot down to make room for an insert
+func (r *CollectionRepository) ShiftPositions(collectionID uint, position int) error {
+	return r.db.Model(&models.CollectionItem{}).
+		Where("collection_id = ? AND position >= ?", collectionID, position).
+		Update("position", gorm.Expr("position + 1")).Error
+}
+
+// NextPosition returns the position after the last item of a collection
+func (r *CollectionRepository) NextPosition(collectionID uint) (int, error) {
+	var next int
+	err := r.db.Model(&models.CollectionItem{}).Where("collection_id = ?", collectionID).
+		Select("COALESCE(MAX(position) + 1, 0)").Scan(&next).Error
+	return next, err
+}
+
+// Reorder assigns positions to items following the order of itemIDs
+func (r *CollectionRepository) Reorder(collectionID uint, itemIDs []uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		for position, itemID := range itemIDs {
+			err := tx.Model(&models.CollectionItem{}).
+				Where("id = ? AND collection_id = ?", itemID, collectionID).
+				Update("position", position).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
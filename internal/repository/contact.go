@@ -1,9 +1,13 @@
 package repository
 
 import (
+	"fmt"
 	"portfolio-be/internal/models"
+	"strings"
+	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type ContactRepository struct {
@@ -18,73 +22,354 @@ func (r *ContactRepository) Create(contact *models.Contact) error {
 	return r.db.Create(contact).Error
 }
 
-func (r *ContactRepository) GetAll(page, limit int) ([]models.Contact, int64, error) {
+// contactSortColumns are the columns the inbox may be sorted by. Status sorts in the
+// order a message moves through: unread, read, replied, then spam.
+var contactSortColumns = map[string]string{
+	"created_at": "contacts.created_at",
+	"status": fmt.Sprintf("CASE contacts.status WHEN '%s' THEN 0 WHEN '%s' THEN 1 WHEN '%s' THEN 2 ELSE 3 END",
+		models.ContactStatusUnread, models.ContactStatusRead, models.ContactStatusReplied),
+}
+
+// IsValidContactSort reports whether the inbox can be sorted by the given field
+func IsValidContactSort(sortBy string) bool {
+	_, ok := contactSortColumns[sortBy]
+	return ok
+}
+
+// likeEscaper escapes the LIKE wildcards in search words
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// filtered applies every filter set on a contact filter
+func (r *ContactRepository) filtered(filter models.ContactFilter) *gorm.DB {
+	query := r.db.Model(&models.Contact{})
+
+	if filter.Status != "" {
+		query = query.Where("contacts.status = ?", filter.Status)
+	} else {
+		// Spam stays out of the inbox until reviewed
+		query = query.Where("contacts.status <> ?", models.ContactStatusSpam)
+	}
+	if filter.Archived {
+		query = query.Where("contacts.archived_at IS NOT NULL")
+	} else {
+		query = query.Where("contacts.archived_at IS NULL")
+	}
+	for _, word := range strings.Fields(filter.Search) {
+		pattern := "%" + likeEscaper.Replace(word) + "%"
+		query = query.Where(`contacts.name LIKE ? ESCAPE '\' OR contacts.email LIKE ? ESCAPE '\' OR contacts.subject LIKE ? ESCAPE '\' OR contacts.message LIKE ? ESCAPE '\'`,
+			pattern, pattern, pattern, pattern)
+	}
+	if filter.LabelID != nil {
+		query = query.Where("contacts.id IN (?)",
+			r.db.Table("contact_label_links").Select("contact_id").Where("contact_label_id = ?", *filter.LabelID))
+	}
+	if filter.AssigneeID != nil {
+		query = query.Where("contacts.assignee_id = ?", *filter.AssigneeID)
+	} else if filter.Unassigned {
+		query = query.Where("contacts.assignee_id IS NULL")
+	}
+	if filter.CreatedFrom != nil {
+		query = query.Where("contacts.created_at >= ?", *filter.CreatedFrom)
+	}
+	if filter.CreatedTo != nil {
+		query = query.Where("contacts.created_at < ?", *filter.CreatedTo)
+	}
+
+	return query
+}
+
+// List returns contacts matching a filter with their labels, newest first unless
+// another sort is given
+func (r *ContactRepository) List(filter models.ContactFilter, limit, offset int) ([]models.Contact, error) {
 	var contacts []models.Contact
-	var total int64
 
-	// Count total records
-	if err := r.db.Model(&models.Contact{}).Count(&total).Error; err != nil {
-		return nil, 0, err
+	sortBy := filter.SortBy
+	if _, ok := contactSortColumns[sortBy]; !ok {
+		sortBy = "created_at"
+	}
+	// Dates default to newest first, statuses to unread first
+	direction := "DESC"
+	if sortBy == "status" {
+		direction = "ASC"
+	}
+	switch {
+	case strings.EqualFold(filter.SortOrder, "asc"):
+		direction = "ASC"
+	case strings.EqualFold(filter.SortOrder, "desc"):
+		direction = "DESC"
 	}
 
-	offset := (page - 1) * limit
-	err := r.db.Order("created_at DESC").
-		Offset(offset).
-		Limit(limit).
-		Find(&contacts).Error
+	err := r.filtered(filter).Preload("Labels", func(db *gorm.DB) *gorm.DB { return db.Order("name ASC") }).
+		Order(contactSortColumns[sortBy] + " " + direction).
+		Order("contacts.created_at DESC").Order("contacts.id DESC").
+		Limit(limit).Offset(offset).Find(&contacts).Error
+	return contacts, err
+}
 
-	return contacts, total, err
+// CountFiltered returns the number of contacts matching a filter
+func (r *ContactRepository) CountFiltered(filter models.ContactFilter) (int64, error) {
+	var count int64
+	err := r.filtered(filter).Count(&count).Error
+	return count, err
 }
 
 func (r *ContactRepository) GetByID(id uint) (*models.Contact, error) {
 	var contact models.Contact
-	err := r.db.First(&contact, id).Error
+	err := r.db.Preload("Labels", func(db *gorm.DB) *gorm.DB { return db.Order("name ASC") }).First(&contact, id).Error
 	return &contact, err
 }
 
+// Update saves a contact; its labels are changed with AddLabel and RemoveLabel
 func (r *ContactRepository) Update(contact *models.Contact) error {
-	return r.db.Save(contact).Error
+	return r.db.Omit(clause.Associations).Save(contact).Error
 }
 
 func (r *ContactRepository) Delete(id uint) error {
 	return r.db.Delete(&models.Contact{}, id).Error
 }
 
-func (r *ContactRepository) GetByStatus(status string, page, limit int) ([]models.Contact, int64, error) {
-	var contacts []models.Contact
-	var total int64
+// MarkRead records that a user read the given contacts and moves unread ones to read.
+// It returns how many of the contacts the user had not read yet; the first read time
+// of the others is kept.
+func (r *ContactRepository) MarkRead(ids []uint, userID uint, readAt time.Time) (int64, error) {
+	var recorded int64
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Exec(`INSERT INTO contact_reads (contact_id, user_id, read_at)
+			SELECT id, ?, ? FROM contacts WHERE id IN ? AND deleted_at IS NULL
+			ON CONFLICT DO NOTHING`, userID, readAt, ids)
+		if result.Error != nil {
+			return result.Error
+		}
+		recorded = result.RowsAffected
 
-	query := r.db.Model(&models.Contact{}).Where("status = ?", status)
+		return tx.Model(&models.Contact{}).
+			Where("id IN ? AND status = ?", ids, models.ContactStatusUnread).
+			Update("status", models.ContactStatusRead).Error
+	})
+	return recorded, err
+}
 
-	if err := query.Count(&total).Error; err != nil {
-		return nil, 0, err
-	}
+// MarkUnread forgets that a user read the given contacts and moves read ones that no
+// one else has read back to unread. It returns how many of the contacts the user had read.
+func (r *ContactRepository) MarkUnread(ids []uint, userID uint) (int64, error) {
+	var removed int64
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("contact_id IN ? AND user_id = ?", ids, userID).Delete(&models.ContactRead{})
+		if result.Error != nil {
+			return result.Error
+		}
+		removed = result.RowsAffected
 
-	offset := (page - 1) * limit
-	err := query.Order("created_at DESC").
-		Offset(offset).
-		Limit(limit).
-		Find(&contacts).Error
+		return tx.Model(&models.Contact{}).
+			Where("id IN ? AND status = ?", ids, models.ContactStatusRead).
+			Where("NOT EXISTS (SELECT 1 FROM contact_reads WHERE contact_reads.contact_id = contacts.id)").
+			Update("status", models.ContactStatusUnread).Error
+	})
+	return removed, err
+}
 
-	return contacts, total, err
+// GetReads returns who read a contact, in the order they read it
+func (r *ContactRepository) GetReads(contactID uint) ([]models.ContactReadReceipt, error) {
+	var receipts []models.ContactReadReceipt
+	err := r.db.Model(&models.ContactRead{}).
+		Select("contact_reads.user_id, COALESCE(users.username, '') AS username, contact_reads.read_at").
+		Joins("LEFT JOIN users ON users.id = contact_reads.user_id").
+		Where("contact_reads.contact_id = ?", contactID).
+		Order("contact_reads.read_at ASC").
+		Scan(&receipts).Error
+	return receipts, err
 }
 
-func (r *ContactRepository) MarkAsRead(id uint) error {
-	return r.db.Model(&models.Contact{}).
-		Where("id = ?", id).
-		Update("status", "read").Error
+// ReadTimes returns when a user read each of the given contacts they have read
+func (r *ContactRepository) ReadTimes(ids []uint, userID uint) (map[uint]time.Time, error) {
+	var reads []models.ContactRead
+	err := r.db.Where("contact_id IN ? AND user_id = ?", ids, userID).Find(&reads).Error
+	if err != nil {
+		return nil, err
+	}
+
+	times := make(map[uint]time.Time, len(reads))
+	for _, read := range reads {
+		times[read.ContactID] = read.ReadAt
+	}
+	return times, nil
 }
 
 func (r *ContactRepository) GetUnreadCount() (int64, error) {
 	var count int64
 	err := r.db.Model(&models.Contact{}).
-		Where("status = ?", "unread").
+		Where("status = ? AND archived_at IS NULL", models.ContactStatusUnread).
+		Count(&count).Error
+	return count, err
+}
+
+// GetUnreadCountForUser counts the inbox messages, spam and archived ones aside, that a
+// user has not read
+func (r *ContactRepository) GetUnreadCountForUser(userID uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.Contact{}).
+		Where("status <> ? AND archived_at IS NULL", models.ContactStatusSpam).
+		Where("id NOT IN (?)", r.db.Model(&models.ContactRead{}).Select("contact_id").Where("user_id = ?", userID)).
 		Count(&count).Error
 	return count, err
 }
 
 func (r *ContactRepository) GetCount() (int64, error) {
 	var count int64
-	err := r.db.Model(&models.Contact{}).Count(&count).Error
+	err := r.db.Model(&models.Contact{}).Where("status <> ?", models.ContactStatusSpam).Count(&count).Error
 	return count, err
 }
+
+// SetStatus sets the status of a contact without touching its other columns
+func (r *ContactRepository) SetStatus(id uint, status string) (bool, error) {
+	result := r.db.Model(&models.Contact{}).Where("id = ?", id).Update("status", status)
+	return result.RowsAffected > 0, result.Error
+}
+
+// SetAssignee assigns a contact to a user, or unassigns it when userID is nil
+func (r *ContactRepository) SetAssignee(id uint, userID *uint) (bool, error) {
+	result := r.db.Model(&models.Contact{}).Where("id = ?", id).Update("assignee_id", userID)
+	return result.RowsAffected > 0, result.Error
+}
+
+// ArchiveMany archives contacts, or moves them back to the inbox when archivedAt is nil
+func (r *ContactRepository) ArchiveMany(ids []uint, archivedAt *time.Time) (int64, error) {
+	query := r.db.Model(&models.Contact{}).Where("id IN ?", ids)
+	if archivedAt != nil {
+		query = query.Where("archived_at IS NULL")
+	} else {
+		query = query.Where("archived_at IS NOT NULL")
+	}
+	result := query.Update("archived_at", archivedAt)
+	return result.RowsAffected, result.Error
+}
+
+// AddLabel labels the given contacts that exist and do not have the label yet
+func (r *ContactRepository) AddLabel(ids []uint, labelID uint) (int64, error) {
+	result := r.db.Exec(`INSERT INTO contact_label_links (contact_id, contact_label_id)
+		SELECT id, ? FROM contacts WHERE id IN ? AND deleted_at IS NULL
+		ON CONFLICT DO NOTHING`, labelID, ids)
+	return result.RowsAffected, result.Error
+}
+
+// RemoveLabel removes a label from the given contacts
+func (r *ContactRepository) RemoveLabel(ids []uint, labelID uint) (int64, error) {
+	result := r.db.Exec("DELETE FROM contact_label_links WHERE contact_label_id = ? AND contact_id IN ?", labelID, ids)
+	return result.RowsAffected, result.Error
+}
+
+// DeleteMany deletes contacts
+func (r *ContactRepository) DeleteMany(ids []uint) (int64, error) {
+	result := r.db.Where("id IN ?", ids).Delete(&models.Contact{})
+	return result.RowsAffected, result.Error
+}
+
+// CountByIPSince counts the messages, including deleted ones, sent from an IP address
+// since the given time
+func (r *ContactRepository) CountByIPSince(ip string, since time.Time) (int64, error) {
+	var count int64
+	err := r.db.Unscoped().Model(&models.Contact{}).
+		Where("ip_address = ? AND created_at >= ?", ip, since).
+		Count(&count).Error
+	return count, err
+}
+
+// CountByEmailSince counts the messages, including deleted ones, sent with an email
+// address since the given time
+func (r *ContactRepository) CountByEmailSince(email string, since time.Time) (int64, error) {
+	var count int64
+	err := r.db.Unscoped().Model(&models.Contact{}).
+		Where("LOWER(email) = LOWER(?) AND created_at >= ?", email, since).
+		Count(&count).Error
+	return count, err
+}
+
+// GetAllByEmail returns every message, including deleted ones, sent with an email
+// address, oldest first, with their labels
+func (r *ContactRepository) GetAllByEmail(email string) ([]models.Contact, error) {
+	var contacts []models.Contact
+	err := r.db.Unscoped().Preload("Labels", func(db *gorm.DB) *gorm.DB { return db.Order("name ASC") }).
+		Where("LOWER(email) = LOWER(?)", email).
+		Order("created_at ASC, id ASC").
+		Find(&contacts).Error
+	return contacts, err
+}
+
+// IDsByEmail returns the IDs of every message, including deleted ones, sent with an
+// email address
+func (r *ContactRepository) IDsByEmail(email string) ([]uint, error) {
+	var ids []uint
+	err := r.db.Unscoped().Model(&models.Contact{}).
+		Where("LOWER(email) = LOWER(?)", email).
+		Pluck("id", &ids).Error
+	return ids, err
+}
+
+// IDsCreatedBefore returns the IDs of up to limit messages, including deleted ones,
+// created before a time, oldest first. With notAnonymized, messages already anonymized
+// are left out.
+func (r *ContactRepository) IDsCreatedBefore(before time.Time, notAnonymized bool, limit int) ([]uint, error) {
+	var ids []uint
+	query := r.db.Unscoped().Model(&models.Contact{}).Where("created_at < ?", before)
+	if notAnonymized {
+		query = query.Where("anonymized_at IS NULL")
+	}
+	err := query.Order("created_at ASC, id ASC").Limit(limit).Pluck("id", &ids).Error
+	return ids, err
+}
+
+// Anonymize erases the sender and content of contacts and of the replies sent to them,
+// keeping the messages themselves. It returns how many contacts and replies it changed.
+func (r *ContactRepository) Anonymize(ids []uint, at time.Time) (int64, int64, error) {
+	var contacts, replies int64
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Unscoped().Model(&models.Contact{}).Where("id IN ?", ids).Updates(map[string]interface{}{
+			"name":          "Anonymized",
+			"email":         "",
+			"subject":       "",
+			"message":       "",
+			"ip_address":    "",
+			"spam_reasons":  "",
+			"anonymized_at": at,
+		})
+		if result.Error != nil {
+			return result.Error
+		}
+		contacts = result.RowsAffected
+
+		// Replies quote the sender's name and message
+		result = tx.Model(&models.ContactReply{}).Where("contact_id IN ?", ids).Updates(map[string]interface{}{
+			"subject":    "",
+			"body":       "",
+			"last_error": "",
+		})
+		replies = result.RowsAffected
+		return result.Error
+	})
+	return contacts, replies, err
+}
+
+// Purge permanently deletes contacts, including deleted ones, with their replies, read
+// receipts and labels. It returns how many contacts and replies it deleted.
+func (r *ContactRepository) Purge(ids []uint) (int64, int64, error) {
+	var contacts, replies int64
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("contact_id IN ?", ids).Delete(&models.ContactReply{})
+		if result.Error != nil {
+			return result.Error
+		}
+		replies = result.RowsAffected
+
+		if err := tx.Where("contact_id IN ?", ids).Delete(&models.ContactRead{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("DELETE FROM contact_label_links WHERE contact_id IN ?", ids).Error; err != nil {
+			return err
+		}
+
+		result = tx.Unscoped().Where("id IN ?", ids).Delete(&models.Contact{})
+		contacts = result.RowsAffected
+		return result.Error
+	})
+	return contacts, replies, err
+}
@@ -0,0 +1,36 @@
+package repository
+
+import (
+	"portfolio-be/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type ContactErasureRepository struct {
+	db *gorm.DB
+}
+
+func NewContactErasureRepository(db *gorm.DB) *ContactErasureRepository {
+	return &ContactErasureRepository{db: db}
+}
+
+func (r *ContactErasureRepository) Create(erasure *models.ContactErasure) error {
+	return r.db.Create(erasure).Error
+}
+
+// List returns the erasures, newest first, optionally only those with a reason
+func (r *ContactErasureRepository) List(reason string, limit, offset int) ([]models.ContactErasure, int64, error) {
+	query := r.db.Model(&models.ContactErasure{})
+	if reason != "" {
+		query = query.Where("reason = ?", reason)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var erasures []models.ContactErasure
+	err := query.Order("created_at DESC, id DESC").Limit(limit).Offset(offset).Find(&erasures).Error
+	return erasures, total, err
+}
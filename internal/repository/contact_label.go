@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"portfolio-be/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type ContactLabelRepository struct {
+	db *gorm.DB
+}
+
+func NewContactLabelRepository(db *gorm.DB) *ContactLabelRepository {
+	return &ContactLabelRepository{db: db}
+}
+
+// Save creates or updates a label
+func (r *ContactLabelRepository) Save(label *models.ContactLabel) error {
+	return r.db.Save(label).Error
+}
+
+func (r *ContactLabelRepository) GetByID(id uint) (*models.ContactLabel, error) {
+	var label models.ContactLabel
+	err := r.db.First(&label, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &label, nil
+}
+
+// GetByName returns the label with a name, ignoring case
+func (r *ContactLabelRepository) GetByName(name string) (*models.ContactLabel, error) {
+	var label models.ContactLabel
+	err := r.db.Where("LOWER(name) = LOWER(?)", name).First(&label).Error
+	if err != nil {
+		return nil, err
+	}
+	return &label, nil
+}
+
+// GetAll returns the labels by name
+func (r *ContactLabelRepository) GetAll() ([]models.ContactLabel, error) {
+	var labels []models.ContactLabel
+	err := r.db.Order("name ASC").Find(&labels).Error
+	return labels, err
+}
+
+// Delete removes a label from every contact and deletes it, reporting whether it existed
+func (r *ContactLabelRepository) Delete(id uint) (bool, error) {
+	var deleted bool
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("DELETE FROM contact_label_links WHERE contact_label_id = ?", id).Error; err != nil {
+			return err
+		}
+		result := tx.Delete(&models.ContactLabel{}, id)
+		deleted = result.RowsAffected > 0
+		return result.Error
+	})
+	return deleted, err
+}
@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"portfolio-be/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type ContactReplyRepository struct {
+	db *gorm.DB
+}
+
+func NewContactReplyRepository(db *gorm.DB) *ContactReplyRepository {
+	return &ContactReplyRepository{db: db}
+}
+
+func (r *ContactReplyRepository) Create(reply *models.ContactReply) error {
+	return r.db.Create(reply).Error
+}
+
+func (r *ContactReplyRepository) GetByID(id uint) (*models.ContactReply, error) {
+	var reply models.ContactReply
+	err := r.db.First(&reply, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &reply, nil
+}
+
+// GetByContact returns the replies to a contact, oldest first
+func (r *ContactReplyRepository) GetByContact(contactID uint) ([]models.ContactReply, error) {
+	var replies []models.ContactReply
+	err := r.db.Where("contact_id = ?", contactID).Order("created_at ASC, id ASC").Find(&replies).Error
+	return replies, err
+}
+
+// SetTask records the task delivering a reply
+func (r *ContactReplyRepository) SetTask(id, taskID uint) error {
+	return r.db.Model(&models.ContactReply{}).Where("id = ?", id).Update("task_id", taskID).Error
+}
+
+// MarkSent records a delivered reply
+func (r *ContactReplyRepository) MarkSent(id uint, sentAt time.Time) error {
+	return r.db.Model(&models.ContactReply{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status":     models.ContactReplySent,
+		"sent_at":    sentAt,
+		"last_error": "",
+	}).Error
+}
+
+// MarkFailed records a failed delivery attempt with the status the reply is left in
+func (r *ContactReplyRepository) MarkFailed(id uint, status, lastError string) error {
+	return r.db.Model(&models.ContactReply{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status":     status,
+		"last_error": lastError,
+	}).Error
+}
+
+type ReplyTemplateRepository struct {
+	db *gorm.DB
+}
+
+func NewReplyTemplateRepository(db *gorm.DB) *ReplyTemplateRepository {
+	return &ReplyTemplateRepository{db: db}
+}
+
+// Save creates or updates a template
+func (r *ReplyTemplateRepository) Save(template *models.ReplyTemplate) error {
+	return r.db.Save(template).Error
+}
+
+func (r *ReplyTemplateRepository) GetByID(id uint) (*models.ReplyTemplate, error) {
+	var template models.ReplyTemplate
+	err := r.db.First(&template, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &template, nil
+}
+
+// GetByName returns the template with a name
+func (r *ReplyTemplateRepository) GetByName(name string) (*models.ReplyTemplate, error) {
+	var template models.ReplyTemplate
+	err := r.db.Where("name = ?", name).First(&template).Error
+	if err != nil {
+		return nil, err
+	}
+	return &template, nil
+}
+
+// GetAll returns the templates by name
+func (r *ReplyTemplateRepository) GetAll() ([]models.ReplyTemplate, error) {
+	var templates []models.ReplyTemplate
+	err := r.db.Order("name ASC").Find(&templates).Error
+	return templates, err
+}
+
+// Delete removes a template and reports whether it existed
+func (r *ReplyTemplateRepository) Delete(id uint) (bool, error) {
+	result := r.db.Delete(&models.ReplyTemplate{}, id)
+	return result.RowsAffected > 0, result.Error
+}
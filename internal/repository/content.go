@@ -27,22 +27,33 @@ func (r *ContentRepository) GetByID(id uint) (*models.Content, error) {
 	return &content, nil
 }
 
-func (r *ContentRepository) GetAll(limit, offset int) ([]models.Content, error) {
-	var contents []models.Content
-	err := r.db.Limit(limit).Offset(offset).Find(&contents).Error
-	return contents, err
+// filtered applies every filter set on a content filter
+func (r *ContentRepository) filtered(filter models.ContentFilter) *gorm.DB {
+	query := r.db.Model(&models.Content{})
+	if filter.Category != "" {
+		query = query.Where("category = ?", filter.Category)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.Tag != "" {
+		query = query.Where("id IN (?)", taggedWith(r.db, models.ReferenceEntityContent, models.TagSlug(filter.Tag)))
+	}
+	return query
 }
 
-func (r *ContentRepository) GetByCategory(category string, limit, offset int) ([]models.Content, error) {
+// List returns contents matching a filter
+func (r *ContentRepository) List(filter models.ContentFilter, limit, offset int) ([]models.Content, error) {
 	var contents []models.Content
-	err := r.db.Where("category = ?", category).Limit(limit).Offset(offset).Find(&contents).Error
+	err := r.filtered(filter).Limit(limit).Offset(offset).Find(&contents).Error
 	return contents, err
 }
 
-func (r *ContentRepository) GetByStatus(status string, limit, offset int) ([]models.Content, error) {
-	var contents []models.Content
-	err := r.db.Where("status = ?", status).Limit(limit).Offset(offset).Find(&contents).Error
-	return contents, err
+// CountFiltered returns the number of contents matching a filter
+func (r *ContentRepository) CountFiltered(filter models.ContentFilter) (int64, error) {
+	var count int64
+	err := r.filtered(filter).Count(&count).Error
+	return count, err
 }
 
 func (r *ContentRepository) Update(content *models.Content) error {
@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"portfolio-be/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type DataMigrationRepository struct {
+	db *gorm.DB
+}
+
+func NewDataMigrationRepository(db *gorm.DB) *DataMigrationRepository {
+	return &DataMigrationRepository{db: db}
+}
+
+// Claim records that an instance starts a migration. It fails to claim a migration that
+// finished or that another instance started after staleBefore; a migration left
+// unfinished by an instance that exited before then is claimed again.
+func (r *DataMigrationRepository) Claim(name, instance string, staleBefore time.Time) (bool, error) {
+	now := time.Now().UTC()
+	result := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.DataMigration{
+		Name:      name,
+		Instance:  instance,
+		StartedAt: now,
+	})
+	if result.Error != nil || result.RowsAffected == 1 {
+		return result.RowsAffected == 1, result.Error
+	}
+
+	result = r.db.Model(&models.DataMigration{}).
+		Where("name = ? AND finished_at IS NULL AND started_at < ?", name, staleBefore.UTC()).
+		Updates(map[string]interface{}{"instance": instance, "started_at": now})
+	return result.RowsAffected == 1, result.Error
+}
+
+// Finish records that a claimed migration completed
+func (r *DataMigrationRepository) Finish(name string, items int) error {
+	return r.db.Model(&models.DataMigration{}).Where("name = ?", name).Updates(map[string]interface{}{
+		"finished_at": time.Now().UTC(),
+		"items":       items,
+	}).Error
+}
+
+// Release forgets a claimed migration that failed, so that it runs again
+func (r *DataMigrationRepository) Release(name string) error {
+	return r.db.Where("name = ? AND finished_at IS NULL", name).Delete(&models.DataMigration{}).Error
+}
@@ -0,0 +1,154 @@
+package repository
+
+import (
+	"portfolio-be/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type JobRepository struct {
+	db *gorm.DB
+}
+
+func NewJobRepository(db *gorm.DB) *JobRepository {
+	return &JobRepository{db: db}
+}
+
+// CreateRun records the start of a job run
+func (r *JobRepository) CreateRun(run *models.JobRun) error {
+	return r.db.Create(run).Error
+}
+
+// FinishRun records the outcome of a job run
+func (r *JobRepository) FinishRun(run *models.JobRun) error {
+	return r.db.Model(&models.JobRun{}).Where("id = ?", run.ID).Updates(map[string]interface{}{
+		"status":          run.Status,
+		"finished_at":     run.FinishedAt,
+		"items_processed": run.ItemsProcessed,
+		"error":           run.Error,
+	}).Error
+}
+
+// GetRuns returns the runs of a job, newest first
+func (r *JobRepository) GetRuns(jobName string, limit, offset int) ([]models.JobRun, int64, error) {
+	var runs []models.JobRun
+	var total int64
+
+	query := r.db.Model(&models.JobRun{}).Where("job_name = ?", jobName)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := query.Order("started_at DESC, id DESC").Limit(limit).Offset(offset).Find(&runs).Error
+	return runs, total, err
+}
+
+// GetLastRun returns the most recent run of a job
+func (r *JobRepository) GetLastRun(jobName string) (*models.JobRun, error) {
+	var run models.JobRun
+	err := r.db.Where("job_name = ?", jobName).Order("started_at DESC, id DESC").First(&run).Error
+	if err != nil {
+		return nil, err
+	}
+	return &run, nil
+}
+
+// MarkInterrupted closes runs left running by an instance that exited mid-run: runs
+// started before the given time whose lease has expired or been taken over
+func (r *JobRepository) MarkInterrupted(before time.Time) (int64, error) {
+	now := time.Now().UTC()
+	result := r.db.Model(&models.JobRun{}).
+		Where("status = ? AND started_at < ?", models.JobRunRunning, before).
+		Where("NOT EXISTS (SELECT 1 FROM job_leases WHERE job_leases.name = job_runs.job_name AND job_leases.token = job_runs.fencing_token AND job_leases.expires_at > ?)", now).
+		Updates(map[string]interface{}{
+			"status":      models.JobRunInterrupted,
+			"finished_at": now,
+			"error":       "instance exited or lost its lease before the run finished",
+		})
+	return result.RowsAffected, result.Error
+}
+
+// DeleteRunsBefore prunes run history older than the given time
+func (r *JobRepository) DeleteRunsBefore(before time.Time) (int64, error) {
+	result := r.db.Where("started_at < ? AND status <> ?", before, models.JobRunRunning).Delete(&models.JobRun{})
+	return result.RowsAffected, result.Error
+}
+
+// GetStates returns the persisted state of every job
+func (r *JobRepository) GetStates() ([]models.JobState, error) {
+	var states []models.JobState
+	err := r.db.Find(&states).Error
+	return states, err
+}
+
+// SetPaused persists whether a job is paused
+func (r *JobRepository) SetPaused(name string, paused bool) error {
+	state := models.JobState{Name: name, Paused: paused, UpdatedAt: time.Now()}
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "name"}},
+		DoUpdates: clause.AssignmentColumns([]string{"paused", "updated_at"}),
+	}).Create(&state).Error
+}
+
+// AcquireLease takes the lease of a job when it is free or expired and returns the new
+// fencing token, or 0 when another holder has it. A scheduled run passes the schedule
+// time it is due at as slot, and is refused when that slot already ran elsewhere.
+func (r *JobRepository) AcquireLease(name, holder string, expiresAt time.Time, slot *time.Time) (int64, error) {
+	now := time.Now().UTC()
+	expiresAt = expiresAt.UTC()
+	if slot != nil {
+		utc := slot.UTC()
+		slot = &utc
+	}
+
+	// A single upsert keeps the check and the takeover atomic across instances
+	var token int64
+	err := r.db.Raw(`INSERT INTO job_leases (name, holder, token, expires_at, last_slot, updated_at)
+		VALUES (?, ?, 1, ?, ?, ?)
+		ON CONFLICT (name) DO UPDATE SET
+			holder = excluded.holder,
+			token = job_leases.token + 1,
+			expires_at = excluded.expires_at,
+			last_slot = COALESCE(excluded.last_slot, job_leases.last_slot),
+			updated_at = excluded.updated_at
+		WHERE job_leases.expires_at <= ?
+			AND (excluded.last_slot IS NULL OR job_leases.last_slot IS NULL OR job_leases.last_slot < excluded.last_slot)
+		RETURNING token`,
+		name, holder, expiresAt, slot, now, now).Scan(&token).Error
+	return token, err
+}
+
+// RenewLease extends a lease still held with the given fencing token
+func (r *JobRepository) RenewLease(name, holder string, token int64, expiresAt time.Time) (bool, error) {
+	now := time.Now().UTC()
+	result := r.db.Model(&models.JobLease{}).
+		Where("name = ? AND holder = ? AND token = ? AND expires_at > ?", name, holder, token, now).
+		Updates(map[string]interface{}{"expires_at": expiresAt.UTC(), "updated_at": now})
+	return result.RowsAffected == 1, result.Error
+}
+
+// HoldsLease reports whether a lease is still held with the given fencing token
+func (r *JobRepository) HoldsLease(name, holder string, token int64) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.JobLease{}).
+		Where("name = ? AND holder = ? AND token = ? AND expires_at > ?", name, holder, token, time.Now().UTC()).
+		Count(&count).Error
+	return count == 1, err
+}
+
+// ReleaseLease expires a lease held with the given fencing token
+func (r *JobRepository) ReleaseLease(name, holder string, token int64) error {
+	now := time.Now().UTC()
+	return r.db.Model(&models.JobLease{}).
+		Where("name = ? AND holder = ? AND token = ?", name, holder, token).
+		Updates(map[string]interface{}{"expires_at": now, "updated_at": now}).Error
+}
+
+// GetActiveLeases returns the leases that have not expired
+func (r *JobRepository) GetActiveLeases() ([]models.JobLease, error) {
+	var leases []models.JobLease
+	err := r.db.Where("expires_at > ?", time.Now().UTC()).Find(&leases).Error
+	return leases, err
+}
@@ -13,6 +13,7 @@ type ProjectRepository interface {
 	Update(project *models.Project) error
 	Delete(id uint) error
 	GetActive() ([]models.Project, error)
+	GetActiveByTag(slug string) ([]models.Project, error)
 	GetCount() (int64, error)
 }
 
@@ -57,6 +58,14 @@ func (r *projectRepository) GetActive() ([]models.Project, error) {
 	return projects, err
 }
 
+func (r *projectRepository) GetActiveByTag(slug string) ([]models.Project, error) {
+	var projects []models.Project
+	err := r.db.Where("is_active = ?", true).
+		Where("id IN (?)", taggedWith(r.db, models.ReferenceEntityProject, slug)).
+		Order("sort_order ASC, created_at ASC").Find(&projects).Error
+	return projects, err
+}
+
 func (r *projectRepository) GetCount() (int64, error) {
 	var count int64
 	err := r.db.Model(&models.Project{}).Count(&count).Error
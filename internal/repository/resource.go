@@ -2,6 +2,7 @@ package repository
 
 import (
 	"portfolio-be/internal/models"
+	"strings"
 	"time"
 
 	"gorm.io/gorm"
@@ -28,44 +29,130 @@ func (r *ResourceRepository) GetByID(id uint) (*models.Resource, error) {
 	return &resource, nil
 }
 
-func (r *ResourceRepository) GetAll(limit, offset int) ([]models.Resource, error) {
-	var resources []models.Resource
-	err := r.db.Preload("Upload").Limit(limit).Offset(offset).Find(&resources).Error
-	return resources, err
+// visible scopes queries to public, active resources when publicOnly is set
+func (r *ResourceRepository) visible(publicOnly bool) *gorm.DB {
+	if publicOnly {
+		return r.db.Where("is_public = ? AND is_active = ?", true, true)
+	}
+	return r.db
 }
 
-func (r *ResourceRepository) GetByType(resourceType models.ResourceType, limit, offset int) ([]models.Resource, error) {
-	var resources []models.Resource
-	err := r.db.Preload("Upload").Where("type = ?", resourceType).Limit(limit).Offset(offset).Find(&resources).Error
-	return resources, err
+// resourceSortColumns are the columns resource listings may be sorted by
+var resourceSortColumns = map[string]string{
+	"name":           "resources.name",
+	"created_at":     "resources.created_at",
+	"view_count":     "resources.view_count",
+	"download_count": "resources.download_count",
 }
 
-func (r *ResourceRepository) GetByCategory(category string, limit, offset int) ([]models.Resource, error) {
-	var resources []models.Resource
-	err := r.db.Preload("Upload").Where("category = ?", category).Limit(limit).Offset(offset).Find(&resources).Error
-	return resources, err
+// IsValidResourceSort reports whether resource listings can be sorted by the given field
+func IsValidResourceSort(sortBy string) bool {
+	_, ok := resourceSortColumns[sortBy]
+	return ok
+}
+
+// filtered applies every filter set on a resource filter
+func (r *ResourceRepository) filtered(filter models.ResourceFilter) *gorm.DB {
+	query := r.visible(filter.PublicOnly).Model(&models.Resource{})
+
+	if filter.Type != "" {
+		query = query.Where("resources.type = ?", filter.Type)
+	}
+	if filter.Category != "" {
+		query = query.Where("resources.category = ?", filter.Category)
+	}
+	if filter.Tag != "" {
+		query = query.Where("resources.id IN (?)", taggedWith(r.db, models.ReferenceEntityResource, models.TagSlug(filter.Tag)))
+	}
+	if filter.Search != "" {
+		searchPattern := "%" + filter.Search + "%"
+		query = query.Where("resources.name LIKE ? OR resources.description LIKE ? OR resources.tags LIKE ?", searchPattern, searchPattern, searchPattern)
+	}
+	if filter.CreatedFrom != nil {
+		query = query.Where("resources.created_at >= ?", *filter.CreatedFrom)
+	}
+	if filter.CreatedTo != nil {
+		query = query.Where("resources.created_at < ?", *filter.CreatedTo)
+	}
+
+	if filter.PublicOnly {
+		// Quarantined files are not listed publicly until their scan is clean
+		query = query.Where("resources.upload_id IN (?)",
+			r.db.Model(&models.Upload{}).Select("id").Where("scan_status = ?", models.UploadScanClean))
+	}
+
+	if filter.ContentType != "" || filter.MinSize > 0 || filter.MaxSize > 0 {
+		uploads := r.db.Model(&models.Upload{}).Select("id")
+		if prefix, ok := contentTypePrefix(filter.ContentType); ok {
+			uploads = uploads.Where("content_type LIKE ?", prefix+"%")
+		} else if filter.ContentType != "" {
+			uploads = uploads.Where("content_type = ?", filter.ContentType)
+		}
+		if filter.MinSize > 0 {
+			uploads = uploads.Where("file_size >= ?", filter.MinSize)
+		}
+		if filter.MaxSize > 0 {
+			uploads = uploads.Where("file_size <= ?", filter.MaxSize)
+		}
+		query = query.Where("resources.upload_id IN (?)", uploads)
+	}
+
+	return query
+}
+
+// contentTypePrefix returns the MIME prefix of a wildcard content type such as "image/*"
+func contentTypePrefix(contentType string) (string, bool) {
+	contentType = strings.TrimSuffix(contentType, "*")
+	if strings.HasSuffix(contentType, "/") {
+		return contentType, true
+	}
+	return "", false
 }
 
-func (r *ResourceRepository) GetPublic(limit, offset int) ([]models.Resource, error) {
+// List returns resources matching a filter, sorted by its sort field (newest first by default)
+func (r *ResourceRepository) List(filter models.ResourceFilter, limit, offset int) ([]models.Resource, error) {
 	var resources []models.Resource
-	err := r.db.Preload("Upload").Where("is_public = ? AND is_active = ?", true, true).Limit(limit).Offset(offset).Find(&resources).Error
+
+	column, ok := resourceSortColumns[filter.SortBy]
+	if !ok {
+		column = resourceSortColumns["created_at"]
+	}
+	direction := "DESC"
+	if strings.EqualFold(filter.SortOrder, "asc") {
+		direction = "ASC"
+	}
+
+	err := r.filtered(filter).Preload("Upload").
+		Order(column + " " + direction).Order("resources.id " + direction).
+		Limit(limit).Offset(offset).Find(&resources).Error
 	return resources, err
 }
 
+// CountFiltered returns the number of resources matching a filter
+func (r *ResourceRepository) CountFiltered(filter models.ResourceFilter) (int64, error) {
+	var count int64
+	err := r.filtered(filter).Count(&count).Error
+	return count, err
+}
+
 func (r *ResourceRepository) Update(id uint, updates map[string]interface{}) error {
 	return r.db.Model(&models.Resource{}).Where("id = ?", id).Updates(updates).Error
 }
 
+// Delete removes a resource along with its collection memberships, tags and upload references
 func (r *ResourceRepository) Delete(id uint) error {
-	return r.db.Delete(&models.Resource{}, id).Error
-}
-
-func (r *ResourceRepository) IncrementViewCount(id uint) error {
-	return r.db.Model(&models.Resource{}).Where("id = ?", id).Update("view_count", gorm.Expr("view_count + 1")).Error
-}
-
-func (r *ResourceRepository) IncrementDownloadCount(id uint) error {
-	return r.db.Model(&models.Resource{}).Where("id = ?", id).Update("download_count", gorm.Expr("download_count + 1")).Error
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("resource_id = ?", id).Delete(&models.CollectionItem{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("entity_type = ? AND entity_id = ?", models.ReferenceEntityResource, id).Delete(&models.Tagging{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("entity_type = ? AND entity_id = ?", models.ReferenceEntityResource, id).Delete(&models.UploadReference{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.Resource{}, id).Error
+	})
 }
 
 func (r *ResourceRepository) Count() (int64, error) {
@@ -111,16 +198,3 @@ func (r *ResourceRepository) GetExpired() ([]models.Resource, error) {
 
 	return resources, err
 }
-
-// Search resources by name, description, or tags
-func (r *ResourceRepository) Search(query string, limit, offset int) ([]models.Resource, error) {
-	var resources []models.Resource
-	searchPattern := "%" + query + "%"
-
-	err := r.db.Preload("Upload").
-		Where("name LIKE ? OR description LIKE ? OR tags LIKE ?", searchPattern, searchPattern, searchPattern).
-		Limit(limit).Offset(offset).
-		Find(&resources).Error
-
-	return resources, err
-}
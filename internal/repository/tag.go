@@ -0,0 +1,160 @@
+package repository
+
+import (
+	"portfolio-be/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// LegacyTags is the raw tag column of a resource, content or project
+type LegacyTags struct {
+	ID   uint
+	Tags string
+}
+
+// taggableTables maps tagged entity types to their tables
+var taggableTables = map[string]string{
+	models.ReferenceEntityResource: "resources",
+	models.ReferenceEntityContent:  "contents",
+	models.ReferenceEntityProject:  "projects",
+}
+
+// taggedWith returns a subquery of the IDs of entities carrying the tag with the given slug
+func taggedWith(db *gorm.DB, entityType, slug string) *gorm.DB {
+	return db.Model(&models.Tagging{}).
+		Select("taggings.entity_id").
+		Joins("JOIN tags ON tags.id = taggings.tag_id").
+		Where("taggings.entity_type = ? AND tags.slug = ?", entityType, slug)
+}
+
+type TagRepository struct {
+	db *gorm.DB
+}
+
+func NewTagRepository(db *gorm.DB) *TagRepository {
+	return &TagRepository{db: db}
+}
+
+// FindOrCreate returns the tag with the given slug, creating it when missing
+func (r *TagRepository) FindOrCreate(name, slug string) (*models.Tag, error) {
+	tag := models.Tag{Name: name, Slug: slug}
+	err := r.db.Where(models.Tag{Slug: slug}).FirstOrCreate(&tag).Error
+	if err != nil {
+		return nil, err
+	}
+	return &tag, nil
+}
+
+// GetAll returns every tag
+func (r *TagRepository) GetAll() ([]models.Tag, error) {
+	var tags []models.Tag
+	err := r.db.Order("id ASC").Find(&tags).Error
+	return tags, err
+}
+
+// UpdateSlug changes the slug of a tag
+func (r *TagRepository) UpdateSlug(id uint, slug string) error {
+	return r.db.Model(&models.Tag{}).Where("id = ?", id).Update("slug", slug).Error
+}
+
+func (r *TagRepository) GetBySlug(slug string) (*models.Tag, error) {
+	var tag models.Tag
+	err := r.db.Where("slug = ?", slug).First(&tag).Error
+	if err != nil {
+		return nil, err
+	}
+	return &tag, nil
+}
+
+// ReplaceForEntity replaces the tags of an entity
+func (r *TagRepository) ReplaceForEntity(entityType string, entityID uint, tagIDs []uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("entity_type = ? AND entity_id = ?", entityType, entityID).Delete(&models.Tagging{}).Error; err != nil {
+			return err
+		}
+		if len(tagIDs) == 0 {
+			return nil
+		}
+
+		taggings := make([]models.Tagging, len(tagIDs))
+		for i, tagID := range tagIDs {
+			taggings[i] = models.Tagging{TagID: tagID, EntityType: entityType, EntityID: entityID}
+		}
+		return tx.Create(&taggings).Error
+	})
+}
+
+// DeleteByEntity removes all tags of an entity
+func (r *TagRepository) DeleteByEntity(entityType string, entityID uint) error {
+	return r.db.Where("entity_type = ? AND entity_id = ?", entityType, entityID).Delete(&models.Tagging{}).Error
+}
+
+// publicTaggings restricts taggings to entities shown on the public site, under the
+// rules of the public listings: public, active resources whose upload scanned clean,
+// published content and active projects
+func (r *TagRepository) publicTaggings() *gorm.DB {
+	resources := r.db.Model(&models.Resource{}).Select("id").
+		Where("is_public = ? AND is_active = ?", true, true).
+		Where("upload_id IN (?)", r.db.Model(&models.Upload{}).Select("id").Where("scan_status = ?", models.UploadScanClean))
+	contents := r.db.Model(&models.Content{}).Select("id").Where("status = ?", models.ContentStatusPublished)
+	projects := r.db.Model(&models.Project{}).Select("id").Where("is_active = ?", true)
+
+	return r.db.Where("taggings.entity_type = ? AND taggings.entity_id IN (?)", models.ReferenceEntityResource, resources).
+		Or("taggings.entity_type = ? AND taggings.entity_id IN (?)", models.ReferenceEntityContent, contents).
+		Or("taggings.entity_type = ? AND taggings.entity_id IN (?)", models.ReferenceEntityProject, projects)
+}
+
+// ListUsage returns tags with their usage counts, most used first. When entityType
+// is set only tags used by that entity type are returned. With publicOnly, only
+// entities shown on the public site are counted and tags used by none are left out.
+func (r *TagRepository) ListUsage(entityType string, publicOnly bool) ([]models.TagUsage, error) {
+	var usage []models.TagUsage
+
+	query := r.db.Model(&models.Tag{}).
+		Select(`tags.id, tags.name, tags.slug, COUNT(taggings.id) AS count,
+			SUM(CASE WHEN taggings.entity_type = ? THEN 1 ELSE 0 END) AS resources,
+			SUM(CASE WHEN taggings.entity_type = ? THEN 1 ELSE 0 END) AS contents,
+			SUM(CASE WHEN taggings.entity_type = ? THEN 1 ELSE 0 END) AS projects`,
+			models.ReferenceEntityResource, models.ReferenceEntityContent, models.ReferenceEntityProject).
+		Joins("JOIN taggings ON taggings.tag_id = tags.id").
+		Group("tags.id, tags.name, tags.slug").
+		Order("count DESC, tags.name ASC")
+
+	if entityType != "" {
+		query = query.Where("taggings.entity_type = ?", entityType)
+	}
+	if publicOnly {
+		query = query.Where(r.publicTaggings())
+	}
+
+	err := query.Scan(&usage).Error
+	return usage, err
+}
+
+// legacyTags selects the raw tag column of entities that have tags
+func (r *TagRepository) legacyTags(entityType string) *gorm.DB {
+	return r.db.Table(taggableTables[entityType]).
+		Select("id, tags").
+		Where("deleted_at IS NULL AND tags IS NOT NULL AND tags <> ''")
+}
+
+// ListLegacyTags returns the raw tag column of every entity that has tags
+func (r *TagRepository) ListLegacyTags(entityType string) ([]LegacyTags, error) {
+	var rows []LegacyTags
+	err := r.legacyTags(entityType).Scan(&rows).Error
+	return rows, err
+}
+
+// ListUntagged returns the raw tag column of entities that have tags but no taggings yet
+func (r *TagRepository) ListUntagged(entityType string) ([]LegacyTags, error) {
+	var rows []LegacyTags
+	err := r.legacyTags(entityType).
+		Where("id NOT IN (?)", r.db.Model(&models.Tagging{}).Select("entity_id").Where("entity_type = ?", entityType)).
+		Scan(&rows).Error
+	return rows, err
+}
+
+// UpdateLegacyTags rewrites the raw tag column of an entity
+func (r *TagRepository) UpdateLegacyTags(entityType string, entityID uint, tags string) error {
+	return r.db.Table(taggableTables[entityType]).Where("id = ?", entityID).Update("tags", tags).Error
+}
@@ -0,0 +1,207 @@
+package repository
+
+import (
+	"portfolio-be/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type TaskRepository struct {
+	db *gorm.DB
+}
+
+func NewTaskRepository(db *gorm.DB) *TaskRepository {
+	return &TaskRepository{db: db}
+}
+
+// Create enqueues a task
+func (r *TaskRepository) Create(task *models.Task) error {
+	return r.db.Create(task).Error
+}
+
+// Claim locks the oldest due task for a worker until lockedUntil and counts the
+// attempt. Pending tasks are due at their run time; running tasks are due again once
+// their lock expires. It returns nil when no task is due.
+func (r *TaskRepository) Claim(worker string, lockedUntil time.Time) (*models.Task, error) {
+	now := time.Now().UTC()
+
+	// A single statement keeps the pick and the lock atomic across workers and instances
+	var tasks []models.Task
+	err := r.db.Raw(`UPDATE tasks SET
+			status = ?,
+			attempts = attempts + 1,
+			locked_by = ?,
+			locked_until = ?,
+			updated_at = ?
+		WHERE id = (
+			SELECT id FROM tasks
+			WHERE (status = ? AND run_at <= ?)
+				OR (status = ? AND locked_until <= ? AND attempts < max_attempts)
+			ORDER BY run_at, id
+			LIMIT 1
+		)
+		RETURNING *`,
+		models.TaskRunning, worker, lockedUntil.UTC(), now,
+		models.TaskPending, now, models.TaskRunning, now).Scan(&tasks).Error
+	if err != nil || len(tasks) == 0 {
+		return nil, err
+	}
+	return &tasks[0], nil
+}
+
+// ExtendLock moves the lock of a task still held by the worker
+func (r *TaskRepository) ExtendLock(id uint, worker string, lockedUntil time.Time) (bool, error) {
+	result := r.db.Model(&models.Task{}).
+		Where("id = ? AND status = ? AND locked_by = ?", id, models.TaskRunning, worker).
+		Updates(map[string]interface{}{"locked_until": lockedUntil.UTC(), "updated_at": time.Now().UTC()})
+	return result.RowsAffected == 1, result.Error
+}
+
+// Complete marks a task held by the worker as succeeded. It reports false when the
+// lock expired and another worker claimed the task.
+func (r *TaskRepository) Complete(id uint, worker string) (bool, error) {
+	now := time.Now().UTC()
+	result := r.db.Model(&models.Task{}).
+		Where("id = ? AND status = ? AND locked_by = ?", id, models.TaskRunning, worker).
+		Updates(map[string]interface{}{
+			"status":       models.TaskSucceeded,
+			"locked_by":    "",
+			"locked_until": nil,
+			"last_error":   "",
+			"finished_at":  now,
+			"updated_at":   now,
+		})
+	return result.RowsAffected == 1, result.Error
+}
+
+// Fail records a failed attempt of a task held by the worker. The task is retried at
+// retryAt, or dead-lettered when retryAt is nil.
+func (r *TaskRepository) Fail(id uint, worker, lastError string, retryAt *time.Time) (bool, error) {
+	now := time.Now().UTC()
+	updates := map[string]interface{}{
+		"locked_by":    "",
+		"locked_until": nil,
+		"last_error":   lastError,
+		"updated_at":   now,
+	}
+	if retryAt != nil {
+		updates["status"] = models.TaskPending
+		updates["run_at"] = retryAt.UTC()
+	} else {
+		updates["status"] = models.TaskDead
+		updates["finished_at"] = now
+	}
+
+	result := r.db.Model(&models.Task{}).
+		Where("id = ? AND status = ? AND locked_by = ?", id, models.TaskRunning, worker).
+		Updates(updates)
+	return result.RowsAffected == 1, result.Error
+}
+
+// DeadLetterExpired dead-letters running tasks whose lock expired on their last attempt
+func (r *TaskRepository) DeadLetterExpired() (int64, error) {
+	now := time.Now().UTC()
+	result := r.db.Model(&models.Task{}).
+		Where("status = ? AND locked_until <= ? AND attempts >= max_attempts", models.TaskRunning, now).
+		Updates(map[string]interface{}{
+			"status":       models.TaskDead,
+			"locked_by":    "",
+			"locked_until": nil,
+			"last_error":   "worker did not report back before its lock expired",
+			"finished_at":  now,
+			"updated_at":   now,
+		})
+	return result.RowsAffected, result.Error
+}
+
+// Requeue puts a dead task back in the queue with a fresh set of attempts
+func (r *TaskRepository) Requeue(id uint) (bool, error) {
+	now := time.Now().UTC()
+	result := r.db.Model(&models.Task{}).
+		Where("id = ? AND status = ?", id, models.TaskDead).
+		Updates(map[string]interface{}{
+			"status":      models.TaskPending,
+			"attempts":    0,
+			"run_at":      now,
+			"finished_at": nil,
+			"updated_at":  now,
+		})
+	return result.RowsAffected == 1, result.Error
+}
+
+// GetByID returns a task
+func (r *TaskRepository) GetByID(id uint) (*models.Task, error) {
+	var task models.Task
+	if err := r.db.First(&task, id).Error; err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+// List returns tasks filtered by status and kind, newest first
+func (r *TaskRepository) List(status, kind string, limit, offset int) ([]models.Task, int64, error) {
+	var tasks []models.Task
+	var total int64
+
+	query := r.db.Model(&models.Task{})
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if kind != "" {
+		query = query.Where("kind = ?", kind)
+	}
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := query.Order("created_at DESC, id DESC").Limit(limit).Offset(offset).Find(&tasks).Error
+	return tasks, total, err
+}
+
+// Stats counts tasks by status
+func (r *TaskRepository) Stats() (*models.TaskStats, error) {
+	var rows []struct {
+		Status string
+		Count  int64
+	}
+	if err := r.db.Model(&models.Task{}).Select("status, COUNT(*) AS count").Group("status").Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	stats := &models.TaskStats{}
+	for _, row := range rows {
+		switch row.Status {
+		case models.TaskPending:
+			stats.Pending = row.Count
+		case models.TaskRunning:
+			stats.Running = row.Count
+		case models.TaskSucceeded:
+			stats.Succeeded = row.Count
+		case models.TaskDead:
+			stats.Dead = row.Count
+		}
+	}
+	return stats, nil
+}
+
+// Delete removes a task that is not running
+func (r *TaskRepository) Delete(id uint) (bool, error) {
+	result := r.db.Where("id = ? AND status <> ?", id, models.TaskRunning).Delete(&models.Task{})
+	return result.RowsAffected == 1, result.Error
+}
+
+// DeleteByRefs removes the tasks about the given records, whatever their status
+func (r *TaskRepository) DeleteByRefs(refs []string) (int64, error) {
+	if len(refs) == 0 {
+		return 0, nil
+	}
+	result := r.db.Where("ref IN ?", refs).Delete(&models.Task{})
+	return result.RowsAffected, result.Error
+}
+
+// DeleteSucceededBefore prunes succeeded tasks finished before the given time
+func (r *TaskRepository) DeleteSucceededBefore(before time.Time) (int64, error) {
+	result := r.db.Where("status = ? AND finished_at < ?", models.TaskSucceeded, before.UTC()).Delete(&models.Task{})
+	return result.RowsAffected, result.Error
+}
@@ -3,6 +3,7 @@ package repository
 import (
 	"fmt"
 	"portfolio-be/internal/models"
+	"portfolio-be/pkg/utils"
 	"time"
 
 	"gorm.io/gorm"
@@ -38,18 +39,165 @@ func (r *UploadRepository) GetByS3Key(s3Key string) (*models.Upload, error) {
 	return &upload, nil
 }
 
+// GetByContentHash returns the oldest upload whose content matches the given SHA-256 hash
+func (r *UploadRepository) GetByContentHash(hash string) (*models.Upload, error) {
+	var upload models.Upload
+	err := r.db.Where("content_hash = ?", hash).Order("id ASC").First(&upload).Error
+	if err != nil {
+		return nil, err
+	}
+	return &upload, nil
+}
+
+// CountByS3Key returns the number of upload records referencing an S3 object, excluding the given upload
+func (r *UploadRepository) CountByS3Key(s3Key string, excludeID uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.Upload{}).Where("s3_key = ? AND id <> ?", s3Key, excludeID).Count(&count).Error
+	return count, err
+}
+
+// GetAllByS3Key returns every upload record sharing an S3 object, oldest first
+func (r *UploadRepository) GetAllByS3Key(s3Key string) ([]models.Upload, error) {
+	var uploads []models.Upload
+	err := r.db.Where("s3_key = ?", s3Key).Order("id ASC").Find(&uploads).Error
+	return uploads, err
+}
+
+// GetUnreferenced returns uploads created before the given time that no entity references
+func (r *UploadRepository) GetUnreferenced(createdBefore time.Time) ([]models.Upload, error) {
+	var uploads []models.Upload
+	err := r.db.Where("created_at < ? AND id NOT IN (?)", createdBefore,
+		r.db.Model(&models.UploadReference{}).Select("upload_id")).
+		Order("id ASC").Find(&uploads).Error
+	return uploads, err
+}
+
+// GetExpiredUnreferenced returns up to limit uploads with id greater than afterID that
+// expired, or were deactivated, before the cutoff and that no resource uses
+func (r *UploadRepository) GetExpiredUnreferenced(cutoff time.Time, afterID uint, limit int) ([]models.Upload, error) {
+	var uploads []models.Upload
+	err := r.db.Where("id > ?", afterID).
+		Where("(expires_at IS NOT NULL AND expires_at < ?) OR (is_active = ? AND updated_at < ?)", cutoff, false, cutoff).
+		Where("id NOT IN (?)", r.db.Model(&models.Resource{}).Select("upload_id")).
+		Order("id ASC").Limit(limit).Find(&uploads).Error
+	return uploads, err
+}
+
+// GetAllS3Keys returns the distinct S3 keys of all upload records, including the
+// poster frames and transcodes derived from them
+func (r *UploadRepository) GetAllS3Keys() ([]string, error) {
+	var keys []string
+	err := r.db.Raw(`SELECT s3_key FROM uploads WHERE deleted_at IS NULL
+		UNION SELECT poster_key FROM uploads WHERE deleted_at IS NULL AND poster_key <> ''
+		UNION SELECT transcoded_key FROM uploads WHERE deleted_at IS NULL AND transcoded_key <> ''`).
+		Scan(&keys).Error
+	return keys, err
+}
+
+// UpdateScanResult records a virus scan verdict on every upload record sharing an S3 object
+func (r *UploadRepository) UpdateScanResult(s3Key, status, signature string, scannedAt time.Time) error {
+	return r.db.Model(&models.Upload{}).Where("s3_key = ?", s3Key).Updates(map[string]interface{}{
+		"scan_status":    status,
+		"scan_signature": signature,
+		"scanned_at":     scannedAt,
+	}).Error
+}
+
+// GetScanDue returns up to limit uploads with id greater than afterID whose scan is
+// pending or failed, or, when rescanBefore is set, that were found clean before it
+func (r *UploadRepository) GetScanDue(rescanBefore *time.Time, afterID uint, limit int) ([]models.Upload, error) {
+	var uploads []models.Upload
+	err := r.scanDue(rescanBefore).Where("id > ?", afterID).Order("id ASC").Limit(limit).Find(&uploads).Error
+	return uploads, err
+}
+
+// MarkScanDueClean marks every upload GetScanDue would return as clean without scanning
+// it, returning how many were marked
+func (r *UploadRepository) MarkScanDueClean(rescanBefore *time.Time, scannedAt time.Time) (int64, error) {
+	result := r.scanDue(rescanBefore).Model(&models.Upload{}).Updates(map[string]interface{}{
+		"scan_status":    models.UploadScanClean,
+		"scan_signature": "",
+		"scanned_at":     scannedAt,
+	})
+	return result.RowsAffected, result.Error
+}
+
+// scanDue selects uploads whose scan is pending or failed or, when rescanBefore is set,
+// that were found clean before it
+func (r *UploadRepository) scanDue(rescanBefore *time.Time) *gorm.DB {
+	due := []string{models.UploadScanPending, models.UploadScanFailed}
+	if rescanBefore != nil {
+		return r.db.Where("scan_status IN ? OR (scan_status = ? AND (scanned_at IS NULL OR scanned_at < ?))",
+			due, models.UploadScanClean, *rescanBefore)
+	}
+	return r.db.Where("scan_status IN ?", due)
+}
+
+// derivedKeyFields are the upload columns UpdateDerivedKey may set
+var derivedKeyFields = map[string]bool{"poster_key": true, "transcoded_key": true}
+
+// UpdateDerivedKey sets a derived file key on every upload record sharing an S3 object
+func (r *UploadRepository) UpdateDerivedKey(s3Key, field, key string) error {
+	if !derivedKeyFields[field] {
+		return fmt.Errorf("unknown derived key field %q", field)
+	}
+	return r.db.Model(&models.Upload{}).Where("s3_key = ?", s3Key).Update(field, key).Error
+}
+
+// UpdateObject points an upload record at the S3 object and derived files set on it
+func (r *UploadRepository) UpdateObject(upload *models.Upload) error {
+	return r.db.Model(&models.Upload{}).Where("id = ?", upload.ID).Updates(map[string]interface{}{
+		"file_name":      upload.FileName,
+		"s3_key":         upload.S3Key,
+		"s3_bucket":      upload.S3Bucket,
+		"poster_key":     upload.PosterKey,
+		"transcoded_key": upload.TranscodedKey,
+	}).Error
+}
+
+// publicOnly restricts a query to uploads shown on the public site: files that scanned
+// clean behind a public, active resource
+func (r *UploadRepository) publicOnly(query *gorm.DB) *gorm.DB {
+	return query.Where("uploads.scan_status = ? AND uploads.id IN (?)", models.UploadScanClean,
+		r.db.Model(&models.Resource{}).Select("upload_id").Where("is_public = ? AND is_active = ?", true, true))
+}
+
 func (r *UploadRepository) GetAll(limit, offset int) ([]models.Upload, error) {
 	var uploads []models.Upload
 	err := r.db.Limit(limit).Offset(offset).Find(&uploads).Error
 	return uploads, err
 }
 
+// GetPublic returns a page of the uploads shown on the public site
+func (r *UploadRepository) GetPublic(limit, offset int) ([]models.Upload, error) {
+	var uploads []models.Upload
+	err := r.publicOnly(r.db).Order("uploads.id ASC").Limit(limit).Offset(offset).Find(&uploads).Error
+	return uploads, err
+}
+
+// GetPublicByID returns an upload shown on the public site
+func (r *UploadRepository) GetPublicByID(id uint) (*models.Upload, error) {
+	var upload models.Upload
+	err := r.publicOnly(r.db).First(&upload, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &upload, nil
+}
+
 func (r *UploadRepository) Count() (int64, error) {
 	var count int64
 	err := r.db.Model(&models.Upload{}).Where("is_active = ?", true).Count(&count).Error
 	return count, err
 }
 
+// CountPublic returns the number of uploads shown on the public site
+func (r *UploadRepository) CountPublic() (int64, error) {
+	var count int64
+	err := r.publicOnly(r.db.Model(&models.Upload{})).Count(&count).Error
+	return count, err
+}
+
 func (r *UploadRepository) Delete(id uint) error {
 	return r.db.Delete(&models.Upload{}, id).Error
 }
@@ -58,11 +206,6 @@ func (r *UploadRepository) DeleteByS3Key(s3Key string) error {
 	return r.db.Where("s3_key = ?", s3Key).Delete(&models.Upload{}).Error
 }
 
-// UpdateURL updates the URL of an upload record
-func (r *UploadRepository) UpdateURL(id uint, newURL string) error {
-	return r.db.Model(&models.Upload{}).Where("id = ?", id).Update("url", newURL).Error
-}
-
 // UpdateExpiry updates the expiry time of an upload record
 func (r *UploadRepository) UpdateExpiry(id uint, expiresAt *time.Time) error {
 	return r.db.Model(&models.Upload{}).Where("id = ?", id).Update("expires_at", expiresAt).Error
@@ -86,12 +229,24 @@ func (r *UploadRepository) GetExpired() ([]models.Upload, error) {
 	return uploads, err
 }
 
-// GetUploadSummary returns upload statistics
-func (r *UploadRepository) GetUploadSummary() (*models.UploadSummary, error) {
+// GetUploadSummary returns upload statistics, limited to the uploads of one user when
+// uploadedBy is set
+func (r *UploadRepository) GetUploadSummary(uploadedBy *uint, publicOnly bool) (*models.UploadSummary, error) {
 	var summary models.UploadSummary
 
+	active := func() *gorm.DB {
+		query := r.db.Model(&models.Upload{}).Where("is_active = ?", true)
+		if uploadedBy != nil {
+			query = query.Where("uploaded_by = ?", *uploadedBy)
+		}
+		if publicOnly {
+			query = r.publicOnly(query)
+		}
+		return query
+	}
+
 	// Get total files count
-	err := r.db.Model(&models.Upload{}).Where("is_active = ?", true).Count(&summary.TotalFiles).Error
+	err := active().Count(&summary.TotalFiles).Error
 	if err != nil {
 		return nil, err
 	}
@@ -100,48 +255,99 @@ func (r *UploadRepository) GetUploadSummary() (*models.UploadSummary, error) {
 	var totalSize struct {
 		Total int64
 	}
-	err = r.db.Model(&models.Upload{}).Where("is_active = ?", true).Select("COALESCE(SUM(file_size), 0) as total").Scan(&totalSize).Error
+	err = active().Select("COALESCE(SUM(file_size), 0) as total").Scan(&totalSize).Error
 	if err != nil {
 		return nil, err
 	}
 	summary.TotalSize = totalSize.Total
-	summary.TotalSizeFormatted = formatFileSize(totalSize.Total)
+	summary.TotalSizeFormatted = utils.FormatFileSize(totalSize.Total)
 
 	// Get images count
-	err = r.db.Model(&models.Upload{}).Where("is_active = ? AND content_type LIKE ?", true, "image/%").Count(&summary.Images).Error
+	err = active().Where("content_type LIKE ?", "image/%").Count(&summary.Images).Error
 	if err != nil {
 		return nil, err
 	}
 
 	// Get documents count
-	err = r.db.Model(&models.Upload{}).Where("is_active = ? AND (content_type LIKE ? OR content_type LIKE ? OR content_type = ?)",
-		true, "application/pdf", "application/%word%", "text/plain").Count(&summary.Documents).Error
+	err = active().Where("content_type LIKE ? OR content_type LIKE ? OR content_type = ?",
+		"application/pdf", "application/%word%", "text/plain").Count(&summary.Documents).Error
 	if err != nil {
 		return nil, err
 	}
 
 	// Get videos count
-	err = r.db.Model(&models.Upload{}).Where("is_active = ? AND content_type LIKE ?", true, "video/%").Count(&summary.Videos).Error
+	err = active().Where("content_type LIKE ?", "video/%").Count(&summary.Videos).Error
+	if err != nil {
+		return nil, err
+	}
+
+	// Get audio count
+	err = active().Where("content_type LIKE ?", "audio/%").Count(&summary.Audio).Error
 	if err != nil {
 		return nil, err
 	}
 
 	// Calculate others
-	summary.Others = summary.TotalFiles - summary.Images - summary.Documents - summary.Videos
+	summary.Others = summary.TotalFiles - summary.Images - summary.Documents - summary.Videos - summary.Audio
 
 	return &summary, nil
 }
 
-// formatFileSize formats file size in bytes to human readable format
-func formatFileSize(size int64) string {
-	const unit = 1024
-	if size < unit {
-		return fmt.Sprintf("%d B", size)
+// GetUsageByUser returns the number and total size of a user's active uploads
+func (r *UploadRepository) GetUsageByUser(userID uint) (int64, int64, error) {
+	var usage struct {
+		Files int64
+		Bytes int64
 	}
-	div, exp := int64(unit), 0
-	for n := size / unit; n >= unit; n /= unit {
-		div *= unit
-		exp++
+	err := r.db.Model(&models.Upload{}).Where("is_active = ? AND uploaded_by = ?", true, userID).
+		Select("COUNT(*) AS files, COALESCE(SUM(file_size), 0) AS bytes").Scan(&usage).Error
+	return usage.Files, usage.Bytes, err
+}
+
+// GetUsageByUserThrough returns the number and total size of a user's active uploads
+// saved up to and including the given upload
+func (r *UploadRepository) GetUsageByUserThrough(userID, uploadID uint) (int64, int64, error) {
+	var usage struct {
+		Files int64
+		Bytes int64
 	}
-	return fmt.Sprintf("%.1f %cB", float64(size)/float64(div), "KMGTPE"[exp])
+	err := r.db.Model(&models.Upload{}).Where("is_active = ? AND uploaded_by = ? AND id <= ?", true, userID, uploadID).
+		Select("COUNT(*) AS files, COALESCE(SUM(file_size), 0) AS bytes").Scan(&usage).Error
+	return usage.Files, usage.Bytes, err
+}
+
+// GetDeduplicationReport returns how much storage is saved by uploads sharing the same S3 object
+func (r *UploadRepository) GetDeduplicationReport() (*models.DeduplicationReport, error) {
+	var report models.DeduplicationReport
+
+	var logical struct {
+		Count int64
+		Total int64
+	}
+	err := r.db.Model(&models.Upload{}).Select("COUNT(*) as count, COALESCE(SUM(file_size), 0) as total").Scan(&logical).Error
+	if err != nil {
+		return nil, err
+	}
+
+	// Each S3 object is stored once no matter how many uploads reference it
+	var stored struct {
+		Count int64
+		Total int64
+	}
+	objects := r.db.Model(&models.Upload{}).Select("s3_key, MAX(file_size) as file_size").Group("s3_key")
+	err = r.db.Table("(?) as objects", objects).Select("COUNT(*) as count, COALESCE(SUM(file_size), 0) as total").Scan(&stored).Error
+	if err != nil {
+		return nil, err
+	}
+
+	report.TotalUploads = logical.Count
+	report.UniqueObjects = stored.Count
+	report.DuplicateUploads = logical.Count - stored.Count
+	report.LogicalSize = logical.Total
+	report.StoredSize = stored.Total
+	report.SavedSize = logical.Total - stored.Total
+	report.SavedSizeFormatted = utils.FormatFileSize(report.SavedSize)
+	report.StoredSizeFormatted = utils.FormatFileSize(report.StoredSize)
+
+	return &report, nil
 }
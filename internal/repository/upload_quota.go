@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"portfolio-be/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type UploadQuotaRepository struct {
+	db *gorm.DB
+}
+
+func NewUploadQuotaRepository(db *gorm.DB) *UploadQuotaRepository {
+	return &UploadQuotaRepository{db: db}
+}
+
+// Save creates or updates a quota
+func (r *UploadQuotaRepository) Save(quota *models.UploadQuota) error {
+	return r.db.Save(quota).Error
+}
+
+func (r *UploadQuotaRepository) GetByID(id uint) (*models.UploadQuota, error) {
+	var quota models.UploadQuota
+	err := r.db.First(&quota, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &quota, nil
+}
+
+// GetAll returns role quotas by role name followed by user quotas by user id
+func (r *UploadQuotaRepository) GetAll() ([]models.UploadQuota, error) {
+	var quotas []models.UploadQuota
+	err := r.db.Order("user_id IS NOT NULL, role ASC, user_id ASC").Find(&quotas).Error
+	return quotas, err
+}
+
+// GetByUser returns the quota of a single user
+func (r *UploadQuotaRepository) GetByUser(userID uint) (*models.UploadQuota, error) {
+	var quota models.UploadQuota
+	err := r.db.Where("user_id = ?", userID).First(&quota).Error
+	if err != nil {
+		return nil, err
+	}
+	return &quota, nil
+}
+
+// GetByRole returns the quota of a role
+func (r *UploadQuotaRepository) GetByRole(role string) (*models.UploadQuota, error) {
+	var quota models.UploadQuota
+	err := r.db.Where("role = ? AND user_id IS NULL", role).First(&quota).Error
+	if err != nil {
+		return nil, err
+	}
+	return &quota, nil
+}
+
+func (r *UploadQuotaRepository) Delete(id uint) error {
+	return r.db.Delete(&models.UploadQuota{}, id).Error
+}
@@ -0,0 +1,152 @@
+package repository
+
+import (
+	"fmt"
+	"portfolio-be/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// EntityURL is a URL stored in an entity field that may point at an upload
+type EntityURL struct {
+	EntityType string
+	EntityID   uint
+	Field      string
+	URL        string
+}
+
+// uploadURLColumns lists the entity columns that may hold upload URLs
+var uploadURLColumns = []struct {
+	entityType string
+	table      string
+	column     string
+}{
+	{models.ReferenceEntityProject, "projects", "image"},
+	{models.ReferenceEntityTestimonial, "testimonials", "image"},
+	{models.ReferenceEntityExperience, "experiences", "icon"},
+	{models.ReferenceEntityContent, "contents", "image_url"},
+}
+
+type UploadReferenceRepository struct {
+	db *gorm.DB
+}
+
+func NewUploadReferenceRepository(db *gorm.DB) *UploadReferenceRepository {
+	return &UploadReferenceRepository{db: db}
+}
+
+// ReplaceForEntity replaces all references held by an entity
+func (r *UploadReferenceRepository) ReplaceForEntity(entityType string, entityID uint, refs []models.UploadReference) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("entity_type = ? AND entity_id = ?", entityType, entityID).Delete(&models.UploadReference{}).Error; err != nil {
+			return err
+		}
+		if len(refs) == 0 {
+			return nil
+		}
+		return tx.Create(&refs).Error
+	})
+}
+
+// DeleteByEntity removes all references held by an entity
+func (r *UploadReferenceRepository) DeleteByEntity(entityType string, entityID uint) error {
+	return r.db.Where("entity_type = ? AND entity_id = ?", entityType, entityID).Delete(&models.UploadReference{}).Error
+}
+
+// DeleteByID removes a single reference
+func (r *UploadReferenceRepository) DeleteByID(id uint) error {
+	return r.db.Delete(&models.UploadReference{}, id).Error
+}
+
+// GetByUploadID returns every reference to an upload
+func (r *UploadReferenceRepository) GetByUploadID(uploadID uint) ([]models.UploadReference, error) {
+	var refs []models.UploadReference
+	err := r.db.Where("upload_id = ?", uploadID).Order("entity_type ASC, entity_id ASC").Find(&refs).Error
+	return refs, err
+}
+
+// GetByEntity returns every reference held by an entity
+func (r *UploadReferenceRepository) GetByEntity(entityType string, entityID uint) ([]models.UploadReference, error) {
+	var refs []models.UploadReference
+	err := r.db.Where("entity_type = ? AND entity_id = ?", entityType, entityID).Find(&refs).Error
+	return refs, err
+}
+
+// GetAll returns the whole reference index
+func (r *UploadReferenceRepository) GetAll() ([]models.UploadReference, error) {
+	var refs []models.UploadReference
+	err := r.db.Order("id ASC").Find(&refs).Error
+	return refs, err
+}
+
+// CountByUploadID returns the number of references to an upload
+func (r *UploadReferenceRepository) CountByUploadID(uploadID uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.UploadReference{}).Where("upload_id = ?", uploadID).Count(&count).Error
+	return count, err
+}
+
+// ReplaceAll rebuilds the whole reference index
+func (r *UploadReferenceRepository) ReplaceAll(refs []models.UploadReference) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("1 = 1").Delete(&models.UploadReference{}).Error; err != nil {
+			return err
+		}
+		if len(refs) == 0 {
+			return nil
+		}
+		return tx.CreateInBatches(&refs, 100).Error
+	})
+}
+
+// ListEntityURLs returns every non-empty URL stored in entity columns that may reference uploads
+func (r *UploadReferenceRepository) ListEntityURLs() ([]EntityURL, error) {
+	var urls []EntityURL
+
+	for _, source := range uploadURLColumns {
+		var rows []struct {
+			ID  uint
+			URL string
+		}
+		err := r.db.Table(source.table).
+			Select(fmt.Sprintf("id, %s as url", source.column)).
+			Where(fmt.Sprintf("deleted_at IS NULL AND %s <> ''", source.column)).
+			Scan(&rows).Error
+		if err != nil {
+			return nil, err
+		}
+
+		for _, row := range rows {
+			urls = append(urls, EntityURL{
+				EntityType: source.entityType,
+				EntityID:   row.ID,
+				Field:      source.column,
+				URL:        row.URL,
+			})
+		}
+	}
+
+	return urls, nil
+}
+
+// ListResourceUploads returns the upload referenced by every resource
+func (r *UploadReferenceRepository) ListResourceUploads() ([]models.Resource, error) {
+	var resources []models.Resource
+	err := r.db.Select("id, upload_id").Find(&resources).Error
+	return resources, err
+}
+
+// ClearEntityField blanks an entity column that references an upload
+func (r *UploadReferenceRepository) ClearEntityField(entityType string, entityID uint, field string) error {
+	return r.UpdateEntityField(entityType, entityID, field, "")
+}
+
+// UpdateEntityField sets an entity column that may reference an upload
+func (r *UploadReferenceRepository) UpdateEntityField(entityType string, entityID uint, field, value string) error {
+	for _, source := range uploadURLColumns {
+		if source.entityType == entityType && source.column == field {
+			return r.db.Table(source.table).Where("id = ?", entityID).Update(source.column, value).Error
+		}
+	}
+	return fmt.Errorf("unknown reference field %s.%s", entityType, field)
+}
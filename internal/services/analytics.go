@@ -0,0 +1,313 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"portfolio-be/internal/config"
+	"portfolio-be/internal/models"
+	"portfolio-be/internal/repository"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// analyticsDayFormat is the layout of the days analytics are aggregated by
+const analyticsDayFormat = "2006-01-02"
+
+// maxAnalyticsRange bounds the number of days an analytics report may span
+const maxAnalyticsRange = 366
+
+// analyticsBreakdownLimit bounds the referrers and countries listed in a report
+const analyticsBreakdownLimit = 20
+
+// ErrInvalidAnalyticsRange is returned when an analytics report range is malformed or too long
+var ErrInvalidAnalyticsRange = errors.New("from and to must be YYYY-MM-DD days, from not after to, at most 366 days apart")
+
+// AnalyticsEvent is a view or download of a resource
+type AnalyticsEvent struct {
+	ResourceID uint
+	Action     string
+	Referrer   string
+	ClientIP   string
+	At         time.Time
+}
+
+// analyticsKey identifies one aggregated daily stat row
+type analyticsKey struct {
+	resourceID uint
+	day        string
+	dimension  string
+	value      string
+}
+
+// AnalyticsService records resource views and downloads without blocking requests.
+// Events are aggregated in memory per day, referrer domain and country, and written
+// in batches; Close flushes whatever is still pending.
+type AnalyticsService struct {
+	repo         *repository.AnalyticsRepository
+	resourceRepo *repository.ResourceRepository
+	geoIP        *GeoIPDatabase
+	config       config.AnalyticsConfig
+
+	mu      sync.RWMutex
+	closed  bool
+	events  chan AnalyticsEvent
+	done    chan struct{}
+	dropped atomic.Int64
+}
+
+func NewAnalyticsService(repo *repository.AnalyticsRepository, resourceRepo *repository.ResourceRepository, cfg config.AnalyticsConfig) *AnalyticsService {
+	s := &AnalyticsService{
+		repo:         repo,
+		resourceRepo: resourceRepo,
+		config:       cfg,
+		events:       make(chan AnalyticsEvent, cfg.BufferSize),
+		done:         make(chan struct{}),
+	}
+
+	if cfg.GeoIPPath != "" {
+		geoIP, err := LoadGeoIPDatabase(cfg.GeoIPPath)
+		if err != nil {
+			log.Printf("GeoIP database not loaded, countries will not be recorded: %v", err)
+		} else {
+			log.Printf("Loaded GeoIP database with %d ranges", geoIP.Size())
+			s.geoIP = geoIP
+		}
+	}
+
+	return s
+}
+
+// Start begins aggregating and flushing recorded events in the background
+func (s *AnalyticsService) Start() {
+	go s.run()
+	log.Println("Analytics recorder started")
+}
+
+// Record queues an event without blocking. Events are dropped when the buffer is
+// full or the recorder is closed.
+func (s *AnalyticsService) Record(event AnalyticsEvent) {
+	if event.At.IsZero() {
+		event.At = time.Now()
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.closed {
+		return
+	}
+
+	select {
+	case s.events <- event:
+	default:
+		s.dropped.Add(1)
+	}
+}
+
+// Close stops accepting events and waits for pending ones to be flushed
+func (s *AnalyticsService) Close(ctx context.Context) error {
+	s.mu.Lock()
+	if !s.closed {
+		s.closed = true
+		close(s.events)
+	}
+	s.mu.Unlock()
+
+	select {
+	case <-s.done:
+		log.Println("Analytics recorder stopped")
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("analytics flush did not finish: %w", ctx.Err())
+	}
+}
+
+// run aggregates events until the recorder is closed, flushing on every tick and
+// whenever a batch is full
+func (s *AnalyticsService) run() {
+	defer close(s.done)
+
+	ticker := time.NewTicker(s.config.FlushInterval)
+	defer ticker.Stop()
+
+	pending := make(map[analyticsKey]*models.AnalyticsBreakdown)
+	counts := make(map[uint]repository.ResourceCounts)
+	batched := 0
+
+	flush := func() {
+		if dropped := s.dropped.Swap(0); dropped > 0 {
+			log.Printf("Analytics buffer full, dropped %d events", dropped)
+		}
+
+		if batched == 0 {
+			return
+		}
+		if err := s.flush(pending, counts); err != nil {
+			log.Printf("Failed to flush %d analytics events: %v", batched, err)
+		}
+		pending = make(map[analyticsKey]*models.AnalyticsBreakdown)
+		counts = make(map[uint]repository.ResourceCounts)
+		batched = 0
+	}
+
+	for {
+		select {
+		case event, ok := <-s.events:
+			if !ok {
+				flush()
+				return
+			}
+			s.aggregate(event, pending, counts)
+			batched++
+			if batched >= s.config.BatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+// aggregate adds an event to the day's total, referrer and country rows
+func (s *AnalyticsService) aggregate(event AnalyticsEvent, pending map[analyticsKey]*models.AnalyticsBreakdown, counts map[uint]repository.ResourceCounts) {
+	day := event.At.UTC().Format(analyticsDayFormat)
+
+	keys := []analyticsKey{{event.ResourceID, day, models.AnalyticsDimensionTotal, ""}}
+	if domain := referrerDomain(event.Referrer); domain != "" {
+		keys = append(keys, analyticsKey{event.ResourceID, day, models.AnalyticsDimensionReferrer, domain})
+	}
+	if country := s.geoIP.Country(event.ClientIP); country != "" {
+		keys = append(keys, analyticsKey{event.ResourceID, day, models.AnalyticsDimensionCountry, country})
+	}
+
+	total := counts[event.ResourceID]
+	for _, key := range keys {
+		stat, ok := pending[key]
+		if !ok {
+			stat = &models.AnalyticsBreakdown{Value: key.value}
+			pending[key] = stat
+		}
+		if event.Action == models.AnalyticsActionDownload {
+			stat.Downloads++
+		} else {
+			stat.Views++
+		}
+	}
+	if event.Action == models.AnalyticsActionDownload {
+		total.Downloads++
+	} else {
+		total.Views++
+	}
+	counts[event.ResourceID] = total
+}
+
+// flush writes aggregated stats and lifetime counters
+func (s *AnalyticsService) flush(pending map[analyticsKey]*models.AnalyticsBreakdown, counts map[uint]repository.ResourceCounts) error {
+	now := time.Now()
+	stats := make([]models.ResourceDailyStat, 0, len(pending))
+	for key, stat := range pending {
+		stats = append(stats, models.ResourceDailyStat{
+			ResourceID: key.resourceID,
+			Day:        key.day,
+			Dimension:  key.dimension,
+			Value:      key.value,
+			Views:      stat.Views,
+			Downloads:  stat.Downloads,
+			CreatedAt:  now,
+			UpdatedAt:  now,
+		})
+	}
+
+	return s.repo.Apply(stats, counts)
+}
+
+// referrerDomain returns the host of a referrer URL without a leading "www."
+func referrerDomain(referrer string) string {
+	if referrer == "" {
+		return ""
+	}
+	parsed, err := url.Parse(referrer)
+	if err != nil || parsed.Hostname() == "" {
+		return ""
+	}
+	return strings.TrimPrefix(strings.ToLower(parsed.Hostname()), "www.")
+}
+
+// GetResourceAnalytics returns the daily views and downloads of a resource between two
+// days, inclusive, with its top referrers and countries. Days default to the last 30.
+func (s *AnalyticsService) GetResourceAnalytics(resourceID uint, from, to string) (*models.ResourceAnalytics, error) {
+	if _, err := s.resourceRepo.GetByID(resourceID); err != nil {
+		return nil, ErrResourceNotFound
+	}
+
+	end := time.Now().UTC().Truncate(24 * time.Hour)
+	if to != "" {
+		parsed, err := time.Parse(analyticsDayFormat, to)
+		if err != nil {
+			return nil, ErrInvalidAnalyticsRange
+		}
+		end = parsed
+	}
+
+	start := end.AddDate(0, 0, -29)
+	if from != "" {
+		parsed, err := time.Parse(analyticsDayFormat, from)
+		if err != nil {
+			return nil, ErrInvalidAnalyticsRange
+		}
+		start = parsed
+	}
+
+	if start.After(end) || end.Sub(start) >= maxAnalyticsRange*24*time.Hour {
+		return nil, ErrInvalidAnalyticsRange
+	}
+	from, to = start.Format(analyticsDayFormat), end.Format(analyticsDayFormat)
+
+	totals, err := s.repo.GetDailyTotals(resourceID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get analytics: %w", err)
+	}
+	referrers, err := s.repo.GetBreakdown(resourceID, models.AnalyticsDimensionReferrer, from, to, analyticsBreakdownLimit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get referrer analytics: %w", err)
+	}
+	countries, err := s.repo.GetBreakdown(resourceID, models.AnalyticsDimensionCountry, from, to, analyticsBreakdownLimit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get country analytics: %w", err)
+	}
+
+	byDay := make(map[string]models.ResourceDailyStat, len(totals))
+	for _, stat := range totals {
+		byDay[stat.Day] = stat
+	}
+
+	report := &models.ResourceAnalytics{
+		ResourceID: resourceID,
+		From:       from,
+		To:         to,
+		Series:     []models.AnalyticsPoint{},
+		Referrers:  referrers,
+		Countries:  countries,
+	}
+	if report.Referrers == nil {
+		report.Referrers = []models.AnalyticsBreakdown{}
+	}
+	if report.Countries == nil {
+		report.Countries = []models.AnalyticsBreakdown{}
+	}
+
+	// Fill days without events so the series has one point per day
+	for day := start; !day.After(end); day = day.AddDate(0, 0, 1) {
+		key := day.Format(analyticsDayFormat)
+		stat := byDay[key]
+		report.Series = append(report.Series, models.AnalyticsPoint{Day: key, Views: stat.Views, Downloads: stat.Downloads})
+		report.TotalViews += stat.Views
+		report.TotalDownloads += stat.Downloads
+	}
+
+	return report, nil
+}
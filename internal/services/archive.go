@@ -0,0 +1,203 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"portfolio-be/internal/config"
+	"portfolio-be/internal/models"
+	"strings"
+)
+
+var (
+	// ErrInvalidArchive is returned when an uploaded file is not a readable ZIP archive
+	ErrInvalidArchive = errors.New("file is not a valid ZIP archive")
+	// ErrArchiveTooLarge is returned when an archive exceeds the size, entry or uncompressed limits
+	ErrArchiveTooLarge = errors.New("archive exceeds the import limits")
+)
+
+// ArchiveImportOptions controls the resources created for imported files
+type ArchiveImportOptions struct {
+	CreateResources bool
+	Category        string
+	Tags            string
+	IsPublic        bool
+}
+
+// ArchiveService imports the files of ZIP archives as uploads and, optionally, resources
+type ArchiveService struct {
+	uploadService   *UploadService
+	resourceService *ResourceService
+	config          config.ArchiveConfig
+}
+
+func NewArchiveService(uploadService *UploadService, resourceService *ResourceService, cfg config.ArchiveConfig) *ArchiveService {
+	return &ArchiveService{
+		uploadService:   uploadService,
+		resourceService: resourceService,
+		config:          cfg,
+	}
+}
+
+// archiveFormOverhead allows for the multipart headers and form fields sent with an archive
+const archiveFormOverhead = 1 << 20
+
+// MaxRequestSize is the largest archive upload request accepted, so that a request body
+// over the archive limit is refused before it is spooled to disk
+func (s *ArchiveService) MaxRequestSize() int64 {
+	return s.config.MaxArchiveSize + archiveFormOverhead
+}
+
+// ImportArchive stores every file of a ZIP archive with the same validation and quota
+// rules as single uploads. Archives over the size, entry count or total uncompressed
+// limits are rejected as a whole; unsafe or failing entries are reported and skipped.
+func (s *ArchiveService) ImportArchive(r io.ReaderAt, size int64, opts ArchiveImportOptions, userID uint) (*models.ArchiveImportReport, error) {
+	if size > s.config.MaxArchiveSize {
+		return nil, fmt.Errorf("%w: archive is larger than %d bytes", ErrArchiveTooLarge, s.config.MaxArchiveSize)
+	}
+
+	archive, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+	}
+
+	// Check declared sizes up front so a zip bomb is rejected before anything is stored
+	var files []*zip.File
+	var uncompressed uint64
+	for _, file := range archive.File {
+		if file.FileInfo().IsDir() {
+			continue
+		}
+		files = append(files, file)
+		uncompressed += file.UncompressedSize64
+	}
+	if len(files) > s.config.MaxEntries {
+		return nil, fmt.Errorf("%w: %d files, at most %d allowed", ErrArchiveTooLarge, len(files), s.config.MaxEntries)
+	}
+	if uncompressed > uint64(s.config.MaxUncompressedSize) {
+		return nil, fmt.Errorf("%w: %d bytes uncompressed, at most %d allowed", ErrArchiveTooLarge, uncompressed, s.config.MaxUncompressedSize)
+	}
+
+	report := &models.ArchiveImportReport{Results: []models.ArchiveEntryResult{}}
+	for _, file := range files {
+		result := s.importEntry(file, opts, userID)
+		switch result.Status {
+		case models.ArchiveEntryUploaded:
+			report.Uploaded++
+		case models.ArchiveEntrySkipped:
+			report.Skipped++
+		default:
+			report.Failed++
+		}
+		report.Results = append(report.Results, result)
+	}
+	report.Entries = len(report.Results)
+
+	return report, nil
+}
+
+// importEntry validates, stores and optionally publishes one archive entry
+func (s *ArchiveService) importEntry(file *zip.File, opts ArchiveImportOptions, userID uint) models.ArchiveEntryResult {
+	result := models.ArchiveEntryResult{Name: file.Name}
+	fail := func(err error) models.ArchiveEntryResult {
+		result.Status = models.ArchiveEntryFailed
+		result.Error = err.Error()
+		return result
+	}
+
+	name, err := archiveEntryName(file.Name)
+	if err != nil {
+		return fail(err)
+	}
+	if isArchiveMetadata(file.Name) {
+		result.Status = models.ArchiveEntrySkipped
+		return result
+	}
+
+	if file.UncompressedSize64 > 0 && (file.CompressedSize64 == 0 ||
+		file.UncompressedSize64/file.CompressedSize64 > uint64(s.config.MaxCompressionRatio)) {
+		return fail(fmt.Errorf("compression ratio exceeds %d:1", s.config.MaxCompressionRatio))
+	}
+
+	data, err := readArchiveEntry(file)
+	if err != nil {
+		return fail(err)
+	}
+
+	upload, err := s.uploadService.StoreFile(bytes.NewReader(data), name, contentTypeFromName(name), int64(len(data)), userID)
+	if err != nil {
+		return fail(err)
+	}
+	result.Status = models.ArchiveEntryUploaded
+	result.Upload = upload
+
+	if opts.CreateResources {
+		isPublic := opts.IsPublic
+		resource, err := s.resourceService.CreateResource(&models.ResourceCreateRequest{
+			Name:     strings.TrimSuffix(name, path.Ext(name)),
+			Type:     models.ResourceType(models.MimeClass(upload.ContentType)),
+			Category: opts.Category,
+			Tags:     opts.Tags,
+			UploadID: upload.ID,
+			IsPublic: &isPublic,
+		})
+		if err != nil {
+			// The upload is kept; only the resource is missing
+			result.Error = fmt.Sprintf("failed to create resource: %v", err)
+		} else {
+			result.ResourceID = &resource.ID
+		}
+	}
+
+	return result
+}
+
+// readArchiveEntry decompresses an entry, refusing to read past its declared size
+func readArchiveEntry(file *zip.File) ([]byte, error) {
+	rc, err := file.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open entry: %w", err)
+	}
+	defer rc.Close()
+
+	data, err := io.ReadAll(io.LimitReader(rc, int64(file.UncompressedSize64)+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read entry: %w", err)
+	}
+	if uint64(len(data)) > file.UncompressedSize64 {
+		return nil, errors.New("entry is larger than its declared size")
+	}
+	return data, nil
+}
+
+// archiveEntryName returns the base file name of an entry, rejecting absolute paths
+// and parent directory references (zip slip)
+func archiveEntryName(name string) (string, error) {
+	normalized := strings.ReplaceAll(name, `\`, "/")
+	if strings.HasPrefix(normalized, "/") || (len(normalized) > 1 && normalized[1] == ':') {
+		return "", errors.New("unsafe path: absolute paths are not allowed")
+	}
+	for _, part := range strings.Split(normalized, "/") {
+		if part == ".." {
+			return "", errors.New("unsafe path: parent directory references are not allowed")
+		}
+	}
+
+	base := path.Base(normalized)
+	if base == "." || base == "/" || base == "" {
+		return "", errors.New("entry has no file name")
+	}
+	return base, nil
+}
+
+// isArchiveMetadata reports whether an entry is operating system metadata such as
+// macOS resource forks or .DS_Store files
+func isArchiveMetadata(name string) bool {
+	name = strings.ReplaceAll(name, `\`, "/")
+	base := path.Base(name)
+	return strings.HasPrefix(name, "__MACOSX/") || strings.HasPrefix(base, "._") ||
+		base == ".DS_Store" || base == "Thumbs.db" || base == "desktop.ini"
+}
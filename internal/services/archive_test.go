@@ -0,0 +1,223 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/flate"
+	"errors"
+	"hash/crc32"
+	"strings"
+	"testing"
+
+	"portfolio-be/internal/config"
+	"portfolio-be/internal/models"
+)
+
+// testArchiveEntry is a file written to a test archive. A non-zero declaredSize is
+// written as the entry's uncompressed size in place of the real one.
+type testArchiveEntry struct {
+	name         string
+	data         []byte
+	declaredSize uint64
+}
+
+// buildTestArchive writes a deflated ZIP archive of entries
+func buildTestArchive(t *testing.T, entries ...testArchiveEntry) *bytes.Reader {
+	t.Helper()
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for _, entry := range entries {
+		if entry.declaredSize == 0 {
+			f, err := w.Create(entry.name)
+			if err != nil {
+				t.Fatalf("failed to create entry: %v", err)
+			}
+			f.Write(entry.data)
+			continue
+		}
+
+		var compressed bytes.Buffer
+		fw, _ := flate.NewWriter(&compressed, flate.DefaultCompression)
+		fw.Write(entry.data)
+		fw.Close()
+		f, err := w.CreateRaw(&zip.FileHeader{
+			Name:               entry.name,
+			Method:             zip.Deflate,
+			CRC32:              crc32.ChecksumIEEE(entry.data),
+			CompressedSize64:   uint64(compressed.Len()),
+			UncompressedSize64: entry.declaredSize,
+		})
+		if err != nil {
+			t.Fatalf("failed to create entry: %v", err)
+		}
+		f.Write(compressed.Bytes())
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("failed to write archive: %v", err)
+	}
+	return bytes.NewReader(buf.Bytes())
+}
+
+// newTestArchiveService returns an archive service without an upload service, for
+// archives whose entries are all rejected before they are stored
+func newTestArchiveService() *ArchiveService {
+	return NewArchiveService(nil, nil, config.ArchiveConfig{
+		MaxArchiveSize:      10 << 20,
+		MaxEntries:          5,
+		MaxUncompressedSize: 4 << 20,
+		MaxCompressionRatio: 100,
+	})
+}
+
+func TestArchiveEntryName(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    string
+		wantErr bool
+	}{
+		{"photo.jpg", "photo.jpg", false},
+		{"gallery/2023/photo.jpg", "photo.jpg", false},
+		{`gallery\photo.jpg`, "photo.jpg", false},
+		{"../x", "", true},
+		{"gallery/../../x", "", true},
+		{`..\x`, "", true},
+		{`gallery\..\..\x`, "", true},
+		{"/abs", "", true},
+		{"/etc/passwd", "", true},
+		{`\abs`, "", true},
+		{`C:\x`, "", true},
+		{"C:/x", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := archiveEntryName(tt.name)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected %q to be rejected, got %q", tt.name, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected %q to be accepted, got %v", tt.name, err)
+			}
+			if got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestIsArchiveMetadata(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"__MACOSX/gallery/._photo.jpg", true},
+		{"__MACOSX/photo.jpg", true},
+		{"gallery/._photo.jpg", true},
+		{"._photo.jpg", true},
+		{`gallery\._photo.jpg`, true},
+		{"gallery/.DS_Store", true},
+		{"Thumbs.db", true},
+		{"gallery/photo.jpg", false},
+		{"gallery/__MACOSX.jpg", false},
+		{"gallery/_photo.jpg", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isArchiveMetadata(tt.name); got != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestImportArchiveRejectsEntries(t *testing.T) {
+	zeros := make([]byte, 1<<20)
+	archive := buildTestArchive(t,
+		testArchiveEntry{name: "../escape.jpg", data: []byte("x")},
+		testArchiveEntry{name: `C:\escape.jpg`, data: []byte("x")},
+		testArchiveEntry{name: "__MACOSX/._photo.jpg", data: []byte("x")},
+		testArchiveEntry{name: "bomb.jpg", data: zeros},
+		testArchiveEntry{name: "liar.jpg", data: bytes.Repeat([]byte("0123456789"), 100), declaredSize: 10},
+	)
+
+	report, err := newTestArchiveService().ImportArchive(archive, archive.Size(), ArchiveImportOptions{}, 1)
+	if err != nil {
+		t.Fatalf("failed to import archive: %v", err)
+	}
+
+	want := map[string]string{
+		"../escape.jpg":        models.ArchiveEntryFailed,
+		`C:\escape.jpg`:        models.ArchiveEntryFailed,
+		"__MACOSX/._photo.jpg": models.ArchiveEntrySkipped,
+		"bomb.jpg":             models.ArchiveEntryFailed,
+		"liar.jpg":             models.ArchiveEntryFailed,
+	}
+	wantErrors := map[string]string{
+		"../escape.jpg": "parent directory",
+		`C:\escape.jpg`: "absolute path",
+		"bomb.jpg":      "compression ratio",
+		"liar.jpg":      "failed to read entry",
+	}
+	if len(report.Results) != len(want) {
+		t.Fatalf("expected %d results, got %d", len(want), len(report.Results))
+	}
+	for _, result := range report.Results {
+		if result.Status != want[result.Name] {
+			t.Errorf("%s: expected %s, got %s (%s)", result.Name, want[result.Name], result.Status, result.Error)
+		}
+		if reason, ok := wantErrors[result.Name]; ok && !strings.Contains(result.Error, reason) {
+			t.Errorf("%s: expected an error about %q, got %q", result.Name, reason, result.Error)
+		}
+		if result.Upload != nil {
+			t.Errorf("%s: expected nothing to be stored", result.Name)
+		}
+	}
+	if report.Skipped != 1 || report.Failed != 4 || report.Uploaded != 0 {
+		t.Errorf("expected 1 skipped and 4 failed, got %+v", report)
+	}
+}
+
+func TestReadArchiveEntryRefusesUndeclaredData(t *testing.T) {
+	archive := buildTestArchive(t, testArchiveEntry{name: "liar.jpg", data: bytes.Repeat([]byte("a"), 4096), declaredSize: 16})
+	reader, err := zip.NewReader(archive, archive.Size())
+	if err != nil {
+		t.Fatalf("failed to open archive: %v", err)
+	}
+
+	data, err := readArchiveEntry(reader.File[0])
+	if err == nil {
+		t.Fatalf("expected an error, read %d bytes", len(data))
+	}
+}
+
+func TestImportArchiveLimits(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []testArchiveEntry
+	}{
+		{
+			name: "too many entries",
+			entries: []testArchiveEntry{
+				{name: "1.jpg"}, {name: "2.jpg"}, {name: "3.jpg"}, {name: "4.jpg"}, {name: "5.jpg"}, {name: "6.jpg"},
+			},
+		},
+		{
+			name: "declared sizes over the uncompressed limit",
+			entries: []testArchiveEntry{
+				{name: "1.jpg", data: []byte("x"), declaredSize: 3 << 20},
+				{name: "2.jpg", data: []byte("x"), declaredSize: 3 << 20},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			archive := buildTestArchive(t, tt.entries...)
+			_, err := newTestArchiveService().ImportArchive(archive, archive.Size(), ArchiveImportOptions{}, 1)
+			if !errors.Is(err, ErrArchiveTooLarge) {
+				t.Fatalf("expected ErrArchiveTooLarge, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,315 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"portfolio-be/internal/models"
+	"portfolio-be/internal/repository"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrCollectionNotFound is returned when a collection or collection item does not exist
+	ErrCollectionNotFound = errors.New("collection not found")
+	// ErrCollectionProjectNotFound is returned when attaching a collection to a missing project
+	ErrCollectionProjectNotFound = errors.New("project not found")
+	// ErrCollectionItemExists is returned when adding a resource that is already in the collection
+	ErrCollectionItemExists = errors.New("resource is already in the collection")
+	// ErrInvalidReorder is returned when a reorder request does not list every item of the collection exactly once
+	ErrInvalidReorder = errors.New("item_ids must list every item of the collection exactly once")
+)
+
+// CollectionService manages ordered resource collections and project galleries
+type CollectionService struct {
+	repo            *repository.CollectionRepository
+	resourceRepo    *repository.ResourceRepository
+	projectRepo     repository.ProjectRepository
+	resourceService *ResourceService
+}
+
+func NewCollectionService(repo *repository.CollectionRepository, resourceRepo *repository.ResourceRepository, projectRepo repository.ProjectRepository, resourceService *ResourceService) *CollectionService {
+	return &CollectionService{
+		repo:            repo,
+		resourceRepo:    resourceRepo,
+		projectRepo:     projectRepo,
+		resourceService: resourceService,
+	}
+}
+
+func (s *CollectionService) CreateCollection(req *models.CollectionRequest) (*models.CollectionResponse, error) {
+	if err := s.validateProject(req.ProjectID); err != nil {
+		return nil, err
+	}
+
+	collection := &models.Collection{
+		Name:        req.Name,
+		Description: req.Description,
+		ProjectID:   req.ProjectID,
+		IsActive:    true,
+	}
+	if req.IsActive != nil {
+		collection.IsActive = *req.IsActive
+	}
+
+	if err := s.repo.Create(collection); err != nil {
+		return nil, fmt.Errorf("failed to create collection: %w", err)
+	}
+
+	response := s.toResponse(collection, false)
+	return &response, nil
+}
+
+func (s *CollectionService) GetCollectionByID(id uint) (*models.CollectionResponse, error) {
+	collection, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, ErrCollectionNotFound
+	}
+
+	response := s.toResponse(collection, false)
+	return &response, nil
+}
+
+func (s *CollectionService) GetAllCollections() ([]models.CollectionResponse, error) {
+	collections, err := s.repo.GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get collections: %w", err)
+	}
+
+	responses := make([]models.CollectionResponse, len(collections))
+	for i := range collections {
+		responses[i] = s.toResponse(&collections[i], false)
+	}
+
+	return responses, nil
+}
+
+func (s *CollectionService) UpdateCollection(id uint, req *models.CollectionRequest) (*models.CollectionResponse, error) {
+	collection, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, ErrCollectionNotFound
+	}
+	if err := s.validateProject(req.ProjectID); err != nil {
+		return nil, err
+	}
+
+	collection.Name = req.Name
+	collection.Description = req.Description
+	collection.ProjectID = req.ProjectID
+	if req.IsActive != nil {
+		collection.IsActive = *req.IsActive
+	}
+
+	if err := s.repo.Update(collection); err != nil {
+		return nil, fmt.Errorf("failed to update collection: %w", err)
+	}
+
+	response := s.toResponse(collection, false)
+	return &response, nil
+}
+
+func (s *CollectionService) DeleteCollection(id uint) error {
+	if _, err := s.repo.GetByID(id); err != nil {
+		return ErrCollectionNotFound
+	}
+
+	if err := s.repo.Delete(id); err != nil {
+		return fmt.Errorf("failed to delete collection: %w", err)
+	}
+	return nil
+}
+
+// AddItem adds a resource to a collection, appending it unless a position is given.
+// Positions past the last item append too.
+func (s *CollectionService) AddItem(collectionID uint, req *models.CollectionItemRequest) (*models.CollectionResponse, error) {
+	if _, err := s.resourceRepo.GetByID(req.ResourceID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrResourceNotFound
+		}
+		return nil, fmt.Errorf("failed to get resource: %w", err)
+	}
+
+	item := &models.CollectionItem{
+		CollectionID: collectionID,
+		ResourceID:   req.ResourceID,
+		Caption:      req.Caption,
+	}
+
+	// Concurrent adds must not both pass the membership check or take the same position
+	err := s.repo.Transaction(func(repo *repository.CollectionRepository) error {
+		found, err := repo.Exists(collectionID)
+		if err != nil {
+			return fmt.Errorf("failed to get collection: %w", err)
+		}
+		if !found {
+			return ErrCollectionNotFound
+		}
+
+		exists, err := repo.HasResource(collectionID, req.ResourceID)
+		if err != nil {
+			return fmt.Errorf("failed to check collection membership: %w", err)
+		}
+		if exists {
+			return ErrCollectionItemExists
+		}
+
+		// Removed items leave gaps, so the end is the position after the last item
+		// rather than the number of items
+		next, err := repo.NextPosition(collectionID)
+		if err != nil {
+			return fmt.Errorf("failed to get next position: %w", err)
+		}
+		item.Position = next
+		if req.Position != nil && *req.Position >= 0 && *req.Position < next {
+			if err := repo.ShiftPositions(collectionID, *req.Position); err != nil {
+				return fmt.Errorf("failed to make room for collection item: %w", err)
+			}
+			item.Position = *req.Position
+		}
+
+		if err := repo.CreateItem(item); err != nil {
+			return fmt.Errorf("failed to add collection item: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetCollectionByID(collectionID)
+}
+
+// UpdateItem updates the caption of a collection item
+func (s *CollectionService) UpdateItem(collectionID, itemID uint, req *models.CollectionItemUpdateRequest) (*models.CollectionResponse, error) {
+	item, err := s.repo.GetItem(collectionID, itemID)
+	if err != nil {
+		return nil, ErrCollectionNotFound
+	}
+
+	if req.Caption != nil {
+		item.Caption = *req.Caption
+		item.UpdatedAt = time.Now()
+		if err := s.repo.UpdateItem(item); err != nil {
+			return nil, fmt.Errorf("failed to update collection item: %w", err)
+		}
+	}
+
+	return s.GetCollectionByID(collectionID)
+}
+
+// RemoveItem removes a resource from a collection; the resource itself is kept
+func (s *CollectionService) RemoveItem(collectionID, itemID uint) error {
+	if _, err := s.repo.GetItem(collectionID, itemID); err != nil {
+		return ErrCollectionNotFound
+	}
+
+	if err := s.repo.DeleteItem(collectionID, itemID); err != nil {
+		return fmt.Errorf("failed to remove collection item: %w", err)
+	}
+	return nil
+}
+
+// ReorderItems sets the order of a collection's items. itemIDs must list every item exactly once.
+func (s *CollectionService) ReorderItems(collectionID uint, itemIDs []uint) (*models.CollectionResponse, error) {
+	collection, err := s.repo.GetByID(collectionID)
+	if err != nil {
+		return nil, ErrCollectionNotFound
+	}
+
+	// Items whose resource was deleted are hidden from responses, so they are not expected here
+	members := make(map[uint]bool, len(collection.Items))
+	for _, item := range collection.Items {
+		if item.Resource.ID != 0 {
+			members[item.ID] = true
+		}
+	}
+	if len(itemIDs) != len(members) {
+		return nil, ErrInvalidReorder
+	}
+	for _, id := range itemIDs {
+		if !members[id] {
+			return nil, ErrInvalidReorder
+		}
+		delete(members, id)
+	}
+
+	if err := s.repo.Reorder(collectionID, itemIDs); err != nil {
+		return nil, fmt.Errorf("failed to reorder collection: %w", err)
+	}
+
+	return s.GetCollectionByID(collectionID)
+}
+
+// AttachGalleries fills in the active collections of each project, keeping only
+// public, active resources
+func (s *CollectionService) AttachGalleries(projects []models.ProjectResponse) error {
+	if len(projects) == 0 {
+		return nil
+	}
+
+	ids := make([]uint, len(projects))
+	for i, project := range projects {
+		ids[i] = project.ID
+	}
+
+	collections, err := s.repo.GetActiveByProjectIDs(ids)
+	if err != nil {
+		return fmt.Errorf("failed to get project galleries: %w", err)
+	}
+
+	galleries := make(map[uint][]models.CollectionResponse)
+	for i := range collections {
+		projectID := *collections[i].ProjectID
+		galleries[projectID] = append(galleries[projectID], s.toResponse(&collections[i], true))
+	}
+
+	for i := range projects {
+		projects[i].Galleries = galleries[projects[i].ID]
+	}
+
+	return nil
+}
+
+// validateProject checks that the project a collection is attached to exists
+func (s *CollectionService) validateProject(projectID *uint) error {
+	if projectID == nil {
+		return nil
+	}
+	if _, err := s.projectRepo.GetByID(*projectID); err != nil {
+		return fmt.Errorf("%w: %d", ErrCollectionProjectNotFound, *projectID)
+	}
+	return nil
+}
+
+// toResponse converts a collection to its response, skipping items whose resource was
+// deleted and, when publicOnly is set, private or inactive resources
+func (s *CollectionService) toResponse(collection *models.Collection, publicOnly bool) models.CollectionResponse {
+	items := []models.CollectionItemResponse{}
+	for i := range collection.Items {
+		item := &collection.Items[i]
+		if item.Resource.ID == 0 {
+			continue
+		}
+		if publicOnly && (!item.Resource.IsPublic || !item.Resource.IsActive) {
+			continue
+		}
+		items = append(items, models.CollectionItemResponse{
+			ID:       item.ID,
+			Position: item.Position,
+			Caption:  item.Caption,
+			Resource: s.resourceService.toResponse(&item.Resource),
+		})
+	}
+
+	return models.CollectionResponse{
+		ID:          collection.ID,
+		Name:        collection.Name,
+		Description: collection.Description,
+		ProjectID:   collection.ProjectID,
+		IsActive:    collection.IsActive,
+		Items:       items,
+		CreatedAt:   collection.CreatedAt,
+		UpdatedAt:   collection.UpdatedAt,
+	}
+}
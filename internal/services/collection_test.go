@@ -0,0 +1,77 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"portfolio-be/internal/models"
+	"portfolio-be/internal/repository"
+	"portfolio-be/internal/testutil"
+)
+
+func TestAddCollectionItemPositions(t *testing.T) {
+	db := testutil.OpenDB(t, filepath.Join(t.TempDir(), "collections.db"))
+	uploadRepo := repository.NewUploadRepository(db)
+	resourceRepo := repository.NewResourceRepository(db)
+	s3Service, _ := newTestS3Service(t)
+	resources := NewResourceService(resourceRepo, uploadRepo, s3Service, nil, nil, nil, nil)
+	service := NewCollectionService(repository.NewCollectionRepository(db), resourceRepo, repository.NewProjectRepository(db), resources)
+
+	createResource := func(name string) uint {
+		t.Helper()
+		upload := &models.Upload{FileName: name + ".jpg", OriginalName: name + ".jpg", S3Key: "uploads/" + name + ".jpg", IsActive: true}
+		if err := uploadRepo.Create(upload); err != nil {
+			t.Fatalf("failed to create upload: %v", err)
+		}
+		resource := &models.Resource{Name: name, Type: models.ResourceTypeImage, UploadID: upload.ID}
+		if err := resourceRepo.Create(resource); err != nil {
+			t.Fatalf("failed to create resource: %v", err)
+		}
+		return resource.ID
+	}
+	order := func(collection *models.CollectionResponse) string {
+		names := ""
+		for _, item := range collection.Items {
+			names += fmt.Sprintf("%s@%d ", item.Resource.Name, item.Position)
+		}
+		return names
+	}
+	position := func(p int) *int { return &p }
+
+	collection, err := service.CreateCollection(&models.CollectionRequest{Name: "Gallery"})
+	if err != nil {
+		t.Fatalf("failed to create collection: %v", err)
+	}
+
+	steps := []struct {
+		name     string
+		position *int
+		want     string
+	}{
+		{"first", nil, "first@0 "},
+		{"second", nil, "first@0 second@1 "},
+		{"front", position(0), "front@0 first@1 second@2 "},
+		{"past the end", position(99), "front@0 first@1 second@2 past the end@3 "},
+		{"middle", position(2), "front@0 first@1 middle@2 second@3 past the end@4 "},
+	}
+	for _, step := range steps {
+		response, err := service.AddItem(collection.ID, &models.CollectionItemRequest{ResourceID: createResource(step.name), Position: step.position})
+		if err != nil {
+			t.Fatalf("failed to add %s: %v", step.name, err)
+		}
+		if got := order(response); got != step.want {
+			t.Fatalf("after adding %s expected %q, got %q", step.name, step.want, got)
+		}
+	}
+
+	// A missing collection is reported as such, and a resource is only added once
+	if _, err := service.AddItem(collection.ID+1, &models.CollectionItemRequest{ResourceID: createResource("orphan")}); !errors.Is(err, ErrCollectionNotFound) {
+		t.Errorf("expected ErrCollectionNotFound, got %v", err)
+	}
+	response, _ := service.GetCollectionByID(collection.ID)
+	if _, err := service.AddItem(collection.ID, &models.CollectionItemRequest{ResourceID: response.Items[0].Resource.ID}); !errors.Is(err, ErrCollectionItemExists) {
+		t.Errorf("expected ErrCollectionItemExists, got %v", err)
+	}
+}
@@ -1,80 +1,370 @@
 package services
 
 import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"portfolio-be/internal/config"
 	"portfolio-be/internal/models"
 	"portfolio-be/internal/repository"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrContactNotFound is returned when a contact message does not exist
+	ErrContactNotFound = errors.New("contact not found")
+	// ErrInvalidFormToken is returned when a submission lacks a valid form token
+	ErrInvalidFormToken = errors.New("invalid or expired form token")
+	// ErrContactRateLimited is returned when an IP or email address sent too many messages
+	ErrContactRateLimited = errors.New("too many messages, please try again later")
+	// ErrInvalidContactSort is returned when listing contacts by an unsupported sort field
+	ErrInvalidContactSort = errors.New("sort must be created_at or status")
+	// ErrInvalidContactStatus is returned for a status other than unread, read, replied or spam
+	ErrInvalidContactStatus = errors.New("status must be unread, read, replied or spam")
+	// ErrContactLabelNotFound is returned when a contact label does not exist
+	ErrContactLabelNotFound = errors.New("contact label not found")
+	// ErrContactLabelExists is returned when a contact label name is already taken
+	ErrContactLabelExists = errors.New("a contact label with this name already exists")
+	// ErrInvalidLabelColor is returned when a label color is not a hex color
+	ErrInvalidLabelColor = errors.New("color must be a hex color such as #3b82f6")
+	// ErrContactLabelRequired is returned when a label bulk action has no label_id
+	ErrContactLabelRequired = errors.New("label_id is required to label or unlabel contacts")
+	// ErrAssigneeNotFound is returned when assigning a contact to a missing user
+	ErrAssigneeNotFound = errors.New("assignee not found")
 )
 
+// labelColorPattern matches #rgb and #rrggbb colors
+var labelColorPattern = regexp.MustCompile(`^#([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
 type ContactService struct {
 	contactRepo *repository.ContactRepository
+	replyRepo   *repository.ContactReplyRepository
+	labelRepo   *repository.ContactLabelRepository
+	userRepo    *repository.UserRepository
+	cfg         config.ContactConfig
+	scorer      SpamScorer
+	captcha     CaptchaVerifier
+	mail        *MailService
 }
 
-func NewContactService(contactRepo *repository.ContactRepository) *ContactService {
+func NewContactService(contactRepo *repository.ContactRepository, replyRepo *repository.ContactReplyRepository, labelRepo *repository.ContactLabelRepository, userRepo *repository.UserRepository, cfg config.ContactConfig, scorer SpamScorer, captcha CaptchaVerifier, mail *MailService) *ContactService {
 	return &ContactService{
 		contactRepo: contactRepo,
+		replyRepo:   replyRepo,
+		labelRepo:   labelRepo,
+		userRepo:    userRepo,
+		cfg:         cfg,
+		scorer:      scorer,
+		captcha:     captcha,
+		mail:        mail,
 	}
 }
 
-func (s *ContactService) CreateContact(req *models.ContactRequest) (*models.Contact, error) {
-	contact := &models.Contact{
+// IssueFormToken returns a signed token recording when the contact form was shown
+func (s *ContactService) IssueFormToken() *models.ContactFormToken {
+	issued := time.Now().Truncate(time.Millisecond)
+	timestamp := strconv.FormatInt(issued.UnixMilli(), 10)
+	return &models.ContactFormToken{
+		Token:           timestamp + "." + s.signFormToken(timestamp),
+		IssuedAt:        issued,
+		ExpiresAt:       issued.Add(s.cfg.FormTokenTTL),
+		MinFillSeconds:  int(s.cfg.MinFillTime / time.Second),
+		CaptchaProvider: s.captcha.Provider(),
+	}
+}
+
+// signFormToken returns the hex HMAC of a form token timestamp
+func (s *ContactService) signFormToken(timestamp string) string {
+	mac := hmac.New(sha256.New, []byte(s.cfg.FormSecret))
+	mac.Write([]byte("contact-form:" + timestamp))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// formFillTime checks a form token and returns how long ago the form was shown
+func (s *ContactService) formFillTime(token string) (time.Duration, error) {
+	timestamp, signature, ok := strings.Cut(token, ".")
+	if !ok || !hmac.Equal([]byte(signature), []byte(s.signFormToken(timestamp))) {
+		return 0, ErrInvalidFormToken
+	}
+	millis, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return 0, ErrInvalidFormToken
+	}
+
+	elapsed := time.Since(time.UnixMilli(millis))
+	if elapsed < 0 || elapsed > s.cfg.FormTokenTTL {
+		return 0, ErrInvalidFormToken
+	}
+	return elapsed, nil
+}
+
+// checkRateLimits refuses senders that sent too many messages within the window
+func (s *ContactService) checkRateLimits(email, clientIP string) error {
+	since := time.Now().Add(-s.cfg.RateLimitWindow)
+
+	if clientIP != "" {
+		count, err := s.contactRepo.CountByIPSince(clientIP, since)
+		if err != nil {
+			return fmt.Errorf("failed to count messages by IP: %w", err)
+		}
+		if count >= int64(s.cfg.RateLimitPerIP) {
+			return ErrContactRateLimited
+		}
+	}
+
+	count, err := s.contactRepo.CountByEmailSince(email, since)
+	if err != nil {
+		return fmt.Errorf("failed to count messages by email: %w", err)
+	}
+	if count >= int64(s.cfg.RateLimitPerEmail) {
+		return ErrContactRateLimited
+	}
+	return nil
+}
+
+// CreateContact stores a contact form submission. Submissions without a valid form
+// token, over the rate limits or failing the CAPTCHA are refused. Submissions that
+// fill in the honeypot, come in faster than a person could type or score as spam are
+// stored with the spam status, so false positives can be reviewed. Other messages
+// notify the configured admins and, when enabled, are acknowledged to the sender.
+func (s *ContactService) CreateContact(ctx context.Context, req *models.ContactRequest, clientIP string) (*models.Contact, error) {
+	filled, err := s.formFillTime(req.FormToken)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkRateLimits(req.Email, clientIP); err != nil {
+		return nil, err
+	}
+
+	verdict := &SpamVerdict{}
+	if err := s.captcha.Verify(ctx, req.CaptchaToken, clientIP); err != nil {
+		if errors.Is(err, ErrCaptchaFailed) {
+			return nil, err
+		}
+		// Keep the message for review rather than lose it while the provider is down
+		log.Printf("Failed to verify contact form CAPTCHA: %v", err)
+		verdict.add(s.cfg.SpamThreshold, "captcha unverified")
+	}
+	if req.Website != "" {
+		verdict.add(s.cfg.SpamThreshold, "honeypot filled")
+	}
+	if filled < s.cfg.MinFillTime {
+		verdict.add(s.cfg.SpamThreshold, fmt.Sprintf("filled in %s", filled.Round(time.Millisecond)))
+	}
+
+	scored, err := s.scorer.Score(ctx, &ContactSubmission{
 		Name:     req.Name,
 		Email:    req.Email,
 		Subject:  req.Subject,
 		Message:  req.Message,
-		Status:   "unread",
-		IsActive: true,
+		ClientIP: clientIP,
+	})
+	if err != nil {
+		log.Printf("Failed to score contact message: %v", err)
+	} else {
+		verdict.Score += scored.Score
+		verdict.Reasons = append(verdict.Reasons, scored.Reasons...)
+	}
+
+	status := models.ContactStatusUnread
+	if verdict.Score >= s.cfg.SpamThreshold {
+		status = models.ContactStatusSpam
+		log.Printf("Contact message from %s flagged as spam (score %d: %s)", clientIP, verdict.Score, strings.Join(verdict.Reasons, ", "))
+	}
+
+	contact := &models.Contact{
+		Name:        req.Name,
+		Email:       req.Email,
+		Subject:     req.Subject,
+		Message:     req.Message,
+		Status:      status,
+		IsActive:    true,
+		IPAddress:   clientIP,
+		SpamScore:   verdict.Score,
+		SpamReasons: strings.Join(verdict.Reasons, ", "),
 	}
 
 	if err := s.contactRepo.Create(contact); err != nil {
 		return nil, err
 	}
 
+	if status != models.ContactStatusSpam {
+		s.notify(contact)
+	}
+
 	return contact, nil
 }
 
-func (s *ContactService) GetAllContacts(page, limit int) ([]models.ContactResponse, int64, error) {
-	contacts, total, err := s.contactRepo.GetAll(page, limit)
+// notify queues the admin notification and the acknowledgement of a new message.
+// The message is already stored, so failures are logged rather than returned.
+func (s *ContactService) notify(contact *models.Contact) {
+	data := ContactMailData{
+		SiteName: s.mail.SiteName(),
+		Contact:  contact,
+		Link:     s.mail.AdminLink(fmt.Sprintf("/contacts/%d", contact.ID)),
+	}
+
+	if len(s.cfg.NotifyEmails) > 0 {
+		email, err := s.mail.Render(MailContactNotification, s.cfg.NotifyEmails, data)
+		if err == nil {
+			// Replying to the notification answers the sender
+			email.ReplyTo = contact.Email
+			email.Ref = contactRef(contact.ID)
+			_, err = s.mail.Queue(email)
+		}
+		if err != nil {
+			log.Printf("Failed to notify admins of contact %d: %v", contact.ID, err)
+		}
+	}
+
+	if s.cfg.AutoReply && s.shouldAcknowledge(contact) {
+		email, err := s.mail.Render(MailContactAcknowledgement, []string{contact.Email}, ContactMailData{SiteName: data.SiteName})
+		if err == nil {
+			email.Ref = contactRef(contact.ID)
+			_, err = s.mail.Queue(email)
+		}
+		if err != nil {
+			log.Printf("Failed to acknowledge contact %d: %v", contact.ID, err)
+		}
+	}
+}
+
+// shouldAcknowledge reports whether a message is far enough from the spam threshold to
+// be acknowledged. Messages scoring half the threshold or more are not, so that forms
+// filled in by bots to mail a third party do not get through on a near miss.
+func (s *ContactService) shouldAcknowledge(contact *models.Contact) bool {
+	return contact.SpamScore*2 < s.cfg.SpamThreshold
+}
+
+// contactRef names a contact on the tasks and email copies about it, so that they are
+// erased with it
+func contactRef(id uint) string {
+	return fmt.Sprintf("contact-%d", id)
+}
+
+// contactRefs returns the refs of the given contacts
+func contactRefs(ids []uint) []string {
+	refs := make([]string, len(ids))
+	for i, id := range ids {
+		refs[i] = contactRef(id)
+	}
+	return refs
+}
+
+// toContactResponse converts a contact to its API response
+func toContactResponse(contact *models.Contact) models.ContactResponse {
+	response := models.ContactResponse{
+		ID:           contact.ID,
+		Name:         contact.Name,
+		Email:        contact.Email,
+		Subject:      contact.Subject,
+		Message:      contact.Message,
+		Status:       contact.Status,
+		IsActive:     contact.IsActive,
+		SpamScore:    contact.SpamScore,
+		AssigneeID:   contact.AssigneeID,
+		ArchivedAt:   contact.ArchivedAt,
+		AnonymizedAt: contact.AnonymizedAt,
+		Labels:       contact.Labels,
+		CreatedAt:    contact.CreatedAt,
+		UpdatedAt:    contact.UpdatedAt,
+	}
+	if response.Labels == nil {
+		response.Labels = []models.ContactLabel{}
+	}
+	if contact.SpamReasons != "" {
+		response.SpamReasons = strings.Split(contact.SpamReasons, ", ")
+	}
+	return response
+}
+
+// ListContacts returns the contacts matching a filter and their total, with when the
+// requesting user read each of them
+func (s *ContactService) ListContacts(filter models.ContactFilter, userID uint, limit, offset int) ([]models.ContactResponse, int64, error) {
+	if filter.SortBy != "" && !repository.IsValidContactSort(filter.SortBy) {
+		return nil, 0, ErrInvalidContactSort
+	}
+	if filter.Status != "" && !isContactStatus(filter.Status) {
+		return nil, 0, ErrInvalidContactStatus
+	}
+
+	total, err := s.contactRepo.CountFiltered(filter)
 	if err != nil {
-		return nil, 0, err
+		return nil, 0, fmt.Errorf("failed to count contacts: %w", err)
+	}
+	contacts, err := s.contactRepo.List(filter, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get contacts: %w", err)
 	}
 
-	var responses []models.ContactResponse
-	for _, contact := range contacts {
-		responses = append(responses, models.ContactResponse{
-			ID:        contact.ID,
-			Name:      contact.Name,
-			Email:     contact.Email,
-			Subject:   contact.Subject,
-			Message:   contact.Message,
-			Status:    contact.Status,
-			IsActive:  contact.IsActive,
-			CreatedAt: contact.CreatedAt,
-			UpdatedAt: contact.UpdatedAt,
-		})
+	ids := make([]uint, len(contacts))
+	for i := range contacts {
+		ids[i] = contacts[i].ID
+	}
+	readTimes, err := s.contactRepo.ReadTimes(ids, userID)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get read times: %w", err)
 	}
 
+	responses := make([]models.ContactResponse, 0, len(contacts))
+	for i := range contacts {
+		response := toContactResponse(&contacts[i])
+		if readAt, ok := readTimes[contacts[i].ID]; ok {
+			response.ReadAt = &readAt
+		}
+		responses = append(responses, response)
+	}
 	return responses, total, nil
 }
 
-func (s *ContactService) GetContactByID(id uint) (*models.ContactResponse, error) {
+// isContactStatus reports whether status is a contact status
+func isContactStatus(status string) bool {
+	switch status {
+	case models.ContactStatusUnread, models.ContactStatusRead, models.ContactStatusReplied, models.ContactStatusSpam:
+		return true
+	}
+	return false
+}
+
+// GetContactByID returns a contact with who read it and its thread of replies. It
+// changes nothing; reading is recorded with MarkAsRead.
+func (s *ContactService) GetContactByID(id, userID uint) (*models.ContactResponse, error) {
 	contact, err := s.contactRepo.GetByID(id)
 	if err != nil {
-		return nil, err
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrContactNotFound
+		}
+		return nil, fmt.Errorf("failed to get contact: %w", err)
 	}
 
-	response := &models.ContactResponse{
-		ID:        contact.ID,
-		Name:      contact.Name,
-		Email:     contact.Email,
-		Subject:   contact.Subject,
-		Message:   contact.Message,
-		Status:    contact.Status,
-		IsActive:  contact.IsActive,
-		CreatedAt: contact.CreatedAt,
-		UpdatedAt: contact.UpdatedAt,
+	reads, err := s.contactRepo.GetReads(contact.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get read receipts: %w", err)
+	}
+	replies, err := s.replyRepo.GetByContact(contact.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get replies: %w", err)
 	}
 
-	return response, nil
+	response := toContactResponse(contact)
+	response.ReadBy = reads
+	for _, read := range reads {
+		if read.UserID == userID {
+			readAt := read.ReadAt
+			response.ReadAt = &readAt
+		}
+	}
+	response.Replies = replies
+	return &response, nil
 }
 
 func (s *ContactService) UpdateContact(id uint, req *models.ContactUpdateRequest) (*models.ContactResponse, error) {
@@ -107,57 +397,203 @@ func (s *ContactService) UpdateContact(id uint, req *models.ContactUpdateRequest
 		return nil, err
 	}
 
-	response := &models.ContactResponse{
-		ID:        contact.ID,
-		Name:      contact.Name,
-		Email:     contact.Email,
-		Subject:   contact.Subject,
-		Message:   contact.Message,
-		Status:    contact.Status,
-		IsActive:  contact.IsActive,
-		CreatedAt: contact.CreatedAt,
-		UpdatedAt: contact.UpdatedAt,
-	}
-
-	return response, nil
+	response := toContactResponse(contact)
+	return &response, nil
 }
 
 func (s *ContactService) DeleteContact(id uint) error {
 	return s.contactRepo.Delete(id)
 }
 
-func (s *ContactService) GetContactsByStatus(status string, page, limit int) ([]models.ContactResponse, int64, error) {
-	contacts, total, err := s.contactRepo.GetByStatus(status, page, limit)
-	if err != nil {
-		return nil, 0, err
+// MarkAsRead records that a user read a contact and moves it from unread to read
+func (s *ContactService) MarkAsRead(id, userID uint) error {
+	if err := s.checkExists(id); err != nil {
+		return err
 	}
-
-	var responses []models.ContactResponse
-	for _, contact := range contacts {
-		responses = append(responses, models.ContactResponse{
-			ID:        contact.ID,
-			Name:      contact.Name,
-			Email:     contact.Email,
-			Subject:   contact.Subject,
-			Message:   contact.Message,
-			Status:    contact.Status,
-			IsActive:  contact.IsActive,
-			CreatedAt: contact.CreatedAt,
-			UpdatedAt: contact.UpdatedAt,
-		})
+	if _, err := s.contactRepo.MarkRead([]uint{id}, userID, time.Now()); err != nil {
+		return fmt.Errorf("failed to mark contact read: %w", err)
 	}
+	return nil
+}
 
-	return responses, total, nil
+// MarkAsUnread forgets that a user read a contact and moves it from read back to unread
+// unless another user has read it too
+func (s *ContactService) MarkAsUnread(id, userID uint) error {
+	if err := s.checkExists(id); err != nil {
+		return err
+	}
+	if _, err := s.contactRepo.MarkUnread([]uint{id}, userID); err != nil {
+		return fmt.Errorf("failed to mark contact unread: %w", err)
+	}
+	return nil
 }
 
-func (s *ContactService) MarkAsRead(id uint) error {
-	return s.contactRepo.MarkAsRead(id)
+// checkExists returns ErrContactNotFound when a contact does not exist
+func (s *ContactService) checkExists(id uint) error {
+	if _, err := s.contactRepo.GetByID(id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrContactNotFound
+		}
+		return fmt.Errorf("failed to get contact: %w", err)
+	}
+	return nil
 }
 
+// GetUnreadCount counts the inbox messages nobody has read
 func (s *ContactService) GetUnreadCount() (int64, error) {
 	return s.contactRepo.GetUnreadCount()
 }
 
+// GetUnreadCountForUser counts the inbox messages a user has not read, whatever other
+// admins did with them
+func (s *ContactService) GetUnreadCountForUser(userID uint) (int64, error) {
+	return s.contactRepo.GetUnreadCountForUser(userID)
+}
+
 func (s *ContactService) GetContactsCount() (int64, error) {
 	return s.contactRepo.GetCount()
 }
+
+// AssignContact assigns a contact to a user, or unassigns it when assigneeID is nil, and
+// returns it as the requesting user sees it
+func (s *ContactService) AssignContact(id uint, assigneeID *uint, userID uint) (*models.ContactResponse, error) {
+	if assigneeID != nil {
+		if _, err := s.userRepo.GetByID(*assigneeID); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, ErrAssigneeNotFound
+			}
+			return nil, fmt.Errorf("failed to get assignee: %w", err)
+		}
+	}
+
+	updated, err := s.contactRepo.SetAssignee(id, assigneeID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to assign contact: %w", err)
+	}
+	if !updated {
+		return nil, ErrContactNotFound
+	}
+	return s.GetContactByID(id, userID)
+}
+
+// BulkUpdate applies an inbox action to many contacts. Marking read or unread applies
+// to the requesting user and counts the contacts whose read state changed for them.
+func (s *ContactService) BulkUpdate(req *models.ContactBulkRequest, userID uint) (*models.ContactBulkResult, error) {
+	var affected int64
+	var err error
+	switch req.Action {
+	case models.ContactBulkMarkRead:
+		affected, err = s.contactRepo.MarkRead(req.IDs, userID, time.Now())
+	case models.ContactBulkMarkUnread:
+		affected, err = s.contactRepo.MarkUnread(req.IDs, userID)
+	case models.ContactBulkArchive:
+		now := time.Now()
+		affected, err = s.contactRepo.ArchiveMany(req.IDs, &now)
+	case models.ContactBulkUnarchive:
+		affected, err = s.contactRepo.ArchiveMany(req.IDs, nil)
+	case models.ContactBulkLabel, models.ContactBulkUnlabel:
+		if req.LabelID == nil {
+			return nil, ErrContactLabelRequired
+		}
+		if _, err := s.getLabel(*req.LabelID); err != nil {
+			return nil, err
+		}
+		if req.Action == models.ContactBulkLabel {
+			affected, err = s.contactRepo.AddLabel(req.IDs, *req.LabelID)
+		} else {
+			affected, err = s.contactRepo.RemoveLabel(req.IDs, *req.LabelID)
+		}
+	default:
+		return nil, fmt.Errorf("unknown bulk action %q", req.Action)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to %s contacts: %w", strings.ReplaceAll(req.Action, "_", " "), err)
+	}
+
+	return &models.ContactBulkResult{Action: req.Action, Affected: affected}, nil
+}
+
+// BulkDelete deletes many contacts
+func (s *ContactService) BulkDelete(ids []uint) (*models.ContactBulkResult, error) {
+	affected, err := s.contactRepo.DeleteMany(ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete contacts: %w", err)
+	}
+	return &models.ContactBulkResult{Action: "delete", Affected: affected}, nil
+}
+
+// GetLabels returns the contact labels by name
+func (s *ContactService) GetLabels() ([]models.ContactLabel, error) {
+	labels, err := s.labelRepo.GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get contact labels: %w", err)
+	}
+	return labels, nil
+}
+
+func (s *ContactService) getLabel(id uint) (*models.ContactLabel, error) {
+	label, err := s.labelRepo.GetByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrContactLabelNotFound
+		}
+		return nil, fmt.Errorf("failed to get contact label: %w", err)
+	}
+	return label, nil
+}
+
+// CreateLabel creates a contact label
+func (s *ContactService) CreateLabel(req *models.ContactLabelRequest) (*models.ContactLabel, error) {
+	label := &models.ContactLabel{}
+	if err := s.saveLabel(label, req); err != nil {
+		return nil, err
+	}
+	return label, nil
+}
+
+// UpdateLabel renames or recolors a contact label
+func (s *ContactService) UpdateLabel(id uint, req *models.ContactLabelRequest) (*models.ContactLabel, error) {
+	label, err := s.getLabel(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.saveLabel(label, req); err != nil {
+		return nil, err
+	}
+	return label, nil
+}
+
+// saveLabel validates a label request and saves it into label
+func (s *ContactService) saveLabel(label *models.ContactLabel, req *models.ContactLabelRequest) error {
+	name := strings.Join(strings.Fields(req.Name), " ")
+	if req.Color != "" && !labelColorPattern.MatchString(req.Color) {
+		return ErrInvalidLabelColor
+	}
+
+	existing, err := s.labelRepo.GetByName(name)
+	switch {
+	case err == nil && existing.ID != label.ID:
+		return ErrContactLabelExists
+	case err != nil && !errors.Is(err, gorm.ErrRecordNotFound):
+		return fmt.Errorf("failed to check contact label name: %w", err)
+	}
+
+	label.Name = name
+	label.Color = strings.ToLower(req.Color)
+	if err := s.labelRepo.Save(label); err != nil {
+		return fmt.Errorf("failed to save contact label: %w", err)
+	}
+	return nil
+}
+
+// DeleteLabel removes a label from every contact and deletes it
+func (s *ContactService) DeleteLabel(id uint) error {
+	deleted, err := s.labelRepo.Delete(id)
+	if err != nil {
+		return fmt.Errorf("failed to delete contact label: %w", err)
+	}
+	if !deleted {
+		return ErrContactLabelNotFound
+	}
+	return nil
+}
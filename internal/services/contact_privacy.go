@@ -0,0 +1,241 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/mail"
+	"portfolio-be/internal/config"
+	"portfolio-be/internal/models"
+	"portfolio-be/internal/repository"
+	"strings"
+	"time"
+)
+
+// ErrInvalidErasureEmail is returned when an export or erasure is not given an email address
+var ErrInvalidErasureEmail = errors.New("email must be an email address")
+
+// retentionBatchSize is how many contacts the retention job erases per transaction
+const retentionBatchSize = 200
+
+// ContactPrivacyService exports and erases the personal data of contact senders and
+// enforces the contact retention policy. Erasing messages also deletes the email tasks
+// and stored email copies about them, which quote the sender and the message. Every
+// erasure is recorded in an audit trail.
+type ContactPrivacyService struct {
+	contactRepo *repository.ContactRepository
+	replyRepo   *repository.ContactReplyRepository
+	erasureRepo *repository.ContactErasureRepository
+	tasks       *TaskQueue
+	mail        *MailService
+	cfg         config.ContactConfig
+}
+
+func NewContactPrivacyService(contactRepo *repository.ContactRepository, replyRepo *repository.ContactReplyRepository, erasureRepo *repository.ContactErasureRepository, tasks *TaskQueue, mail *MailService, cfg config.ContactConfig) *ContactPrivacyService {
+	if cfg.RetentionAction != models.ContactErasureDelete {
+		cfg.RetentionAction = models.ContactErasureAnonymize
+	}
+	if cfg.Retention > 0 {
+		log.Printf("Contact retention: %s messages after %d days", cfg.RetentionAction, int(cfg.Retention/(24*time.Hour)))
+	}
+	return &ContactPrivacyService{
+		contactRepo: contactRepo,
+		replyRepo:   replyRepo,
+		erasureRepo: erasureRepo,
+		tasks:       tasks,
+		mail:        mail,
+		cfg:         cfg,
+	}
+}
+
+// normalizeEmail returns the bare, lowercased address of an email
+func normalizeEmail(email string) (string, error) {
+	address, err := mail.ParseAddress(strings.TrimSpace(email))
+	if err != nil {
+		return "", ErrInvalidErasureEmail
+	}
+	return strings.ToLower(address.Address), nil
+}
+
+// hashEmail returns the hex SHA-256 of a normalized email address
+func hashEmail(email string) string {
+	sum := sha256.Sum256([]byte(email))
+	return hex.EncodeToString(sum[:])
+}
+
+// Export returns every message, including deleted ones, sent with an email address and
+// the replies sent to them
+func (s *ContactPrivacyService) Export(email string) (*models.ContactExport, error) {
+	email, err := normalizeEmail(email)
+	if err != nil {
+		return nil, err
+	}
+
+	contacts, err := s.contactRepo.GetAllByEmail(email)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get contacts: %w", err)
+	}
+
+	export := &models.ContactExport{
+		Email:      email,
+		ExportedAt: time.Now(),
+		Messages:   make([]models.ContactExportMessage, 0, len(contacts)),
+	}
+	for _, contact := range contacts {
+		replies, err := s.replyRepo.GetByContact(contact.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get replies: %w", err)
+		}
+
+		message := models.ContactExportMessage{
+			ID:        contact.ID,
+			Name:      contact.Name,
+			Email:     contact.Email,
+			Subject:   contact.Subject,
+			Message:   contact.Message,
+			Status:    contact.Status,
+			IPAddress: contact.IPAddress,
+			Labels:    make([]string, 0, len(contact.Labels)),
+			Replies:   replies,
+			CreatedAt: contact.CreatedAt,
+			UpdatedAt: contact.UpdatedAt,
+		}
+		if message.Replies == nil {
+			message.Replies = []models.ContactReply{}
+		}
+		for _, label := range contact.Labels {
+			message.Labels = append(message.Labels, label.Name)
+		}
+		if contact.DeletedAt.Valid {
+			deletedAt := contact.DeletedAt.Time
+			message.DeletedAt = &deletedAt
+		}
+		export.Messages = append(export.Messages, message)
+	}
+	return export, nil
+}
+
+// Erase permanently deletes every message, including deleted ones, sent with an email
+// address, with the replies sent to them, and records the erasure. The erasure is
+// recorded even when nothing was stored, to show that the request was carried out.
+func (s *ContactPrivacyService) Erase(email string, actorID uint, actor string) (*models.ContactErasure, error) {
+	email, err := normalizeEmail(email)
+	if err != nil {
+		return nil, err
+	}
+
+	ids, err := s.contactRepo.IDsByEmail(email)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get contacts: %w", err)
+	}
+
+	erasure := &models.ContactErasure{
+		Action:    models.ContactErasureDelete,
+		Reason:    models.ContactErasureRequest,
+		EmailHash: hashEmail(email),
+		ActorID:   &actorID,
+		Actor:     actor,
+	}
+	if len(ids) > 0 {
+		if erasure.Tasks, err = s.forget(ids); err != nil {
+			return nil, err
+		}
+		if erasure.Contacts, erasure.Replies, err = s.contactRepo.Purge(ids); err != nil {
+			return nil, fmt.Errorf("failed to erase contacts: %w", err)
+		}
+	}
+
+	if err := s.erasureRepo.Create(erasure); err != nil {
+		return nil, fmt.Errorf("failed to record erasure: %w", err)
+	}
+	log.Printf("User %s erased %d contact messages of one sender (erasure %d)", actor, erasure.Contacts, erasure.ID)
+	return erasure, nil
+}
+
+// ApplyRetention anonymizes or deletes the messages older than the retention period and
+// records the erasure. It returns how many messages it erased, and stops between batches
+// once ctx is cancelled or its job lease is lost.
+func (s *ContactPrivacyService) ApplyRetention(ctx context.Context) (int64, error) {
+	if s.cfg.Retention <= 0 {
+		return 0, nil
+	}
+
+	cutoff := time.Now().Add(-s.cfg.Retention)
+	anonymize := s.cfg.RetentionAction == models.ContactErasureAnonymize
+	erasure := &models.ContactErasure{
+		Action: s.cfg.RetentionAction,
+		Reason: models.ContactErasureRetention,
+	}
+
+	var err error
+	for {
+		if err = CheckJobLease(ctx); err != nil {
+			break
+		}
+		var ids []uint
+		ids, err = s.contactRepo.IDsCreatedBefore(cutoff, anonymize, retentionBatchSize)
+		if err != nil {
+			err = fmt.Errorf("failed to get expired contacts: %w", err)
+			break
+		}
+		if len(ids) == 0 {
+			break
+		}
+
+		var tasks, contacts, replies int64
+		if tasks, err = s.forget(ids); err != nil {
+			break
+		}
+		erasure.Tasks += tasks
+		if anonymize {
+			contacts, replies, err = s.contactRepo.Anonymize(ids, time.Now())
+		} else {
+			contacts, replies, err = s.contactRepo.Purge(ids)
+		}
+		if err != nil {
+			err = fmt.Errorf("failed to erase expired contacts: %w", err)
+			break
+		}
+		erasure.Contacts += contacts
+		erasure.Replies += replies
+	}
+
+	// Batches already erased are recorded even when a later one failed
+	if erasure.Contacts > 0 {
+		if recordErr := s.erasureRepo.Create(erasure); recordErr != nil {
+			if err == nil {
+				err = fmt.Errorf("failed to record erasure: %w", recordErr)
+			} else {
+				log.Printf("Failed to record retention erasure of %d contacts: %v", erasure.Contacts, recordErr)
+			}
+		}
+	}
+	return erasure.Contacts, err
+}
+
+// forget deletes the email tasks and stored email copies about contacts. It runs before
+// the contacts are erased, so that a failure leaves them to be erased again.
+func (s *ContactPrivacyService) forget(ids []uint) (int64, error) {
+	refs := contactRefs(ids)
+	tasks, err := s.tasks.DeleteByRefs(refs)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete email tasks: %w", err)
+	}
+	if _, err := s.mail.DeleteCopies(refs); err != nil {
+		return tasks, err
+	}
+	return tasks, nil
+}
+
+// GetErasures returns the erasure audit trail, newest first, optionally only the
+// erasures with a reason
+func (s *ContactPrivacyService) GetErasures(reason string, limit, offset int) ([]models.ContactErasure, int64, error) {
+	erasures, total, err := s.erasureRepo.List(reason, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get erasures: %w", err)
+	}
+	return erasures, total, nil
+}
@@ -0,0 +1,160 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"portfolio-be/internal/config"
+	"portfolio-be/internal/models"
+	"portfolio-be/internal/repository"
+	"portfolio-be/internal/testutil"
+)
+
+func TestContactErasureDeletesEmailTasksAndCopies(t *testing.T) {
+	db := testutil.OpenDB(t, filepath.Join(t.TempDir(), "contacts.db"))
+	dropDir := t.TempDir()
+
+	contactCfg := config.ContactConfig{
+		NotifyEmails:    []string{"admin@example.com"},
+		AutoReply:       true,
+		SpamThreshold:   5,
+		Retention:       30 * 24 * time.Hour,
+		RetentionAction: models.ContactErasureAnonymize,
+	}
+	mailer, err := NewMailer(config.MailConfig{Driver: "file", From: "Site <site@example.com>", DropDir: dropDir})
+	if err != nil {
+		t.Fatalf("failed to create mailer: %v", err)
+	}
+	taskRepo := repository.NewTaskRepository(db)
+	tasks := NewTaskQueue(taskRepo, config.TaskConfig{MaxAttempts: 3}, "test")
+	mail := NewMailService(mailer, tasks, config.MailConfig{SiteName: "Test"})
+	contactRepo := repository.NewContactRepository(db)
+	replyRepo := repository.NewContactReplyRepository(db)
+	contacts := NewContactService(contactRepo, replyRepo, repository.NewContactLabelRepository(db),
+		repository.NewUserRepository(db), contactCfg, nil, nil, mail)
+	privacy := NewContactPrivacyService(contactRepo, replyRepo, repository.NewContactErasureRepository(db), tasks, mail, contactCfg)
+
+	// Each message is notified and acknowledged, and the emails are delivered to the
+	// drop directory, leaving the tasks and a copy of each email behind
+	submit := func(name, email, message string, age time.Duration) {
+		t.Helper()
+		contact := &models.Contact{Name: name, Email: email, Message: message, Status: models.ContactStatusUnread}
+		if err := contactRepo.Create(contact); err != nil {
+			t.Fatalf("failed to create contact: %v", err)
+		}
+		if age > 0 {
+			contact.CreatedAt = time.Now().Add(-age)
+			if err := db.Model(contact).Update("created_at", contact.CreatedAt).Error; err != nil {
+				t.Fatalf("failed to age contact: %v", err)
+			}
+		}
+		contacts.notify(contact)
+
+		queued, _, err := taskRepo.List("", TaskSendEmail, 100, 0)
+		if err != nil {
+			t.Fatalf("failed to list tasks: %v", err)
+		}
+		for _, task := range queued {
+			if task.Ref == contactRef(contact.ID) {
+				if err := mail.handleSend(context.Background(), task.Payload); err != nil {
+					t.Fatalf("failed to send email: %v", err)
+				}
+			}
+		}
+	}
+	submit("Jane", "jane@example.com", "Jane's secret message", 0)
+	submit("Bob", "bob@example.com", "Bob's old message", 60*24*time.Hour)
+	submit("Carol", "carol@example.com", "Carol's message", 0)
+
+	// remaining reports how many tasks and email copies quote a text
+	remaining := func(text string) (int, int) {
+		t.Helper()
+		queued, _, err := taskRepo.List("", "", 100, 0)
+		if err != nil {
+			t.Fatalf("failed to list tasks: %v", err)
+		}
+		taskCount := 0
+		for _, task := range queued {
+			if strings.Contains(string(task.Payload), text) {
+				taskCount++
+			}
+		}
+		entries, err := os.ReadDir(dropDir)
+		if err != nil {
+			t.Fatalf("failed to list drop directory: %v", err)
+		}
+		copyCount := 0
+		for _, entry := range entries {
+			data, err := os.ReadFile(filepath.Join(dropDir, entry.Name()))
+			if err != nil {
+				t.Fatalf("failed to read email: %v", err)
+			}
+			if strings.Contains(string(data), text) {
+				copyCount++
+			}
+		}
+		return taskCount, copyCount
+	}
+	for _, text := range []string{"jane@example.com", "bob@example.com", "carol@example.com"} {
+		if taskCount, copyCount := remaining(text); taskCount != 2 || copyCount != 2 {
+			t.Fatalf("expected 2 tasks and 2 copies quoting %s, got %d and %d", text, taskCount, copyCount)
+		}
+	}
+
+	erasure, err := privacy.Erase("Jane <JANE@example.com>", 1, "admin")
+	if err != nil {
+		t.Fatalf("failed to erase: %v", err)
+	}
+	if erasure.Contacts != 1 || erasure.Tasks != 2 {
+		t.Fatalf("expected the erasure of 1 contact and 2 tasks, got %d and %d", erasure.Contacts, erasure.Tasks)
+	}
+	if taskCount, copyCount := remaining("jane@example.com"); taskCount != 0 || copyCount != 0 {
+		t.Fatalf("expected no tasks or copies quoting the erased sender, got %d and %d", taskCount, copyCount)
+	}
+
+	erased, err := privacy.ApplyRetention(context.Background())
+	if err != nil {
+		t.Fatalf("failed to apply retention: %v", err)
+	}
+	if erased != 1 {
+		t.Fatalf("expected retention to anonymize 1 contact, got %d", erased)
+	}
+	if taskCount, copyCount := remaining("Bob's old message"); taskCount != 0 || copyCount != 0 {
+		t.Fatalf("expected no tasks or copies quoting the expired message, got %d and %d", taskCount, copyCount)
+	}
+
+	if taskCount, copyCount := remaining("carol@example.com"); taskCount != 2 || copyCount != 2 {
+		t.Fatalf("expected the tasks and copies of other senders to stay, got %d and %d", taskCount, copyCount)
+	}
+}
+
+func TestReplyToAnonymizedContactIsRejected(t *testing.T) {
+	db := testutil.OpenDB(t, filepath.Join(t.TempDir(), "contacts.db"))
+	taskRepo := repository.NewTaskRepository(db)
+	tasks := NewTaskQueue(taskRepo, config.TaskConfig{MaxAttempts: 3}, "test")
+	mail := NewMailService(NoopMailer{}, tasks, config.MailConfig{SiteName: "Test"})
+	contactRepo := repository.NewContactRepository(db)
+	replyRepo := repository.NewContactReplyRepository(db)
+	replies := NewContactReplyService(contactRepo, replyRepo, repository.NewReplyTemplateRepository(db), mail, tasks)
+
+	contact := &models.Contact{Name: "Jane", Email: "jane@example.com", Message: "Hello", Status: models.ContactStatusUnread}
+	if err := contactRepo.Create(contact); err != nil {
+		t.Fatalf("failed to create contact: %v", err)
+	}
+	if _, _, err := contactRepo.Anonymize([]uint{contact.ID}, time.Now()); err != nil {
+		t.Fatalf("failed to anonymize contact: %v", err)
+	}
+
+	_, err := replies.Reply(contact.ID, 1, "admin", &models.ContactReplyRequest{Body: "Thanks"})
+	if !errors.Is(err, ErrContactAnonymized) {
+		t.Fatalf("expected ErrContactAnonymized, got %v", err)
+	}
+	if queued, total, err := taskRepo.List("", "", 10, 0); err != nil || total != 0 {
+		t.Fatalf("expected no reply to be queued, got %d tasks (%v): %v", total, err, queued)
+	}
+}
@@ -0,0 +1,343 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"portfolio-be/internal/models"
+	"portfolio-be/internal/repository"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrEmptyReply is returned when a reply has neither a body nor a template
+	ErrEmptyReply = errors.New("body or template_id is required")
+	// ErrReplyTemplateNotFound is returned when a reply template does not exist
+	ErrReplyTemplateNotFound = errors.New("reply template not found")
+	// ErrReplyTemplateExists is returned when a reply template name is already taken
+	ErrReplyTemplateExists = errors.New("a reply template with this name already exists")
+	// ErrInvalidReplyTemplate is returned when a reply template does not parse or render
+	ErrInvalidReplyTemplate = errors.New("invalid reply template")
+	// ErrContactAnonymized is returned when replying to a message whose sender and
+	// content were erased by the retention policy
+	ErrContactAnonymized = errors.New("the contact message was anonymized")
+)
+
+// TaskContactReply is the task kind that delivers a contact reply
+const TaskContactReply = "contact.reply"
+
+// contactReplyPayload is the payload of a contact reply task
+type contactReplyPayload struct {
+	ReplyID uint `json:"reply_id"`
+}
+
+// ReplyTemplateData is the data reply templates are rendered with
+type ReplyTemplateData struct {
+	Name     string
+	Email    string
+	Subject  string
+	SiteName string
+	Author   string
+}
+
+// ContactReplyService sends admin replies to contact messages and manages canned replies
+type ContactReplyService struct {
+	contactRepo  *repository.ContactRepository
+	replyRepo    *repository.ContactReplyRepository
+	templateRepo *repository.ReplyTemplateRepository
+	mail         *MailService
+	tasks        *TaskQueue
+}
+
+// NewContactReplyService creates the reply service and registers the delivery task
+func NewContactReplyService(contactRepo *repository.ContactRepository, replyRepo *repository.ContactReplyRepository, templateRepo *repository.ReplyTemplateRepository, mail *MailService, tasks *TaskQueue) *ContactReplyService {
+	s := &ContactReplyService{
+		contactRepo:  contactRepo,
+		replyRepo:    replyRepo,
+		templateRepo: templateRepo,
+		mail:         mail,
+		tasks:        tasks,
+	}
+	tasks.Register(TaskContactReply, 0, s.deliver)
+	return s
+}
+
+// Reply stores a reply to a contact message, queues its delivery and marks the message
+// replied. The body may come from a canned template rendered for the message.
+func (s *ContactReplyService) Reply(contactID, authorID uint, author string, req *models.ContactReplyRequest) (*models.ContactReply, error) {
+	contact, err := s.contactRepo.GetByID(contactID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrContactNotFound
+		}
+		return nil, fmt.Errorf("failed to get contact: %w", err)
+	}
+	if contact.AnonymizedAt != nil {
+		return nil, ErrContactAnonymized
+	}
+
+	subject := req.Subject
+	body := req.Body
+	if req.TemplateID != nil {
+		template, err := s.getTemplate(*req.TemplateID)
+		if err != nil {
+			return nil, err
+		}
+		data := s.templateData(contact, author)
+		if strings.TrimSpace(body) == "" {
+			if body, err = renderReplyTemplate(template.Body, data); err != nil {
+				return nil, err
+			}
+		}
+		if strings.TrimSpace(subject) == "" && template.Subject != "" {
+			if subject, err = renderReplyTemplate(template.Subject, data); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if strings.TrimSpace(body) == "" {
+		return nil, ErrEmptyReply
+	}
+	// Subjects are one line, whatever the template or message contains
+	subject = strings.Join(strings.Fields(subject), " ")
+	if subject == "" {
+		subject = s.defaultSubject(contact)
+	}
+
+	reply := &models.ContactReply{
+		ContactID: contact.ID,
+		AuthorID:  authorID,
+		Author:    author,
+		Subject:   subject,
+		Body:      body,
+		Status:    models.ContactReplyQueued,
+		// Set once so that retries send the same message
+		MessageID: s.mail.NewMessageID(),
+	}
+	if err := s.replyRepo.Create(reply); err != nil {
+		return nil, fmt.Errorf("failed to create reply: %w", err)
+	}
+
+	task, err := s.tasks.EnqueueRef(TaskContactReply, contactRef(contact.ID), contactReplyPayload{ReplyID: reply.ID})
+	if err != nil {
+		if markErr := s.replyRepo.MarkFailed(reply.ID, models.ContactReplyFailed, err.Error()); markErr != nil {
+			log.Printf("Failed to record failure of reply %d: %v", reply.ID, markErr)
+		}
+		return nil, fmt.Errorf("failed to queue reply: %w", err)
+	}
+	if err := s.replyRepo.SetTask(reply.ID, task.ID); err != nil {
+		log.Printf("Failed to record task of reply %d: %v", reply.ID, err)
+	}
+	reply.TaskID = &task.ID
+
+	// Replying implies the author read the message
+	if _, err := s.contactRepo.MarkRead([]uint{contact.ID}, authorID, time.Now()); err != nil {
+		log.Printf("Failed to record that user %d read contact %d: %v", authorID, contact.ID, err)
+	}
+
+	// Only the status is written, so that changes other admins made since the contact
+	// was loaded are kept
+	if contact.Status != models.ContactStatusReplied {
+		if _, err := s.contactRepo.SetStatus(contact.ID, models.ContactStatusReplied); err != nil {
+			return nil, fmt.Errorf("failed to mark contact replied: %w", err)
+		}
+		contact.Status = models.ContactStatusReplied
+	}
+
+	return reply, nil
+}
+
+// defaultSubject answers the subject of a message, or names the site when it had none
+func (s *ContactReplyService) defaultSubject(contact *models.Contact) string {
+	subject := strings.Join(strings.Fields(contact.Subject), " ")
+	switch {
+	case subject == "":
+		return "Re: Your message to " + s.mail.SiteName()
+	case strings.HasPrefix(strings.ToLower(subject), "re:"):
+		return subject
+	default:
+		return "Re: " + subject
+	}
+}
+
+// deliver sends a queued reply and records the outcome on the reply. A failure on the
+// last attempt, or one that retrying cannot fix, marks the reply failed.
+func (s *ContactReplyService) deliver(ctx context.Context, payload json.RawMessage) error {
+	var p contactReplyPayload
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return PermanentError(fmt.Errorf("invalid reply payload: %w", err))
+	}
+
+	reply, err := s.replyRepo.GetByID(p.ReplyID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return PermanentError(fmt.Errorf("reply %d no longer exists", p.ReplyID))
+		}
+		return fmt.Errorf("failed to get reply: %w", err)
+	}
+	if reply.Status == models.ContactReplySent {
+		return nil
+	}
+
+	err = s.send(ctx, reply)
+	if err == nil {
+		if err := s.replyRepo.MarkSent(reply.ID, time.Now()); err != nil {
+			// Not returned: retrying would send the reply again
+			log.Printf("Failed to record delivery of reply %d: %v", reply.ID, err)
+		}
+		return nil
+	}
+
+	status := models.ContactReplyQueued
+	if errors.As(err, new(permanentError)) || IsFinalAttempt(ctx) {
+		status = models.ContactReplyFailed
+	}
+	if markErr := s.replyRepo.MarkFailed(reply.ID, status, err.Error()); markErr != nil {
+		log.Printf("Failed to record failure of reply %d: %v", reply.ID, markErr)
+	}
+	return err
+}
+
+// send renders a reply with the message it answers and sends it
+func (s *ContactReplyService) send(ctx context.Context, reply *models.ContactReply) error {
+	contact, err := s.contactRepo.GetByID(reply.ContactID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return PermanentError(errors.New("the contact message was deleted"))
+		}
+		return fmt.Errorf("failed to get contact: %w", err)
+	}
+	if contact.AnonymizedAt != nil {
+		return PermanentError(ErrContactAnonymized)
+	}
+
+	email, err := s.mail.Render(MailContactReply, []string{contact.Email}, ContactReplyMailData{
+		SiteName: s.mail.SiteName(),
+		Contact:  contact,
+		Reply:    reply,
+	})
+	if err != nil {
+		return PermanentError(err)
+	}
+	email.MessageID = reply.MessageID
+	email.Ref = contactRef(contact.ID)
+	return s.mail.Send(ctx, email)
+}
+
+// templateData returns the data reply templates are rendered with for a message
+func (s *ContactReplyService) templateData(contact *models.Contact, author string) ReplyTemplateData {
+	return ReplyTemplateData{
+		Name:     contact.Name,
+		Email:    contact.Email,
+		Subject:  contact.Subject,
+		SiteName: s.mail.SiteName(),
+		Author:   author,
+	}
+}
+
+// renderReplyTemplate renders a reply template text
+func renderReplyTemplate(text string, data ReplyTemplateData) (string, error) {
+	tmpl, err := texttemplate.New("reply").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidReplyTemplate, err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidReplyTemplate, err)
+	}
+	return buf.String(), nil
+}
+
+// GetTemplates returns the canned replies by name
+func (s *ContactReplyService) GetTemplates() ([]models.ReplyTemplate, error) {
+	templates, err := s.templateRepo.GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get reply templates: %w", err)
+	}
+	return templates, nil
+}
+
+// GetTemplate returns a canned reply
+func (s *ContactReplyService) GetTemplate(id uint) (*models.ReplyTemplate, error) {
+	return s.getTemplate(id)
+}
+
+func (s *ContactReplyService) getTemplate(id uint) (*models.ReplyTemplate, error) {
+	template, err := s.templateRepo.GetByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrReplyTemplateNotFound
+		}
+		return nil, fmt.Errorf("failed to get reply template: %w", err)
+	}
+	return template, nil
+}
+
+// CreateTemplate stores a canned reply
+func (s *ContactReplyService) CreateTemplate(req *models.ReplyTemplateRequest) (*models.ReplyTemplate, error) {
+	template := &models.ReplyTemplate{}
+	if err := s.saveTemplate(template, req); err != nil {
+		return nil, err
+	}
+	return template, nil
+}
+
+// UpdateTemplate replaces a canned reply
+func (s *ContactReplyService) UpdateTemplate(id uint, req *models.ReplyTemplateRequest) (*models.ReplyTemplate, error) {
+	template, err := s.getTemplate(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.saveTemplate(template, req); err != nil {
+		return nil, err
+	}
+	return template, nil
+}
+
+// saveTemplate validates a template request and saves it into template
+func (s *ContactReplyService) saveTemplate(template *models.ReplyTemplate, req *models.ReplyTemplateRequest) error {
+	name := strings.TrimSpace(req.Name)
+	existing, err := s.templateRepo.GetByName(name)
+	switch {
+	case err == nil && existing.ID != template.ID:
+		return ErrReplyTemplateExists
+	case err != nil && !errors.Is(err, gorm.ErrRecordNotFound):
+		return fmt.Errorf("failed to check reply template name: %w", err)
+	}
+
+	// Render with sample data so that unknown fields are rejected now rather than
+	// when an admin replies
+	sample := ReplyTemplateData{Name: "Jane Doe", Email: "jane@example.com", Subject: "Hello", SiteName: s.mail.SiteName(), Author: "admin"}
+	for _, text := range []string{req.Subject, req.Body} {
+		if _, err := renderReplyTemplate(text, sample); err != nil {
+			return err
+		}
+	}
+
+	template.Name = name
+	template.Subject = req.Subject
+	template.Body = req.Body
+	if err := s.templateRepo.Save(template); err != nil {
+		return fmt.Errorf("failed to save reply template: %w", err)
+	}
+	return nil
+}
+
+// DeleteTemplate removes a canned reply
+func (s *ContactReplyService) DeleteTemplate(id uint) error {
+	deleted, err := s.templateRepo.Delete(id)
+	if err != nil {
+		return fmt.Errorf("failed to delete reply template: %w", err)
+	}
+	if !deleted {
+		return ErrReplyTemplateNotFound
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package services
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"portfolio-be/internal/config"
+	"portfolio-be/internal/models"
+	"portfolio-be/internal/repository"
+	"portfolio-be/internal/testutil"
+
+	"gorm.io/gorm"
+)
+
+func TestReplyKeepsConcurrentInboxChanges(t *testing.T) {
+	db := testutil.OpenDB(t, filepath.Join(t.TempDir(), "contacts.db"))
+	tasks := NewTaskQueue(repository.NewTaskRepository(db), config.TaskConfig{MaxAttempts: 3}, "test")
+	mail := NewMailService(NoopMailer{}, tasks, config.MailConfig{SiteName: "Test"})
+	contactRepo := repository.NewContactRepository(db)
+	replies := NewContactReplyService(contactRepo, repository.NewContactReplyRepository(db), repository.NewReplyTemplateRepository(db), mail, tasks)
+
+	assignee := &models.User{Username: "support", Email: "support@example.com", Password: "secret", Role: "admin"}
+	if err := db.Create(assignee).Error; err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	contact := &models.Contact{Name: "Jane", Email: "jane@example.com", Message: "Hello", Status: models.ContactStatusUnread}
+	if err := contactRepo.Create(contact); err != nil {
+		t.Fatalf("failed to create contact: %v", err)
+	}
+
+	// Another admin assigns and archives the contact after Reply loaded it
+	archivedAt := time.Now()
+	err := db.Callback().Create().Before("gorm:create").Register("test:concurrent_admin", func(tx *gorm.DB) {
+		if _, ok := tx.Statement.Dest.(*models.ContactReply); !ok {
+			return
+		}
+		if _, err := contactRepo.SetAssignee(contact.ID, &assignee.ID); err != nil {
+			t.Errorf("failed to assign contact: %v", err)
+		}
+		if _, err := contactRepo.ArchiveMany([]uint{contact.ID}, &archivedAt); err != nil {
+			t.Errorf("failed to archive contact: %v", err)
+		}
+	})
+	if err != nil {
+		t.Fatalf("failed to register callback: %v", err)
+	}
+
+	if _, err := replies.Reply(contact.ID, assignee.ID, "support", &models.ContactReplyRequest{Body: "Thanks"}); err != nil {
+		t.Fatalf("failed to reply: %v", err)
+	}
+
+	saved, err := contactRepo.GetByID(contact.ID)
+	if err != nil {
+		t.Fatalf("failed to get contact: %v", err)
+	}
+	if saved.Status != models.ContactStatusReplied {
+		t.Errorf("expected the contact to be replied, got %s", saved.Status)
+	}
+	if saved.AssigneeID == nil || *saved.AssigneeID != assignee.ID {
+		t.Errorf("expected the concurrent assignment to be kept, got %v", saved.AssigneeID)
+	}
+	if saved.ArchivedAt == nil {
+		t.Error("expected the concurrent archive to be kept")
+	}
+}
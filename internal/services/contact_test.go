@@ -0,0 +1,137 @@
+package services
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"portfolio-be/internal/config"
+	"portfolio-be/internal/models"
+	"portfolio-be/internal/repository"
+	"portfolio-be/internal/testutil"
+)
+
+func TestAcknowledgementCarriesNoSubmittedContent(t *testing.T) {
+	db := testutil.OpenDB(t, filepath.Join(t.TempDir(), "contacts.db"))
+	contactCfg := config.ContactConfig{AutoReply: true, SpamThreshold: 6}
+	taskRepo := repository.NewTaskRepository(db)
+	tasks := NewTaskQueue(taskRepo, config.TaskConfig{MaxAttempts: 3}, "test")
+	mail := NewMailService(NoopMailer{}, tasks, config.MailConfig{SiteName: "Test Site"})
+	contactRepo := repository.NewContactRepository(db)
+	contacts := NewContactService(contactRepo, repository.NewContactReplyRepository(db), repository.NewContactLabelRepository(db),
+		repository.NewUserRepository(db), contactCfg, nil, nil, mail)
+
+	// acknowledgements returns the emails queued for a new message
+	seen := map[uint]bool{}
+	acknowledgements := func(contact *models.Contact) []Email {
+		t.Helper()
+		if err := contactRepo.Create(contact); err != nil {
+			t.Fatalf("failed to create contact: %v", err)
+		}
+		contacts.notify(contact)
+
+		queued, _, err := taskRepo.List("", TaskSendEmail, 100, 0)
+		if err != nil {
+			t.Fatalf("failed to list tasks: %v", err)
+		}
+		var emails []Email
+		for _, task := range queued {
+			if seen[task.ID] {
+				continue
+			}
+			seen[task.ID] = true
+			var email Email
+			if err := json.Unmarshal(task.Payload, &email); err != nil {
+				t.Fatalf("failed to decode email: %v", err)
+			}
+			emails = append(emails, email)
+		}
+		return emails
+	}
+
+	// A form filled in to mail a third party only gets the fixed text to them
+	emails := acknowledgements(&models.Contact{
+		Name:    "Buy now at spam.example",
+		Email:   "victim@example.com",
+		Subject: "You won",
+		Message: "Claim your prize at https://spam.example",
+		Status:  models.ContactStatusUnread,
+	})
+	if len(emails) != 1 {
+		t.Fatalf("expected one acknowledgement, got %d", len(emails))
+	}
+	email := emails[0]
+	if len(email.To) != 1 || email.To[0] != "victim@example.com" {
+		t.Fatalf("expected the acknowledgement to go to the sender, got %v", email.To)
+	}
+	for _, submitted := range []string{"spam.example", "You won", "prize"} {
+		if strings.Contains(email.Subject+email.Text+email.HTML, submitted) {
+			t.Fatalf("acknowledgement quotes submitted text %q", submitted)
+		}
+	}
+	if !strings.Contains(email.Text, "Test Site") {
+		t.Fatalf("expected the acknowledgement to name the site, got %q", email.Text)
+	}
+
+	// A message close to the spam threshold is not acknowledged
+	emails = acknowledgements(&models.Contact{
+		Name:      "Jane",
+		Email:     "jane@example.com",
+		Message:   "Hello",
+		Status:    models.ContactStatusUnread,
+		SpamScore: 3,
+	})
+	if len(emails) != 0 {
+		t.Fatalf("expected no acknowledgement of a message scoring half the threshold, got %d", len(emails))
+	}
+}
+
+func TestContactStaysReadWhileAnotherAdminHasReadIt(t *testing.T) {
+	db := testutil.OpenDB(t, filepath.Join(t.TempDir(), "contacts.db"))
+	contactRepo := repository.NewContactRepository(db)
+	contacts := NewContactService(contactRepo, repository.NewContactReplyRepository(db), repository.NewContactLabelRepository(db),
+		repository.NewUserRepository(db), config.ContactConfig{}, nil, nil, nil)
+
+	var admins []uint
+	for _, name := range []string{"alice", "bob"} {
+		user := &models.User{Username: name, Email: name + "@example.com", Password: "secret", Role: "admin"}
+		if err := db.Create(user).Error; err != nil {
+			t.Fatalf("failed to create user: %v", err)
+		}
+		admins = append(admins, user.ID)
+	}
+	contact := &models.Contact{Name: "Visitor", Email: "visitor@example.com", Subject: "Hello", Message: "Hi there", Status: models.ContactStatusUnread}
+	if err := contactRepo.Create(contact); err != nil {
+		t.Fatalf("failed to create contact: %v", err)
+	}
+	status := func() string {
+		t.Helper()
+		stored, err := contactRepo.GetByID(contact.ID)
+		if err != nil {
+			t.Fatalf("failed to get contact: %v", err)
+		}
+		return stored.Status
+	}
+
+	for _, admin := range admins {
+		if err := contacts.MarkAsRead(contact.ID, admin); err != nil {
+			t.Fatalf("failed to mark contact read: %v", err)
+		}
+	}
+
+	// The other admin's read keeps the message read
+	if err := contacts.MarkAsUnread(contact.ID, admins[0]); err != nil {
+		t.Fatalf("failed to mark contact unread: %v", err)
+	}
+	if got := status(); got != models.ContactStatusRead {
+		t.Fatalf("expected the contact to stay read, got %s", got)
+	}
+
+	if err := contacts.MarkAsUnread(contact.ID, admins[1]); err != nil {
+		t.Fatalf("failed to mark contact unread: %v", err)
+	}
+	if got := status(); got != models.ContactStatusUnread {
+		t.Errorf("expected the contact to be unread once no one has read it, got %s", got)
+	}
+}
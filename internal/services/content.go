@@ -4,14 +4,17 @@ import (
 	"fmt"
 	"portfolio-be/internal/models"
 	"portfolio-be/internal/repository"
+	"strings"
 )
 
 type ContentService struct {
-	repo *repository.ContentRepository
+	repo       *repository.ContentRepository
+	references *UploadReferenceService
+	tags       *TagService
 }
 
-func NewContentService(repo *repository.ContentRepository) *ContentService {
-	return &ContentService{repo: repo}
+func NewContentService(repo *repository.ContentRepository, references *UploadReferenceService, tags *TagService) *ContentService {
+	return &ContentService{repo: repo, references: references, tags: tags}
 }
 
 func (s *ContentService) CreateContent(req models.ContentRequest) (*models.ContentResponse, error) {
@@ -20,7 +23,7 @@ func (s *ContentService) CreateContent(req models.ContentRequest) (*models.Conte
 		Description: req.Description,
 		Body:        req.Body,
 		Category:    req.Category,
-		Tags:        req.Tags,
+		Tags:        strings.Join(models.ParseTags(req.Tags), ","),
 		Status:      req.Status,
 		ImageURL:    req.ImageURL,
 	}
@@ -34,6 +37,10 @@ func (s *ContentService) CreateContent(req models.ContentRequest) (*models.Conte
 		return nil, fmt.Errorf("failed to create content: %w", err)
 	}
 
+	if err := s.syncReferences(content); err != nil {
+		return nil, err
+	}
+
 	response := content.ToResponse()
 	return &response, nil
 }
@@ -48,20 +55,17 @@ func (s *ContentService) GetContentByID(id uint) (*models.ContentResponse, error
 	return &response, nil
 }
 
-func (s *ContentService) GetAllContent(limit, offset int, category, status string) ([]models.ContentResponse, error) {
-	var contents []models.Content
-	var err error
-
-	if category != "" {
-		contents, err = s.repo.GetByCategory(category, limit, offset)
-	} else if status != "" {
-		contents, err = s.repo.GetByStatus(status, limit, offset)
-	} else {
-		contents, err = s.repo.GetAll(limit, offset)
+// GetAllContent returns a page of contents matching a filter along with the total
+// number of matches
+func (s *ContentService) GetAllContent(filter models.ContentFilter, limit, offset int) ([]models.ContentResponse, int64, error) {
+	contents, err := s.repo.List(filter, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get contents: %w", err)
 	}
 
+	total, err := s.repo.CountFiltered(filter)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get contents: %w", err)
+		return nil, 0, fmt.Errorf("failed to count contents: %w", err)
 	}
 
 	responses := make([]models.ContentResponse, len(contents))
@@ -69,7 +73,7 @@ func (s *ContentService) GetAllContent(limit, offset int, category, status strin
 		responses[i] = content.ToResponse()
 	}
 
-	return responses, nil
+	return responses, total, nil
 }
 
 func (s *ContentService) UpdateContent(id uint, req models.ContentRequest) (*models.ContentResponse, error) {
@@ -83,7 +87,7 @@ func (s *ContentService) UpdateContent(id uint, req models.ContentRequest) (*mod
 	content.Description = req.Description
 	content.Body = req.Body
 	content.Category = req.Category
-	content.Tags = req.Tags
+	content.Tags = strings.Join(models.ParseTags(req.Tags), ",")
 	content.Status = req.Status
 	content.ImageURL = req.ImageURL
 
@@ -91,6 +95,10 @@ func (s *ContentService) UpdateContent(id uint, req models.ContentRequest) (*mod
 		return nil, fmt.Errorf("failed to update content: %w", err)
 	}
 
+	if err := s.syncReferences(content); err != nil {
+		return nil, err
+	}
+
 	response := content.ToResponse()
 	return &response, nil
 }
@@ -100,7 +108,18 @@ func (s *ContentService) DeleteContent(id uint) error {
 		return fmt.Errorf("failed to delete content: %w", err)
 	}
 
-	return nil
+	if err := s.references.RemoveEntity(models.ReferenceEntityContent, id); err != nil {
+		return err
+	}
+	return s.tags.RemoveEntity(models.ReferenceEntityContent, id)
+}
+
+// syncReferences indexes the uploads and tags used by a content item
+func (s *ContentService) syncReferences(content *models.Content) error {
+	if err := s.references.SyncEntity(models.ReferenceEntityContent, content.ID, map[string]string{"image_url": content.ImageURL}); err != nil {
+		return err
+	}
+	return s.tags.SyncEntity(models.ReferenceEntityContent, content.ID, models.ParseTags(content.Tags))
 }
 
 func (s *ContentService) GetContentCount() (int64, error) {
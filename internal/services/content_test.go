@@ -0,0 +1,43 @@
+package services
+
+import (
+	"path/filepath"
+	"testing"
+
+	"portfolio-be/internal/models"
+	"portfolio-be/internal/repository"
+	"portfolio-be/internal/testutil"
+)
+
+func TestContentFiltersCombineWithTag(t *testing.T) {
+	db := testutil.OpenDB(t, filepath.Join(t.TempDir(), "contents.db"))
+	references := NewUploadReferenceService(repository.NewUploadReferenceRepository(db), repository.NewUploadRepository(db), repository.NewResourceRepository(db))
+	service := NewContentService(repository.NewContentRepository(db), references, NewTagService(repository.NewTagRepository(db)))
+
+	for _, req := range []models.ContentRequest{
+		{Title: "Published Go", Category: "blog", Tags: "golang", Status: models.ContentStatusPublished},
+		{Title: "Draft Go", Category: "blog", Tags: "golang", Status: "draft"},
+		{Title: "Published Go note", Category: "notes", Tags: "golang", Status: models.ContentStatusPublished},
+		{Title: "Published Rust", Category: "blog", Tags: "rust", Status: models.ContentStatusPublished},
+	} {
+		if _, err := service.CreateContent(req); err != nil {
+			t.Fatalf("failed to create content: %v", err)
+		}
+	}
+
+	contents, total, err := service.GetAllContent(models.ContentFilter{Category: "blog", Status: models.ContentStatusPublished, Tag: "Golang"}, 10, 0)
+	if err != nil {
+		t.Fatalf("failed to list contents: %v", err)
+	}
+	if total != 1 || len(contents) != 1 || contents[0].Title != "Published Go" {
+		t.Fatalf("expected only the published Go blog post, got %d of %d", len(contents), total)
+	}
+
+	contents, total, err = service.GetAllContent(models.ContentFilter{Tag: "golang"}, 1, 0)
+	if err != nil {
+		t.Fatalf("failed to list contents: %v", err)
+	}
+	if total != 3 || len(contents) != 1 {
+		t.Fatalf("expected a page of 1 out of 3 tagged contents, got %d of %d", len(contents), total)
+	}
+}
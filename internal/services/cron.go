@@ -2,120 +2,186 @@ package services
 
 import (
 	"context"
+	"fmt"
 	"log"
-	"time"
+	"portfolio-be/internal/config"
+	"portfolio-be/internal/models"
 )
 
-// CronService handles scheduled tasks
+// Names of the scheduled jobs
+const (
+	JobURLRefresh       = "url-refresh"
+	JobUploadCleanup    = "upload-cleanup"
+	JobUploadScan       = "upload-scan"
+	JobContactRetention = "contact-retention"
+)
+
+// CronService registers the scheduled maintenance jobs
 type CronService struct {
-	ctx             context.Context
-	cancel          context.CancelFunc
+	scheduler       *Scheduler
 	resourceService *ResourceService
 	uploadService   *UploadService
-	ticker          *time.Ticker
+	privacyService  *ContactPrivacyService
+	cleanupConfig   config.CleanupConfig
+	scanConfig      config.ScanConfig
+	jobsConfig      config.JobsConfig
 }
 
 // NewCronService creates a new cron service
-func NewCronService(resourceService *ResourceService, uploadService *UploadService) *CronService {
-	ctx, cancel := context.WithCancel(context.Background())
+func NewCronService(scheduler *Scheduler, resourceService *ResourceService, uploadService *UploadService, privacyService *ContactPrivacyService, cleanupConfig config.CleanupConfig, scanConfig config.ScanConfig, jobsConfig config.JobsConfig) *CronService {
 	return &CronService{
-		ctx:             ctx,
-		cancel:          cancel,
+		scheduler:       scheduler,
 		resourceService: resourceService,
 		uploadService:   uploadService,
+		privacyService:  privacyService,
+		cleanupConfig:   cleanupConfig,
+		scanConfig:      scanConfig,
+		jobsConfig:      jobsConfig,
+	}
+}
+
+// Register registers the jobs on their configured schedules
+func (cs *CronService) Register() error {
+	jobs := []struct {
+		name, description, schedule string
+		fn                          JobFunc
+	}{
+		{JobURLRefresh, "Extend the expiry of uploads used by resources that expire within 24 hours", cs.jobsConfig.URLRefreshSchedule, cs.refreshExpiredURLs},
+		{JobUploadCleanup, "Delete uploads expired or inactive past the grace period and not used by any resource", cs.jobsConfig.UploadCleanupSchedule, cs.cleanupExpiredUploadsJob},
+		{JobUploadScan, "Scan quarantined uploads and rescan clean ones due for a periodic rescan", cs.jobsConfig.UploadScanSchedule, cs.rescanUploadsJob},
+		{JobContactRetention, "Anonymize or delete contact messages older than the retention period", cs.jobsConfig.ContactRetentionSchedule, cs.privacyService.ApplyRetention},
+	}
+	for _, job := range jobs {
+		if err := cs.scheduler.Register(job.name, job.description, job.schedule, job.fn); err != nil {
+			return err
+		}
 	}
+	return nil
 }
 
-// Start starts the cron service with all scheduled jobs
+// Start starts running the registered jobs on their schedules
 func (cs *CronService) Start() error {
-	// Run jobs every hour for testing, change to 24 hours for production
-	cs.ticker = time.NewTicker(1 * time.Hour) // Change to 24 * time.Hour for daily execution
+	if err := cs.scheduler.Start(); err != nil {
+		return err
+	}
 
-	go cs.runScheduledJobs()
+	// Scan uploads left pending by a restart or stored before scanning was enabled
+	if _, err := cs.scheduler.Trigger(JobUploadScan, models.JobTriggerStartup); err != nil {
+		log.Printf("Failed to start upload scan job: %v", err)
+	}
 
 	log.Println("Cron service started successfully")
 	return nil
 }
 
-// Stop stops the cron service
-func (cs *CronService) Stop() {
-	if cs.ticker != nil {
-		cs.ticker.Stop()
-	}
-	cs.cancel()
-	log.Println("Cron service stopped")
+// Stop stops the scheduler and waits for running jobs
+func (cs *CronService) Stop(ctx context.Context) error {
+	return cs.scheduler.Stop(ctx)
 }
 
-// runScheduledJobs runs the scheduled jobs
-func (cs *CronService) runScheduledJobs() {
-	for {
-		select {
-		case <-cs.ctx.Done():
-			return
-		case <-cs.ticker.C:
-			cs.RefreshExpiredURLsJob()
-			cs.CleanupExpiredUploadsJob()
-		}
-	}
+// refreshExpiredURLs is the job that refreshes expired URLs
+func (cs *CronService) refreshExpiredURLs(ctx context.Context) (int64, error) {
+	refreshed, err := cs.resourceService.RefreshExpiredURLs(ctx)
+	return int64(refreshed), err
 }
 
-// RefreshExpiredURLsJob is the job that refreshes expired URLs
-func (cs *CronService) RefreshExpiredURLsJob() {
-	log.Println("Starting URL refresh job...")
+// cleanupExpiredUploadsJob is the job that cleans up truly expired uploads
+func (cs *CronService) cleanupExpiredUploadsJob(ctx context.Context) (int64, error) {
+	report, err := cs.cleanupExpiredUploads(ctx, false)
+	if err != nil {
+		return 0, err
+	}
+	return int64(report.Deleted), reportErrors(report.Failed, report.Errors)
+}
 
-	start := time.Now()
-	err := cs.resourceService.RefreshExpiredURLs()
+// cleanupExpiredUploads removes uploads that have been expired or inactive for longer
+// than the configured grace period and are not used by any resource
+func (cs *CronService) cleanupExpiredUploads(ctx context.Context, dryRun bool) (*models.CleanupReport, error) {
+	report, err := cs.uploadService.CleanupExpiredUploads(ctx, cs.cleanupConfig.GracePeriod, cs.cleanupConfig.BatchSize, dryRun)
 	if err != nil {
-		log.Printf("Error during URL refresh job: %v", err)
-		return
+		return nil, fmt.Errorf("failed to clean up expired uploads: %w", err)
 	}
 
-	duration := time.Since(start)
-	log.Printf("URL refresh job completed successfully in %v", duration)
+	log.Printf("Cleanup completed in %v (dry run: %v): scanned %d, deleted %d, skipped %d, failed %d, freed %s",
+		report.FinishedAt.Sub(report.StartedAt), dryRun, report.Scanned, report.Deleted, report.Skipped, report.Failed, report.BytesFreedFormatted)
+	return report, nil
 }
 
-// CleanupExpiredUploadsJob is the job that cleans up truly expired uploads
-func (cs *CronService) CleanupExpiredUploadsJob() {
-	log.Println("Starting cleanup expired uploads job...")
+// RunCleanupNow runs the expired upload cleanup immediately. In dry-run mode nothing
+// is deleted and the report lists what would be removed; otherwise the run is recorded
+// in the job history and refused while a scheduled cleanup is in progress.
+func (cs *CronService) RunCleanupNow(dryRun bool) (*models.CleanupReport, error) {
+	if dryRun {
+		return cs.cleanupExpiredUploads(context.Background(), true)
+	}
 
-	start := time.Now()
-	err := cs.cleanupExpiredUploads()
+	var report *models.CleanupReport
+	run, err := cs.scheduler.Run(JobUploadCleanup, func(ctx context.Context) (int64, error) {
+		var err error
+		report, err = cs.cleanupExpiredUploads(ctx, false)
+		if err != nil {
+			return 0, err
+		}
+		return int64(report.Deleted), reportErrors(report.Failed, report.Errors)
+	})
 	if err != nil {
-		log.Printf("Error during cleanup job: %v", err)
-		return
+		return nil, err
 	}
-
-	duration := time.Since(start)
-	log.Printf("Cleanup job completed successfully in %v", duration)
+	if report == nil {
+		return nil, fmt.Errorf("cleanup failed: %s", run.Error)
+	}
+	return report, nil
 }
 
-// cleanupExpiredUploads removes uploads that have been expired for more than 30 days
-func (cs *CronService) cleanupExpiredUploads() error {
-	// This is a placeholder for cleanup logic
-	// You might want to:
-	// 1. Find uploads expired for more than 30 days
-	// 2. Remove from S3
-	// 3. Mark as inactive in database
-	// 4. Optionally remove from database completely
+// rescanUploadsJob is the job that scans quarantined uploads and rescans clean ones
+// with the latest signatures
+func (cs *CronService) rescanUploadsJob(ctx context.Context) (int64, error) {
+	report, err := cs.rescanUploads(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return int64(report.Scanned), reportErrors(report.Failed, report.Errors)
+}
 
-	log.Println("Cleanup logic would run here...")
-	// Implementation would depend on your business requirements
+// rescanUploads scans uploads that are pending, failed or due for a periodic rescan
+func (cs *CronService) rescanUploads(ctx context.Context) (*models.ScanReport, error) {
+	report, err := cs.uploadService.RescanUploads(ctx, cs.scanConfig.BatchSize, cs.scanConfig.RescanAfter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to rescan uploads: %w", err)
+	}
 
-	return nil
+	log.Printf("Upload scan completed in %v: scanned %d, clean %d, infected %d, failed %d",
+		report.FinishedAt.Sub(report.StartedAt), report.Scanned, report.Clean, report.Infected, report.Failed)
+	return report, nil
 }
 
-// RunURLRefreshNow runs the URL refresh job immediately (for testing/manual trigger)
-func (cs *CronService) RunURLRefreshNow() error {
-	log.Println("Running URL refresh job manually...")
-	cs.RefreshExpiredURLsJob()
-	return nil
+// RunRescanNow runs the upload scan job immediately and records it in the job history
+func (cs *CronService) RunRescanNow() (*models.ScanReport, error) {
+	var report *models.ScanReport
+	run, err := cs.scheduler.Run(JobUploadScan, func(ctx context.Context) (int64, error) {
+		var err error
+		report, err = cs.rescanUploads(ctx)
+		if err != nil {
+			return 0, err
+		}
+		return int64(report.Scanned), reportErrors(report.Failed, report.Errors)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if report == nil {
+		return nil, fmt.Errorf("upload scan failed: %s", run.Error)
+	}
+	return report, nil
 }
 
-// GetStatus returns the current status of the cron service
-func (cs *CronService) GetStatus() map[string]interface{} {
-	return map[string]interface{}{
-		"running":   cs.ctx.Err() == nil,
-		"next_tick": time.Now().Add(1 * time.Hour), // Approximate next run time
-		"last_run":  time.Now(),                    // This should be tracked properly in production
+// reportErrors fails a job run whose report lists failed items
+func reportErrors(failed int, errs []string) error {
+	if failed == 0 {
+		return nil
+	}
+	for _, e := range errs {
+		log.Printf("Job error: %s", e)
 	}
+	return fmt.Errorf("%d items failed", failed)
 }
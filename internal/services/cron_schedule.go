@@ -0,0 +1,183 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidCronExpression is returned for schedules that do not parse
+var ErrInvalidCronExpression = errors.New("invalid cron expression")
+
+// cronDescriptors are the shorthand schedules accepted in place of five fields
+var cronDescriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// cronField is the set of values a cron field matches. any is set for fields starting
+// with *, such as * or */2, which count as unrestricted for the day-matching rule.
+type cronField struct {
+	values map[int]bool
+	any    bool
+}
+
+// CronSchedule is a parsed cron expression: five fields (minute, hour, day of month,
+// month, day of week) with *, lists, ranges and steps, a descriptor such as @daily,
+// or "@every <duration>"
+type CronSchedule struct {
+	expression string
+	every      time.Duration
+	minute     cronField
+	hour       cronField
+	dom        cronField
+	month      cronField
+	dow        cronField
+}
+
+// ParseCronSchedule parses a cron expression
+func ParseCronSchedule(expression string) (*CronSchedule, error) {
+	expression = strings.TrimSpace(expression)
+	schedule := &CronSchedule{expression: expression}
+
+	if rest, ok := strings.CutPrefix(expression, "@every "); ok {
+		every, err := time.ParseDuration(strings.TrimSpace(rest))
+		if err != nil || every < time.Second {
+			return nil, fmt.Errorf("%w %q: @every needs a duration of at least 1s", ErrInvalidCronExpression, expression)
+		}
+		schedule.every = every
+		return schedule, nil
+	}
+	if descriptor, ok := cronDescriptors[expression]; ok {
+		expression = descriptor
+	}
+
+	fields := strings.Fields(expression)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("%w %q: expected 5 fields", ErrInvalidCronExpression, schedule.expression)
+	}
+
+	bounds := [5][2]int{{0, 59}, {0, 23}, {1, 31}, {1, 12}, {0, 7}}
+	targets := []*cronField{&schedule.minute, &schedule.hour, &schedule.dom, &schedule.month, &schedule.dow}
+	for i, field := range fields {
+		parsed, err := parseCronField(field, bounds[i][0], bounds[i][1])
+		if err != nil {
+			return nil, fmt.Errorf("%w %q: %v", ErrInvalidCronExpression, schedule.expression, err)
+		}
+		*targets[i] = parsed
+	}
+
+	// Sunday may be written as 0 or 7
+	if schedule.dow.values[7] {
+		schedule.dow.values[0] = true
+	}
+	if schedule.Next(time.Now()).IsZero() {
+		return nil, fmt.Errorf("%w %q: never fires", ErrInvalidCronExpression, schedule.expression)
+	}
+	return schedule, nil
+}
+
+// parseCronField parses a comma-separated list of *, n, a-b, */s, a-b/s or n/s
+func parseCronField(field string, min, max int) (cronField, error) {
+	result := cronField{values: make(map[int]bool), any: strings.HasPrefix(field, "*")}
+
+	for _, part := range strings.Split(field, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			parsed, err := strconv.Atoi(stepPart)
+			if err != nil || parsed < 1 {
+				return result, fmt.Errorf("invalid step %q", part)
+			}
+			step = parsed
+		}
+
+		start, end := min, max
+		switch {
+		case rangePart == "*":
+		case strings.Contains(rangePart, "-"):
+			from, to, _ := strings.Cut(rangePart, "-")
+			var err1, err2 error
+			start, err1 = strconv.Atoi(from)
+			end, err2 = strconv.Atoi(to)
+			if err1 != nil || err2 != nil {
+				return result, fmt.Errorf("invalid range %q", part)
+			}
+		default:
+			value, err := strconv.Atoi(rangePart)
+			if err != nil {
+				return result, fmt.Errorf("invalid value %q", part)
+			}
+			start = value
+			if !hasStep {
+				end = value
+			}
+		}
+
+		if start < min || end > max || start > end {
+			return result, fmt.Errorf("%q is outside %d-%d", part, min, max)
+		}
+		for value := start; value <= end; value += step {
+			result.values[value] = true
+		}
+	}
+
+	return result, nil
+}
+
+// String returns the expression the schedule was parsed from
+func (s *CronSchedule) String() string {
+	return s.expression
+}
+
+// Next returns the first time after t that the schedule fires
+func (s *CronSchedule) Next(t time.Time) time.Time {
+	if s.every > 0 {
+		// Align to multiples of the interval so every instance computes the same times
+		return t.Truncate(s.every).Add(s.every)
+	}
+
+	next := t.Truncate(time.Minute).Add(time.Minute)
+	// Every valid expression fires within a few years; stop after 5 in case of
+	// impossible dates such as 31 February
+	limit := next.AddDate(5, 0, 0)
+	for next.Before(limit) {
+		if !s.month.values[int(next.Month())] {
+			next = time.Date(next.Year(), next.Month()+1, 1, 0, 0, 0, 0, next.Location())
+			continue
+		}
+		if !s.matchesDay(next) {
+			next = time.Date(next.Year(), next.Month(), next.Day()+1, 0, 0, 0, 0, next.Location())
+			continue
+		}
+		if !s.hour.values[next.Hour()] {
+			next = time.Date(next.Year(), next.Month(), next.Day(), next.Hour()+1, 0, 0, 0, next.Location())
+			continue
+		}
+		if !s.minute.values[next.Minute()] {
+			next = next.Add(time.Minute)
+			continue
+		}
+		return next
+	}
+	return time.Time{}
+}
+
+// matchesDay applies the cron rule that when both day fields are restricted, a day
+// matching either one fires. As in Vixie cron, a field starting with * is not
+// restricted, so then a day must match both.
+func (s *CronSchedule) matchesDay(t time.Time) bool {
+	dom := s.dom.values[t.Day()]
+	dow := s.dow.values[int(t.Weekday())]
+	if s.dom.any || s.dow.any {
+		return dom && dow
+	}
+	return dom || dow
+}
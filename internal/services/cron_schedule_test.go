@@ -0,0 +1,88 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseCronScheduleRejectsInvalidExpressions(t *testing.T) {
+	for _, expression := range []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"a * * * *",
+		"1-x * * * *",
+		"0 0 31 2 *",
+		"0 0 30 2 *",
+		"@fortnightly",
+		"@every 500ms",
+		"@every soon",
+	} {
+		if _, err := ParseCronSchedule(expression); !errors.Is(err, ErrInvalidCronExpression) {
+			t.Errorf("%q: expected ErrInvalidCronExpression, got %v", expression, err)
+		}
+	}
+}
+
+func TestCronScheduleNext(t *testing.T) {
+	// Thursday 1 October 2026
+	at := func(month time.Month, day, hour, minute int) time.Time {
+		return time.Date(2026, month, day, hour, minute, 0, 0, time.UTC)
+	}
+	from := at(time.October, 1, 10, 7)
+
+	tests := []struct {
+		name       string
+		expression string
+		from       time.Time
+		want       time.Time
+	}{
+		{"every minute", "* * * * *", from, at(time.October, 1, 10, 8)},
+		{"value", "30 * * * *", from, at(time.October, 1, 10, 30)},
+		{"step", "*/15 * * * *", from, at(time.October, 1, 10, 15)},
+		{"step from value", "5/20 * * * *", from, at(time.October, 1, 10, 25)},
+		{"list", "0,45 * * * *", from, at(time.October, 1, 10, 45)},
+		{"range", "0 12-14 * * *", from, at(time.October, 1, 12, 0)},
+		{"range with step", "0 9-17/4 * * *", from, at(time.October, 1, 13, 0)},
+		{"list of ranges", "0 1-2,20-21 * * *", from, at(time.October, 1, 20, 0)},
+		{"month", "0 0 1 3 *", from, time.Date(2027, time.March, 1, 0, 0, 0, 0, time.UTC)},
+		{"leap day", "0 0 29 2 *", from, time.Date(2028, time.February, 29, 0, 0, 0, 0, time.UTC)},
+		{"hourly", "@hourly", from, at(time.October, 1, 11, 0)},
+		{"daily", "@daily", from, at(time.October, 2, 0, 0)},
+		{"midnight", "@midnight", from, at(time.October, 2, 0, 0)},
+		{"weekly", "@weekly", from, at(time.October, 4, 0, 0)},
+		{"monthly", "@monthly", from, at(time.November, 1, 0, 0)},
+		{"yearly", "@yearly", from, time.Date(2027, time.January, 1, 0, 0, 0, 0, time.UTC)},
+		{"annually", "@annually", from, time.Date(2027, time.January, 1, 0, 0, 0, 0, time.UTC)},
+		{"sunday as 0", "0 0 * * 0", from, at(time.October, 4, 0, 0)},
+		{"sunday as 7", "0 0 * * 7", from, at(time.October, 4, 0, 0)},
+		{"weekday range through 7", "0 0 * * 5-7", from, at(time.October, 2, 0, 0)},
+		// Both day fields restricted: the 13th or any Friday
+		{"day of month or week, week first", "0 0 13 * 5", from, at(time.October, 2, 0, 0)},
+		{"day of month or week, month first", "0 0 13 * 5", at(time.October, 10, 0, 0), at(time.October, 13, 0, 0)},
+		// A day field starting with * is unrestricted: odd days that are Mondays
+		{"stepped day of month and week", "0 0 */2 * 1", from, at(time.October, 5, 0, 0)},
+		{"stepped day of month and week, later", "0 0 */2 * 1", at(time.October, 6, 0, 0), at(time.October, 19, 0, 0)},
+		{"day of month and stepped week", "0 0 10 * */3", from, at(time.October, 10, 0, 0)},
+		{"every", "@every 90s", at(time.October, 1, 10, 7).Add(10 * time.Second), at(time.October, 1, 10, 7).Add(30 * time.Second)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule, err := ParseCronSchedule(tt.expression)
+			if err != nil {
+				t.Fatalf("failed to parse %q: %v", tt.expression, err)
+			}
+			if got := schedule.Next(tt.from); !got.Equal(tt.want) {
+				t.Errorf("%q after %s: expected %s, got %s", tt.expression, tt.from.Format(time.RFC1123), tt.want.Format(time.RFC1123), got.Format(time.RFC1123))
+			}
+		})
+	}
+}
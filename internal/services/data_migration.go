@@ -0,0 +1,65 @@
+package services
+
+import (
+	"log"
+	"portfolio-be/internal/repository"
+	"time"
+)
+
+// dataMigrationStaleAfter is how long a migration claimed by an instance that never
+// finished it blocks the others from running it
+const dataMigrationStaleAfter = time.Hour
+
+// dataMigration is a one-time rewrite of existing rows, returning how many it changed
+type dataMigration struct {
+	name string
+	run  func() (int, error)
+}
+
+// DataMigrationService runs one-time data migrations that need the services rather than
+// the schema, such as backfills of new indexes. Each migration runs once: the instance
+// that claims it runs it and the others skip it. A failed migration runs again on the
+// next start.
+type DataMigrationService struct {
+	repo       *repository.DataMigrationRepository
+	instance   string
+	migrations []dataMigration
+}
+
+func NewDataMigrationService(repo *repository.DataMigrationRepository, instance string) *DataMigrationService {
+	return &DataMigrationService{repo: repo, instance: instance}
+}
+
+// Register adds a migration; migrations run in registration order
+func (s *DataMigrationService) Register(name string, run func() (int, error)) {
+	s.migrations = append(s.migrations, dataMigration{name: name, run: run})
+}
+
+// Run runs the migrations that have not run yet. Failures are logged rather than
+// returned, and the failed migration runs again on the next start.
+func (s *DataMigrationService) Run() {
+	for _, m := range s.migrations {
+		claimed, err := s.repo.Claim(m.name, s.instance, time.Now().Add(-dataMigrationStaleAfter))
+		if err != nil {
+			log.Printf("Failed to claim data migration %s: %v", m.name, err)
+			continue
+		}
+		if !claimed {
+			continue
+		}
+
+		items, err := m.run()
+		if err != nil {
+			log.Printf("Data migration %s failed after %d rows: %v", m.name, items, err)
+			if err := s.repo.Release(m.name); err != nil {
+				log.Printf("Failed to release data migration %s: %v", m.name, err)
+			}
+			continue
+		}
+		if err := s.repo.Finish(m.name, items); err != nil {
+			log.Printf("Failed to record data migration %s: %v", m.name, err)
+			continue
+		}
+		log.Printf("Data migration %s updated %d rows", m.name, items)
+	}
+}
@@ -0,0 +1,48 @@
+package services
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"portfolio-be/internal/repository"
+	"portfolio-be/internal/testutil"
+)
+
+func TestDataMigrationsRunOnceAcrossInstances(t *testing.T) {
+	repo := repository.NewDataMigrationRepository(testutil.OpenDB(t, filepath.Join(t.TempDir(), "migrations.db")))
+
+	runs := map[string]int{}
+	failing := true
+	newInstance := func(instance string) *DataMigrationService {
+		s := NewDataMigrationService(repo, instance)
+		s.Register("backfill", func() (int, error) {
+			runs["backfill"]++
+			return 3, nil
+		})
+		s.Register("flaky", func() (int, error) {
+			runs["flaky"]++
+			if failing {
+				return 0, errors.New("database is locked")
+			}
+			return 1, nil
+		})
+		return s
+	}
+
+	newInstance("api-1").Run()
+	newInstance("api-2").Run()
+	if runs["backfill"] != 1 {
+		t.Fatalf("expected the migration to run once across instances, ran %d times", runs["backfill"])
+	}
+	if runs["flaky"] != 2 {
+		t.Fatalf("expected a failed migration to run again, ran %d times", runs["flaky"])
+	}
+
+	failing = false
+	newInstance("api-1").Run()
+	newInstance("api-2").Run()
+	if runs["backfill"] != 1 || runs["flaky"] != 3 {
+		t.Fatalf("expected finished migrations not to run again, got %v", runs)
+	}
+}
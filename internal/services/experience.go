@@ -19,10 +19,11 @@ type ExperienceService interface {
 
 type experienceService struct {
 	experienceRepo repository.ExperienceRepository
+	references     *UploadReferenceService
 }
 
-func NewExperienceService(experienceRepo repository.ExperienceRepository) ExperienceService {
-	return &experienceService{experienceRepo: experienceRepo}
+func NewExperienceService(experienceRepo repository.ExperienceRepository, references *UploadReferenceService) ExperienceService {
+	return &experienceService{experienceRepo: experienceRepo, references: references}
 }
 
 func (s *experienceService) CreateExperience(request *models.ExperienceRequest) (*models.ExperienceResponse, error) {
@@ -48,6 +49,10 @@ func (s *experienceService) CreateExperience(request *models.ExperienceRequest)
 		return nil, err
 	}
 
+	if err := s.syncReferences(experience); err != nil {
+		return nil, err
+	}
+
 	response := s.convertToResponse(experience)
 	return &response, nil
 }
@@ -102,12 +107,24 @@ func (s *experienceService) UpdateExperience(id uint, request *models.Experience
 		return nil, err
 	}
 
+	if err := s.syncReferences(experience); err != nil {
+		return nil, err
+	}
+
 	response := s.convertToResponse(experience)
 	return &response, nil
 }
 
 func (s *experienceService) DeleteExperience(id uint) error {
-	return s.experienceRepo.Delete(id)
+	if err := s.experienceRepo.Delete(id); err != nil {
+		return err
+	}
+	return s.references.RemoveEntity(models.ReferenceEntityExperience, id)
+}
+
+// syncReferences indexes the uploads used by an experience
+func (s *experienceService) syncReferences(experience *models.Experience) error {
+	return s.references.SyncEntity(models.ReferenceEntityExperience, experience.ID, map[string]string{"icon": experience.Icon})
 }
 
 func (s *experienceService) GetActiveExperiences() ([]models.ExperienceResponse, error) {
@@ -0,0 +1,96 @@
+package services
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sort"
+	"strings"
+)
+
+// geoIPRange maps an inclusive IP range to an ISO country code
+type geoIPRange struct {
+	start   net.IP
+	end     net.IP
+	country string
+}
+
+// GeoIPDatabase resolves IP addresses to countries from a locally loaded
+// IP-to-country CSV with start_ip,end_ip,country_code rows, such as the free
+// DB-IP or IP2Location LITE country databases
+type GeoIPDatabase struct {
+	ranges []geoIPRange
+}
+
+// LoadGeoIPDatabase reads an IP-to-country CSV. Rows that do not parse are skipped.
+func LoadGeoIPDatabase(path string) (*GeoIPDatabase, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open GeoIP database: %w", err)
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	reader.ReuseRecord = true
+
+	db := &GeoIPDatabase{}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read GeoIP database: %w", err)
+		}
+		if len(record) < 3 {
+			continue
+		}
+
+		start := net.ParseIP(strings.TrimSpace(record[0]))
+		end := net.ParseIP(strings.TrimSpace(record[1]))
+		country := strings.ToUpper(strings.TrimSpace(record[2]))
+		if start == nil || end == nil || country == "" || country == "-" {
+			continue
+		}
+		db.ranges = append(db.ranges, geoIPRange{start: start.To16(), end: end.To16(), country: country})
+	}
+
+	sort.Slice(db.ranges, func(i, j int) bool {
+		return bytes.Compare(db.ranges[i].start, db.ranges[j].start) < 0
+	})
+
+	return db, nil
+}
+
+// Country returns the country code of an IP address, or an empty string when unknown
+func (g *GeoIPDatabase) Country(ip string) string {
+	if g == nil {
+		return ""
+	}
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return ""
+	}
+	addr := parsed.To16()
+
+	// Find the last range starting at or before the address
+	i := sort.Search(len(g.ranges), func(i int) bool {
+		return bytes.Compare(g.ranges[i].start, addr) > 0
+	}) - 1
+	if i < 0 || bytes.Compare(addr, g.ranges[i].end) > 0 {
+		return ""
+	}
+	return g.ranges[i].country
+}
+
+// Size returns the number of IP ranges loaded
+func (g *GeoIPDatabase) Size() int {
+	if g == nil {
+		return 0
+	}
+	return len(g.ranges)
+}
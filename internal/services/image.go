@@ -0,0 +1,497 @@
+package services
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+)
+
+// ErrImageTooLarge is returned for images with more pixels than uploads accept, which
+// would take too much memory to decode
+var ErrImageTooLarge = errors.New("image dimensions exceed the maximum")
+
+// ImageMetadata holds the display metadata extracted from an uploaded image
+type ImageMetadata struct {
+	Width         int
+	Height        int
+	DominantColor string
+	BlurHash      string
+}
+
+// ProcessImage strips EXIF/XMP metadata from an image, normalizes its orientation
+// and extracts width, height, dominant color and a blurhash placeholder.
+// WebP images only get their width and height. Formats that cannot be decoded (e.g.
+// SVG) are returned unchanged with nil metadata.
+// Images with more than maxPixels pixels are rejected from their header before they are
+// decoded; zero disables the limit.
+func ProcessImage(data []byte, contentType string, maxPixels int64) ([]byte, *ImageMetadata, error) {
+	var (
+		cleaned []byte
+		img     image.Image
+		err     error
+	)
+
+	switch contentType {
+	case "image/jpeg", "image/jpg", "image/png", "image/gif":
+		if err := checkImageSize(data, maxPixels); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	switch contentType {
+	case "image/jpeg", "image/jpg":
+		cleaned, img, err = processJPEG(data)
+	case "image/png":
+		cleaned, err = stripPNGMetadata(data)
+		if err == nil {
+			img, err = png.Decode(bytes.NewReader(cleaned))
+		}
+	case "image/gif":
+		// GIF carries no EXIF data; only decode it for metadata
+		cleaned = data
+		img, err = gif.Decode(bytes.NewReader(data))
+	case "image/webp":
+		// WebP cannot be decoded with the standard library, so its dimensions are read
+		// from the header and no dominant color or blurhash is extracted
+		width, height, err := webpSize(data)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to process image: %w", err)
+		}
+		if err := checkPixels(width, height, maxPixels); err != nil {
+			return nil, nil, err
+		}
+		cleaned, err = stripWebPMetadata(data)
+		if err != nil {
+			return nil, nil, err
+		}
+		return cleaned, &ImageMetadata{Width: width, Height: height}, nil
+	default:
+		return data, nil, nil
+	}
+
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to process image: %w", err)
+	}
+
+	bounds := img.Bounds()
+	metadata := &ImageMetadata{
+		Width:         bounds.Dx(),
+		Height:        bounds.Dy(),
+		DominantColor: dominantColor(img),
+		BlurHash:      encodeBlurHash(img, 4, 3),
+	}
+
+	return cleaned, metadata, nil
+}
+
+// checkImageSize reads the dimensions an image declares in its header and rejects it
+// when it has more than maxPixels pixels
+func checkImageSize(data []byte, maxPixels int64) error {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to process image: %w", err)
+	}
+	return checkPixels(cfg.Width, cfg.Height, maxPixels)
+}
+
+// checkPixels rejects dimensions with more than maxPixels pixels; zero disables the limit
+func checkPixels(width, height int, maxPixels int64) error {
+	if maxPixels > 0 && int64(width)*int64(height) > maxPixels {
+		return fmt.Errorf("%w: %dx%d is more than %d pixels", ErrImageTooLarge, width, height, maxPixels)
+	}
+	return nil
+}
+
+// processJPEG removes metadata segments from a JPEG. When the EXIF orientation
+// requires it, the image is re-encoded with the rotation applied.
+func processJPEG(data []byte) ([]byte, image.Image, error) {
+	orientation := jpegOrientation(data)
+
+	if orientation > 1 && orientation <= 8 {
+		img, err := jpeg.Decode(bytes.NewReader(data))
+		if err != nil {
+			return nil, nil, err
+		}
+
+		img = applyOrientation(img, orientation)
+
+		// Re-encoding drops every APPn segment, so the output is already clean apart from
+		// the ICC profile, which is copied over so colors render as before
+		var buf bytes.Buffer
+		buf.Write(data[:2])
+		for _, segment := range jpegICCSegments(data) {
+			buf.Write(segment)
+		}
+		var encoded bytes.Buffer
+		if err := jpeg.Encode(&encoded, img, &jpeg.Options{Quality: 90}); err != nil {
+			return nil, nil, err
+		}
+		buf.Write(encoded.Bytes()[2:])
+		return buf.Bytes(), img, nil
+	}
+
+	cleaned, err := stripJPEGMetadata(data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	img, err := jpeg.Decode(bytes.NewReader(cleaned))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return cleaned, img, nil
+}
+
+// stripJPEGMetadata copies a JPEG without its EXIF/XMP (APP1), IPTC (APP13) and comment segments.
+// The ICC profile (APP2) is kept so colors render correctly.
+func stripJPEGMetadata(data []byte) ([]byte, error) {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return nil, fmt.Errorf("invalid JPEG header")
+	}
+
+	out := bytes.NewBuffer(make([]byte, 0, len(data)))
+	out.Write(data[:2])
+
+	pos := 2
+	for pos+4 <= len(data) {
+		if data[pos] != 0xFF {
+			return nil, fmt.Errorf("invalid JPEG marker at offset %d", pos)
+		}
+		marker := data[pos+1]
+
+		// Start of scan: the rest of the file is entropy-coded image data
+		if marker == 0xDA {
+			out.Write(data[pos:])
+			return out.Bytes(), nil
+		}
+
+		length := int(binary.BigEndian.Uint16(data[pos+2 : pos+4]))
+		end := pos + 2 + length
+		if length < 2 || end > len(data) {
+			return nil, fmt.Errorf("truncated JPEG segment at offset %d", pos)
+		}
+
+		switch marker {
+		case 0xE1, 0xED, 0xFE:
+			// Skip APP1 (EXIF/XMP), APP13 (IPTC) and COM segments
+		default:
+			out.Write(data[pos:end])
+		}
+
+		pos = end
+	}
+
+	return nil, fmt.Errorf("JPEG has no image data")
+}
+
+// jpegICCSegments returns the APP2 segments of a JPEG that hold its ICC profile,
+// including their markers, in file order
+func jpegICCSegments(data []byte) [][]byte {
+	var segments [][]byte
+
+	pos := 2
+	for pos+4 <= len(data) && data[pos] == 0xFF {
+		marker := data[pos+1]
+		if marker == 0xDA {
+			break
+		}
+
+		length := int(binary.BigEndian.Uint16(data[pos+2 : pos+4]))
+		end := pos + 2 + length
+		if length < 2 || end > len(data) {
+			break
+		}
+
+		if marker == 0xE2 && bytes.HasPrefix(data[pos+4:end], []byte("ICC_PROFILE\x00")) {
+			segments = append(segments, data[pos:end])
+		}
+
+		pos = end
+	}
+
+	return segments
+}
+
+// jpegOrientation reads the EXIF orientation tag from a JPEG, returning 1 when absent
+func jpegOrientation(data []byte) int {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return 1
+	}
+
+	pos := 2
+	for pos+4 <= len(data) && data[pos] == 0xFF {
+		marker := data[pos+1]
+		if marker == 0xDA {
+			break
+		}
+
+		length := int(binary.BigEndian.Uint16(data[pos+2 : pos+4]))
+		end := pos + 2 + length
+		if length < 2 || end > len(data) {
+			break
+		}
+
+		segment := data[pos+4 : end]
+		if marker == 0xE1 && len(segment) > 6 && string(segment[:6]) == "Exif\x00\x00" {
+			return exifOrientation(segment[6:])
+		}
+
+		pos = end
+	}
+
+	return 1
+}
+
+// exifOrientation parses a TIFF header and looks up tag 0x0112 in IFD0
+func exifOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+
+	offset := int(order.Uint32(tiff[4:8]))
+	if offset+2 > len(tiff) {
+		return 1
+	}
+
+	entries := int(order.Uint16(tiff[offset : offset+2]))
+	for i := 0; i < entries; i++ {
+		entry := offset + 2 + i*12
+		if entry+12 > len(tiff) {
+			break
+		}
+		if order.Uint16(tiff[entry:entry+2]) == 0x0112 {
+			return int(order.Uint16(tiff[entry+8 : entry+10]))
+		}
+	}
+
+	return 1
+}
+
+// applyOrientation transforms an image so that it displays upright for the given EXIF orientation
+func applyOrientation(src image.Image, orientation int) image.Image {
+	bounds := src.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+
+	dstW, dstH := w, h
+	if orientation >= 5 {
+		dstW, dstH = h, w
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dstW, dstH))
+
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2:
+				dx, dy = w-1-x, y
+			case 3:
+				dx, dy = w-1-x, h-1-y
+			case 4:
+				dx, dy = x, h-1-y
+			case 5:
+				dx, dy = y, x
+			case 6:
+				dx, dy = h-1-y, x
+			case 7:
+				dx, dy = h-1-y, w-1-x
+			case 8:
+				dx, dy = y, w-1-x
+			default:
+				dx, dy = x, y
+			}
+			dst.Set(dx, dy, src.At(bounds.Min.X+x, bounds.Min.Y+y))
+		}
+	}
+
+	return dst
+}
+
+// stripPNGMetadata removes textual, timestamp and EXIF chunks from a PNG
+func stripPNGMetadata(data []byte) ([]byte, error) {
+	signature := []byte("\x89PNG\r\n\x1a\n")
+	if !bytes.HasPrefix(data, signature) {
+		return nil, fmt.Errorf("invalid PNG header")
+	}
+
+	out := bytes.NewBuffer(make([]byte, 0, len(data)))
+	out.Write(signature)
+
+	pos := len(signature)
+	for pos+8 <= len(data) {
+		length := int(binary.BigEndian.Uint32(data[pos : pos+4]))
+		chunkType := string(data[pos+4 : pos+8])
+		end := pos + 12 + length
+		if end > len(data) {
+			return nil, fmt.Errorf("truncated PNG chunk at offset %d", pos)
+		}
+
+		switch chunkType {
+		case "eXIf", "tEXt", "zTXt", "iTXt", "tIME":
+			// Drop metadata chunks
+		default:
+			out.Write(data[pos:end])
+		}
+
+		pos = end
+		if chunkType == "IEND" {
+			break
+		}
+	}
+
+	return out.Bytes(), nil
+}
+
+// stripWebPMetadata removes EXIF and XMP chunks from an extended (VP8X) WebP file
+func stripWebPMetadata(data []byte) ([]byte, error) {
+	if len(data) < 12 || string(data[:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
+		return nil, fmt.Errorf("invalid WebP header")
+	}
+
+	out := bytes.NewBuffer(make([]byte, 0, len(data)))
+	out.Write(data[:12])
+
+	pos := 12
+	for pos+8 <= len(data) {
+		chunkType := string(data[pos : pos+4])
+		size := int(binary.LittleEndian.Uint32(data[pos+4 : pos+8]))
+		end := pos + 8 + size + size%2
+		if end > len(data) {
+			return nil, fmt.Errorf("truncated WebP chunk at offset %d", pos)
+		}
+
+		switch chunkType {
+		case "EXIF", "XMP ":
+			// Drop metadata chunks
+		case "VP8X":
+			chunk := append([]byte(nil), data[pos:end]...)
+			if len(chunk) > 8 {
+				// Clear the EXIF (0x08) and XMP (0x04) presence flags
+				chunk[8] &^= 0x08 | 0x04
+			}
+			out.Write(chunk)
+		default:
+			out.Write(data[pos:end])
+		}
+
+		pos = end
+	}
+
+	result := out.Bytes()
+	binary.LittleEndian.PutUint32(result[4:8], uint32(len(result)-8))
+	return result, nil
+}
+
+// webpSize reads the canvas size of a WebP image from its VP8X header, or from the
+// frame header of a simple lossy (VP8) or lossless (VP8L) image
+func webpSize(data []byte) (int, int, error) {
+	if len(data) < 12 || string(data[:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
+		return 0, 0, fmt.Errorf("invalid WebP header")
+	}
+
+	pos := 12
+	for pos+8 <= len(data) {
+		chunkType := string(data[pos : pos+4])
+		size := int(binary.LittleEndian.Uint32(data[pos+4 : pos+8]))
+		payload := data[pos+8:]
+		if size > len(payload) {
+			return 0, 0, fmt.Errorf("truncated WebP chunk at offset %d", pos)
+		}
+		payload = payload[:size]
+
+		switch chunkType {
+		case "VP8X":
+			if len(payload) < 10 {
+				return 0, 0, fmt.Errorf("invalid WebP VP8X chunk")
+			}
+			width := int(payload[4]) | int(payload[5])<<8 | int(payload[6])<<16
+			height := int(payload[7]) | int(payload[8])<<8 | int(payload[9])<<16
+			return width + 1, height + 1, nil
+		case "VP8 ":
+			// Frame tag (3 bytes), start code 9d 01 2a, then 14-bit width and height
+			if len(payload) < 10 || payload[3] != 0x9d || payload[4] != 0x01 || payload[5] != 0x2a {
+				return 0, 0, fmt.Errorf("invalid WebP VP8 frame header")
+			}
+			width := int(binary.LittleEndian.Uint16(payload[6:8]) & 0x3fff)
+			height := int(binary.LittleEndian.Uint16(payload[8:10]) & 0x3fff)
+			return width, height, nil
+		case "VP8L":
+			// Signature 0x2f, then 14-bit width-1 and height-1
+			if len(payload) < 5 || payload[0] != 0x2f {
+				return 0, 0, fmt.Errorf("invalid WebP VP8L header")
+			}
+			bits := binary.LittleEndian.Uint32(payload[1:5])
+			return int(bits&0x3fff) + 1, int(bits>>14&0x3fff) + 1, nil
+		}
+
+		pos += 8 + size + size%2
+	}
+
+	return 0, 0, fmt.Errorf("WebP image has no VP8X, VP8 or VP8L chunk")
+}
+
+// dominantColor returns the most frequent color of an image as a hex string.
+// Colors are bucketed to 4 bits per channel and the image is sampled on a grid of at most 100x100.
+func dominantColor(img image.Image) string {
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	if w == 0 || h == 0 {
+		return ""
+	}
+
+	type bucket struct {
+		count   int
+		r, g, b int
+	}
+	buckets := make(map[int]*bucket)
+
+	stepX := max(w/100, 1)
+	stepY := max(h/100, 1)
+
+	var best *bucket
+	for y := bounds.Min.Y; y < bounds.Max.Y; y += stepY {
+		for x := bounds.Min.X; x < bounds.Max.X; x += stepX {
+			c := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+			if c.A < 128 {
+				continue
+			}
+
+			key := int(c.R>>4)<<8 | int(c.G>>4)<<4 | int(c.B>>4)
+			b, ok := buckets[key]
+			if !ok {
+				b = &bucket{}
+				buckets[key] = b
+			}
+			b.count++
+			b.r += int(c.R)
+			b.g += int(c.G)
+			b.b += int(c.B)
+
+			if best == nil || b.count > best.count {
+				best = b
+			}
+		}
+	}
+
+	if best == nil {
+		return ""
+	}
+
+	return fmt.Sprintf("#%02x%02x%02x", best.r/best.count, best.g/best.count, best.b/best.count)
+}
@@ -0,0 +1,178 @@
+package services
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+// encodeTestPNG encodes a small opaque PNG
+func encodeTestPNG(t *testing.T, width, height int) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for x := 0; x < width; x++ {
+		for y := 0; y < height; y++ {
+			img.Set(x, y, color.RGBA{R: 200, G: 40, B: 40, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("failed to encode PNG: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestProcessImageRejectsOversizedHeader(t *testing.T) {
+	// A tiny PNG whose IHDR chunk claims 50000x50000 pixels; decoding it would
+	// allocate about 10 GB
+	data := encodeTestPNG(t, 1, 1)
+	binary.BigEndian.PutUint32(data[16:20], 50000)
+	binary.BigEndian.PutUint32(data[20:24], 50000)
+	binary.BigEndian.PutUint32(data[29:33], crc32.ChecksumIEEE(data[12:29]))
+	if len(data) > 100 {
+		t.Fatalf("expected a tiny file, got %d bytes", len(data))
+	}
+
+	_, metadata, err := ProcessImage(data, "image/png", 50_000_000)
+	if !errors.Is(err, ErrImageTooLarge) {
+		t.Fatalf("expected ErrImageTooLarge, got %v", err)
+	}
+	if metadata != nil {
+		t.Errorf("expected no metadata, got %+v", metadata)
+	}
+}
+
+func TestProcessImageAcceptsImageWithinLimit(t *testing.T) {
+	data := encodeTestPNG(t, 8, 4)
+
+	_, metadata, err := ProcessImage(data, "image/png", 32)
+	if err != nil {
+		t.Fatalf("failed to process image: %v", err)
+	}
+	if metadata.Width != 8 || metadata.Height != 4 {
+		t.Errorf("expected 8x4, got %dx%d", metadata.Width, metadata.Height)
+	}
+
+	if _, _, err := ProcessImage(data, "image/png", 31); !errors.Is(err, ErrImageTooLarge) {
+		t.Errorf("expected one pixel over the limit to be rejected, got %v", err)
+	}
+}
+
+// jpegSegment builds a JPEG marker segment holding payload
+func jpegSegment(marker byte, payload []byte) []byte {
+	segment := []byte{0xFF, marker, 0, 0}
+	binary.BigEndian.PutUint16(segment[2:], uint16(len(payload)+2))
+	return append(segment, payload...)
+}
+
+func TestProcessImageKeepsICCProfileOfRotatedJPEG(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 8, 4))
+	var encoded bytes.Buffer
+	if err := jpeg.Encode(&encoded, img, nil); err != nil {
+		t.Fatalf("failed to encode JPEG: %v", err)
+	}
+
+	// A big-endian EXIF block with a single orientation entry of 6 (rotate 90 degrees)
+	exif := []byte("Exif\x00\x00MM\x00\x2a\x00\x00\x00\x08\x00\x01\x01\x12\x00\x03\x00\x00\x00\x01\x00\x06\x00\x00\x00\x00\x00\x00\x00\x00")
+	icc := jpegSegment(0xE2, []byte("ICC_PROFILE\x00\x01\x01test profile"))
+	data := append([]byte{0xFF, 0xD8}, jpegSegment(0xE1, exif)...)
+	data = append(data, icc...)
+	data = append(data, encoded.Bytes()[2:]...)
+
+	cleaned, metadata, err := ProcessImage(data, "image/jpeg", 0)
+	if err != nil {
+		t.Fatalf("failed to process image: %v", err)
+	}
+	if metadata.Width != 4 || metadata.Height != 8 {
+		t.Errorf("expected the rotated image to be 4x8, got %dx%d", metadata.Width, metadata.Height)
+	}
+	if !bytes.Contains(cleaned, icc) {
+		t.Error("expected the ICC profile to be kept")
+	}
+	if bytes.Contains(cleaned, []byte("Exif")) {
+		t.Error("expected the EXIF data to be stripped")
+	}
+	if _, err := jpeg.Decode(bytes.NewReader(cleaned)); err != nil {
+		t.Errorf("failed to decode the processed image: %v", err)
+	}
+}
+
+// buildTestWebP assembles a RIFF/WEBP container from chunks
+func buildTestWebP(chunks ...[]byte) []byte {
+	var body bytes.Buffer
+	body.WriteString("WEBP")
+	for _, chunk := range chunks {
+		body.Write(chunk)
+	}
+	var buf bytes.Buffer
+	buf.WriteString("RIFF")
+	binary.Write(&buf, binary.LittleEndian, uint32(body.Len()))
+	buf.Write(body.Bytes())
+	return buf.Bytes()
+}
+
+// webpChunk encodes a WebP chunk, padded to an even length
+func webpChunk(chunkType string, payload []byte) []byte {
+	var buf bytes.Buffer
+	buf.WriteString(chunkType)
+	binary.Write(&buf, binary.LittleEndian, uint32(len(payload)))
+	buf.Write(payload)
+	if len(payload)%2 == 1 {
+		buf.WriteByte(0)
+	}
+	return buf.Bytes()
+}
+
+func TestProcessImageReadsWebPDimensions(t *testing.T) {
+	// Lossless 640x480: signature, then 14-bit width-1 and height-1
+	vp8l := make([]byte, 5)
+	vp8l[0] = 0x2f
+	binary.LittleEndian.PutUint32(vp8l[1:], uint32(639)|uint32(479)<<14)
+	// Lossy 320x200: frame tag, start code, 14-bit width and height
+	vp8 := []byte{0, 0, 0, 0x9d, 0x01, 0x2a, 0, 0, 0, 0}
+	binary.LittleEndian.PutUint16(vp8[6:], 320)
+	binary.LittleEndian.PutUint16(vp8[8:], 200)
+	// Extended 1024x768 canvas with the EXIF flag set
+	vp8x := []byte{0x08, 0, 0, 0, 0xff, 0x03, 0, 0xff, 0x02, 0}
+
+	tests := []struct {
+		name          string
+		data          []byte
+		width, height int
+	}{
+		{"lossless", buildTestWebP(webpChunk("VP8L", vp8l)), 640, 480},
+		{"lossy", buildTestWebP(webpChunk("VP8 ", vp8)), 320, 200},
+		{"extended", buildTestWebP(webpChunk("VP8X", vp8x), webpChunk("VP8L", vp8l), webpChunk("EXIF", []byte("GPS"))), 1024, 768},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cleaned, metadata, err := ProcessImage(tt.data, "image/webp", 50_000_000)
+			if err != nil {
+				t.Fatalf("failed to process image: %v", err)
+			}
+			if metadata == nil || metadata.Width != tt.width || metadata.Height != tt.height {
+				t.Fatalf("expected %dx%d, got %+v", tt.width, tt.height, metadata)
+			}
+			if bytes.Contains(cleaned, []byte("EXIF")) {
+				t.Error("expected the EXIF chunk to be stripped")
+			}
+		})
+	}
+}
+
+func TestProcessImageRejectsOversizedWebP(t *testing.T) {
+	// A VP8X header claiming a 16384x16384 canvas
+	vp8x := []byte{0, 0, 0, 0, 0xff, 0x3f, 0, 0xff, 0x3f, 0}
+	data := buildTestWebP(webpChunk("VP8X", vp8x))
+
+	_, _, err := ProcessImage(data, "image/webp", 50_000_000)
+	if !errors.Is(err, ErrImageTooLarge) {
+		t.Fatalf("expected ErrImageTooLarge, got %v", err)
+	}
+}
@@ -0,0 +1,204 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	htmltemplate "html/template"
+	"net/mail"
+	"portfolio-be/internal/config"
+	"portfolio-be/internal/models"
+	"strings"
+	texttemplate "text/template"
+)
+
+// TaskSendEmail is the task kind that sends an email
+const TaskSendEmail = "mail.send"
+
+// Email template names
+const (
+	MailContactNotification    = "contact-notification"
+	MailContactAcknowledgement = "contact-acknowledgement"
+	MailContactReply           = "contact-reply"
+)
+
+// mailTemplate renders the subject and bodies of an email
+type mailTemplate struct {
+	subject *texttemplate.Template
+	text    *texttemplate.Template
+	html    *htmltemplate.Template
+}
+
+// newMailTemplate parses the parts of an email template
+func newMailTemplate(name, subject, text, html string) mailTemplate {
+	return mailTemplate{
+		subject: texttemplate.Must(texttemplate.New(name + "-subject").Parse(subject)),
+		text:    texttemplate.Must(texttemplate.New(name + "-text").Parse(text)),
+		html:    htmltemplate.Must(htmltemplate.New(name + "-html").Parse(html)),
+	}
+}
+
+// mailTemplates are the templates rendered by MailService.Render
+var mailTemplates = map[string]mailTemplate{
+	MailContactNotification: newMailTemplate(MailContactNotification,
+		`New message from {{.Contact.Name}}{{if .Contact.Subject}}: {{.Contact.Subject}}{{end}}`,
+		`{{.Contact.Name}} <{{.Contact.Email}}> sent a message through the {{.SiteName}} contact form.
+{{if .Contact.Subject}}
+Subject: {{.Contact.Subject}}
+{{end}}
+{{.Contact.Message}}
+{{if .Link}}
+Open it in the admin: {{.Link}}
+{{end}}`,
+		`<p><strong>{{.Contact.Name}}</strong> &lt;{{.Contact.Email}}&gt; sent a message through the {{.SiteName}} contact form.</p>
+{{if .Contact.Subject}}<p><strong>Subject:</strong> {{.Contact.Subject}}</p>{{end}}
+<blockquote style="white-space: pre-wrap">{{.Contact.Message}}</blockquote>
+{{if .Link}}<p><a href="{{.Link}}">Open it in the admin</a></p>{{end}}`),
+	// The acknowledgement goes to whatever address was typed into the form, so it carries
+	// nothing the sender wrote: otherwise the form could mail any text to anyone
+	MailContactAcknowledgement: newMailTemplate(MailContactAcknowledgement,
+		`We received your message to {{.SiteName}}`,
+		`Hello,
+
+Thank you for getting in touch. This is an automatic confirmation that your message reached {{.SiteName}}; we will reply as soon as we can.
+`,
+		`<p>Hello,</p>
+<p>Thank you for getting in touch. This is an automatic confirmation that your message reached {{.SiteName}}; we will reply as soon as we can.</p>`),
+	MailContactReply: newMailTemplate(MailContactReply,
+		`{{.Reply.Subject}}`,
+		`{{.Reply.Body}}
+
+-----
+On {{.Contact.CreatedAt.Format "Jan 2, 2006 15:04 MST"}}, {{.Contact.Name}} wrote:
+
+{{.Contact.Message}}
+`,
+		`<div style="white-space: pre-wrap">{{.Reply.Body}}</div>
+<p>On {{.Contact.CreatedAt.Format "Jan 2, 2006 15:04 MST"}}, {{.Contact.Name}} wrote:</p>
+<blockquote style="white-space: pre-wrap">{{.Contact.Message}}</blockquote>`),
+}
+
+// ContactMailData is the data of the contact email templates
+type ContactMailData struct {
+	SiteName string
+	Contact  *models.Contact
+	// Link points to the contact in the admin interface, when configured
+	Link string
+}
+
+// ContactReplyMailData is the data of the contact reply template
+type ContactReplyMailData struct {
+	SiteName string
+	Contact  *models.Contact
+	Reply    *models.ContactReply
+}
+
+// MailService renders templated email and sends it through the task queue, so that
+// failed deliveries are retried in the background
+type MailService struct {
+	mailer Mailer
+	tasks  *TaskQueue
+	cfg    config.MailConfig
+	sender string
+}
+
+// NewMailService creates a mail service and registers the send task with the queue
+func NewMailService(mailer Mailer, tasks *TaskQueue, cfg config.MailConfig) *MailService {
+	sender := "noreply@localhost"
+	if from, err := mail.ParseAddress(cfg.From); err == nil {
+		sender = from.Address
+	}
+
+	s := &MailService{mailer: mailer, tasks: tasks, cfg: cfg, sender: sender}
+	tasks.Register(TaskSendEmail, 0, s.handleSend)
+	return s
+}
+
+// handleSend delivers a queued email
+func (s *MailService) handleSend(ctx context.Context, payload json.RawMessage) error {
+	var email Email
+	if err := json.Unmarshal(payload, &email); err != nil {
+		return PermanentError(fmt.Errorf("invalid email payload: %w", err))
+	}
+	return s.mailer.Send(ctx, &email)
+}
+
+// Queue stores an email for background delivery and returns its task
+func (s *MailService) Queue(email *Email) (*models.Task, error) {
+	if email.MessageID == "" {
+		email.MessageID = s.NewMessageID()
+	}
+	task, err := s.tasks.EnqueueRef(TaskSendEmail, email.Ref, email)
+	if err != nil {
+		return nil, fmt.Errorf("failed to queue email: %w", err)
+	}
+	return task, nil
+}
+
+// Send delivers an email right away, for task handlers that track delivery themselves
+func (s *MailService) Send(ctx context.Context, email *Email) error {
+	if email.MessageID == "" {
+		email.MessageID = s.NewMessageID()
+	}
+	return s.mailer.Send(ctx, email)
+}
+
+// DeleteCopies removes the copies of the email about the given records that the mailer
+// keeps, if it keeps any
+func (s *MailService) DeleteCopies(refs []string) (int, error) {
+	archive, ok := s.mailer.(MailArchive)
+	if !ok {
+		return 0, nil
+	}
+	deleted, err := archive.DeleteByRefs(refs)
+	if err != nil {
+		return deleted, fmt.Errorf("failed to delete email copies: %w", err)
+	}
+	return deleted, nil
+}
+
+// NewMessageID returns a Message-ID in the sender's domain
+func (s *MailService) NewMessageID() string {
+	return NewMessageID(s.sender)
+}
+
+// Render renders a named template into an email to the given recipients
+func (s *MailService) Render(name string, to []string, data interface{}) (*Email, error) {
+	tmpl, ok := mailTemplates[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown email template %q", name)
+	}
+
+	var subject, text, html bytes.Buffer
+	if err := tmpl.subject.Execute(&subject, data); err != nil {
+		return nil, fmt.Errorf("failed to render %s subject: %w", name, err)
+	}
+	if err := tmpl.text.Execute(&text, data); err != nil {
+		return nil, fmt.Errorf("failed to render %s text: %w", name, err)
+	}
+	if err := tmpl.html.Execute(&html, data); err != nil {
+		return nil, fmt.Errorf("failed to render %s HTML: %w", name, err)
+	}
+
+	return &Email{
+		To: to,
+		// Subjects are one line, whatever the submitted values contain
+		Subject: strings.Join(strings.Fields(subject.String()), " "),
+		Text:    text.String(),
+		HTML:    html.String(),
+	}, nil
+}
+
+// SiteName is the site name used in email templates
+func (s *MailService) SiteName() string {
+	return s.cfg.SiteName
+}
+
+// AdminLink returns the admin interface URL of a path, or "" when no admin URL is set
+func (s *MailService) AdminLink(path string) string {
+	if s.cfg.AdminURL == "" {
+		return ""
+	}
+	return s.cfg.AdminURL + path
+}
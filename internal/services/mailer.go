@@ -0,0 +1,351 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"portfolio-be/internal/config"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Email is a message to send. Text is required; HTML is sent as an alternative when set.
+type Email struct {
+	To      []string `json:"to"`
+	ReplyTo string   `json:"reply_to,omitempty"`
+	Subject string   `json:"subject"`
+	Text    string   `json:"text"`
+	HTML    string   `json:"html,omitempty"`
+	// MessageID is set once so that retries of the same email carry the same id
+	MessageID string `json:"message_id,omitempty"`
+	// Ref names the record the email is about, so that its task and any stored copy can
+	// be deleted along with the record. It is not sent.
+	Ref string `json:"ref,omitempty"`
+}
+
+// Mailer delivers email
+type Mailer interface {
+	Send(ctx context.Context, email *Email) error
+}
+
+// MailArchive is implemented by mailers that keep a copy of the email they deliver
+type MailArchive interface {
+	// DeleteByRefs removes the stored copies of the email about the given records
+	DeleteByRefs(refs []string) (int, error)
+}
+
+// NewMailer returns the mailer selected by the configuration
+func NewMailer(cfg config.MailConfig) (Mailer, error) {
+	from, err := mail.ParseAddress(cfg.From)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sender address %q: %w", cfg.From, err)
+	}
+
+	switch cfg.Driver {
+	case "smtp":
+		log.Printf("Sending email through %s:%d", cfg.SMTPHost, cfg.SMTPPort)
+		return &SMTPMailer{
+			from:     from,
+			host:     cfg.SMTPHost,
+			port:     cfg.SMTPPort,
+			username: cfg.SMTPUsername,
+			password: cfg.SMTPPassword,
+			tlsMode:  cfg.SMTPTLS,
+			timeout:  cfg.Timeout,
+		}, nil
+	case "file":
+		if err := os.MkdirAll(cfg.DropDir, 0o755); err != nil {
+			return nil, fmt.Errorf("failed to create mail drop directory: %w", err)
+		}
+		log.Printf("Writing email to %s instead of sending it", cfg.DropDir)
+		return &FileMailer{from: from, dir: cfg.DropDir}, nil
+	default:
+		log.Println("Email is disabled, messages are logged and discarded")
+		return NoopMailer{}, nil
+	}
+}
+
+// NoopMailer logs and discards email
+type NoopMailer struct{}
+
+func (NoopMailer) Send(ctx context.Context, email *Email) error {
+	log.Printf("Email disabled, not sending %q to %s", email.Subject, strings.Join(email.To, ", "))
+	return nil
+}
+
+// FileMailer writes each email to an .eml file, for development. The file name carries
+// the email's ref, so that the copies about a record can be found and deleted.
+type FileMailer struct {
+	from *mail.Address
+	dir  string
+}
+
+func (m *FileMailer) Send(ctx context.Context, email *Email) error {
+	message, err := buildMessage(m.from, email)
+	if err != nil {
+		return PermanentError(err)
+	}
+
+	name := time.Now().UTC().Format("20060102T150405.000")
+	if isFileSafeRef(email.Ref) {
+		name += "-" + email.Ref
+	}
+	name += "-" + randomHex(4) + ".eml"
+	if err := os.WriteFile(filepath.Join(m.dir, name), message, 0o644); err != nil {
+		return fmt.Errorf("failed to write email: %w", err)
+	}
+	return nil
+}
+
+func (m *FileMailer) DeleteByRefs(refs []string) (int, error) {
+	if len(refs) == 0 {
+		return 0, nil
+	}
+	wanted := make(map[string]bool, len(refs))
+	for _, ref := range refs {
+		wanted[ref] = true
+	}
+
+	entries, err := os.ReadDir(m.dir)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list mail drop directory: %w", err)
+	}
+	deleted := 0
+	for _, entry := range entries {
+		if entry.IsDir() || !wanted[mailFileRef(entry.Name())] {
+			continue
+		}
+		if err := os.Remove(filepath.Join(m.dir, entry.Name())); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return deleted, fmt.Errorf("failed to delete email: %w", err)
+		}
+		deleted++
+	}
+	return deleted, nil
+}
+
+// isFileSafeRef reports whether a ref can be used in a file name
+func isFileSafeRef(ref string) bool {
+	if ref == "" {
+		return false
+	}
+	for _, r := range ref {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+// mailFileRef returns the ref in the name of a file written by FileMailer, which is
+// between the timestamp and the random suffix
+func mailFileRef(name string) string {
+	name, ok := strings.CutSuffix(name, ".eml")
+	if !ok {
+		return ""
+	}
+	first, last := strings.Index(name, "-"), strings.LastIndex(name, "-")
+	if first < 0 || last <= first {
+		return ""
+	}
+	return name[first+1 : last]
+}
+
+// SMTPMailer sends email through an SMTP server
+type SMTPMailer struct {
+	from     *mail.Address
+	host     string
+	port     int
+	username string
+	password string
+	tlsMode  string
+	timeout  time.Duration
+}
+
+func (m *SMTPMailer) Send(ctx context.Context, email *Email) error {
+	message, err := buildMessage(m.from, email)
+	if err != nil {
+		return PermanentError(err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, m.timeout)
+	defer cancel()
+
+	dialer := net.Dialer{}
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(m.host, strconv.Itoa(m.port)))
+	if err != nil {
+		return fmt.Errorf("failed to connect to SMTP server: %w", err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	if m.tlsMode == "tls" {
+		conn = tls.Client(conn, &tls.Config{ServerName: m.host})
+	}
+
+	client, err := smtp.NewClient(conn, m.host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to start SMTP session: %w", err)
+	}
+	defer client.Close()
+
+	if m.tlsMode == "starttls" {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return PermanentError(errors.New("SMTP server does not support STARTTLS"))
+		}
+		if err := client.StartTLS(&tls.Config{ServerName: m.host}); err != nil {
+			return fmt.Errorf("failed to start TLS: %w", err)
+		}
+	}
+	if m.username != "" {
+		if err := client.Auth(smtp.PlainAuth("", m.username, m.password, m.host)); err != nil {
+			return smtpError("authenticate", err)
+		}
+	}
+
+	if err := client.Mail(m.from.Address); err != nil {
+		return smtpError("set sender", err)
+	}
+	for _, to := range email.To {
+		// buildMessage already rejected unparseable recipients
+		address, _ := mail.ParseAddress(to)
+		if err := client.Rcpt(address.Address); err != nil {
+			return smtpError("add recipient "+address.Address, err)
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return smtpError("start message", err)
+	}
+	if _, err := w.Write(message); err != nil {
+		return fmt.Errorf("failed to write message: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return smtpError("send message", err)
+	}
+	return client.Quit()
+}
+
+// smtpError wraps an SMTP failure; permanent (5xx) replies are not retried
+func smtpError(action string, err error) error {
+	err = fmt.Errorf("failed to %s: %w", action, err)
+	var reply *textproto.Error
+	if errors.As(err, &reply) && reply.Code >= 500 {
+		return PermanentError(err)
+	}
+	return err
+}
+
+// buildMessage renders an email as a MIME message with a plain text body and, when
+// set, an HTML alternative
+func buildMessage(from *mail.Address, email *Email) ([]byte, error) {
+	if len(email.To) == 0 {
+		return nil, errors.New("email has no recipients")
+	}
+	to := make([]string, len(email.To))
+	for i, address := range email.To {
+		parsed, err := mail.ParseAddress(address)
+		if err != nil {
+			return nil, fmt.Errorf("invalid recipient %q: %w", address, err)
+		}
+		to[i] = parsed.String()
+	}
+
+	var buf bytes.Buffer
+	header := func(name, value string) {
+		// Drop line breaks so user input cannot inject headers
+		value = strings.NewReplacer("\r", " ", "\n", " ").Replace(value)
+		fmt.Fprintf(&buf, "%s: %s\r\n", name, value)
+	}
+	header("From", from.String())
+	header("To", strings.Join(to, ", "))
+	if email.ReplyTo != "" {
+		replyTo, err := mail.ParseAddress(email.ReplyTo)
+		if err != nil {
+			return nil, fmt.Errorf("invalid reply-to address %q: %w", email.ReplyTo, err)
+		}
+		header("Reply-To", replyTo.String())
+	}
+	header("Subject", mime.QEncoding.Encode("utf-8", email.Subject))
+	header("Date", time.Now().Format(time.RFC1123Z))
+	messageID := email.MessageID
+	if messageID == "" {
+		messageID = NewMessageID(from.Address)
+	}
+	header("Message-ID", messageID)
+	header("MIME-Version", "1.0")
+
+	if email.HTML == "" {
+		header("Content-Type", "text/plain; charset=utf-8")
+		header("Content-Transfer-Encoding", "quoted-printable")
+		buf.WriteString("\r\n")
+		if err := writeQuotedPrintable(&buf, email.Text); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
+	parts := multipart.NewWriter(&buf)
+	header("Content-Type", "multipart/alternative; boundary="+parts.Boundary())
+	buf.WriteString("\r\n")
+	for _, part := range []struct{ contentType, body string }{
+		{"text/plain; charset=utf-8", email.Text},
+		{"text/html; charset=utf-8", email.HTML},
+	} {
+		w, err := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := writeQuotedPrintable(w, part.body); err != nil {
+			return nil, err
+		}
+	}
+	if err := parts.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// writeQuotedPrintable writes a body with quoted-printable encoding
+func writeQuotedPrintable(w io.Writer, body string) error {
+	qp := quotedprintable.NewWriter(w)
+	if _, err := qp.Write([]byte(body)); err != nil {
+		return err
+	}
+	return qp.Close()
+}
+
+// NewMessageID returns a unique Message-ID header value in the sender's domain
+func NewMessageID(sender string) string {
+	domain := "localhost"
+	if at := strings.LastIndex(sender, "@"); at >= 0 {
+		domain = sender[at+1:]
+	}
+	return fmt.Sprintf("<%d.%s@%s>", time.Now().UnixNano(), randomHex(8), domain)
+}
+
+// randomHex returns n random bytes as hex
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
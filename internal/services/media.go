@@ -0,0 +1,335 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// errUnsupportedMedia is returned by the native prober for containers it cannot parse
+var errUnsupportedMedia = errors.New("unsupported media container")
+
+// MediaInfo holds the technical metadata probed from an audio or video file
+type MediaInfo struct {
+	// Duration is the playback length in seconds
+	Duration   float64
+	Width      int
+	Height     int
+	VideoCodec string
+	AudioCodec string
+}
+
+// MediaProber extracts duration, dimensions and codecs from audio and video files
+type MediaProber interface {
+	Probe(data []byte, contentType string) (*MediaInfo, error)
+}
+
+// IsMediaType reports whether a content type is audio or video
+func IsMediaType(contentType string) bool {
+	return strings.HasPrefix(contentType, "video/") || strings.HasPrefix(contentType, "audio/")
+}
+
+// NewMediaProber returns an ffprobe-backed prober when the binary is available,
+// falling back to the native prober otherwise
+func NewMediaProber(ffprobePath string) MediaProber {
+	if path, err := exec.LookPath(ffprobePath); err == nil {
+		return &FFprobeMediaProber{binary: path, timeout: 30 * time.Second}
+	}
+	return NativeMediaProber{}
+}
+
+// NativeMediaProber parses MP4/QuickTime and WAV headers without external tools.
+// Other containers return an error and are stored without media metadata.
+type NativeMediaProber struct{}
+
+func (NativeMediaProber) Probe(data []byte, contentType string) (*MediaInfo, error) {
+	switch {
+	case len(data) >= 12 && string(data[0:4]) == "RIFF" && string(data[8:12]) == "WAVE":
+		return probeWAV(data)
+	case len(data) >= 8 && isMP4Box(string(data[4:8])):
+		return probeMP4(data)
+	default:
+		return nil, fmt.Errorf("%w: %s", errUnsupportedMedia, contentType)
+	}
+}
+
+// isMP4Box reports whether a box type can start an MP4/QuickTime file
+func isMP4Box(boxType string) bool {
+	switch boxType {
+	case "ftyp", "moov", "mdat", "free", "skip", "wide":
+		return true
+	}
+	return false
+}
+
+// mp4Codecs maps sample entry formats to codec names
+var mp4Codecs = map[string]string{
+	"avc1": "h264", "avc3": "h264", "hvc1": "hevc", "hev1": "hevc",
+	"vp08": "vp8", "vp09": "vp9", "av01": "av1", "mp4v": "mpeg4",
+	"mp4a": "aac", "Opus": "opus", ".mp3": "mp3", "ac-3": "ac3",
+	"ec-3": "eac3", "alac": "alac", "fLaC": "flac",
+}
+
+// mp4Boxes iterates over the boxes of an MP4 byte range, calling fn with each box type and payload
+func mp4Boxes(data []byte, fn func(boxType string, payload []byte)) {
+	for len(data) >= 8 {
+		size := uint64(binary.BigEndian.Uint32(data[0:4]))
+		boxType := string(data[4:8])
+		header := uint64(8)
+
+		switch size {
+		case 0:
+			size = uint64(len(data))
+		case 1:
+			if len(data) < 16 {
+				return
+			}
+			size = binary.BigEndian.Uint64(data[8:16])
+			header = 16
+		}
+		if size < header || size > uint64(len(data)) {
+			// Truncated box, e.g. a partially read mdat; keep what is complete
+			return
+		}
+
+		fn(boxType, data[header:size])
+		data = data[size:]
+	}
+}
+
+// probeMP4 reads the duration from mvhd and, per track, the handler, dimensions and codec
+func probeMP4(data []byte) (*MediaInfo, error) {
+	info := &MediaInfo{}
+	foundMovie := false
+
+	mp4Boxes(data, func(boxType string, moov []byte) {
+		if boxType != "moov" {
+			return
+		}
+		foundMovie = true
+		mp4Boxes(moov, func(boxType string, payload []byte) {
+			switch boxType {
+			case "mvhd":
+				info.Duration = mvhdDuration(payload)
+			case "trak":
+				probeMP4Track(payload, info)
+			}
+		})
+	})
+
+	if !foundMovie {
+		return nil, fmt.Errorf("%w: no moov box", errUnsupportedMedia)
+	}
+	return info, nil
+}
+
+// mvhdDuration returns the movie duration in seconds from an mvhd payload
+func mvhdDuration(p []byte) float64 {
+	if len(p) < 20 {
+		return 0
+	}
+	if p[0] == 1 {
+		if len(p) < 32 {
+			return 0
+		}
+		timescale := binary.BigEndian.Uint32(p[20:24])
+		duration := binary.BigEndian.Uint64(p[24:32])
+		if timescale == 0 {
+			return 0
+		}
+		return float64(duration) / float64(timescale)
+	}
+	timescale := binary.BigEndian.Uint32(p[12:16])
+	duration := binary.BigEndian.Uint32(p[16:20])
+	if timescale == 0 {
+		return 0
+	}
+	return float64(duration) / float64(timescale)
+}
+
+// probeMP4Track fills in the dimensions and codec of a video or audio track
+func probeMP4Track(trak []byte, info *MediaInfo) {
+	var width, height int
+	var handler, codec string
+
+	mp4Boxes(trak, func(boxType string, payload []byte) {
+		switch boxType {
+		case "tkhd":
+			// Width and height are 16.16 fixed point at the end of the header
+			offset := 76
+			if len(payload) > 0 && payload[0] == 1 {
+				offset = 88
+			}
+			if len(payload) >= offset+8 {
+				width = int(binary.BigEndian.Uint32(payload[offset:]) >> 16)
+				height = int(binary.BigEndian.Uint32(payload[offset+4:]) >> 16)
+			}
+		case "mdia":
+			mp4Boxes(payload, func(boxType string, payload []byte) {
+				switch boxType {
+				case "hdlr":
+					if len(payload) >= 12 {
+						handler = string(payload[8:12])
+					}
+				case "minf":
+					codec = mp4SampleFormat(payload)
+				}
+			})
+		}
+	})
+
+	if name, ok := mp4Codecs[codec]; ok {
+		codec = name
+	}
+
+	switch handler {
+	case "vide":
+		if info.VideoCodec == "" {
+			info.VideoCodec = codec
+			info.Width, info.Height = width, height
+		}
+	case "soun":
+		if info.AudioCodec == "" {
+			info.AudioCodec = codec
+		}
+	}
+}
+
+// mp4SampleFormat returns the format of the first sample entry under minf/stbl/stsd
+func mp4SampleFormat(minf []byte) string {
+	format := ""
+	mp4Boxes(minf, func(boxType string, stbl []byte) {
+		if boxType != "stbl" {
+			return
+		}
+		mp4Boxes(stbl, func(boxType string, stsd []byte) {
+			if boxType == "stsd" && len(stsd) >= 16 {
+				format = strings.TrimRight(string(stsd[12:16]), "\x00")
+			}
+		})
+	})
+	return format
+}
+
+// probeWAV reads the codec from the fmt chunk and derives the duration from the data chunk
+func probeWAV(data []byte) (*MediaInfo, error) {
+	info := &MediaInfo{}
+	var byteRate uint32
+	var dataSize uint32
+
+	for chunks := data[12:]; len(chunks) >= 8; {
+		id := string(chunks[0:4])
+		size := binary.LittleEndian.Uint32(chunks[4:8])
+		body := chunks[8:]
+		if uint64(size) < uint64(len(body)) {
+			body = body[:size]
+		}
+
+		switch id {
+		case "fmt ":
+			if len(body) >= 12 {
+				switch binary.LittleEndian.Uint16(body[0:2]) {
+				case 1:
+					info.AudioCodec = "pcm"
+				case 3:
+					info.AudioCodec = "pcm_float"
+				default:
+					info.AudioCodec = "wav"
+				}
+				byteRate = binary.LittleEndian.Uint32(body[8:12])
+			}
+		case "data":
+			dataSize = size
+		}
+
+		// Chunks are word aligned
+		next := 8 + uint64(size) + uint64(size%2)
+		if next > uint64(len(chunks)) {
+			break
+		}
+		chunks = chunks[next:]
+	}
+
+	if byteRate == 0 {
+		return nil, fmt.Errorf("%w: WAV without fmt chunk", errUnsupportedMedia)
+	}
+	info.Duration = float64(dataSize) / float64(byteRate)
+	return info, nil
+}
+
+// FFprobeMediaProber probes any container ffprobe understands
+type FFprobeMediaProber struct {
+	binary  string
+	timeout time.Duration
+}
+
+// ffprobeOutput is the subset of `ffprobe -print_format json` output that is used
+type ffprobeOutput struct {
+	Streams []struct {
+		CodecType string `json:"codec_type"`
+		CodecName string `json:"codec_name"`
+		Width     int    `json:"width"`
+		Height    int    `json:"height"`
+	} `json:"streams"`
+	Format struct {
+		Duration string `json:"duration"`
+	} `json:"format"`
+}
+
+func (p *FFprobeMediaProber) Probe(data []byte, contentType string) (*MediaInfo, error) {
+	// ffprobe needs a seekable input for files with the index at the end
+	file, err := os.CreateTemp("", "probe-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create probe file: %w", err)
+	}
+	defer os.Remove(file.Name())
+
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to write probe file: %w", err)
+	}
+	file.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), p.timeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, p.binary, "-v", "error", "-print_format", "json", "-show_format", "-show_streams", file.Name())
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("ffprobe failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	var output ffprobeOutput
+	if err := json.Unmarshal(stdout.Bytes(), &output); err != nil {
+		return nil, fmt.Errorf("failed to parse ffprobe output: %w", err)
+	}
+
+	info := &MediaInfo{}
+	info.Duration, _ = strconv.ParseFloat(output.Format.Duration, 64)
+	for _, stream := range output.Streams {
+		switch stream.CodecType {
+		case "video":
+			// Skip cover art attached to audio files
+			if info.VideoCodec == "" && !strings.HasPrefix(contentType, "audio/") {
+				info.VideoCodec = stream.CodecName
+				info.Width, info.Height = stream.Width, stream.Height
+			}
+		case "audio":
+			if info.AudioCodec == "" {
+				info.AudioCodec = stream.CodecName
+			}
+		}
+	}
+
+	return info, nil
+}
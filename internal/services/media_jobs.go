@@ -0,0 +1,238 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"portfolio-be/internal/config"
+	"portfolio-be/internal/repository"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// MediaJobKind is the kind of derived file a media job produces
+type MediaJobKind string
+
+const (
+	// MediaJobPoster extracts a JPEG poster frame from a video
+	MediaJobPoster MediaJobKind = "poster"
+	// MediaJobTranscode converts a video to H.264/AAC MP4 for browser playback
+	MediaJobTranscode MediaJobKind = "transcode"
+)
+
+// transcodeTypes are video types browsers cannot play natively
+var transcodeTypes = []string{"video/avi", "video/x-msvideo", "video/quicktime", "video/x-matroska"}
+
+// MediaJob asks for a derived file of an upload
+type MediaJob struct {
+	Kind     MediaJobKind `json:"kind"`
+	UploadID uint         `json:"upload_id"`
+}
+
+// TaskMediaJob is the task kind that runs a media job
+const TaskMediaJob = "media.job"
+
+// MediaJobsFor returns the jobs to run for a newly stored upload of the given type
+func MediaJobsFor(uploadID uint, contentType string) []MediaJob {
+	if !strings.HasPrefix(contentType, "video/") {
+		return nil
+	}
+
+	jobs := []MediaJob{{Kind: MediaJobPoster, UploadID: uploadID}}
+	if slices.Contains(transcodeTypes, contentType) {
+		jobs = append(jobs, MediaJob{Kind: MediaJobTranscode, UploadID: uploadID})
+	}
+	return jobs
+}
+
+// MediaJobRunner runs poster-frame and transcode jobs for uploaded media
+type MediaJobRunner interface {
+	// Submit queues a job to run in the background
+	Submit(job MediaJob) error
+}
+
+// NewMediaJobRunner returns an ffmpeg-backed runner that runs jobs on the task queue
+// when the binary is available, and a runner that discards jobs otherwise
+func NewMediaJobRunner(cfg config.MediaConfig, repo *repository.UploadRepository, s3Service *S3Service, tasks *TaskQueue) MediaJobRunner {
+	path, err := exec.LookPath(cfg.FFmpegPath)
+	if err != nil {
+		log.Printf("ffmpeg not found, poster frames and transcodes are disabled")
+		return NoopMediaJobRunner{}
+	}
+
+	runner := &FFmpegJobRunner{
+		binary:    path,
+		timeout:   cfg.JobTimeout,
+		repo:      repo,
+		s3Service: s3Service,
+		tasks:     tasks,
+		slots:     make(chan struct{}, cfg.Workers),
+	}
+	// The task timeout also covers waiting for a free ffmpeg slot
+	tasks.Register(TaskMediaJob, cfg.JobTimeout, runner.handle)
+	log.Printf("Media jobs run on the task queue with at most %d ffmpeg processes", cfg.Workers)
+	return runner
+}
+
+// NoopMediaJobRunner discards media jobs
+type NoopMediaJobRunner struct{}
+
+func (NoopMediaJobRunner) Submit(MediaJob) error { return nil }
+
+// FFmpegJobRunner runs media jobs with a local ffmpeg binary. Jobs are queued as tasks,
+// so they survive restarts and are retried on failure; slots bounds how many ffmpeg
+// processes run at once across the task workers.
+type FFmpegJobRunner struct {
+	binary    string
+	timeout   time.Duration
+	repo      *repository.UploadRepository
+	s3Service *S3Service
+	tasks     *TaskQueue
+	slots     chan struct{}
+}
+
+func (r *FFmpegJobRunner) Submit(job MediaJob) error {
+	_, err := r.tasks.Enqueue(TaskMediaJob, job)
+	return err
+}
+
+// handle runs a media job task once an ffmpeg slot is free
+func (r *FFmpegJobRunner) handle(ctx context.Context, payload json.RawMessage) error {
+	var job MediaJob
+	if err := json.Unmarshal(payload, &job); err != nil {
+		return PermanentError(fmt.Errorf("invalid media job payload: %w", err))
+	}
+
+	select {
+	case r.slots <- struct{}{}:
+		defer func() { <-r.slots }()
+	case <-ctx.Done():
+		return fmt.Errorf("no ffmpeg slot became free: %w", ctx.Err())
+	}
+
+	start := time.Now()
+	if err := r.run(ctx, job); err != nil {
+		return fmt.Errorf("media %s job for upload %d failed: %w", job.Kind, job.UploadID, err)
+	}
+	log.Printf("Media %s job for upload %d completed in %v", job.Kind, job.UploadID, time.Since(start))
+	return nil
+}
+
+// run downloads the source file, runs ffmpeg and stores the result on every upload
+// sharing the source object. A job whose result is already stored does nothing, so
+// retried tasks do not redo finished work.
+func (r *FFmpegJobRunner) run(ctx context.Context, job MediaJob) error {
+	upload, err := r.repo.GetByID(job.UploadID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return PermanentError(fmt.Errorf("upload not found: %w", err))
+		}
+		return fmt.Errorf("failed to get upload: %w", err)
+	}
+	if (job.Kind == MediaJobPoster && upload.PosterKey != "") || (job.Kind == MediaJobTranscode && upload.TranscodedKey != "") {
+		return nil
+	}
+
+	dir, err := os.MkdirTemp("", "media-job-*")
+	if err != nil {
+		return fmt.Errorf("failed to create work directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	source := filepath.Join(dir, "source"+filepath.Ext(upload.OriginalName))
+	if err := r.download(upload.S3Key, source); err != nil {
+		return err
+	}
+
+	var (
+		output      string
+		contentType string
+		field       string
+		args        []string
+	)
+	switch job.Kind {
+	case MediaJobPoster:
+		output = filepath.Join(dir, "poster.jpg")
+		contentType, field = "image/jpeg", "poster_key"
+		// Grab a frame one second in, or halfway through shorter clips
+		offset := 1.0
+		if upload.Duration > 0 && upload.Duration < 2 {
+			offset = upload.Duration / 2
+		}
+		args = []string{"-ss", strconv.FormatFloat(offset, 'f', 3, 64), "-i", source,
+			"-frames:v", "1", "-vf", "scale='min(1280,iw)':-2", "-q:v", "3", output}
+	case MediaJobTranscode:
+		output = filepath.Join(dir, "transcoded.mp4")
+		contentType, field = "video/mp4", "transcoded_key"
+		args = []string{"-i", source, "-c:v", "libx264", "-preset", "veryfast", "-crf", "23",
+			"-pix_fmt", "yuv420p", "-c:a", "aac", "-movflags", "+faststart", output}
+	default:
+		return PermanentError(fmt.Errorf("unknown media job kind %q", job.Kind))
+	}
+
+	if err := r.ffmpeg(ctx, args); err != nil {
+		return err
+	}
+
+	data, err := os.ReadFile(output)
+	if err != nil {
+		return fmt.Errorf("failed to read %s output: %w", job.Kind, err)
+	}
+
+	name := strings.TrimSuffix(upload.OriginalName, filepath.Ext(upload.OriginalName)) + filepath.Ext(output)
+	key, err := r.s3Service.UploadBytes(data, name, contentType)
+	if err != nil {
+		return fmt.Errorf("failed to store %s output: %w", job.Kind, err)
+	}
+
+	if err := r.repo.UpdateDerivedKey(upload.S3Key, field, key); err != nil {
+		r.s3Service.DeleteFileLater(key)
+		return fmt.Errorf("failed to save %s key: %w", job.Kind, err)
+	}
+	return nil
+}
+
+// download copies an S3 object to a local file
+func (r *FFmpegJobRunner) download(key, path string) error {
+	stream, err := r.s3Service.GetObject(key, ObjectRequest{})
+	if err != nil {
+		return fmt.Errorf("failed to fetch source: %w", err)
+	}
+	defer stream.Body.Close()
+
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create source file: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := io.Copy(file, stream.Body); err != nil {
+		return fmt.Errorf("failed to download source: %w", err)
+	}
+	return nil
+}
+
+// ffmpeg runs the ffmpeg binary with the given arguments within the job timeout
+func (r *FFmpegJobRunner) ffmpeg(ctx context.Context, args []string) error {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, r.binary, append([]string{"-y", "-v", "error"}, args...)...)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("ffmpeg failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
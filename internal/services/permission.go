@@ -134,7 +134,7 @@ func (s *PermissionService) DeletePermission(id uint) error {
 
 // Helper function to initialize default permissions
 func (s *PermissionService) InitializeDefaultPermissions() error {
-	resources := []string{"users", "roles", "permissions", "projects", "technologies", "experiences", "testimonials", "contacts", "services", "uploads"}
+	resources := []string{"users", "roles", "permissions", "projects", "technologies", "experiences", "testimonials", "contacts", "services", "uploads", "jobs", "tasks"}
 	actions := []string{"create", "read", "update", "delete"}
 
 	for _, resource := range resources {
@@ -13,16 +13,19 @@ type ProjectService interface {
 	UpdateProject(id uint, request *models.ProjectRequest) (*models.ProjectResponse, error)
 	DeleteProject(id uint) error
 	GetActiveProjects() ([]models.ProjectResponse, error)
+	GetActiveProjectsByTag(tag string) ([]models.ProjectResponse, error)
 	GetProjectsCount() (int64, error)
 	UpdateProjectsOrder(items []OrderItem) error
 }
 
 type projectService struct {
 	projectRepo repository.ProjectRepository
+	references  *UploadReferenceService
+	tags        *TagService
 }
 
-func NewProjectService(projectRepo repository.ProjectRepository) ProjectService {
-	return &projectService{projectRepo: projectRepo}
+func NewProjectService(projectRepo repository.ProjectRepository, references *UploadReferenceService, tags *TagService) ProjectService {
+	return &projectService{projectRepo: projectRepo, references: references, tags: tags}
 }
 
 func (s *projectService) CreateProject(request *models.ProjectRequest) (*models.ProjectResponse, error) {
@@ -48,6 +51,10 @@ func (s *projectService) CreateProject(request *models.ProjectRequest) (*models.
 		return nil, err
 	}
 
+	if err := s.syncReferences(project); err != nil {
+		return nil, err
+	}
+
 	response := s.convertToResponse(project)
 	return &response, nil
 }
@@ -102,12 +109,30 @@ func (s *projectService) UpdateProject(id uint, request *models.ProjectRequest)
 		return nil, err
 	}
 
+	if err := s.syncReferences(project); err != nil {
+		return nil, err
+	}
+
 	response := s.convertToResponse(project)
 	return &response, nil
 }
 
 func (s *projectService) DeleteProject(id uint) error {
-	return s.projectRepo.Delete(id)
+	if err := s.projectRepo.Delete(id); err != nil {
+		return err
+	}
+	if err := s.references.RemoveEntity(models.ReferenceEntityProject, id); err != nil {
+		return err
+	}
+	return s.tags.RemoveEntity(models.ReferenceEntityProject, id)
+}
+
+// syncReferences indexes the uploads and tags used by a project
+func (s *projectService) syncReferences(project *models.Project) error {
+	if err := s.references.SyncEntity(models.ReferenceEntityProject, project.ID, map[string]string{"image": project.Image}); err != nil {
+		return err
+	}
+	return s.tags.SyncEntity(models.ReferenceEntityProject, project.ID, projectTagNames(project.Tags))
 }
 
 func (s *projectService) GetActiveProjects() ([]models.ProjectResponse, error) {
@@ -124,6 +149,20 @@ func (s *projectService) GetActiveProjects() ([]models.ProjectResponse, error) {
 	return responses, nil
 }
 
+func (s *projectService) GetActiveProjectsByTag(tag string) ([]models.ProjectResponse, error) {
+	projects, err := s.projectRepo.GetActiveByTag(models.TagSlug(tag))
+	if err != nil {
+		return nil, err
+	}
+
+	responses := []models.ProjectResponse{}
+	for _, project := range projects {
+		responses = append(responses, s.convertToResponse(&project))
+	}
+
+	return responses, nil
+}
+
 func (s *projectService) convertToResponse(project *models.Project) models.ProjectResponse {
 	// Parse JSON string back to slice
 	var tags []models.ProjectTag
@@ -1,23 +1,46 @@
 package services
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"log"
 	"portfolio-be/internal/models"
 	"portfolio-be/internal/repository"
+	"strings"
 	"time"
 )
 
+var (
+	// ErrResourceNotFound is returned when a resource does not exist or is hidden from the caller
+	ErrResourceNotFound = errors.New("resource not found")
+	// ErrResourcePrivate is returned when a private resource is requested without
+	// uploads:read. It wraps ErrResourceNotFound, so the resource stays hidden from
+	// callers that do not ask for the reason.
+	ErrResourcePrivate = fmt.Errorf("%w: resource is private", ErrResourceNotFound)
+	// ErrInvalidResourceSort is returned when listing resources by an unsupported sort field
+	ErrInvalidResourceSort = errors.New("sort must be one of name, created_at, view_count or download_count")
+)
+
 type ResourceService struct {
-	repo       *repository.ResourceRepository
-	uploadRepo *repository.UploadRepository
-	s3Service  *S3Service
+	repo          *repository.ResourceRepository
+	uploadRepo    *repository.UploadRepository
+	s3Service     *S3Service
+	uploadService *UploadService
+	references    *UploadReferenceService
+	tags          *TagService
+	analytics     *AnalyticsService
 }
 
-func NewResourceService(repo *repository.ResourceRepository, uploadRepo *repository.UploadRepository, s3Service *S3Service) *ResourceService {
+func NewResourceService(repo *repository.ResourceRepository, uploadRepo *repository.UploadRepository, s3Service *S3Service, uploadService *UploadService, references *UploadReferenceService, tags *TagService, analytics *AnalyticsService) *ResourceService {
 	return &ResourceService{
-		repo:       repo,
-		uploadRepo: uploadRepo,
-		s3Service:  s3Service,
+		repo:          repo,
+		uploadRepo:    uploadRepo,
+		s3Service:     s3Service,
+		uploadService: uploadService,
+		references:    references,
+		tags:          tags,
+		analytics:     analytics,
 	}
 }
 
@@ -33,7 +56,7 @@ func (s *ResourceService) CreateResource(req *models.ResourceCreateRequest) (*mo
 		Description: req.Description,
 		Type:        req.Type,
 		Category:    req.Category,
-		Tags:        req.Tags,
+		Tags:        strings.Join(models.ParseTags(req.Tags), ","),
 		UploadID:    req.UploadID,
 		Alt:         req.Alt,
 		IsPublic:    true,
@@ -51,79 +74,127 @@ func (s *ResourceService) CreateResource(req *models.ResourceCreateRequest) (*mo
 		return nil, fmt.Errorf("failed to create resource: %w", err)
 	}
 
+	if err := s.references.SyncResource(resource.ID, resource.UploadID); err != nil {
+		return nil, err
+	}
+	if err := s.tags.SyncEntity(models.ReferenceEntityResource, resource.ID, models.ParseTags(resource.Tags)); err != nil {
+		return nil, err
+	}
+
 	// Load the upload relationship
 	resource.Upload = *upload
-	response := resource.ToResponse()
+	response := s.toResponse(resource)
 	return &response, nil
 }
 
+// GetResourceByID returns a resource for the admin API. Admin reads are not views:
+// only the public paths record analytics, through LogAccess.
 func (s *ResourceService) GetResourceByID(id uint) (*models.ResourceResponse, error) {
 	resource, err := s.repo.GetByID(id)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get resource: %w", err)
 	}
 
-	// Increment view count
-	go s.repo.IncrementViewCount(id)
-
-	response := resource.ToResponse()
+	response := s.toResponse(resource)
 	return &response, nil
 }
 
-func (s *ResourceService) GetAllResources(limit, offset int) ([]models.ResourceResponse, error) {
-	resources, err := s.repo.GetAll(limit, offset)
+// GetAccessibleResource returns an active resource for a public endpoint. Private
+// resources are only returned when canReadPrivate is set.
+func (s *ResourceService) GetAccessibleResource(id uint, canReadPrivate bool) (*models.ResourceResponse, error) {
+	resource, err := s.getAccessible(id, canReadPrivate)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get resources: %w", err)
+		return nil, err
 	}
 
-	responses := make([]models.ResourceResponse, len(resources))
-	for i, resource := range resources {
-		responses[i] = resource.ToResponse()
+	response := s.toResponse(resource)
+	return &response, nil
+}
+
+// GetDownloadableResource returns an accessible resource whose file may be served,
+// rejecting files quarantined by the virus scanner
+func (s *ResourceService) GetDownloadableResource(id uint, canReadPrivate bool) (*models.ResourceResponse, error) {
+	resource, err := s.getAccessible(id, canReadPrivate)
+	if err != nil {
+		return nil, err
+	}
+	if resource.Upload.IsQuarantined() {
+		return nil, ErrUploadQuarantined
 	}
 
-	return responses, nil
+	response := s.toResponse(resource)
+	return &response, nil
 }
 
-func (s *ResourceService) GetResourcesByType(resourceType models.ResourceType, limit, offset int) ([]models.ResourceResponse, error) {
-	resources, err := s.repo.GetByType(resourceType, limit, offset)
+// OpenResourceContent opens the file of an accessible resource for streaming. The
+// resource is returned alongside storage errors. The caller must close the stream body.
+func (s *ResourceService) OpenResourceContent(id uint, canReadPrivate bool, req ObjectRequest) (*models.Resource, *ObjectStream, error) {
+	resource, err := s.getAccessible(id, canReadPrivate)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get resources by type: %w", err)
+		return nil, nil, err
+	}
+	if resource.Upload.IsQuarantined() {
+		return resource, nil, ErrUploadQuarantined
 	}
 
-	responses := make([]models.ResourceResponse, len(resources))
-	for i, resource := range resources {
-		responses[i] = resource.ToResponse()
+	stream, err := s.s3Service.GetObject(resource.Upload.S3Key, req)
+	if err != nil {
+		return resource, nil, err
 	}
 
-	return responses, nil
+	return resource, stream, nil
 }
 
-func (s *ResourceService) GetResourcesByCategory(category string, limit, offset int) ([]models.ResourceResponse, error) {
-	resources, err := s.repo.GetByCategory(category, limit, offset)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get resources by category: %w", err)
+// getAccessible loads an active resource, rejecting private ones unless canReadPrivate is set
+func (s *ResourceService) getAccessible(id uint, canReadPrivate bool) (*models.Resource, error) {
+	resource, err := s.repo.GetByID(id)
+	if err != nil || !resource.IsActive {
+		return nil, ErrResourceNotFound
+	}
+	if !resource.IsPublic && !canReadPrivate {
+		return nil, ErrResourcePrivate
 	}
+	return resource, nil
+}
 
-	responses := make([]models.ResourceResponse, len(resources))
-	for i, resource := range resources {
-		responses[i] = resource.ToResponse()
+// LogAccess logs a view, download or content stream of a resource and records it for analytics
+func (s *ResourceService) LogAccess(resourceID uint, isPublic bool, action string, userID uint, clientIP, referrer string) {
+	user := "anonymous"
+	if userID != 0 {
+		user = fmt.Sprintf("%d", userID)
 	}
+	log.Printf("Resource access: action=%s resource=%d public=%t user=%s ip=%s", action, resourceID, isPublic, user, clientIP)
 
-	return responses, nil
+	event := AnalyticsEvent{ResourceID: resourceID, Action: models.AnalyticsActionDownload, Referrer: referrer, ClientIP: clientIP}
+	if action == models.AnalyticsActionView {
+		event.Action = models.AnalyticsActionView
+	}
+	s.analytics.Record(event)
 }
 
-func (s *ResourceService) GetPublicResources(limit, offset int) ([]models.ResourceResponse, error) {
-	resources, err := s.repo.GetPublic(limit, offset)
+// ListResources returns a page of resources matching a filter along with the total
+// number of matches
+func (s *ResourceService) ListResources(filter models.ResourceFilter, limit, offset int) ([]models.ResourceResponse, int64, error) {
+	if filter.SortBy != "" && !repository.IsValidResourceSort(filter.SortBy) {
+		return nil, 0, ErrInvalidResourceSort
+	}
+
+	resources, err := s.repo.List(filter, limit, offset)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get public resources: %w", err)
+		return nil, 0, fmt.Errorf("failed to get resources: %w", err)
+	}
+
+	total, err := s.repo.CountFiltered(filter)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count resources: %w", err)
 	}
 
 	responses := make([]models.ResourceResponse, len(resources))
 	for i, resource := range resources {
-		responses[i] = resource.ToResponse()
+		responses[i] = s.toResponse(&resource)
 	}
 
-	return responses, nil
+	return responses, total, nil
 }
 
 func (s *ResourceService) UpdateResource(id uint, req *models.ResourceUpdateRequest) (*models.ResourceResponse, error) {
@@ -148,7 +219,7 @@ func (s *ResourceService) UpdateResource(id uint, req *models.ResourceUpdateRequ
 		updates["category"] = *req.Category
 	}
 	if req.Tags != nil {
-		updates["tags"] = *req.Tags
+		updates["tags"] = strings.Join(models.ParseTags(*req.Tags), ",")
 	}
 	if req.Alt != nil {
 		updates["alt"] = *req.Alt
@@ -166,6 +237,11 @@ func (s *ResourceService) UpdateResource(id uint, req *models.ResourceUpdateRequ
 			return nil, fmt.Errorf("failed to update resource: %w", err)
 		}
 	}
+	if req.Tags != nil {
+		if err := s.tags.SyncEntity(models.ReferenceEntityResource, id, models.ParseTags(*req.Tags)); err != nil {
+			return nil, err
+		}
+	}
 
 	// Return updated resource
 	updatedResource, err := s.repo.GetByID(id)
@@ -173,41 +249,39 @@ func (s *ResourceService) UpdateResource(id uint, req *models.ResourceUpdateRequ
 		return nil, fmt.Errorf("failed to get updated resource: %w", err)
 	}
 
-	response := updatedResource.ToResponse()
+	response := s.toResponse(updatedResource)
 	return &response, nil
 }
 
 func (s *ResourceService) DeleteResource(id uint) error {
 	// Check if resource exists
-	_, err := s.repo.GetByID(id)
+	resource, err := s.repo.GetByID(id)
 	if err != nil {
 		return fmt.Errorf("resource not found: %w", err)
 	}
 
-	// Delete resource (upload will be handled separately if needed)
+	// The resource goes in one transaction with its upload references, collection items and tags
 	if err := s.repo.Delete(id); err != nil {
 		return fmt.Errorf("failed to delete resource: %w", err)
 	}
 
-	return nil
-}
-
-func (s *ResourceService) SearchResources(query string, limit, offset int) ([]models.ResourceResponse, error) {
-	resources, err := s.repo.Search(query, limit, offset)
+	// Delete the upload too unless another entity still uses it. The resource is gone
+	// already, so a failure is only logged and the orphan report picks the upload up.
+	if resource.Upload.ID == 0 {
+		return nil
+	}
+	referenced, err := s.references.IsReferenced(resource.UploadID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to search resources: %w", err)
+		log.Printf("Failed to check references to upload %d of deleted resource %d: %v", resource.UploadID, id, err)
+		return nil
 	}
-
-	responses := make([]models.ResourceResponse, len(resources))
-	for i, resource := range resources {
-		responses[i] = resource.ToResponse()
+	if !referenced {
+		if err := s.uploadService.DeleteUpload(resource.UploadID, false); err != nil {
+			log.Printf("Failed to delete upload %d of deleted resource %d: %v", resource.UploadID, id, err)
+		}
 	}
 
-	return responses, nil
-}
-
-func (s *ResourceService) IncrementDownloadCount(id uint) error {
-	return s.repo.IncrementDownloadCount(id)
+	return nil
 }
 
 // GetResourceStats returns statistics about resources
@@ -235,57 +309,57 @@ func (s *ResourceService) GetResourceStats() (map[string]interface{}, error) {
 	}, nil
 }
 
-// RefreshExpiredURLs checks for expired uploads and refreshes their URLs
-func (s *ResourceService) RefreshExpiredURLs() error {
+// RefreshExpiredURLs extends the expiry of uploads used by resources that expire within
+// 24 hours and returns how many were extended. URLs are computed at read time, so only
+// the expiry needs refreshing. The refresh stops once ctx is cancelled or its job lease
+// is lost.
+func (s *ResourceService) RefreshExpiredURLs(ctx context.Context) (int, error) {
 	// Get resources with uploads expiring within 24 hours
 	resources, err := s.repo.GetExpiringSoon(24 * time.Hour)
 	if err != nil {
-		return fmt.Errorf("failed to get expiring resources: %w", err)
+		return 0, fmt.Errorf("failed to get expiring resources: %w", err)
 	}
 
+	refreshed := 0
 	for _, resource := range resources {
-		// Generate new presigned URL with extended expiry
-		newURL, err := s.s3Service.GeneratePresignedURL(resource.Upload.S3Key, 7*24*time.Hour) // 7 days
-		if err != nil {
-			fmt.Printf("Failed to generate new URL for upload %d: %v\n", resource.Upload.ID, err)
-			continue
+		if err := CheckJobLease(ctx); err != nil {
+			return refreshed, fmt.Errorf("refresh stopped after %d uploads: %w", refreshed, err)
 		}
-
-		// Update upload URL and expiry
 		newExpiry := time.Now().Add(7 * 24 * time.Hour)
-		if err := s.uploadRepo.UpdateURL(resource.Upload.ID, newURL); err != nil {
-			fmt.Printf("Failed to update URL for upload %d: %v\n", resource.Upload.ID, err)
-			continue
-		}
-
 		if err := s.uploadRepo.UpdateExpiry(resource.Upload.ID, &newExpiry); err != nil {
 			fmt.Printf("Failed to update expiry for upload %d: %v\n", resource.Upload.ID, err)
 			continue
 		}
 
-		fmt.Printf("Refreshed URL for upload %d (resource: %s)\n", resource.Upload.ID, resource.Name)
+		refreshed++
+		fmt.Printf("Refreshed expiry for upload %d (resource: %s)\n", resource.Upload.ID, resource.Name)
 	}
 
-	return nil
+	return refreshed, nil
 }
 
-// GetResourceDownloadURL generates a download URL for a resource and increments download count
-func (s *ResourceService) GetResourceDownloadURL(id uint) (string, error) {
-	resource, err := s.repo.GetByID(id)
-	if err != nil {
-		return "", fmt.Errorf("resource not found: %w", err)
+// toResponse converts a resource to its response, resolving the upload URL from its key:
+// public resources get the public URL, private ones a short-lived presigned URL.
+// Quarantined uploads get no URLs.
+func (s *ResourceService) toResponse(resource *models.Resource) models.ResourceResponse {
+	if resource.Upload.IsQuarantined() {
+		return resource.ToResponse()
 	}
 
-	// Increment download count
-	go s.repo.IncrementDownloadCount(id)
-
-	// Generate presigned URL for download (valid for 1 hour)
-	downloadURL, err := s.s3Service.GeneratePresignedURL(resource.Upload.S3Key, 1*time.Hour)
-	if err != nil {
-		return "", fmt.Errorf("failed to generate download URL: %w", err)
+	if resource.Upload.S3Key != "" {
+		url, err := s.s3Service.ResolveURL(resource.Upload.S3Key, resource.IsPublic)
+		if err != nil {
+			fmt.Printf("Failed to resolve URL for upload %d: %v\n", resource.Upload.ID, err)
+		}
+		resource.Upload.URL = url
 	}
-
-	return downloadURL, nil
+	if resource.Upload.PosterKey != "" {
+		resource.Upload.PosterURL, _ = s.s3Service.ResolveURL(resource.Upload.PosterKey, resource.IsPublic)
+	}
+	if resource.Upload.TranscodedKey != "" {
+		resource.Upload.TranscodedURL, _ = s.s3Service.ResolveURL(resource.Upload.TranscodedKey, resource.IsPublic)
+	}
+	return resource.ToResponse()
 }
 
 // CountResources returns total count of resources
@@ -302,14 +376,3 @@ func (s *ResourceService) CountResourcesByType(resourceType models.ResourceType)
 func (s *ResourceService) CountResourcesByCategory(category string) (int64, error) {
 	return s.repo.CountByCategory(category)
 }
-
-// CountSearchResults returns count of search results (simplified implementation)
-func (s *ResourceService) CountSearchResults(query string) (int64, error) {
-	// For simplicity, we'll get all search results and count them
-	// In production, you might want to optimize this with a separate count query
-	resources, err := s.repo.Search(query, 1000, 0) // Get up to 1000 results
-	if err != nil {
-		return 0, err
-	}
-	return int64(len(resources)), nil
-}
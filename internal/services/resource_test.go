@@ -0,0 +1,186 @@
+package services
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"portfolio-be/internal/config"
+	"portfolio-be/internal/models"
+	"portfolio-be/internal/repository"
+	"portfolio-be/internal/testutil"
+)
+
+func TestPublicResourceAccess(t *testing.T) {
+	db := testutil.OpenDB(t, filepath.Join(t.TempDir(), "resources.db"))
+	uploadRepo := repository.NewUploadRepository(db)
+	resourceRepo := repository.NewResourceRepository(db)
+	s3Service, _ := newTestS3Service(t)
+	service := NewResourceService(resourceRepo, uploadRepo, s3Service, nil, nil, nil, nil)
+
+	create := func(name string, public, active bool, scanStatus string) *models.Resource {
+		t.Helper()
+		upload := &models.Upload{FileName: name + ".pdf", OriginalName: name + ".pdf", S3Key: "uploads/" + name + ".pdf",
+			S3Bucket: "test", IsActive: true, ScanStatus: scanStatus}
+		if err := uploadRepo.Create(upload); err != nil {
+			t.Fatalf("failed to create upload: %v", err)
+		}
+		resource := &models.Resource{Name: name, Type: "document", UploadID: upload.ID, IsPublic: public, IsActive: active}
+		resource.Upload = *upload
+		if err := resourceRepo.Create(resource); err != nil {
+			t.Fatalf("failed to create resource: %v", err)
+		}
+		// Boolean zero values are not written by Create
+		db.Model(resource).Updates(map[string]interface{}{"is_public": public, "is_active": active})
+		return resource
+	}
+	public := create("public", true, true, models.UploadScanClean)
+	private := create("private", false, true, models.UploadScanClean)
+	inactive := create("inactive", true, false, models.UploadScanClean)
+	quarantined := create("quarantined", true, true, models.UploadScanInfected)
+
+	tests := []struct {
+		name           string
+		resource       *models.Resource
+		canReadPrivate bool
+		wantErr        error
+	}{
+		{"anonymous reads public", public, false, nil},
+		{"anonymous reads private", private, false, ErrResourceNotFound},
+		{"anonymous reads inactive", inactive, false, ErrResourceNotFound},
+		{"reader reads private", private, true, nil},
+		{"reader reads inactive", inactive, true, ErrResourceNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, get := range []func(uint, bool) (*models.ResourceResponse, error){
+				service.GetAccessibleResource, service.GetDownloadableResource,
+			} {
+				response, err := get(tt.resource.ID, tt.canReadPrivate)
+				if tt.wantErr != nil {
+					if !errors.Is(err, tt.wantErr) {
+						t.Fatalf("expected %v, got %v", tt.wantErr, err)
+					}
+					if response != nil {
+						t.Errorf("expected no resource, got %+v", response)
+					}
+					continue
+				}
+				if err != nil {
+					t.Fatalf("expected the resource, got %v", err)
+				}
+				if response.ID != tt.resource.ID {
+					t.Errorf("expected resource %d, got %d", tt.resource.ID, response.ID)
+				}
+			}
+		})
+	}
+
+	// Anonymous callers are told a private resource is private when they ask
+	if _, err := service.GetAccessibleResource(private.ID, false); !errors.Is(err, ErrResourcePrivate) {
+		t.Errorf("expected ErrResourcePrivate, got %v", err)
+	}
+
+	// Private files are linked through presigned URLs, public ones through the public URL
+	response, err := service.GetAccessibleResource(private.ID, true)
+	if err != nil {
+		t.Fatalf("failed to get private resource: %v", err)
+	}
+	if !strings.Contains(response.Upload.URL, "X-Amz-Signature=") {
+		t.Errorf("expected a presigned URL for the private file, got %s", response.Upload.URL)
+	}
+	response, err = service.GetAccessibleResource(public.ID, false)
+	if err != nil {
+		t.Fatalf("failed to get public resource: %v", err)
+	}
+	if response.Upload.URL != s3Service.GetFileURL(public.Upload.S3Key) {
+		t.Errorf("expected the public URL for the public file, got %s", response.Upload.URL)
+	}
+
+	// A quarantined file can be looked at but not downloaded
+	if _, err := service.GetAccessibleResource(quarantined.ID, false); err != nil {
+		t.Errorf("expected the quarantined resource to be visible, got %v", err)
+	}
+	if _, err := service.GetDownloadableResource(quarantined.ID, true); !errors.Is(err, ErrUploadQuarantined) {
+		t.Errorf("expected ErrUploadQuarantined, got %v", err)
+	}
+	if _, _, err := service.OpenResourceContent(quarantined.ID, true, ObjectRequest{}); !errors.Is(err, ErrUploadQuarantined) {
+		t.Errorf("expected ErrUploadQuarantined when streaming, got %v", err)
+	}
+}
+
+func TestResourceURLsArePresignedForPrivateFiles(t *testing.T) {
+	s3Service, _ := newTestS3Service(t)
+
+	publicURL, err := s3Service.ResolveURL("uploads/public.pdf", true)
+	if err != nil {
+		t.Fatalf("failed to resolve public URL: %v", err)
+	}
+	if publicURL != s3Service.GetFileURL("uploads/public.pdf") || strings.Contains(publicURL, "X-Amz-Signature") {
+		t.Errorf("expected the plain public URL, got %s", publicURL)
+	}
+
+	privateURL, err := s3Service.ResolveURL("uploads/private.pdf", false)
+	if err != nil {
+		t.Fatalf("failed to resolve private URL: %v", err)
+	}
+	if !strings.Contains(privateURL, "/test/uploads/private.pdf?") ||
+		!strings.Contains(privateURL, "X-Amz-Signature=") || !strings.Contains(privateURL, "X-Amz-Expires=3600") {
+		t.Errorf("expected a presigned URL valid for an hour, got %s", privateURL)
+	}
+}
+
+func TestDeleteResourceSucceedsWhenItsUploadCannotBeDeleted(t *testing.T) {
+	db := testutil.OpenDB(t, filepath.Join(t.TempDir(), "resources.db"))
+	uploadRepo := repository.NewUploadRepository(db)
+	resourceRepo := repository.NewResourceRepository(db)
+	refRepo := repository.NewUploadReferenceRepository(db)
+	references := NewUploadReferenceService(refRepo, uploadRepo, resourceRepo)
+	s3Service, _ := newTestS3Service(t)
+	uploads := NewUploadService(uploadRepo, s3Service, references, nil, nil, NoopScanner{}, nil, config.ImageConfig{})
+	service := NewResourceService(resourceRepo, uploadRepo, s3Service, uploads, references, nil, nil)
+
+	create := func(name string) *models.Resource {
+		t.Helper()
+		upload := &models.Upload{FileName: name + ".pdf", OriginalName: name + ".pdf", S3Key: "uploads/" + name + ".pdf", IsActive: true}
+		if err := uploadRepo.Create(upload); err != nil {
+			t.Fatalf("failed to create upload: %v", err)
+		}
+		resource := &models.Resource{Name: name, Type: models.ResourceTypeDocument, UploadID: upload.ID}
+		if err := resourceRepo.Create(resource); err != nil {
+			t.Fatalf("failed to create resource: %v", err)
+		}
+		if err := references.SyncResource(resource.ID, upload.ID); err != nil {
+			t.Fatalf("failed to sync resource: %v", err)
+		}
+		return resource
+	}
+	deleted := func(resource *models.Resource) (resourceGone, referencesGone, uploadGone bool) {
+		t.Helper()
+		_, err := resourceRepo.GetByID(resource.ID)
+		refs, _ := refRepo.GetByEntity(models.ReferenceEntityResource, resource.ID)
+		_, uploadErr := uploadRepo.GetByID(resource.UploadID)
+		return err != nil, len(refs) == 0, uploadErr != nil
+	}
+
+	resource := create("report")
+	if err := service.DeleteResource(resource.ID); err != nil {
+		t.Fatalf("failed to delete resource: %v", err)
+	}
+	if resourceGone, referencesGone, uploadGone := deleted(resource); !resourceGone || !referencesGone || !uploadGone {
+		t.Fatalf("expected the resource, its references and its upload to be deleted, got %t, %t and %t", resourceGone, referencesGone, uploadGone)
+	}
+
+	// The upload is left for the orphan report when it cannot be deleted
+	resource = create("locked")
+	if err := db.Exec("CREATE TRIGGER lock_uploads BEFORE UPDATE OF deleted_at ON uploads BEGIN SELECT RAISE(ABORT, 'uploads are locked'); END").Error; err != nil {
+		t.Fatalf("failed to lock uploads: %v", err)
+	}
+	if err := service.DeleteResource(resource.ID); err != nil {
+		t.Fatalf("expected the resource to be deleted despite its upload, got %v", err)
+	}
+	if resourceGone, referencesGone, uploadGone := deleted(resource); !resourceGone || !referencesGone || uploadGone {
+		t.Fatalf("expected the resource and its references to be deleted and the upload kept, got %t, %t and %t", resourceGone, referencesGone, uploadGone)
+	}
+}
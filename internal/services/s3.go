@@ -2,10 +2,18 @@ package services
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"log"
 	"mime/multipart"
+	"net/http"
 	"path/filepath"
 	"portfolio-be/internal/config"
+	"portfolio-be/internal/models"
+	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
@@ -16,10 +24,41 @@ import (
 	"github.com/google/uuid"
 )
 
+// ErrInvalidRange is returned when a requested byte range cannot be satisfied
+var ErrInvalidRange = errors.New("requested range not satisfiable")
+
+// ObjectRequest holds the range and conditional headers forwarded when reading an object
+type ObjectRequest struct {
+	Range           string
+	IfNoneMatch     string
+	IfModifiedSince *time.Time
+}
+
+// ObjectStream is an object body streamed from S3 along with its HTTP metadata.
+// Body is nil when NotModified is set.
+type ObjectStream struct {
+	Body          io.ReadCloser
+	ContentType   string
+	ContentLength int64
+	ContentRange  string
+	ETag          string
+	LastModified  *time.Time
+	NotModified   bool
+}
+
+// TaskS3Delete is the task kind that deletes an S3 object
+const TaskS3Delete = "s3.delete"
+
+// s3DeleteTask is the payload of an s3.delete task
+type s3DeleteTask struct {
+	Key string `json:"key"`
+}
+
 type S3Service struct {
 	client *s3.S3
 	bucket string
 	config config.S3Config
+	tasks  *TaskQueue
 }
 
 func NewS3Service(cfg config.S3Config) (*S3Service, error) {
@@ -56,26 +95,31 @@ func NewS3Service(cfg config.S3Config) (*S3Service, error) {
 	}, nil
 }
 
-func (s *S3Service) UploadFile(file multipart.File, header *multipart.FileHeader) (string, string, error) {
-	// Generate unique filename
-	ext := filepath.Ext(header.Filename)
-	fileName := fmt.Sprintf("%s%s", uuid.New().String(), ext)
-	key := fmt.Sprintf("uploads/%s", fileName)
-
+func (s *S3Service) UploadFile(file multipart.File, header *multipart.FileHeader) (string, error) {
 	// Read file content
 	buf := bytes.NewBuffer(nil)
 	if _, err := buf.ReadFrom(file); err != nil {
-		return "", "", fmt.Errorf("failed to read file: %w", err)
+		return "", fmt.Errorf("failed to read file: %w", err)
 	}
 
+	return s.UploadBytes(buf.Bytes(), header.Filename, header.Header.Get("Content-Type"))
+}
+
+// UploadBytes uploads an in-memory file under a new unique key and returns the key
+func (s *S3Service) UploadBytes(data []byte, originalName, contentType string) (string, error) {
+	// Generate unique filename
+	ext := filepath.Ext(originalName)
+	fileName := fmt.Sprintf("%s%s", uuid.New().String(), ext)
+	key := fmt.Sprintf("uploads/%s", fileName)
+
 	// Upload to S3
 	_, err := s.client.PutObject(&s3.PutObjectInput{
 		Bucket:      aws.String(s.bucket),
 		Key:         aws.String(key),
-		Body:        bytes.NewReader(buf.Bytes()),
-		ContentType: aws.String(header.Header.Get("Content-Type")),
+		Body:        bytes.NewReader(data),
+		ContentType: aws.String(contentType),
 		Metadata: map[string]*string{
-			"original-filename": aws.String(header.Filename),
+			"original-filename": aws.String(originalName),
 			"upload-time":       aws.String(time.Now().Format(time.RFC3339)),
 		},
 	})
@@ -85,35 +129,33 @@ func (s *S3Service) UploadFile(file multipart.File, header *multipart.FileHeader
 			if awsErr.Code() == "NoSuchBucket" {
 				fmt.Printf("Bucket missing during upload, attempting to recreate...\n")
 				if recreateErr := ensureBucketExists(s.client, s.bucket); recreateErr != nil {
-					return "", "", fmt.Errorf("failed to recreate bucket: %w", recreateErr)
+					return "", fmt.Errorf("failed to recreate bucket: %w", recreateErr)
 				}
 
 				// Retry the upload after recreating bucket
 				_, retryErr := s.client.PutObject(&s3.PutObjectInput{
 					Bucket:      aws.String(s.bucket),
 					Key:         aws.String(key),
-					Body:        bytes.NewReader(buf.Bytes()),
-					ContentType: aws.String(header.Header.Get("Content-Type")),
+					Body:        bytes.NewReader(data),
+					ContentType: aws.String(contentType),
 					ACL:         aws.String("public-read"),
 					Metadata: map[string]*string{
-						"original-filename": aws.String(header.Filename),
+						"original-filename": aws.String(originalName),
 						"upload-time":       aws.String(time.Now().Format(time.RFC3339)),
 					},
 				})
 				if retryErr != nil {
-					return "", "", fmt.Errorf("failed to upload to S3 after bucket recreation: %w", retryErr)
+					return "", fmt.Errorf("failed to upload to S3 after bucket recreation: %w", retryErr)
 				}
 			} else {
-				return "", "", fmt.Errorf("failed to upload to S3: %w", err)
+				return "", fmt.Errorf("failed to upload to S3: %w", err)
 			}
 		} else {
-			return "", "", fmt.Errorf("failed to upload to S3: %w", err)
+			return "", fmt.Errorf("failed to upload to S3: %w", err)
 		}
 	}
 
-	url := fmt.Sprintf("%s/%s/%s", "https://media.moclawr.com", s.bucket, key)
-
-	return key, url, nil
+	return key, nil
 }
 
 func (s *S3Service) DeleteFile(key string) error {
@@ -128,8 +170,52 @@ func (s *S3Service) DeleteFile(key string) error {
 	return nil
 }
 
+// RegisterTasks registers the S3 task handlers and routes DeleteFileLater through the queue
+func (s *S3Service) RegisterTasks(tasks *TaskQueue) {
+	tasks.Register(TaskS3Delete, 0, func(ctx context.Context, payload json.RawMessage) error {
+		var task s3DeleteTask
+		if err := json.Unmarshal(payload, &task); err != nil || task.Key == "" {
+			return PermanentError(fmt.Errorf("invalid s3.delete payload: %s", payload))
+		}
+		// Deleting a missing object succeeds, so retries are harmless
+		return s.DeleteFile(task.Key)
+	})
+	s.tasks = tasks
+}
+
+// DeleteFileLater queues the deletion of an object the caller no longer needs, so a
+// failed or interrupted delete is retried instead of leaving an orphan. Without a
+// task queue, or when enqueueing fails, the object is deleted right away.
+func (s *S3Service) DeleteFileLater(key string) {
+	if s.tasks != nil {
+		_, err := s.tasks.Enqueue(TaskS3Delete, s3DeleteTask{Key: key})
+		if err == nil {
+			return
+		}
+		log.Printf("Failed to queue deletion of %s, deleting now: %v", key, err)
+	}
+	if err := s.DeleteFile(key); err != nil {
+		log.Printf("Failed to delete %s: %v", key, err)
+	}
+}
+
+// GetFileURL returns the public URL of a key, served from the configured public/CDN
+// base URL or, when none is set, from the S3 endpoint
 func (s *S3Service) GetFileURL(key string) string {
-	return fmt.Sprintf("%s/%s/%s", s.config.Endpoint, s.bucket, key)
+	base := s.config.PublicBaseURL
+	if base == "" {
+		base = fmt.Sprintf("%s/%s", strings.TrimRight(s.config.Endpoint, "/"), s.bucket)
+	}
+	return fmt.Sprintf("%s/%s", strings.TrimRight(base, "/"), key)
+}
+
+// ResolveURL returns the URL clients should use for a key: the public URL for public
+// files, or a presigned URL valid for the configured expiry for private ones
+func (s *S3Service) ResolveURL(key string, public bool) (string, error) {
+	if public {
+		return s.GetFileURL(key), nil
+	}
+	return s.GeneratePresignedURL(key, s.config.PresignExpiry)
 }
 
 // GeneratePresignedURL generates a presigned URL for file access with expiration
@@ -147,6 +233,75 @@ func (s *S3Service) GeneratePresignedURL(key string, duration time.Duration) (st
 	return url, nil
 }
 
+// GetObject opens an object for streaming, honouring Range, If-None-Match and
+// If-Modified-Since. The caller must close the returned body.
+func (s *S3Service) GetObject(key string, req ObjectRequest) (*ObjectStream, error) {
+	input := &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	}
+	if req.Range != "" {
+		input.Range = aws.String(req.Range)
+	}
+	if req.IfNoneMatch != "" {
+		input.IfNoneMatch = aws.String(req.IfNoneMatch)
+	}
+	if req.IfModifiedSince != nil {
+		input.IfModifiedSince = req.IfModifiedSince
+	}
+
+	request, output := s.client.GetObjectRequest(input)
+	if err := request.Send(); err != nil {
+		if reqErr, ok := err.(awserr.RequestFailure); ok {
+			switch reqErr.StatusCode() {
+			case http.StatusNotModified:
+				// The object's own ETag, not the If-None-Match list that matched it
+				var etag string
+				if request.HTTPResponse != nil {
+					etag = request.HTTPResponse.Header.Get("ETag")
+				}
+				return &ObjectStream{NotModified: true, ETag: etag}, nil
+			case http.StatusRequestedRangeNotSatisfiable:
+				return nil, ErrInvalidRange
+			}
+		}
+		return nil, fmt.Errorf("failed to get object from S3: %w", err)
+	}
+
+	return &ObjectStream{
+		Body:          output.Body,
+		ContentType:   aws.StringValue(output.ContentType),
+		ContentLength: aws.Int64Value(output.ContentLength),
+		ContentRange:  aws.StringValue(output.ContentRange),
+		ETag:          aws.StringValue(output.ETag),
+		LastModified:  output.LastModified,
+	}, nil
+}
+
+// ListObjects returns every object stored under the given key prefix
+func (s *S3Service) ListObjects(prefix string) ([]models.StorageObject, error) {
+	var objects []models.StorageObject
+
+	err := s.client.ListObjectsV2Pages(&s3.ListObjectsV2Input{
+		Bucket: aws.String(s.bucket),
+		Prefix: aws.String(prefix),
+	}, func(page *s3.ListObjectsV2Output, lastPage bool) bool {
+		for _, obj := range page.Contents {
+			objects = append(objects, models.StorageObject{
+				Key:          aws.StringValue(obj.Key),
+				Size:         aws.Int64Value(obj.Size),
+				LastModified: aws.TimeValue(obj.LastModified),
+			})
+		}
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list S3 objects: %w", err)
+	}
+
+	return objects, nil
+}
+
 // setupCORS configures CORS for the S3 bucket to allow frontend access
 func setupCORS(client *s3.S3, bucket string) error {
 	corsConfig := &s3.PutBucketCorsInput{
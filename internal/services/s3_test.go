@@ -0,0 +1,124 @@
+package services
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"portfolio-be/internal/config"
+)
+
+// fakeS3 is an in-memory S3 bucket serving the requests S3Service makes, with Range and
+// conditional GETs handled by http.ServeContent
+type fakeS3 struct {
+	mu       sync.Mutex
+	objects  map[string][]byte
+	modified time.Time
+}
+
+// newTestS3Service returns an S3 service backed by an in-memory bucket
+func newTestS3Service(t *testing.T) (*S3Service, *fakeS3) {
+	t.Helper()
+	fake := &fakeS3{objects: make(map[string][]byte), modified: time.Now().UTC().Truncate(time.Second)}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	s3Service, err := NewS3Service(config.S3Config{
+		Endpoint:        server.URL,
+		Region:          "us-east-1",
+		Bucket:          "test",
+		AccessKeyID:     "test",
+		SecretAccessKey: "test",
+		ForcePathStyle:  true,
+		PresignExpiry:   time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("failed to create S3 service: %v", err)
+	}
+	return s3Service, fake
+}
+
+// has reports whether an object is stored
+func (f *fakeS3) has(key string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	_, ok := f.objects[key]
+	return ok
+}
+
+// count returns the number of stored objects
+func (f *fakeS3) count() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.objects)
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// Path-style requests: /<bucket> or /<bucket>/<key>
+	_, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if key == "" {
+		// HeadBucket and PutBucketCors
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch r.Method {
+	case http.MethodPut:
+		data, _ := io.ReadAll(r.Body)
+		f.objects[key] = data
+		w.Header().Set("ETag", fakeETag(data))
+		w.WriteHeader(http.StatusOK)
+	case http.MethodDelete:
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	case http.MethodGet, http.MethodHead:
+		data, ok := f.objects[key]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("ETag", fakeETag(data))
+		w.Header().Set("Content-Type", "application/octet-stream")
+		http.ServeContent(w, r, key, f.modified, bytes.NewReader(data))
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// fakeETag returns the quoted MD5 ETag S3 gives a single-part object
+func fakeETag(data []byte) string {
+	sum := md5.Sum(data)
+	return `"` + hex.EncodeToString(sum[:]) + `"`
+}
+
+func TestGetObjectNotModifiedReturnsObjectETag(t *testing.T) {
+	s3Service, _ := newTestS3Service(t)
+	key, err := s3Service.UploadBytes([]byte("hello world"), "hello.txt", "text/plain")
+	if err != nil {
+		t.Fatalf("failed to upload object: %v", err)
+	}
+	etag := fakeETag([]byte("hello world"))
+
+	for _, ifNoneMatch := range []string{etag, `"other", ` + etag, "*"} {
+		stream, err := s3Service.GetObject(key, ObjectRequest{IfNoneMatch: ifNoneMatch})
+		if err != nil {
+			t.Fatalf("If-None-Match %s: failed to get object: %v", ifNoneMatch, err)
+		}
+		if !stream.NotModified {
+			stream.Body.Close()
+			t.Fatalf("If-None-Match %s: expected not modified", ifNoneMatch)
+		}
+		if stream.ETag != etag {
+			t.Errorf("If-None-Match %s: expected ETag %s, got %s", ifNoneMatch, etag, stream.ETag)
+		}
+	}
+}
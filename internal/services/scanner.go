@@ -0,0 +1,167 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"portfolio-be/internal/config"
+	"strings"
+	"time"
+)
+
+// ErrUploadQuarantined is returned when serving a file whose virus scan is not clean
+var ErrUploadQuarantined = errors.New("file is quarantined until its virus scan is clean")
+
+// clamdChunkSize is the size of the chunks streamed to clamd
+const clamdChunkSize = 64 * 1024
+
+// eicarSignature is the standard antivirus test string detected by StubScanner
+const eicarSignature = `X5O!P%@AP[4\PZX54(P^)7CC)7}$EICAR-STANDARD-ANTIVIRUS-TEST-FILE!$H+H*`
+
+// ScanResult is the verdict of a virus scan
+type ScanResult struct {
+	Infected bool
+	// Signature names the malware found, when infected
+	Signature string
+}
+
+// Scanner checks file contents for malware
+type Scanner interface {
+	Scan(ctx context.Context, r io.Reader) (*ScanResult, error)
+}
+
+// NewScanner returns the scanner selected by the configuration
+func NewScanner(cfg config.ScanConfig) Scanner {
+	switch cfg.Scanner {
+	case "clamd":
+		log.Printf("Scanning uploads with clamd at %s", cfg.ClamdAddress)
+		return &ClamdScanner{address: cfg.ClamdAddress, timeout: cfg.Timeout}
+	case "stub":
+		log.Println("Scanning uploads with the EICAR test stub")
+		return StubScanner{}
+	default:
+		log.Println("Upload virus scanning is disabled, uploads are marked clean")
+		return NoopScanner{}
+	}
+}
+
+// NoopScanner reports every file as clean
+type NoopScanner struct{}
+
+func (NoopScanner) Scan(context.Context, io.Reader) (*ScanResult, error) {
+	return &ScanResult{}, nil
+}
+
+// StubScanner flags files containing the EICAR test string. Setting Err simulates
+// an unavailable scanner.
+type StubScanner struct {
+	Err error
+}
+
+func (s StubScanner) Scan(ctx context.Context, r io.Reader) (*ScanResult, error) {
+	if s.Err != nil {
+		return nil, s.Err
+	}
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	if bytes.Contains(data, []byte(eicarSignature)) {
+		return &ScanResult{Infected: true, Signature: "Eicar-Test-Signature"}, nil
+	}
+	return &ScanResult{}, nil
+}
+
+// ClamdScanner streams files to a ClamAV daemon over TCP with the INSTREAM command
+type ClamdScanner struct {
+	address string
+	timeout time.Duration
+}
+
+func (s *ClamdScanner) Scan(ctx context.Context, r io.Reader) (*ScanResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", s.address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to clamd: %w", err)
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	if err := s.stream(conn, r); err != nil {
+		// clamd closes the connection early when the stream exceeds StreamMaxLength;
+		// its reply explains why
+		if reply, readErr := readClamdReply(conn); readErr == nil && reply != "" {
+			return nil, fmt.Errorf("clamd rejected stream: %s", reply)
+		}
+		return nil, err
+	}
+
+	reply, err := readClamdReply(conn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read clamd reply: %w", err)
+	}
+	return parseClamdReply(reply)
+}
+
+// stream sends the INSTREAM command followed by length-prefixed chunks and a zero-length terminator
+func (s *ClamdScanner) stream(conn net.Conn, r io.Reader) error {
+	if _, err := conn.Write([]byte("zINSTREAM\x00")); err != nil {
+		return fmt.Errorf("failed to send clamd command: %w", err)
+	}
+
+	buf := make([]byte, 4+clamdChunkSize)
+	for {
+		n, err := r.Read(buf[4:])
+		if n > 0 {
+			binary.BigEndian.PutUint32(buf[:4], uint32(n))
+			if _, werr := conn.Write(buf[:4+n]); werr != nil {
+				return fmt.Errorf("failed to stream file to clamd: %w", werr)
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read file: %w", err)
+		}
+	}
+
+	if _, err := conn.Write([]byte{0, 0, 0, 0}); err != nil {
+		return fmt.Errorf("failed to end clamd stream: %w", err)
+	}
+	return nil
+}
+
+// readClamdReply reads a NUL-terminated clamd reply
+func readClamdReply(conn net.Conn) (string, error) {
+	reply, err := io.ReadAll(io.LimitReader(conn, 4096))
+	if err != nil && len(reply) == 0 {
+		return "", err
+	}
+	return strings.TrimSpace(strings.TrimRight(string(reply), "\x00")), nil
+}
+
+// parseClamdReply interprets "stream: OK", "stream: <signature> FOUND" and "... ERROR" replies
+func parseClamdReply(reply string) (*ScanResult, error) {
+	verdict := strings.TrimSpace(strings.TrimPrefix(reply, "stream:"))
+	switch {
+	case verdict == "OK":
+		return &ScanResult{}, nil
+	case strings.HasSuffix(verdict, " FOUND"):
+		return &ScanResult{Infected: true, Signature: strings.TrimSuffix(verdict, " FOUND")}, nil
+	default:
+		return nil, fmt.Errorf("clamd scan failed: %s", reply)
+	}
+}
@@ -0,0 +1,563 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"portfolio-be/internal/config"
+	"portfolio-be/internal/models"
+	"portfolio-be/internal/repository"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	// ErrJobNotFound is returned for job names that are not registered
+	ErrJobNotFound = errors.New("job not found")
+	// ErrJobRunning is returned when a job is started while a run is in progress
+	ErrJobRunning = errors.New("job is already running")
+	// ErrJobLeased is returned when another instance holds the lease of a job
+	ErrJobLeased = errors.New("job is running on another instance")
+	// ErrLeaseLost fails a run whose lease expired or was taken over before it finished
+	ErrLeaseLost = errors.New("job lease was lost before the run finished")
+)
+
+// JobFunc is the work of a scheduled job. It returns the number of items processed.
+// Jobs call CheckJobLease with ctx before each write.
+type JobFunc func(ctx context.Context) (int64, error)
+
+// jobLeaseKey is the context key of the lease held by a job run
+type jobLeaseKey struct{}
+
+// jobLease is the lease a job run holds while it executes
+type jobLease struct {
+	repo   *repository.JobRepository
+	name   string
+	holder string
+	token  int64
+}
+
+// CheckJobLease returns an error when ctx is cancelled or belongs to a job run whose
+// lease expired or was taken over, in which case it returns ErrLeaseLost. Renewals
+// cancel a run only once they notice the lost lease, so a stalled run checks its
+// fencing token before each write instead of overwriting the work of the instance that
+// took the job over. Outside of job runs only cancellation is reported.
+func CheckJobLease(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	lease, ok := ctx.Value(jobLeaseKey{}).(jobLease)
+	if !ok {
+		return nil
+	}
+	held, err := lease.repo.HoldsLease(lease.name, lease.holder, lease.token)
+	if err != nil {
+		return fmt.Errorf("failed to check job lease: %w", err)
+	}
+	if !held {
+		return ErrLeaseLost
+	}
+	return nil
+}
+
+// scheduledJob is a job registered with the scheduler
+type scheduledJob struct {
+	name        string
+	description string
+	schedule    *CronSchedule
+	fn          JobFunc
+	running     atomic.Bool
+
+	// paused and next are guarded by the scheduler mutex
+	paused bool
+	next   time.Time
+}
+
+// Scheduler runs named jobs on cron schedules and records their run history. Every
+// run holds a database lease on its job, so when several instances share the database
+// a job never runs twice at once and each tick of its schedule runs on one instance.
+type Scheduler struct {
+	repo      *repository.JobRepository
+	instance  string
+	leaseTTL  time.Duration
+	retention time.Duration
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wake   chan struct{}
+	wg     sync.WaitGroup
+
+	mu      sync.Mutex
+	jobs    map[string]*scheduledJob
+	order   []string
+	started bool
+}
+
+// NewScheduler creates a scheduler; run history older than the configured retention is
+// pruned, zero keeps it forever
+func NewScheduler(repo *repository.JobRepository, cfg config.JobsConfig) *Scheduler {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Scheduler{
+		repo:      repo,
+		instance:  cfg.InstanceID,
+		leaseTTL:  cfg.LeaseTTL,
+		retention: cfg.HistoryRetention,
+		ctx:       ctx,
+		cancel:    cancel,
+		wake:      make(chan struct{}, 1),
+		jobs:      make(map[string]*scheduledJob),
+	}
+}
+
+// Register adds a job with a cron expression. Jobs must be registered before Start.
+func (s *Scheduler) Register(name, description, expression string, fn JobFunc) error {
+	schedule, err := ParseCronSchedule(expression)
+	if err != nil {
+		return fmt.Errorf("failed to register job %s: %w", name, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, exists := s.jobs[name]; exists {
+		return fmt.Errorf("job %s is already registered", name)
+	}
+	s.jobs[name] = &scheduledJob{name: name, description: description, schedule: schedule, fn: fn}
+	s.order = append(s.order, name)
+	return nil
+}
+
+// Start restores paused jobs, closes runs interrupted by a previous exit and starts
+// running jobs on their schedules
+func (s *Scheduler) Start() error {
+	now := time.Now()
+	s.closeInterrupted(now)
+	if err := s.loadStates(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	for _, job := range s.jobs {
+		job.next = job.schedule.Next(now)
+	}
+	s.started = true
+	s.mu.Unlock()
+
+	s.wg.Add(1)
+	go s.loop()
+
+	log.Printf("Scheduler started with %d jobs as instance %s", len(s.order), s.instance)
+	return nil
+}
+
+// loadStates applies pause states persisted by this or another instance
+func (s *Scheduler) loadStates() error {
+	states, err := s.repo.GetStates()
+	if err != nil {
+		return fmt.Errorf("failed to get job states: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, state := range states {
+		if job, ok := s.jobs[state.Name]; ok {
+			job.paused = state.Paused
+		}
+	}
+	return nil
+}
+
+// closeInterrupted marks runs whose instance exited or lost the lease mid-run
+func (s *Scheduler) closeInterrupted(before time.Time) {
+	count, err := s.repo.MarkInterrupted(before)
+	if err != nil {
+		log.Printf("Failed to close interrupted job runs: %v", err)
+	} else if count > 0 {
+		log.Printf("Marked %d job runs as interrupted", count)
+	}
+}
+
+// Stop stops scheduling, cancels running jobs and waits for them to finish
+func (s *Scheduler) Stop(ctx context.Context) error {
+	s.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		log.Println("Scheduler stopped")
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("scheduled jobs did not finish: %w", ctx.Err())
+	}
+}
+
+// loop sleeps until the earliest due job and starts every job that is due
+func (s *Scheduler) loop() {
+	defer s.wg.Done()
+
+	timer := time.NewTimer(s.untilNext())
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-s.wake:
+		case <-timer.C:
+			s.startDue(time.Now())
+		}
+
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(s.untilNext())
+	}
+}
+
+// untilNext returns how long to sleep until the next unpaused job is due
+func (s *Scheduler) untilNext() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var next time.Time
+	for _, job := range s.jobs {
+		if job.paused || job.next.IsZero() {
+			continue
+		}
+		if next.IsZero() || job.next.Before(next) {
+			next = job.next
+		}
+	}
+	// Wake up at least every minute to pick up pauses and resumes made on other instances
+	if next.IsZero() {
+		return time.Minute
+	}
+	return min(max(time.Until(next), 0), time.Minute)
+}
+
+// startDue starts the jobs whose next run time has passed
+func (s *Scheduler) startDue(now time.Time) {
+	if err := s.loadStates(); err != nil {
+		log.Printf("Failed to refresh job states: %v", err)
+	}
+	s.closeInterrupted(now)
+
+	type dueJob struct {
+		job  *scheduledJob
+		slot time.Time
+	}
+	s.mu.Lock()
+	var due []dueJob
+	for _, name := range s.order {
+		job := s.jobs[name]
+		if job.next.IsZero() || job.next.After(now) {
+			continue
+		}
+		if !job.paused {
+			due = append(due, dueJob{job: job, slot: job.next})
+		}
+		job.next = job.schedule.Next(now)
+	}
+	s.mu.Unlock()
+
+	for _, d := range due {
+		if _, err := s.start(d.job, models.JobTriggerSchedule, d.job.fn, &d.slot, true); err != nil {
+			switch {
+			case errors.Is(err, ErrJobRunning):
+				log.Printf("Skipping scheduled run of job %s: previous run is still in progress", d.job.name)
+			case errors.Is(err, ErrJobLeased):
+				log.Printf("Skipping scheduled run of job %s: another instance ran or is running it", d.job.name)
+			default:
+				log.Printf("Failed to start job %s: %v", d.job.name, err)
+			}
+		}
+	}
+}
+
+// Trigger starts a run of a job in the background, even when the job is paused
+func (s *Scheduler) Trigger(name, trigger string) (*models.JobRun, error) {
+	job, err := s.job(name)
+	if err != nil {
+		return nil, err
+	}
+	return s.start(job, trigger, job.fn, nil, true)
+}
+
+// Run runs fn as a manual run of a job and waits for it. It shares the job's history
+// and overlap protection, for endpoints that need the result of the work.
+func (s *Scheduler) Run(name string, fn JobFunc) (*models.JobRun, error) {
+	job, err := s.job(name)
+	if err != nil {
+		return nil, err
+	}
+	return s.start(job, models.JobTriggerManual, fn, nil, false)
+}
+
+// start takes the job's lease, records a run and executes fn, in the background when
+// async is set. Scheduled runs pass the schedule time they are due at as slot.
+func (s *Scheduler) start(job *scheduledJob, trigger string, fn JobFunc, slot *time.Time, async bool) (*models.JobRun, error) {
+	if s.ctx.Err() != nil {
+		return nil, errors.New("scheduler is stopped")
+	}
+	if !job.running.CompareAndSwap(false, true) {
+		return nil, ErrJobRunning
+	}
+
+	token, err := s.repo.AcquireLease(job.name, s.instance, time.Now().Add(s.leaseTTL), slot)
+	if err != nil || token == 0 {
+		job.running.Store(false)
+		if err != nil {
+			return nil, fmt.Errorf("failed to acquire job lease: %w", err)
+		}
+		return nil, ErrJobLeased
+	}
+
+	run := &models.JobRun{
+		JobName:      job.name,
+		Trigger:      trigger,
+		Instance:     s.instance,
+		FencingToken: token,
+		Status:       models.JobRunRunning,
+		StartedAt:    time.Now(),
+	}
+	if err := s.repo.CreateRun(run); err != nil {
+		s.releaseLease(job.name, token)
+		job.running.Store(false)
+		return nil, fmt.Errorf("failed to record job run: %w", err)
+	}
+
+	s.wg.Add(1)
+	if !async {
+		s.execute(job, run, fn)
+		return run, nil
+	}
+
+	started := *run
+	go s.execute(job, run, fn)
+	return &started, nil
+}
+
+// execute runs a job while renewing its lease and records its outcome. The job's
+// context carries the lease for CheckJobLease and is cancelled when the lease is lost.
+func (s *Scheduler) execute(job *scheduledJob, run *models.JobRun, fn JobFunc) {
+	defer s.wg.Done()
+	defer job.running.Store(false)
+
+	leased := context.WithValue(s.ctx, jobLeaseKey{}, jobLease{repo: s.repo, name: job.name, holder: s.instance, token: run.FencingToken})
+	ctx, cancel := context.WithCancel(leased)
+	var lost atomic.Bool
+	renewed := make(chan struct{})
+	go func() {
+		defer close(renewed)
+		if !s.keepLease(ctx, job.name, run.FencingToken) {
+			lost.Store(true)
+			cancel()
+		}
+	}()
+
+	log.Printf("Starting job %s (%s, fencing token %d)...", job.name, run.Trigger, run.FencingToken)
+	items, err := safeRun(ctx, fn)
+	cancel()
+	<-renewed
+
+	switch {
+	case errors.Is(err, ErrLeaseLost):
+		// The run noticed the lost lease itself through CheckJobLease
+		lost.Store(true)
+	case lost.Load() && err != nil:
+		err = fmt.Errorf("%w: %v", ErrLeaseLost, err)
+	case lost.Load():
+		err = ErrLeaseLost
+	}
+
+	finished := time.Now()
+	run.FinishedAt = &finished
+	run.ItemsProcessed = items
+	run.Status = models.JobRunSucceeded
+	if err != nil {
+		run.Status = models.JobRunFailed
+		run.Error = err.Error()
+		log.Printf("Job %s failed after %v: %v", job.name, finished.Sub(run.StartedAt), err)
+	} else {
+		log.Printf("Job %s completed in %v, %d items processed", job.name, finished.Sub(run.StartedAt), items)
+	}
+	run.Duration = finished.Sub(run.StartedAt).Round(time.Millisecond).String()
+
+	if err := s.repo.FinishRun(run); err != nil {
+		log.Printf("Failed to record outcome of job %s: %v", job.name, err)
+	}
+	if !lost.Load() {
+		s.releaseLease(job.name, run.FencingToken)
+	}
+	if s.retention > 0 {
+		if _, err := s.repo.DeleteRunsBefore(finished.Add(-s.retention)); err != nil {
+			log.Printf("Failed to prune job history: %v", err)
+		}
+	}
+}
+
+// keepLease renews a lease until ctx is done. It returns false when the lease was lost
+// to expiry or to another instance.
+func (s *Scheduler) keepLease(ctx context.Context, name string, token int64) bool {
+	ticker := time.NewTicker(s.leaseTTL / 3)
+	defer ticker.Stop()
+
+	expiresAt := time.Now().Add(s.leaseTTL)
+	for {
+		select {
+		case <-ctx.Done():
+			return true
+		case <-ticker.C:
+		}
+
+		next := time.Now().Add(s.leaseTTL)
+		held, err := s.repo.RenewLease(name, s.instance, token, next)
+		switch {
+		case err == nil && held:
+			expiresAt = next
+		case err == nil:
+			log.Printf("Lease of job %s (fencing token %d) was taken over, stopping the run", name, token)
+			return false
+		case time.Now().After(expiresAt):
+			log.Printf("Lease of job %s expired while renewals failed: %v", name, err)
+			return false
+		default:
+			log.Printf("Failed to renew lease of job %s, retrying: %v", name, err)
+		}
+	}
+}
+
+// releaseLease frees a job's lease so other instances may run it
+func (s *Scheduler) releaseLease(name string, token int64) {
+	if err := s.repo.ReleaseLease(name, s.instance, token); err != nil {
+		log.Printf("Failed to release lease of job %s: %v", name, err)
+	}
+}
+
+// safeRun turns a panicking job into a failed run
+func safeRun(ctx context.Context, fn JobFunc) (items int64, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job panicked: %v", r)
+		}
+	}()
+	return fn(ctx)
+}
+
+// Pause stops a job from running on its schedule; manual triggers still work
+func (s *Scheduler) Pause(name string) error {
+	return s.setPaused(name, true)
+}
+
+// Resume puts a paused job back on its schedule
+func (s *Scheduler) Resume(name string) error {
+	return s.setPaused(name, false)
+}
+
+func (s *Scheduler) setPaused(name string, paused bool) error {
+	job, err := s.job(name)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.SetPaused(name, paused); err != nil {
+		return fmt.Errorf("failed to save job state: %w", err)
+	}
+
+	s.mu.Lock()
+	job.paused = paused
+	if !paused && s.started {
+		job.next = job.schedule.Next(time.Now())
+	}
+	s.mu.Unlock()
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// List returns the status of every registered job
+func (s *Scheduler) List() []models.JobStatus {
+	s.mu.Lock()
+	statuses := make([]models.JobStatus, len(s.order))
+	for i, name := range s.order {
+		job := s.jobs[name]
+		statuses[i] = models.JobStatus{
+			Name:        job.name,
+			Description: job.description,
+			Schedule:    job.schedule.String(),
+			Paused:      job.paused,
+			Running:     job.running.Load(),
+		}
+		if !job.paused && !job.next.IsZero() {
+			next := job.next
+			statuses[i].NextRun = &next
+		}
+	}
+	s.mu.Unlock()
+
+	leases, err := s.repo.GetActiveLeases()
+	if err != nil {
+		log.Printf("Failed to get job leases: %v", err)
+	}
+	holders := make(map[string]string, len(leases))
+	for _, lease := range leases {
+		holders[lease.Name] = lease.Holder
+	}
+
+	for i := range statuses {
+		if holder, ok := holders[statuses[i].Name]; ok {
+			statuses[i].Running = true
+			statuses[i].Instance = holder
+		}
+		run, err := s.repo.GetLastRun(statuses[i].Name)
+		if err == nil {
+			statuses[i].LastRun = withDuration(run)
+		}
+	}
+	return statuses
+}
+
+// History returns the runs of a job, newest first
+func (s *Scheduler) History(name string, limit, offset int) ([]models.JobRun, int64, error) {
+	if _, err := s.job(name); err != nil {
+		return nil, 0, err
+	}
+
+	runs, total, err := s.repo.GetRuns(name, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get job runs: %w", err)
+	}
+	for i := range runs {
+		withDuration(&runs[i])
+	}
+	return runs, total, nil
+}
+
+func (s *Scheduler) job(name string) (*scheduledJob, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job, ok := s.jobs[name]
+	if !ok {
+		return nil, ErrJobNotFound
+	}
+	return job, nil
+}
+
+// withDuration fills in the duration of a finished run
+func withDuration(run *models.JobRun) *models.JobRun {
+	if run.FinishedAt != nil {
+		run.Duration = run.FinishedAt.Sub(run.StartedAt).Round(time.Millisecond).String()
+	}
+	return run
+}
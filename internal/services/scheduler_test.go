@@ -0,0 +1,375 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"portfolio-be/internal/config"
+	"portfolio-be/internal/models"
+	"portfolio-be/internal/repository"
+	"portfolio-be/internal/testutil"
+)
+
+func newTestScheduler(t *testing.T, path, instance string, leaseTTL time.Duration) (*Scheduler, *repository.JobRepository) {
+	t.Helper()
+	repo := repository.NewJobRepository(testutil.OpenDB(t, path))
+	scheduler := NewScheduler(repo, config.JobsConfig{InstanceID: instance, LeaseTTL: leaseTTL})
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		scheduler.Stop(ctx)
+	})
+	return scheduler, repo
+}
+
+func TestSchedulersRunEachTickOnce(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jobs.db")
+
+	var active, maxActive, runs atomic.Int64
+	var mu sync.Mutex
+	ranOn := make(map[string]int)
+
+	var schedulers []*Scheduler
+	var repo *repository.JobRepository
+	for i := 0; i < 3; i++ {
+		instance := fmt.Sprintf("replica-%d", i)
+		scheduler, r := newTestScheduler(t, path, instance, 2*time.Second)
+		repo = r
+		err := scheduler.Register("tick", "test job", "@every 1s", func(ctx context.Context) (int64, error) {
+			current := active.Add(1)
+			defer active.Add(-1)
+			for {
+				peak := maxActive.Load()
+				if current <= peak || maxActive.CompareAndSwap(peak, current) {
+					break
+				}
+			}
+
+			runs.Add(1)
+			mu.Lock()
+			ranOn[instance]++
+			mu.Unlock()
+			time.Sleep(200 * time.Millisecond)
+			return 1, nil
+		})
+		if err != nil {
+			t.Fatalf("failed to register job: %v", err)
+		}
+		schedulers = append(schedulers, scheduler)
+	}
+	for _, scheduler := range schedulers {
+		if err := scheduler.Start(); err != nil {
+			t.Fatalf("failed to start scheduler: %v", err)
+		}
+	}
+
+	time.Sleep(3500 * time.Millisecond)
+	for _, scheduler := range schedulers {
+		if err := scheduler.Stop(context.Background()); err != nil {
+			t.Fatalf("failed to stop scheduler: %v", err)
+		}
+	}
+
+	if maxActive.Load() != 1 {
+		t.Errorf("expected runs never to overlap, saw %d at once", maxActive.Load())
+	}
+
+	history, total, err := repo.GetRuns("tick", 100, 0)
+	if err != nil {
+		t.Fatalf("failed to get runs: %v", err)
+	}
+	if total < 3 || total > 4 {
+		t.Fatalf("expected one run per tick over 3.5s, got %d runs (%v)", total, ranOn)
+	}
+	if total != runs.Load() {
+		t.Errorf("recorded %d runs but the job ran %d times", total, runs.Load())
+	}
+
+	seconds := make(map[int64]bool)
+	tokens := make(map[int64]bool)
+	for _, run := range history {
+		if run.Status != models.JobRunSucceeded {
+			t.Errorf("run %d has status %s: %s", run.ID, run.Status, run.Error)
+		}
+		if seconds[run.StartedAt.Unix()] {
+			t.Errorf("tick at %v ran more than once", run.StartedAt.Truncate(time.Second))
+		}
+		seconds[run.StartedAt.Unix()] = true
+		if tokens[run.FencingToken] {
+			t.Errorf("fencing token %d was issued twice", run.FencingToken)
+		}
+		tokens[run.FencingToken] = true
+	}
+}
+
+func TestLeaseFencesExpiredHolder(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jobs.db")
+	repoA := repository.NewJobRepository(testutil.OpenDB(t, path))
+	repoB := repository.NewJobRepository(testutil.OpenDB(t, path))
+
+	tokenA, err := repoA.AcquireLease("cleanup", "a", time.Now().Add(100*time.Millisecond), nil)
+	if err != nil || tokenA == 0 {
+		t.Fatalf("expected a to acquire the lease, got token %d: %v", tokenA, err)
+	}
+	if token, err := repoB.AcquireLease("cleanup", "b", time.Now().Add(time.Minute), nil); err != nil || token != 0 {
+		t.Fatalf("expected b to be refused while a holds the lease, got token %d: %v", token, err)
+	}
+
+	time.Sleep(150 * time.Millisecond)
+	tokenB, err := repoB.AcquireLease("cleanup", "b", time.Now().Add(time.Minute), nil)
+	if err != nil || tokenB <= tokenA {
+		t.Fatalf("expected b to take over the expired lease with a higher token, got %d after %d: %v", tokenB, tokenA, err)
+	}
+
+	if held, err := repoA.RenewLease("cleanup", "a", tokenA, time.Now().Add(time.Minute)); err != nil || held {
+		t.Fatalf("expected a's stale token to be fenced out, held=%v: %v", held, err)
+	}
+	if err := repoA.ReleaseLease("cleanup", "a", tokenA); err != nil {
+		t.Fatalf("failed to release: %v", err)
+	}
+
+	leases, err := repoB.GetActiveLeases()
+	if err != nil {
+		t.Fatalf("failed to get leases: %v", err)
+	}
+	if len(leases) != 1 || leases[0].Holder != "b" || leases[0].Token != tokenB {
+		t.Fatalf("expected b to still hold the lease, got %+v", leases)
+	}
+}
+
+func TestScheduledSlotRunsOnce(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jobs.db")
+	repoA := repository.NewJobRepository(testutil.OpenDB(t, path))
+	repoB := repository.NewJobRepository(testutil.OpenDB(t, path))
+
+	slot := time.Date(2024, 1, 1, 3, 30, 0, 0, time.UTC)
+	token, err := repoA.AcquireLease("cleanup", "a", time.Now().Add(time.Minute), &slot)
+	if err != nil || token == 0 {
+		t.Fatalf("expected a to acquire the lease: %v", err)
+	}
+	if err := repoA.ReleaseLease("cleanup", "a", token); err != nil {
+		t.Fatalf("failed to release: %v", err)
+	}
+
+	// A late replica firing for the same tick after a finished is refused
+	if token, err := repoB.AcquireLease("cleanup", "b", time.Now().Add(time.Minute), &slot); err != nil || token != 0 {
+		t.Fatalf("expected the finished slot to be refused, got token %d: %v", token, err)
+	}
+	// The next tick and manual runs are allowed
+	next := slot.Add(24 * time.Hour)
+	if token, err := repoB.AcquireLease("cleanup", "b", time.Now().Add(time.Minute), &next); err != nil || token == 0 {
+		t.Fatalf("expected the next slot to be acquired: %v", err)
+	}
+}
+
+func TestManualTriggerOnAnotherInstance(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jobs.db")
+	release := make(chan struct{})
+	job := func(ctx context.Context) (int64, error) {
+		<-release
+		return 0, nil
+	}
+
+	schedulerA, repo := newTestScheduler(t, path, "a", time.Minute)
+	schedulerB, _ := newTestScheduler(t, path, "b", time.Minute)
+	for _, scheduler := range []*Scheduler{schedulerA, schedulerB} {
+		if err := scheduler.Register("report", "test job", "@daily", job); err != nil {
+			t.Fatalf("failed to register job: %v", err)
+		}
+		if err := scheduler.Start(); err != nil {
+			t.Fatalf("failed to start scheduler: %v", err)
+		}
+	}
+
+	run, err := schedulerA.Trigger("report", models.JobTriggerManual)
+	if err != nil {
+		t.Fatalf("failed to trigger job: %v", err)
+	}
+	if _, err := schedulerB.Trigger("report", models.JobTriggerManual); !errors.Is(err, ErrJobLeased) {
+		t.Fatalf("expected ErrJobLeased from the other instance, got %v", err)
+	}
+
+	statuses := schedulerB.List()
+	if !statuses[0].Running || statuses[0].Instance != "a" {
+		t.Fatalf("expected b to report the job running on a, got %+v", statuses[0])
+	}
+
+	close(release)
+	waitForRun(t, repo, run.ID, models.JobRunSucceeded)
+
+	if _, err := schedulerB.Trigger("report", models.JobTriggerManual); err != nil {
+		t.Fatalf("expected b to run the job once a finished, got %v", err)
+	}
+}
+
+func TestLostLeaseCancelsRun(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jobs.db")
+	schedulerA, repoA := newTestScheduler(t, path, "a", 300*time.Millisecond)
+	schedulerB, _ := newTestScheduler(t, path, "b", time.Minute)
+
+	cancelled := make(chan struct{})
+	err := schedulerA.Register("report", "test job", "@daily", func(ctx context.Context) (int64, error) {
+		<-ctx.Done()
+		close(cancelled)
+		return 0, ctx.Err()
+	})
+	if err != nil {
+		t.Fatalf("failed to register job: %v", err)
+	}
+	if err := schedulerB.Register("report", "test job", "@daily", func(ctx context.Context) (int64, error) {
+		return 1, nil
+	}); err != nil {
+		t.Fatalf("failed to register job: %v", err)
+	}
+
+	run, err := schedulerA.Trigger("report", models.JobTriggerManual)
+	if err != nil {
+		t.Fatalf("failed to trigger job: %v", err)
+	}
+
+	// Simulate a stalled instance whose lease expired before it could renew it
+	db := testutil.OpenDB(t, path)
+	if err := db.Model(&models.JobLease{}).Where("name = ?", "report").
+		Update("expires_at", time.Now().UTC().Add(-time.Second)).Error; err != nil {
+		t.Fatalf("failed to expire lease: %v", err)
+	}
+	runB, err := schedulerB.Trigger("report", models.JobTriggerManual)
+	if err != nil {
+		t.Fatalf("expected b to take over the expired lease: %v", err)
+	}
+	if runB.FencingToken <= run.FencingToken {
+		t.Fatalf("expected a higher fencing token, got %d after %d", runB.FencingToken, run.FencingToken)
+	}
+
+	select {
+	case <-cancelled:
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected the run on a to be cancelled after losing its lease")
+	}
+	finished := waitForRun(t, repoA, run.ID, models.JobRunFailed)
+	if finished.Error == "" {
+		t.Fatal("expected the lost lease to be recorded on the run")
+	}
+}
+
+func TestStalledRunCannotWriteAfterTakeover(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jobs.db")
+	// Renewals on a run only every 20s, so it does not notice the takeover by itself
+	schedulerA, repoA := newTestScheduler(t, path, "a", time.Minute)
+	schedulerB, _ := newTestScheduler(t, path, "b", time.Minute)
+
+	if err := CheckJobLease(context.Background()); err != nil {
+		t.Fatalf("expected writes outside of job runs to be allowed, got %v", err)
+	}
+
+	stalled, resume := make(chan struct{}), make(chan struct{})
+	var beforeStall, afterStall error
+	err := schedulerA.Register("report", "test job", "@daily", func(ctx context.Context) (int64, error) {
+		beforeStall = CheckJobLease(ctx)
+		close(stalled)
+		<-resume
+		afterStall = CheckJobLease(ctx)
+		return 0, afterStall
+	})
+	if err != nil {
+		t.Fatalf("failed to register job: %v", err)
+	}
+	if err := schedulerB.Register("report", "test job", "@daily", func(ctx context.Context) (int64, error) {
+		return 1, CheckJobLease(ctx)
+	}); err != nil {
+		t.Fatalf("failed to register job: %v", err)
+	}
+
+	run, err := schedulerA.Trigger("report", models.JobTriggerManual)
+	if err != nil {
+		t.Fatalf("failed to trigger job: %v", err)
+	}
+	<-stalled
+
+	// The stalled run's lease expires and b takes the job over
+	db := testutil.OpenDB(t, path)
+	if err := db.Model(&models.JobLease{}).Where("name = ?", "report").
+		Update("expires_at", time.Now().UTC().Add(-time.Second)).Error; err != nil {
+		t.Fatalf("failed to expire lease: %v", err)
+	}
+	runB, err := schedulerB.Trigger("report", models.JobTriggerManual)
+	if err != nil {
+		t.Fatalf("expected b to take over the expired lease: %v", err)
+	}
+	waitForRun(t, repoA, runB.ID, models.JobRunSucceeded)
+	close(resume)
+
+	finished := waitForRun(t, repoA, run.ID, models.JobRunFailed)
+	if beforeStall != nil || !errors.Is(afterStall, ErrLeaseLost) {
+		t.Fatalf("expected the write check to pass before and fail after the takeover, got %v and %v", beforeStall, afterStall)
+	}
+	if finished.Error != ErrLeaseLost.Error() {
+		t.Errorf("expected the run to fail with the lost lease, got %q", finished.Error)
+	}
+}
+
+func TestStartMarksOnlyAbandonedRunsInterrupted(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jobs.db")
+	repo := repository.NewJobRepository(testutil.OpenDB(t, path))
+
+	started := time.Now().Add(-time.Minute)
+	token, err := repo.AcquireLease("live", "b", time.Now().Add(time.Minute), nil)
+	if err != nil || token == 0 {
+		t.Fatalf("failed to acquire lease: %v", err)
+	}
+	live := &models.JobRun{JobName: "live", Trigger: models.JobTriggerSchedule, Instance: "b", FencingToken: token, Status: models.JobRunRunning, StartedAt: started}
+	abandoned := &models.JobRun{JobName: "dead", Trigger: models.JobTriggerSchedule, Instance: "c", FencingToken: 7, Status: models.JobRunRunning, StartedAt: started}
+	for _, run := range []*models.JobRun{live, abandoned} {
+		if err := repo.CreateRun(run); err != nil {
+			t.Fatalf("failed to create run: %v", err)
+		}
+	}
+
+	scheduler, _ := newTestScheduler(t, path, "a", time.Minute)
+	if err := scheduler.Start(); err != nil {
+		t.Fatalf("failed to start scheduler: %v", err)
+	}
+
+	if run := getRun(t, repo, "live"); run.Status != models.JobRunRunning {
+		t.Errorf("expected the run holding a live lease to keep running, got %s", run.Status)
+	}
+	if run := getRun(t, repo, "dead"); run.Status != models.JobRunInterrupted {
+		t.Errorf("expected the abandoned run to be interrupted, got %s", run.Status)
+	}
+}
+
+func getRun(t *testing.T, repo *repository.JobRepository, name string) *models.JobRun {
+	t.Helper()
+	run, err := repo.GetLastRun(name)
+	if err != nil {
+		t.Fatalf("failed to get run of %s: %v", name, err)
+	}
+	return run
+}
+
+// waitForRun polls until a run reaches the given status
+func waitForRun(t *testing.T, repo *repository.JobRepository, id uint, status string) *models.JobRun {
+	t.Helper()
+	deadline := time.Now().Add(3 * time.Second)
+	for {
+		runs, _, err := repo.GetRuns("report", 100, 0)
+		if err != nil {
+			t.Fatalf("failed to get runs: %v", err)
+		}
+		for _, run := range runs {
+			if run.ID == id && run.Status == status {
+				return &run
+			}
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("run %d did not reach status %s", id, status)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
@@ -0,0 +1,204 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"portfolio-be/internal/config"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// ErrCaptchaFailed is returned when the CAPTCHA response is missing or rejected
+var ErrCaptchaFailed = errors.New("captcha verification failed")
+
+// defaultSpamKeywords are scored when no keywords are configured
+var defaultSpamKeywords = []string{
+	"viagra", "cialis", "casino", "backlinks", "seo services", "payday loan",
+	"crypto investment", "forex signals", "escort", "web traffic",
+}
+
+// linkPattern matches links and BBCode link tags
+var linkPattern = regexp.MustCompile(`(?i)(https?://|www\.)\S+|\[url[=\]]`)
+
+// ContactSubmission is a contact form submission as seen by the spam checks
+type ContactSubmission struct {
+	Name     string
+	Email    string
+	Subject  string
+	Message  string
+	ClientIP string
+}
+
+// SpamVerdict is the score a spam scorer gives a submission and the reasons for it
+type SpamVerdict struct {
+	Score   int
+	Reasons []string
+}
+
+// add raises the score for a reason
+func (v *SpamVerdict) add(score int, reason string) {
+	v.Score += score
+	v.Reasons = append(v.Reasons, reason)
+}
+
+// SpamScorer scores how likely a submission is spam. Higher scores are more likely spam.
+type SpamScorer interface {
+	Score(ctx context.Context, submission *ContactSubmission) (*SpamVerdict, error)
+}
+
+// NewSpamScorer returns the built-in keyword and link heuristics
+func NewSpamScorer(cfg config.ContactConfig) SpamScorer {
+	configured := cfg.SpamKeywords
+	if len(configured) == 0 {
+		configured = defaultSpamKeywords
+	}
+	keywords := make([]string, len(configured))
+	for i, keyword := range configured {
+		keywords[i] = strings.ToLower(keyword)
+	}
+	return &HeuristicSpamScorer{keywords: keywords, maxLinks: cfg.MaxLinks}
+}
+
+// HeuristicSpamScorer scores spam keywords, links beyond an allowance, links in the
+// name or subject, and shouting
+type HeuristicSpamScorer struct {
+	keywords []string
+	maxLinks int
+}
+
+func (s *HeuristicSpamScorer) Score(ctx context.Context, submission *ContactSubmission) (*SpamVerdict, error) {
+	verdict := &SpamVerdict{}
+
+	text := strings.ToLower(submission.Subject + "\n" + submission.Message)
+	for _, keyword := range s.keywords {
+		if strings.Contains(text, keyword) {
+			verdict.add(3, "keyword: "+keyword)
+		}
+	}
+
+	if links := len(linkPattern.FindAllString(submission.Message, -1)); links > s.maxLinks {
+		verdict.add(2*(links-s.maxLinks), fmt.Sprintf("%d links", links))
+	}
+	if linkPattern.MatchString(submission.Name) || linkPattern.MatchString(submission.Subject) {
+		verdict.add(5, "link in name or subject")
+	}
+	if isShouting(submission.Subject) || isShouting(submission.Message) {
+		verdict.add(1, "all caps")
+	}
+
+	return verdict, nil
+}
+
+// isShouting reports whether a text of some length has letters and all of them are upper case
+func isShouting(text string) bool {
+	letters := 0
+	for _, r := range text {
+		if unicode.IsLetter(r) {
+			if unicode.IsLower(r) {
+				return false
+			}
+			letters++
+		}
+	}
+	return letters >= 20
+}
+
+// CaptchaVerifier checks the response token of a CAPTCHA widget
+type CaptchaVerifier interface {
+	// Provider names the widget the form must show, or is empty when CAPTCHA is disabled
+	Provider() string
+	// Verify returns ErrCaptchaFailed when the token is rejected and another error when
+	// the provider could not be asked
+	Verify(ctx context.Context, token, clientIP string) error
+}
+
+// NewCaptchaVerifier returns the CAPTCHA verifier selected by the configuration
+func NewCaptchaVerifier(cfg config.ContactConfig) CaptchaVerifier {
+	client := &http.Client{Timeout: cfg.CaptchaTimeout}
+	switch cfg.Captcha {
+	case "turnstile":
+		log.Println("Verifying contact form CAPTCHA with Cloudflare Turnstile")
+		return &SiteVerifyCaptcha{provider: "turnstile", endpoint: "https://challenges.cloudflare.com/turnstile/v0/siteverify", secret: cfg.CaptchaSecret, client: client}
+	case "hcaptcha":
+		log.Println("Verifying contact form CAPTCHA with hCaptcha")
+		return &SiteVerifyCaptcha{provider: "hcaptcha", endpoint: "https://api.hcaptcha.com/siteverify", secret: cfg.CaptchaSecret, client: client}
+	case "stub":
+		log.Println("Verifying contact form CAPTCHA with the local stub")
+		return StubCaptcha{}
+	default:
+		return NoopCaptcha{}
+	}
+}
+
+// NoopCaptcha accepts every submission
+type NoopCaptcha struct{}
+
+func (NoopCaptcha) Provider() string { return "" }
+
+func (NoopCaptcha) Verify(context.Context, string, string) error { return nil }
+
+// StubCaptcha accepts any token except "fail" and an empty one, for local development
+type StubCaptcha struct{}
+
+func (StubCaptcha) Provider() string { return "stub" }
+
+func (StubCaptcha) Verify(ctx context.Context, token, clientIP string) error {
+	if token == "" || token == "fail" {
+		return ErrCaptchaFailed
+	}
+	return nil
+}
+
+// SiteVerifyCaptcha verifies tokens with a siteverify endpoint, the protocol shared by
+// Turnstile and hCaptcha
+type SiteVerifyCaptcha struct {
+	provider string
+	endpoint string
+	secret   string
+	client   *http.Client
+}
+
+func (c *SiteVerifyCaptcha) Provider() string { return c.provider }
+
+func (c *SiteVerifyCaptcha) Verify(ctx context.Context, token, clientIP string) error {
+	if token == "" {
+		return ErrCaptchaFailed
+	}
+
+	form := url.Values{"secret": {c.secret}, "response": {token}}
+	if clientIP != "" {
+		form.Set("remoteip", clientIP)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return fmt.Errorf("failed to build %s request: %w", c.provider, err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach %s: %w", c.provider, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned status %d", c.provider, resp.StatusCode)
+	}
+
+	var result struct {
+		Success    bool     `json:"success"`
+		ErrorCodes []string `json:"error-codes"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("failed to decode %s response: %w", c.provider, err)
+	}
+	if !result.Success {
+		return fmt.Errorf("%w: %s", ErrCaptchaFailed, strings.Join(result.ErrorCodes, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,171 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"portfolio-be/internal/models"
+	"portfolio-be/internal/repository"
+	"strings"
+)
+
+// ErrInvalidTagType is returned when listing tags for an unknown entity type
+var ErrInvalidTagType = errors.New("type must be one of resource, content or project")
+
+// TagService maintains the normalized tags of resources, contents and projects
+type TagService struct {
+	repo *repository.TagRepository
+}
+
+func NewTagService(repo *repository.TagRepository) *TagService {
+	return &TagService{repo: repo}
+}
+
+// SyncEntity replaces the tags of an entity with the given names
+func (s *TagService) SyncEntity(entityType string, entityID uint, names []string) error {
+	names = models.NormalizeTags(names)
+
+	tagIDs := make([]uint, 0, len(names))
+	for _, name := range names {
+		tag, err := s.repo.FindOrCreate(name, models.TagSlug(name))
+		if err != nil {
+			return fmt.Errorf("failed to save tag %q: %w", name, err)
+		}
+		tagIDs = append(tagIDs, tag.ID)
+	}
+
+	if err := s.repo.ReplaceForEntity(entityType, entityID, tagIDs); err != nil {
+		return fmt.Errorf("failed to save tags: %w", err)
+	}
+	return nil
+}
+
+// RemoveEntity drops the tags of a deleted entity
+func (s *TagService) RemoveEntity(entityType string, entityID uint) error {
+	if err := s.repo.DeleteByEntity(entityType, entityID); err != nil {
+		return fmt.Errorf("failed to remove tags: %w", err)
+	}
+	return nil
+}
+
+// GetTags returns tags with usage counts, optionally limited to one entity type. With
+// publicOnly only entities visitors can see are counted, so that tags of private or
+// unpublished entities are not revealed.
+func (s *TagService) GetTags(entityType string, publicOnly bool) ([]models.TagUsage, error) {
+	switch entityType {
+	case "", models.ReferenceEntityResource, models.ReferenceEntityContent, models.ReferenceEntityProject:
+	default:
+		return nil, ErrInvalidTagType
+	}
+
+	usage, err := s.repo.ListUsage(entityType, publicOnly)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tags: %w", err)
+	}
+	if usage == nil {
+		usage = []models.TagUsage{}
+	}
+	return usage, nil
+}
+
+// Backfill parses the tag strings of entities written before tags were normalized.
+// Comma separated resource and content tags are rewritten in normalized form.
+func (s *TagService) Backfill() (int, error) {
+	tagged := 0
+
+	for _, entityType := range []string{models.ReferenceEntityResource, models.ReferenceEntityContent} {
+		rows, err := s.repo.ListUntagged(entityType)
+		if err != nil {
+			return tagged, fmt.Errorf("failed to list %s tags: %w", entityType, err)
+		}
+		for _, row := range rows {
+			names := models.ParseTags(row.Tags)
+			if err := s.SyncEntity(entityType, row.ID, names); err != nil {
+				return tagged, err
+			}
+			if err := s.repo.UpdateLegacyTags(entityType, row.ID, strings.Join(names, ",")); err != nil {
+				return tagged, fmt.Errorf("failed to rewrite %s tags: %w", entityType, err)
+			}
+			tagged++
+		}
+	}
+
+	rows, err := s.repo.ListUntagged(models.ReferenceEntityProject)
+	if err != nil {
+		return tagged, fmt.Errorf("failed to list project tags: %w", err)
+	}
+	for _, row := range rows {
+		names := projectTagNames(row.Tags)
+		if len(names) == 0 {
+			continue
+		}
+		if err := s.SyncEntity(models.ReferenceEntityProject, row.ID, names); err != nil {
+			return tagged, err
+		}
+		tagged++
+	}
+
+	return tagged, nil
+}
+
+// Reslug gives existing tags the slug their name has now, then retags every entity
+// from its tag column. Tags such as "C++" and "C" that used to share a slug, and so a
+// tag, get one each again.
+func (s *TagService) Reslug() (int, error) {
+	changed := 0
+
+	tags, err := s.repo.GetAll()
+	if err != nil {
+		return changed, fmt.Errorf("failed to list tags: %w", err)
+	}
+	for _, tag := range tags {
+		slug := models.TagSlug(tag.Name)
+		if slug == tag.Slug || slug == "" {
+			continue
+		}
+		// Keep the old slug rather than clash with a tag created since
+		if _, err := s.repo.GetBySlug(slug); err == nil {
+			continue
+		}
+		if err := s.repo.UpdateSlug(tag.ID, slug); err != nil {
+			return changed, fmt.Errorf("failed to update the slug of tag %q: %w", tag.Name, err)
+		}
+		changed++
+	}
+
+	for _, entityType := range []string{models.ReferenceEntityResource, models.ReferenceEntityContent, models.ReferenceEntityProject} {
+		rows, err := s.repo.ListLegacyTags(entityType)
+		if err != nil {
+			return changed, fmt.Errorf("failed to list %s tags: %w", entityType, err)
+		}
+		for _, row := range rows {
+			var names []string
+			if entityType == models.ReferenceEntityProject {
+				names = projectTagNames(row.Tags)
+			} else {
+				names = models.ParseTags(row.Tags)
+			}
+			if err := s.SyncEntity(entityType, row.ID, names); err != nil {
+				return changed, err
+			}
+			changed++
+		}
+	}
+
+	return changed, nil
+}
+
+// projectTagNames extracts tag names from a project's JSON tag column, falling back
+// to a comma separated list for rows that were not stored as JSON
+func projectTagNames(raw string) []string {
+	var tags []models.ProjectTag
+	if err := json.Unmarshal([]byte(raw), &tags); err != nil {
+		return models.ParseTags(raw)
+	}
+
+	names := make([]string, len(tags))
+	for i, tag := range tags {
+		names[i] = tag.Name
+	}
+	return models.NormalizeTags(names)
+}
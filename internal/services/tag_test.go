@@ -0,0 +1,98 @@
+package services
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"portfolio-be/internal/models"
+	"portfolio-be/internal/repository"
+	"portfolio-be/internal/testutil"
+)
+
+func TestTagSlugsTellSymbolsApart(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"C", "c"},
+		{"C++", "c-plus-plus"},
+		{"C#", "c-sharp"},
+		{"F#", "f-sharp"},
+		{"Node.js API", "node-js-api"},
+		{".NET", "dot-net"},
+		{"  Go  ", "go"},
+		{"   ", ""},
+	}
+	for _, tt := range tests {
+		if got := models.TagSlug(tt.name); got != tt.want {
+			t.Errorf("TagSlug(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	// Names without letters or digits still get distinct slugs
+	rocket, star := models.TagSlug("🚀"), models.TagSlug("⭐")
+	if !strings.HasPrefix(rocket, "tag-") || !strings.HasPrefix(star, "tag-") || rocket == star {
+		t.Errorf("expected distinct hashed slugs for symbol-only names, got %q and %q", rocket, star)
+	}
+}
+
+func TestTagsCPlusPlusCSharpAndCStayApart(t *testing.T) {
+	db := testutil.OpenDB(t, filepath.Join(t.TempDir(), "tags.db"))
+	repo := repository.NewTagRepository(db)
+	service := NewTagService(repo)
+
+	if err := service.SyncEntity(models.ReferenceEntityResource, 1, []string{"C++", "C#", "C", "c"}); err != nil {
+		t.Fatalf("failed to tag resource: %v", err)
+	}
+	tags, err := service.GetTags("", false)
+	if err != nil {
+		t.Fatalf("failed to list tags: %v", err)
+	}
+	slugs := map[string]string{}
+	for _, tag := range tags {
+		slugs[tag.Name] = tag.Slug
+	}
+	if len(tags) != 3 || slugs["c++"] != "c-plus-plus" || slugs["c#"] != "c-sharp" || slugs["c"] != "c" {
+		t.Fatalf("expected the tags c++, c# and c, got %v", slugs)
+	}
+
+	// Tags saved when "C++" and "C" shared the slug "c" are split by Reslug
+	db.Exec("DELETE FROM taggings")
+	db.Exec("DELETE FROM tags")
+	merged := &models.Tag{Name: "c++", Slug: "c"}
+	if err := db.Create(merged).Error; err != nil {
+		t.Fatalf("failed to create tag: %v", err)
+	}
+	resources := []*models.Resource{
+		{Name: "Engine", Type: models.ResourceTypeDocument, Tags: "c++"},
+		{Name: "Kernel", Type: models.ResourceTypeDocument, Tags: "c"},
+	}
+	for _, resource := range resources {
+		if err := db.Omit("Upload").Create(resource).Error; err != nil {
+			t.Fatalf("failed to create resource: %v", err)
+		}
+		if err := repo.ReplaceForEntity(models.ReferenceEntityResource, resource.ID, []uint{merged.ID}); err != nil {
+			t.Fatalf("failed to tag resource: %v", err)
+		}
+	}
+
+	if _, err := service.Reslug(); err != nil {
+		t.Fatalf("failed to reslug tags: %v", err)
+	}
+	for _, tt := range []struct {
+		resource *models.Resource
+		slug     string
+	}{{resources[0], "c-plus-plus"}, {resources[1], "c"}} {
+		var ids []uint
+		taggedWith := db.Model(&models.Tagging{}).Select("taggings.entity_id").
+			Joins("JOIN tags ON tags.id = taggings.tag_id").
+			Where("taggings.entity_type = ? AND tags.slug = ?", models.ReferenceEntityResource, tt.slug)
+		if err := taggedWith.Scan(&ids).Error; err != nil {
+			t.Fatalf("failed to list tagged resources: %v", err)
+		}
+		if len(ids) != 1 || ids[0] != tt.resource.ID {
+			t.Errorf("expected only %s to be tagged %s, got %v", tt.resource.Name, tt.slug, ids)
+		}
+	}
+}
@@ -0,0 +1,456 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"portfolio-be/internal/config"
+	"portfolio-be/internal/models"
+	"portfolio-be/internal/repository"
+	"sort"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrTaskNotFound is returned for unknown task ids
+	ErrTaskNotFound = errors.New("task not found")
+	// ErrUnknownTaskKind is returned when enqueueing a kind without a registered handler
+	ErrUnknownTaskKind = errors.New("no handler registered for task kind")
+	// ErrTaskNotDead is returned when retrying a task that is not dead-lettered
+	ErrTaskNotDead = errors.New("only dead tasks can be retried")
+	// ErrTaskRunning is returned when deleting a task a worker holds
+	ErrTaskRunning = errors.New("task is running")
+)
+
+// TaskHandler performs a task. It may run more than once for the same task, after a
+// failure or when a worker died mid-task, so it must be idempotent.
+type TaskHandler func(ctx context.Context, payload json.RawMessage) error
+
+// permanentError marks a task failure that retrying cannot fix
+type permanentError struct {
+	err error
+}
+
+func (e permanentError) Error() string { return e.err.Error() }
+
+func (e permanentError) Unwrap() error { return e.err }
+
+// PermanentError makes a handler failure dead-letter the task without further retries
+func PermanentError(err error) error {
+	return permanentError{err: err}
+}
+
+// taskAttemptKey is the context key of the attempt a handler runs
+type taskAttemptKey struct{}
+
+// taskAttempt is the attempt a handler runs and how many attempts the task has
+type taskAttempt struct {
+	attempt     int
+	maxAttempts int
+}
+
+// IsFinalAttempt reports whether a task handler runs the last attempt of its task, so
+// that a failure will dead-letter the task instead of retrying it
+func IsFinalAttempt(ctx context.Context) bool {
+	attempt, ok := ctx.Value(taskAttemptKey{}).(taskAttempt)
+	return ok && attempt.attempt >= attempt.maxAttempts
+}
+
+// taskKind is a task kind registered with the queue
+type taskKind struct {
+	handler TaskHandler
+	timeout time.Duration
+}
+
+// TaskQueue is a database-backed queue of background tasks run by a pool of workers.
+// Tasks survive restarts: a task is locked to its worker for the visibility timeout
+// and the lock is renewed while it runs, so tasks of a worker that crashed are picked
+// up again by any instance once the lock expires. Failed tasks are retried with
+// exponential backoff and dead-lettered after the configured number of attempts.
+type TaskQueue struct {
+	repo     *repository.TaskRepository
+	cfg      config.TaskConfig
+	instance string
+
+	// stop ends claiming; ctx is cancelled only when running tasks miss the shutdown deadline
+	stop   chan struct{}
+	ctx    context.Context
+	cancel context.CancelFunc
+	wake   chan struct{}
+	wg     sync.WaitGroup
+
+	mu      sync.RWMutex
+	kinds   map[string]taskKind
+	started bool
+	stopped bool
+}
+
+// NewTaskQueue creates a task queue; workers start with Start
+func NewTaskQueue(repo *repository.TaskRepository, cfg config.TaskConfig, instanceID string) *TaskQueue {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &TaskQueue{
+		repo:     repo,
+		cfg:      cfg,
+		instance: instanceID,
+		stop:     make(chan struct{}),
+		ctx:      ctx,
+		cancel:   cancel,
+		wake:     make(chan struct{}, 1),
+		kinds:    make(map[string]taskKind),
+	}
+}
+
+// Register sets the handler of a task kind. Each attempt is cancelled after timeout;
+// zero uses the visibility timeout. Kinds must be registered before Start.
+func (q *TaskQueue) Register(kind string, timeout time.Duration, handler TaskHandler) {
+	if timeout <= 0 {
+		timeout = q.cfg.VisibilityTimeout
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.kinds[kind] = taskKind{handler: handler, timeout: timeout}
+}
+
+// Kinds returns the registered task kinds
+func (q *TaskQueue) Kinds() []string {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+
+	kinds := make([]string, 0, len(q.kinds))
+	for kind := range q.kinds {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	return kinds
+}
+
+// Enqueue stores a task with a JSON-encoded payload for the workers to run as soon as
+// one is free
+func (q *TaskQueue) Enqueue(kind string, payload interface{}) (*models.Task, error) {
+	return q.EnqueueRef(kind, "", payload)
+}
+
+// EnqueueRef is Enqueue for a task about the record named by ref, see DeleteByRefs
+func (q *TaskQueue) EnqueueRef(kind, ref string, payload interface{}) (*models.Task, error) {
+	q.mu.RLock()
+	_, ok := q.kinds[kind]
+	q.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownTaskKind, kind)
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode task payload: %w", err)
+	}
+
+	task := &models.Task{
+		Kind:        kind,
+		Ref:         ref,
+		Payload:     data,
+		Status:      models.TaskPending,
+		MaxAttempts: q.cfg.MaxAttempts,
+		RunAt:       time.Now().UTC(),
+	}
+	if err := q.repo.Create(task); err != nil {
+		return nil, fmt.Errorf("failed to enqueue %s task: %w", kind, err)
+	}
+
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+	return task, nil
+}
+
+// Start starts the workers and the janitor that dead-letters abandoned tasks and
+// prunes succeeded ones
+func (q *TaskQueue) Start() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.started || q.stopped {
+		return
+	}
+	q.started = true
+
+	for i := 1; i <= q.cfg.Workers; i++ {
+		q.wg.Add(1)
+		go q.work(fmt.Sprintf("%s-%d", q.instance, i))
+	}
+	q.wg.Add(1)
+	go q.janitor()
+
+	log.Printf("Task queue started with %d workers as instance %s", q.cfg.Workers, q.instance)
+}
+
+// Close stops claiming tasks and waits for running ones. Tasks still running when ctx
+// is done are cancelled and retried later.
+func (q *TaskQueue) Close(ctx context.Context) error {
+	q.mu.Lock()
+	if !q.stopped {
+		q.stopped = true
+		close(q.stop)
+	}
+	q.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		q.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		q.cancel()
+		log.Println("Task queue stopped")
+		return nil
+	case <-ctx.Done():
+		q.cancel()
+		return fmt.Errorf("tasks did not finish: %w", ctx.Err())
+	}
+}
+
+// work runs due tasks until the queue stops, polling when the queue is empty
+func (q *TaskQueue) work(worker string) {
+	defer q.wg.Done()
+
+	for {
+		select {
+		case <-q.stop:
+			return
+		default:
+		}
+
+		task, err := q.repo.Claim(worker, time.Now().Add(q.cfg.VisibilityTimeout))
+		if err != nil {
+			log.Printf("Task worker %s failed to claim a task: %v", worker, err)
+		}
+		if task != nil {
+			q.run(worker, task)
+			continue
+		}
+
+		select {
+		case <-q.stop:
+			return
+		case <-q.wake:
+		case <-time.After(q.cfg.PollInterval):
+		}
+	}
+}
+
+// run executes a claimed task while renewing its lock and records the outcome
+func (q *TaskQueue) run(worker string, task *models.Task) {
+	q.mu.RLock()
+	kind, ok := q.kinds[task.Kind]
+	q.mu.RUnlock()
+	if !ok {
+		q.finish(worker, task, PermanentError(fmt.Errorf("%w: %s", ErrUnknownTaskKind, task.Kind)))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(q.ctx, kind.timeout)
+	ctx = context.WithValue(ctx, taskAttemptKey{}, taskAttempt{attempt: task.Attempts, maxAttempts: task.MaxAttempts})
+	renewed := make(chan struct{})
+	go func() {
+		defer close(renewed)
+		if !q.keepLock(ctx, worker, task.ID) {
+			cancel()
+		}
+	}()
+
+	start := time.Now()
+	err := safeHandle(ctx, kind.handler, task.Payload)
+	cancel()
+	<-renewed
+
+	if err == nil {
+		log.Printf("Task %d (%s) completed in %v", task.ID, task.Kind, time.Since(start))
+	}
+	q.finish(worker, task, err)
+}
+
+// finish completes a task, schedules its retry or dead-letters it
+func (q *TaskQueue) finish(worker string, task *models.Task, err error) {
+	var held bool
+	var dbErr error
+	switch {
+	case err == nil:
+		held, dbErr = q.repo.Complete(task.ID, worker)
+	case errors.As(err, new(permanentError)) || task.Attempts >= task.MaxAttempts:
+		log.Printf("Task %d (%s) dead-lettered after %d attempts: %v", task.ID, task.Kind, task.Attempts, err)
+		held, dbErr = q.repo.Fail(task.ID, worker, err.Error(), nil)
+	default:
+		retryAt := time.Now().Add(q.backoff(task.Attempts))
+		log.Printf("Task %d (%s) failed on attempt %d of %d, retrying at %s: %v",
+			task.ID, task.Kind, task.Attempts, task.MaxAttempts, retryAt.Format(time.RFC3339), err)
+		held, dbErr = q.repo.Fail(task.ID, worker, err.Error(), &retryAt)
+	}
+
+	switch {
+	case dbErr != nil:
+		log.Printf("Failed to record outcome of task %d (%s): %v", task.ID, task.Kind, dbErr)
+	case !held:
+		log.Printf("Task %d (%s) lock expired before it finished; another worker owns it", task.ID, task.Kind)
+	}
+}
+
+// backoff returns the delay before retrying a task that failed the given attempt
+func (q *TaskQueue) backoff(attempt int) time.Duration {
+	delay := q.cfg.BackoffBase
+	for i := 1; i < attempt && delay < q.cfg.BackoffMax; i++ {
+		delay *= 2
+	}
+	return min(delay, q.cfg.BackoffMax)
+}
+
+// keepLock renews a task lock until ctx is done. It returns false when the lock
+// expired while renewals failed or another worker took the task over.
+func (q *TaskQueue) keepLock(ctx context.Context, worker string, id uint) bool {
+	ticker := time.NewTicker(q.cfg.VisibilityTimeout / 3)
+	defer ticker.Stop()
+
+	lockedUntil := time.Now().Add(q.cfg.VisibilityTimeout)
+	for {
+		select {
+		case <-ctx.Done():
+			return true
+		case <-ticker.C:
+		}
+
+		next := time.Now().Add(q.cfg.VisibilityTimeout)
+		held, err := q.repo.ExtendLock(id, worker, next)
+		switch {
+		case err == nil && held:
+			lockedUntil = next
+		case err == nil:
+			log.Printf("Task %d was taken over by another worker, cancelling it", id)
+			return false
+		case time.Now().After(lockedUntil):
+			log.Printf("Lock of task %d expired while renewals failed: %v", id, err)
+			return false
+		default:
+			log.Printf("Failed to renew lock of task %d, retrying: %v", id, err)
+		}
+	}
+}
+
+// janitor periodically dead-letters tasks abandoned on their last attempt and prunes
+// succeeded tasks past the retention period
+func (q *TaskQueue) janitor() {
+	defer q.wg.Done()
+
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-q.stop:
+			return
+		case <-ticker.C:
+		}
+
+		if count, err := q.repo.DeadLetterExpired(); err != nil {
+			log.Printf("Failed to dead-letter abandoned tasks: %v", err)
+		} else if count > 0 {
+			log.Printf("Dead-lettered %d tasks abandoned on their last attempt", count)
+		}
+		if q.cfg.Retention > 0 {
+			if _, err := q.repo.DeleteSucceededBefore(time.Now().Add(-q.cfg.Retention)); err != nil {
+				log.Printf("Failed to prune succeeded tasks: %v", err)
+			}
+		}
+	}
+}
+
+// safeHandle runs a task handler, turning a panic into an error
+func safeHandle(ctx context.Context, handler TaskHandler, payload json.RawMessage) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("task panicked: %v", r)
+		}
+	}()
+	return handler(ctx, payload)
+}
+
+// List returns tasks filtered by status and kind, newest first
+func (q *TaskQueue) List(status, kind string, limit, offset int) ([]models.Task, int64, error) {
+	tasks, total, err := q.repo.List(status, kind, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get tasks: %w", err)
+	}
+	return tasks, total, nil
+}
+
+// Get returns a task
+func (q *TaskQueue) Get(id uint) (*models.Task, error) {
+	task, err := q.repo.GetByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTaskNotFound
+		}
+		return nil, fmt.Errorf("failed to get task: %w", err)
+	}
+	return task, nil
+}
+
+// Stats counts tasks by status
+func (q *TaskQueue) Stats() (*models.TaskStats, error) {
+	stats, err := q.repo.Stats()
+	if err != nil {
+		return nil, fmt.Errorf("failed to count tasks: %w", err)
+	}
+	return stats, nil
+}
+
+// Retry puts a dead task back in the queue with a fresh set of attempts
+func (q *TaskQueue) Retry(id uint) (*models.Task, error) {
+	requeued, err := q.repo.Requeue(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retry task: %w", err)
+	}
+	if !requeued {
+		if _, err := q.Get(id); err != nil {
+			return nil, err
+		}
+		return nil, ErrTaskNotDead
+	}
+
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+	return q.Get(id)
+}
+
+// Delete removes a task that no worker holds
+func (q *TaskQueue) Delete(id uint) error {
+	deleted, err := q.repo.Delete(id)
+	if err != nil {
+		return fmt.Errorf("failed to delete task: %w", err)
+	}
+	if !deleted {
+		if _, err := q.Get(id); err != nil {
+			return err
+		}
+		return ErrTaskRunning
+	}
+	return nil
+}
+
+// DeleteByRefs removes the tasks about the given records, including running ones, so
+// that payloads and errors quoting a record's data go with it. A worker still running
+// one of them finishes the attempt but its outcome is not recorded.
+func (q *TaskQueue) DeleteByRefs(refs []string) (int64, error) {
+	deleted, err := q.repo.DeleteByRefs(refs)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete tasks: %w", err)
+	}
+	return deleted, nil
+}
@@ -0,0 +1,288 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"portfolio-be/internal/config"
+	"portfolio-be/internal/models"
+	"portfolio-be/internal/repository"
+	"portfolio-be/internal/testutil"
+)
+
+// newTestTaskQueue returns a queue on the database at path with a "test" task kind.
+// Workers are not started; tests claim and finish tasks themselves.
+func newTestTaskQueue(t *testing.T, path string, maxAttempts int) (*TaskQueue, *repository.TaskRepository) {
+	t.Helper()
+	repo := repository.NewTaskRepository(testutil.OpenDB(t, path))
+	queue := NewTaskQueue(repo, config.TaskConfig{
+		Workers:           1,
+		VisibilityTimeout: time.Minute,
+		MaxAttempts:       maxAttempts,
+		BackoffBase:       time.Millisecond,
+		BackoffMax:        time.Millisecond,
+	}, "test")
+	queue.Register("test", 0, func(context.Context, json.RawMessage) error { return nil })
+	return queue, repo
+}
+
+// enqueueTestTasks enqueues count tasks of the "test" kind
+func enqueueTestTasks(t *testing.T, queue *TaskQueue, count int) []*models.Task {
+	t.Helper()
+	var tasks []*models.Task
+	for i := 0; i < count; i++ {
+		task, err := queue.Enqueue("test", map[string]int{"n": i})
+		if err != nil {
+			t.Fatalf("failed to enqueue task: %v", err)
+		}
+		tasks = append(tasks, task)
+	}
+	return tasks
+}
+
+// getTestTask reloads a task
+func getTestTask(t *testing.T, repo *repository.TaskRepository, id uint) *models.Task {
+	t.Helper()
+	task, err := repo.GetByID(id)
+	if err != nil {
+		t.Fatalf("failed to get task %d: %v", id, err)
+	}
+	return task
+}
+
+func TestClaimHandsEachTaskToOneWorker(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.db")
+	queue, _ := newTestTaskQueue(t, path, 3)
+	tasks := enqueueTestTasks(t, queue, 30)
+
+	// Workers of two instances, each with its own connection
+	repos := []*repository.TaskRepository{
+		repository.NewTaskRepository(testutil.OpenDB(t, path)),
+		repository.NewTaskRepository(testutil.OpenDB(t, path)),
+	}
+
+	var mu sync.Mutex
+	claimedBy := make(map[uint][]string)
+	var wg sync.WaitGroup
+	for i := 0; i < 6; i++ {
+		worker := fmt.Sprintf("worker-%d", i)
+		repo := repos[i%len(repos)]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				task, err := repo.Claim(worker, time.Now().Add(time.Minute))
+				if err != nil {
+					t.Errorf("failed to claim a task: %v", err)
+					return
+				}
+				if task == nil {
+					return
+				}
+				mu.Lock()
+				claimedBy[task.ID] = append(claimedBy[task.ID], worker)
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(claimedBy) != len(tasks) {
+		t.Errorf("expected %d tasks to be claimed, got %d", len(tasks), len(claimedBy))
+	}
+	for id, workers := range claimedBy {
+		if len(workers) != 1 {
+			t.Errorf("task %d was claimed by %v", id, workers)
+		}
+	}
+}
+
+func TestExpiredLockIsClaimedAgain(t *testing.T) {
+	queue, repo := newTestTaskQueue(t, filepath.Join(t.TempDir(), "tasks.db"), 3)
+	enqueueTestTasks(t, queue, 1)
+
+	first, err := repo.Claim("worker-1", time.Now().Add(time.Minute))
+	if err != nil || first == nil {
+		t.Fatalf("expected to claim the task, got %v, %v", first, err)
+	}
+	if task, _ := repo.Claim("worker-2", time.Now().Add(time.Minute)); task != nil {
+		t.Fatalf("expected a locked task not to be claimed again, got task %d", task.ID)
+	}
+
+	// The first worker dies and its lock expires
+	if _, err := repo.ExtendLock(first.ID, "worker-1", time.Now().Add(-time.Second)); err != nil {
+		t.Fatalf("failed to expire lock: %v", err)
+	}
+	second, err := repo.Claim("worker-2", time.Now().Add(time.Minute))
+	if err != nil || second == nil {
+		t.Fatalf("expected the expired task to be claimed again, got %v, %v", second, err)
+	}
+	if second.ID != first.ID || second.Attempts != 2 || second.LockedBy != "worker-2" {
+		t.Errorf("expected attempt 2 of task %d on worker-2, got %+v", first.ID, second)
+	}
+
+	// The first worker's late outcome is not recorded
+	if held, err := repo.Complete(first.ID, "worker-1"); err != nil || held {
+		t.Errorf("expected the stale worker not to complete the task, got %v, %v", held, err)
+	}
+	if task := getTestTask(t, repo, first.ID); task.Status != models.TaskRunning || task.LockedBy != "worker-2" {
+		t.Errorf("expected the task to stay running on worker-2, got %s on %q", task.Status, task.LockedBy)
+	}
+}
+
+func TestFailedTaskRetriesThenDeadLetters(t *testing.T) {
+	queue, repo := newTestTaskQueue(t, filepath.Join(t.TempDir(), "tasks.db"), 2)
+	tasks := enqueueTestTasks(t, queue, 1)
+	failure := errors.New("mail server unavailable")
+
+	task, _ := repo.Claim("worker-1", time.Now().Add(time.Minute))
+	queue.finish("worker-1", task, failure)
+	retried := getTestTask(t, repo, tasks[0].ID)
+	if retried.Status != models.TaskPending || retried.LastError != failure.Error() || retried.LockedUntil != nil {
+		t.Fatalf("expected the task to be pending retry, got %+v", retried)
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	task, _ = repo.Claim("worker-1", time.Now().Add(time.Minute))
+	if task == nil || task.Attempts != 2 {
+		t.Fatalf("expected the retry to be claimed as attempt 2, got %+v", task)
+	}
+	queue.finish("worker-1", task, failure)
+	dead := getTestTask(t, repo, tasks[0].ID)
+	if dead.Status != models.TaskDead || dead.FinishedAt == nil {
+		t.Errorf("expected the task to be dead-lettered after its last attempt, got %+v", dead)
+	}
+}
+
+func TestPermanentErrorDeadLettersOnFirstAttempt(t *testing.T) {
+	queue, repo := newTestTaskQueue(t, filepath.Join(t.TempDir(), "tasks.db"), 5)
+	tasks := enqueueTestTasks(t, queue, 1)
+
+	task, _ := repo.Claim("worker-1", time.Now().Add(time.Minute))
+	queue.finish("worker-1", task, PermanentError(errors.New("recipient address rejected")))
+
+	dead := getTestTask(t, repo, tasks[0].ID)
+	if dead.Status != models.TaskDead || dead.Attempts != 1 {
+		t.Errorf("expected the task to be dead after 1 attempt, got %s after %d", dead.Status, dead.Attempts)
+	}
+	if dead.LastError != "recipient address rejected" {
+		t.Errorf("expected the handler error to be recorded, got %q", dead.LastError)
+	}
+}
+
+func TestTaskBackoffIsCapped(t *testing.T) {
+	queue := NewTaskQueue(nil, config.TaskConfig{BackoffBase: time.Second, BackoffMax: 10 * time.Second}, "test")
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{3, 4 * time.Second},
+		{4, 8 * time.Second},
+		{5, 10 * time.Second},
+		{60, 10 * time.Second},
+	}
+	for _, tt := range tests {
+		if got := queue.backoff(tt.attempt); got != tt.want {
+			t.Errorf("attempt %d: expected %v, got %v", tt.attempt, tt.want, got)
+		}
+	}
+}
+
+func TestDeadLetterExpiredOnlyTakesAbandonedLastAttempts(t *testing.T) {
+	_, repo := newTestTaskQueue(t, filepath.Join(t.TempDir(), "tasks.db"), 3)
+
+	expired := time.Now().Add(-time.Second).UTC()
+	locked := time.Now().Add(time.Minute).UTC()
+	tasks := map[string]*models.Task{
+		// Abandoned on its last attempt
+		models.TaskDead: {Attempts: 3, LockedUntil: &expired},
+		// Abandoned with attempts left, so it is claimed again instead
+		"retry": {Attempts: 1, LockedUntil: &expired},
+		// Still held by a live worker
+		"held": {Attempts: 3, LockedUntil: &locked},
+	}
+	for _, task := range tasks {
+		task.Kind, task.Status, task.MaxAttempts, task.LockedBy, task.RunAt = "test", models.TaskRunning, 3, "worker-1", expired
+		if err := repo.Create(task); err != nil {
+			t.Fatalf("failed to create task: %v", err)
+		}
+	}
+
+	count, err := repo.DeadLetterExpired()
+	if err != nil {
+		t.Fatalf("failed to dead-letter tasks: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("expected 1 task to be dead-lettered, got %d", count)
+	}
+	for name, task := range tasks {
+		want := models.TaskRunning
+		if name == models.TaskDead {
+			want = models.TaskDead
+		}
+		if got := getTestTask(t, repo, task.ID); got.Status != want {
+			t.Errorf("%s task: expected %s, got %s", name, want, got.Status)
+		}
+	}
+}
+
+func TestRetryResetsAttemptsOfDeadTasks(t *testing.T) {
+	queue, repo := newTestTaskQueue(t, filepath.Join(t.TempDir(), "tasks.db"), 1)
+	tasks := enqueueTestTasks(t, queue, 1)
+
+	if _, err := queue.Retry(tasks[0].ID); !errors.Is(err, ErrTaskNotDead) {
+		t.Errorf("expected a pending task not to be retried, got %v", err)
+	}
+	if _, err := queue.Retry(9999); !errors.Is(err, ErrTaskNotFound) {
+		t.Errorf("expected ErrTaskNotFound, got %v", err)
+	}
+
+	task, _ := repo.Claim("worker-1", time.Now().Add(time.Minute))
+	queue.finish("worker-1", task, errors.New("boom"))
+	if dead := getTestTask(t, repo, task.ID); dead.Status != models.TaskDead {
+		t.Fatalf("expected the task to be dead, got %s", dead.Status)
+	}
+
+	retried, err := queue.Retry(task.ID)
+	if err != nil {
+		t.Fatalf("failed to retry task: %v", err)
+	}
+	if retried.Status != models.TaskPending || retried.Attempts != 0 || retried.FinishedAt != nil {
+		t.Errorf("expected a pending task with no attempts, got %+v", retried)
+	}
+	if again, _ := repo.Claim("worker-1", time.Now().Add(time.Minute)); again == nil || again.ID != task.ID || again.Attempts != 1 {
+		t.Errorf("expected the retried task to be claimed as attempt 1, got %+v", again)
+	}
+}
+
+func TestDeleteRefusesRunningTasks(t *testing.T) {
+	queue, repo := newTestTaskQueue(t, filepath.Join(t.TempDir(), "tasks.db"), 3)
+	tasks := enqueueTestTasks(t, queue, 2)
+
+	running, _ := repo.Claim("worker-1", time.Now().Add(time.Minute))
+	if err := queue.Delete(running.ID); !errors.Is(err, ErrTaskRunning) {
+		t.Errorf("expected ErrTaskRunning, got %v", err)
+	}
+	if _, err := repo.GetByID(running.ID); err != nil {
+		t.Errorf("expected the running task to be kept, got %v", err)
+	}
+
+	pending := tasks[1]
+	if pending.ID == running.ID {
+		pending = tasks[0]
+	}
+	if err := queue.Delete(pending.ID); err != nil {
+		t.Errorf("failed to delete pending task: %v", err)
+	}
+	if err := queue.Delete(pending.ID); !errors.Is(err, ErrTaskNotFound) {
+		t.Errorf("expected ErrTaskNotFound for a deleted task, got %v", err)
+	}
+}
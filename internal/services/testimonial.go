@@ -18,10 +18,11 @@ type TestimonialService interface {
 
 type testimonialService struct {
 	testimonialRepo repository.TestimonialRepository
+	references      *UploadReferenceService
 }
 
-func NewTestimonialService(testimonialRepo repository.TestimonialRepository) TestimonialService {
-	return &testimonialService{testimonialRepo: testimonialRepo}
+func NewTestimonialService(testimonialRepo repository.TestimonialRepository, references *UploadReferenceService) TestimonialService {
+	return &testimonialService{testimonialRepo: testimonialRepo, references: references}
 }
 
 func (s *testimonialService) CreateTestimonial(request *models.TestimonialRequest) (*models.TestimonialResponse, error) {
@@ -40,6 +41,10 @@ func (s *testimonialService) CreateTestimonial(request *models.TestimonialReques
 		return nil, err
 	}
 
+	if err := s.syncReferences(testimonial); err != nil {
+		return nil, err
+	}
+
 	response := testimonial.ToResponse()
 	return &response, nil
 }
@@ -87,12 +92,24 @@ func (s *testimonialService) UpdateTestimonial(id uint, request *models.Testimon
 		return nil, err
 	}
 
+	if err := s.syncReferences(testimonial); err != nil {
+		return nil, err
+	}
+
 	response := testimonial.ToResponse()
 	return &response, nil
 }
 
 func (s *testimonialService) DeleteTestimonial(id uint) error {
-	return s.testimonialRepo.Delete(id)
+	if err := s.testimonialRepo.Delete(id); err != nil {
+		return err
+	}
+	return s.references.RemoveEntity(models.ReferenceEntityTestimonial, id)
+}
+
+// syncReferences indexes the uploads used by a testimonial
+func (s *testimonialService) syncReferences(testimonial *models.Testimonial) error {
+	return s.references.SyncEntity(models.ReferenceEntityTestimonial, testimonial.ID, map[string]string{"image": testimonial.Image})
 }
 
 func (s *testimonialService) GetActiveTestimonials() ([]models.TestimonialResponse, error) {
@@ -1,113 +1,284 @@
 package services
 
 import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"io"
+	"log"
 	"mime/multipart"
+	"portfolio-be/internal/config"
 	"portfolio-be/internal/models"
 	"portfolio-be/internal/repository"
+	"portfolio-be/pkg/utils"
 	"slices"
 	"strings"
 	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrUploadInUse is returned when deleting an upload that entities still reference
+	ErrUploadInUse = errors.New("upload is still referenced")
+	// ErrFileTypeNotAllowed is returned for content types uploads never accept
+	ErrFileTypeNotAllowed = errors.New("file type is not allowed")
 )
 
 type UploadService struct {
-	repo      *repository.UploadRepository
-	s3Service *S3Service
+	repo       *repository.UploadRepository
+	s3Service  *S3Service
+	references *UploadReferenceService
+	prober     MediaProber
+	mediaJobs  MediaJobRunner
+	scanner    Scanner
+	quotas     *UploadQuotaService
+	images     config.ImageConfig
 }
 
-func NewUploadService(repo *repository.UploadRepository, s3Service *S3Service) *UploadService {
+func NewUploadService(repo *repository.UploadRepository, s3Service *S3Service, references *UploadReferenceService, prober MediaProber, mediaJobs MediaJobRunner, scanner Scanner, quotas *UploadQuotaService, images config.ImageConfig) *UploadService {
 	return &UploadService{
-		repo:      repo,
-		s3Service: s3Service,
+		repo:       repo,
+		s3Service:  s3Service,
+		references: references,
+		prober:     prober,
+		mediaJobs:  mediaJobs,
+		scanner:    scanner,
+		quotas:     quotas,
+		images:     images,
 	}
 }
 
-func (s *UploadService) UploadFile(file multipart.File, header *multipart.FileHeader) (*models.UploadResponse, error) {
-	// Validate file type (optional - you can add more restrictions)
-	allowedTypes := []string{
-		"image/jpeg", "image/jpg", "image/png", "image/gif", "image/webp", "image/svg+xml",
-		"video/mp4", "video/webm", "video/ogg", "video/avi", "video/quicktime",
-		"application/pdf", "text/plain", "application/msword",
-		"application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+// allowedUploadTypes are the content types uploads accept
+var allowedUploadTypes = []string{
+	"image/jpeg", "image/jpg", "image/png", "image/gif", "image/webp", "image/svg+xml",
+	"video/mp4", "video/webm", "video/ogg", "video/avi", "video/quicktime",
+	"audio/mpeg", "audio/mp3", "audio/wav", "audio/x-wav", "audio/ogg", "audio/webm",
+	"audio/aac", "audio/mp4", "audio/x-m4a", "audio/flac",
+	"application/pdf", "text/plain", "application/msword",
+	"application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+}
+
+// contentTypeFromName detects a content type from a file name's extension
+func contentTypeFromName(name string) string {
+	ext := strings.ToLower(name[strings.LastIndex(name, ".")+1:])
+	switch ext {
+	case "jpg", "jpeg":
+		return "image/jpeg"
+	case "png":
+		return "image/png"
+	case "gif":
+		return "image/gif"
+	case "webp":
+		return "image/webp"
+	case "svg":
+		return "image/svg+xml"
+	case "mp4":
+		return "video/mp4"
+	case "webm":
+		return "video/webm"
+	case "ogg":
+		return "video/ogg"
+	case "avi":
+		return "video/avi"
+	case "mov":
+		return "video/quicktime"
+	case "mp3":
+		return "audio/mpeg"
+	case "wav":
+		return "audio/wav"
+	case "oga":
+		return "audio/ogg"
+	case "m4a":
+		return "audio/mp4"
+	case "aac":
+		return "audio/aac"
+	case "flac":
+		return "audio/flac"
+	case "pdf":
+		return "application/pdf"
+	case "txt":
+		return "text/plain"
+	case "doc":
+		return "application/msword"
+	case "docx":
+		return "application/vnd.openxmlformats-officedocument.wordprocessingml.document"
+	default:
+		return "application/octet-stream"
 	}
+}
 
+// UploadFile stores a file uploaded by a user, enforcing the user's upload quota
+func (s *UploadService) UploadFile(file multipart.File, header *multipart.FileHeader, userID uint) (*models.UploadResponse, error) {
 	contentType := header.Header.Get("Content-Type")
 	if contentType == "" {
 		// Try to detect from filename
-		ext := strings.ToLower(header.Filename[strings.LastIndex(header.Filename, ".")+1:])
-		switch ext {
-		case "jpg", "jpeg":
-			contentType = "image/jpeg"
-		case "png":
-			contentType = "image/png"
-		case "gif":
-			contentType = "image/gif"
-		case "webp":
-			contentType = "image/webp"
-		case "svg":
-			contentType = "image/svg+xml"
-		case "mp4":
-			contentType = "video/mp4"
-		case "webm":
-			contentType = "video/webm"
-		case "ogg":
-			contentType = "video/ogg"
-		case "avi":
-			contentType = "video/avi"
-		case "mov":
-			contentType = "video/quicktime"
-		case "pdf":
-			contentType = "application/pdf"
-		default:
-			contentType = "application/octet-stream"
-		}
+		contentType = contentTypeFromName(header.Filename)
 	}
 
+	return s.StoreFile(file, header.Filename, contentType, header.Size, userID)
+}
+
+// StoreFile validates and stores the contents of a file of the given size on behalf of
+// a user, enforcing the user's upload quota
+func (s *UploadService) StoreFile(r io.Reader, name, contentType string, size int64, userID uint) (*models.UploadResponse, error) {
 	// Check if content type is allowed
-	allowed := slices.Contains(allowedTypes, contentType)
-	if !allowed {
-		return nil, fmt.Errorf("file type %s is not allowed", contentType)
+	if !slices.Contains(allowedUploadTypes, contentType) {
+		return nil, fmt.Errorf("%w: %s", ErrFileTypeNotAllowed, contentType)
+	}
+
+	if err := s.quotas.Check(userID, size, contentType); err != nil {
+		return nil, err
 	}
 
-	// Upload to S3
-	s3Key, url, err := s.s3Service.UploadFile(file, header)
+	data, err := io.ReadAll(r)
 	if err != nil {
-		return nil, fmt.Errorf("failed to upload file: %w", err)
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	// Strip EXIF/XMP metadata, normalize orientation and extract image metadata
+	var metadata *ImageMetadata
+	if strings.HasPrefix(contentType, "image/") {
+		data, metadata, err = ProcessImage(data, contentType, s.images.MaxPixels)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Probe duration, dimensions and codecs; files that cannot be probed are still accepted
+	var mediaInfo *MediaInfo
+	if IsMediaType(contentType) {
+		mediaInfo, err = s.prober.Probe(data, contentType)
+		if err != nil {
+			log.Printf("Failed to probe %s: %v", name, err)
+		}
 	}
 
+	hash := sha256.Sum256(data)
+	contentHash := hex.EncodeToString(hash[:])
+
 	// Set expiry time for the URL (7 days from now)
 	expiresAt := time.Now().Add(365 * 24 * time.Hour)
 
-	// Save to database
 	upload := &models.Upload{
-		FileName:     s3Key[strings.LastIndex(s3Key, "/")+1:], // Extract filename from s3 key
-		OriginalName: header.Filename,
-		FileSize:     header.Size,
+		OriginalName: name,
+		FileSize:     int64(len(data)),
 		ContentType:  contentType,
-		S3Key:        s3Key,
-		S3Bucket:     s.s3Service.bucket,
-		URL:          url,
 		ExpiresAt:    &expiresAt,
 		IsActive:     true,
+		ContentHash:  contentHash,
+	}
+	if userID != 0 {
+		upload.UploadedBy = &userID
 	}
 
+	if metadata != nil {
+		upload.Width = metadata.Width
+		upload.Height = metadata.Height
+		upload.DominantColor = metadata.DominantColor
+		upload.BlurHash = metadata.BlurHash
+	}
+	if mediaInfo != nil {
+		upload.Duration = mediaInfo.Duration
+		upload.Width = mediaInfo.Width
+		upload.Height = mediaInfo.Height
+		upload.VideoCodec = mediaInfo.VideoCodec
+		upload.AudioCodec = mediaInfo.AudioCodec
+	}
+
+	// Reuse the stored object when identical content was uploaded before
+	uploadedNew := false
+	existing, err := s.repo.GetByContentHash(contentHash)
+	if err == nil {
+		upload.FileName = existing.FileName
+		upload.S3Key = existing.S3Key
+		upload.S3Bucket = existing.S3Bucket
+		upload.PosterKey = existing.PosterKey
+		upload.TranscodedKey = existing.TranscodedKey
+		upload.ScanStatus = existing.ScanStatus
+		upload.ScanSignature = existing.ScanSignature
+		upload.ScannedAt = existing.ScannedAt
+	} else {
+		existing = nil
+		if err := s.storeObject(upload, data, contentType); err != nil {
+			return nil, err
+		}
+		uploadedNew = true
+	}
+
+	// Save to database
 	if err := s.repo.Create(upload); err != nil {
-		// If database save fails, try to clean up S3
-		s.s3Service.DeleteFile(s3Key)
+		// If database save fails, clean up S3
+		if uploadedNew {
+			s.s3Service.DeleteFileLater(upload.S3Key)
+		}
 		return nil, fmt.Errorf("failed to save upload record: %w", err)
 	}
 
-	response := upload.ToResponse()
+	// Parallel uploads all pass the quota check before any of them is saved
+	if err := s.quotas.Confirm(userID, upload.ID); err != nil {
+		if _, removeErr := s.removeUpload(upload); removeErr != nil {
+			log.Printf("Failed to remove upload %d over quota: %v", upload.ID, removeErr)
+		}
+		return nil, err
+	}
+
+	// A concurrent delete of the reused upload deletes the object unless it counts this
+	// record (see removeUpload). It counts it unless the reused upload was deleted
+	// before this record was saved, so then the object gets a copy of its own.
+	if existing != nil {
+		if _, err := s.repo.GetByID(existing.ID); errors.Is(err, gorm.ErrRecordNotFound) {
+			if err := s.storeObject(upload, data, contentType); err != nil {
+				s.repo.Delete(upload.ID)
+				return nil, err
+			}
+			if err := s.repo.UpdateObject(upload); err != nil {
+				s.s3Service.DeleteFileLater(upload.S3Key)
+				s.repo.Delete(upload.ID)
+				return nil, fmt.Errorf("failed to save upload record: %w", err)
+			}
+			uploadedNew = true
+		}
+	}
+
+	// Uploads stay quarantined until the scan is clean; failed scans are retried by the cron service
+	if upload.ScanStatus != models.UploadScanClean && upload.ScanStatus != models.UploadScanInfected {
+		if err := s.applyScan(context.Background(), upload, bytes.NewReader(data)); err != nil {
+			log.Printf("Upload %d stays quarantined until rescanned: %v", upload.ID, err)
+		}
+	}
+
+	// Derived files are stored once per S3 object, so only new objects get jobs
+	if uploadedNew {
+		for _, job := range MediaJobsFor(upload.ID, contentType) {
+			if err := s.mediaJobs.Submit(job); err != nil {
+				log.Printf("Failed to queue media %s job for upload %d: %v", job.Kind, upload.ID, err)
+			}
+		}
+	}
+
+	response := s.toResponse(upload)
 	return &response, nil
 }
 
-func (s *UploadService) GetUploadByID(id uint) (*models.UploadResponse, error) {
-	upload, err := s.repo.GetByID(id)
+// GetUploadByID returns an upload. With publicOnly set, only uploads shown on the
+// public site are returned.
+func (s *UploadService) GetUploadByID(id uint, publicOnly bool) (*models.UploadResponse, error) {
+	var upload *models.Upload
+	var err error
+	if publicOnly {
+		upload, err = s.repo.GetPublicByID(id)
+	} else {
+		upload, err = s.repo.GetByID(id)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get upload: %w", err)
 	}
 
-	response := upload.ToResponse()
+	response := s.toResponse(upload)
 	return &response, nil
 }
 
@@ -119,44 +290,53 @@ func (s *UploadService) GetAllUploads(limit, offset int) ([]models.UploadRespons
 
 	responses := make([]models.UploadResponse, len(uploads))
 	for i, upload := range uploads {
-		responses[i] = upload.ToResponse()
+		responses[i] = s.toResponse(&upload)
 	}
 
 	return responses, nil
 }
 
-func (s *UploadService) GetUploadsWithCount(limit, offset int) ([]models.UploadResponse, int64, error) {
-	uploads, err := s.repo.GetAll(limit, offset)
+// GetUploadsWithCount returns a page of uploads and their total. With publicOnly set,
+// only uploads shown on the public site are listed and counted.
+func (s *UploadService) GetUploadsWithCount(publicOnly bool, limit, offset int) ([]models.UploadResponse, int64, error) {
+	uploads, err := s.listUploads(publicOnly, limit, offset)
 	if err != nil {
 		return nil, 0, fmt.Errorf("failed to get uploads: %w", err)
 	}
 
-	count, err := s.repo.Count()
+	var count int64
+	if publicOnly {
+		count, err = s.repo.CountPublic()
+	} else {
+		count, err = s.repo.Count()
+	}
 	if err != nil {
 		return nil, 0, fmt.Errorf("failed to get upload count: %w", err)
 	}
 
 	responses := make([]models.UploadResponse, len(uploads))
 	for i, upload := range uploads {
-		responses[i] = upload.ToResponse()
+		responses[i] = s.toResponse(&upload)
 	}
 
 	return responses, count, nil
 }
 
-func (s *UploadService) GetAllUploadsWithSummary(limit, offset int) (*models.UploadListResponse, error) {
-	uploads, err := s.repo.GetAll(limit, offset)
+// GetAllUploadsWithSummary returns a page of uploads with statistics over all of them.
+// With publicOnly set, both cover only uploads shown on the public site.
+func (s *UploadService) GetAllUploadsWithSummary(publicOnly bool, limit, offset int) (*models.UploadListResponse, error) {
+	uploads, err := s.listUploads(publicOnly, limit, offset)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get uploads: %w", err)
 	}
 
 	responses := make([]models.UploadResponse, len(uploads))
 	for i, upload := range uploads {
-		responses[i] = upload.ToResponse()
+		responses[i] = s.toResponse(&upload)
 	}
 
 	// Get summary
-	summary, err := s.repo.GetUploadSummary()
+	summary, err := s.repo.GetUploadSummary(nil, publicOnly)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get upload summary: %w", err)
 	}
@@ -167,22 +347,394 @@ func (s *UploadService) GetAllUploadsWithSummary(limit, offset int) (*models.Upl
 	}, nil
 }
 
-func (s *UploadService) DeleteUpload(id uint) error {
+func (s *UploadService) listUploads(publicOnly bool, limit, offset int) ([]models.Upload, error) {
+	if publicOnly {
+		return s.repo.GetPublic(limit, offset)
+	}
+	return s.repo.GetAll(limit, offset)
+}
+
+// DeleteUpload deletes an upload. Uploads still referenced by other entities are
+// rejected with ErrUploadInUse unless cascade is set, in which case referencing
+// resources are deleted and referencing URL fields are cleared first.
+func (s *UploadService) DeleteUpload(id uint, cascade bool) error {
 	// Get upload record first
 	upload, err := s.repo.GetByID(id)
 	if err != nil {
 		return fmt.Errorf("failed to get upload: %w", err)
 	}
 
-	// Delete from S3
-	if err := s.s3Service.DeleteFile(upload.S3Key); err != nil {
-		return fmt.Errorf("failed to delete file from S3: %w", err)
+	refs, err := s.references.GetReferences(id)
+	if err != nil {
+		return err
+	}
+
+	if len(refs) > 0 {
+		if !cascade {
+			return fmt.Errorf("%w by %d entities", ErrUploadInUse, len(refs))
+		}
+		for _, ref := range refs {
+			if err := s.references.DetachReference(ref); err != nil {
+				return err
+			}
+		}
+	}
+
+	_, err = s.removeUpload(upload)
+	return err
+}
+
+// removeUpload deletes an upload record and, once no other upload shares it, its S3
+// object. The record is deleted before the others are counted, so that an upload
+// saved meanwhile reusing the object is either counted or stores its own copy (see
+// StoreFile). It reports whether the S3 object was deleted.
+func (s *UploadService) removeUpload(upload *models.Upload) (bool, error) {
+	if err := s.repo.Delete(upload.ID); err != nil {
+		return false, fmt.Errorf("failed to delete upload record: %w", err)
 	}
 
-	// Delete from database
-	if err := s.repo.Delete(id); err != nil {
-		return fmt.Errorf("failed to delete upload record: %w", err)
+	references, err := s.repo.CountByS3Key(upload.S3Key, upload.ID)
+	if err != nil {
+		return false, fmt.Errorf("failed to count upload references: %w", err)
+	}
+	if references > 0 {
+		return false, nil
 	}
 
+	// The record is gone, so a failed delete is retried in the background
+	for _, key := range []string{upload.S3Key, upload.PosterKey, upload.TranscodedKey} {
+		if key == "" {
+			continue
+		}
+		s.s3Service.DeleteFileLater(key)
+	}
+	return true, nil
+}
+
+// storeObject uploads the contents of a new upload to S3 and points the upload at it
+func (s *UploadService) storeObject(upload *models.Upload, data []byte, contentType string) error {
+	s3Key, err := s.s3Service.UploadBytes(data, upload.OriginalName, contentType)
+	if err != nil {
+		return fmt.Errorf("failed to upload file: %w", err)
+	}
+
+	upload.FileName = s3Key[strings.LastIndex(s3Key, "/")+1:] // Extract filename from s3 key
+	upload.S3Key = s3Key
+	upload.S3Bucket = s.s3Service.bucket
+	upload.PosterKey = ""
+	upload.TranscodedKey = ""
 	return nil
 }
+
+// CleanupExpiredUploads deletes uploads that expired or were deactivated more than
+// gracePeriod ago and that no resource uses, working through them batchSize at a
+// time. Uploads still referenced by other entities are skipped. In dry-run mode
+// nothing is deleted and the report describes what would be removed. The cleanup stops
+// before the next upload once ctx is cancelled or its job lease is lost.
+func (s *UploadService) CleanupExpiredUploads(ctx context.Context, gracePeriod time.Duration, batchSize int, dryRun bool) (*models.CleanupReport, error) {
+	report := &models.CleanupReport{
+		DryRun:      dryRun,
+		GracePeriod: gracePeriod.String(),
+		StartedAt:   time.Now(),
+	}
+	cutoff := report.StartedAt.Add(-gracePeriod)
+
+	// candidates counts the uploads of each S3 object a dry run would delete
+	candidates := make(map[string]int64)
+	var lastID uint
+	for {
+		uploads, err := s.repo.GetExpiredUnreferenced(cutoff, lastID, batchSize)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get expired uploads: %w", err)
+		}
+		if len(uploads) == 0 {
+			break
+		}
+		report.Batches++
+
+		for i := range uploads {
+			if err := CheckJobLease(ctx); err != nil {
+				return nil, fmt.Errorf("cleanup stopped after %d uploads: %w", report.Deleted, err)
+			}
+			upload := &uploads[i]
+			lastID = upload.ID
+			report.Scanned++
+
+			referenced, err := s.references.IsReferenced(upload.ID)
+			if err != nil {
+				report.Failed++
+				report.Errors = append(report.Errors, fmt.Sprintf("upload %d: %v", upload.ID, err))
+				continue
+			}
+			if referenced {
+				report.Skipped++
+				continue
+			}
+
+			// An S3 object is freed with the last of its uploads, so a dry run counts its
+			// bytes once every other upload sharing it was already a candidate
+			if dryRun {
+				shared, err := s.repo.CountByS3Key(upload.S3Key, upload.ID)
+				if err != nil {
+					report.Failed++
+					report.Errors = append(report.Errors, fmt.Sprintf("upload %d: %v", upload.ID, err))
+					continue
+				}
+				report.Deleted++
+				if shared == candidates[upload.S3Key] {
+					report.BytesFreed += upload.FileSize
+				}
+				candidates[upload.S3Key]++
+				continue
+			}
+
+			freed, err := s.removeUpload(upload)
+			if freed {
+				report.BytesFreed += upload.FileSize
+			}
+			if err != nil {
+				report.Failed++
+				report.Errors = append(report.Errors, fmt.Sprintf("upload %d: %v", upload.ID, err))
+				continue
+			}
+			report.Deleted++
+		}
+
+		if len(uploads) < batchSize {
+			break
+		}
+	}
+
+	report.FinishedAt = time.Now()
+	report.BytesFreedFormatted = utils.FormatFileSize(report.BytesFreed)
+	return report, nil
+}
+
+// applyScan scans file contents and records the verdict on every upload sharing the
+// S3 object. Scanner errors leave the upload quarantined with a failed status. A scan
+// run by a job whose lease was lost meanwhile is not recorded.
+func (s *UploadService) applyScan(ctx context.Context, upload *models.Upload, r io.Reader) error {
+	status, signature := models.UploadScanClean, ""
+	result, scanErr := s.scanner.Scan(ctx, r)
+	switch {
+	case scanErr != nil:
+		status = models.UploadScanFailed
+	case result.Infected:
+		status, signature = models.UploadScanInfected, result.Signature
+		log.Printf("Upload %d (%s) is infected with %s and stays quarantined", upload.ID, upload.OriginalName, signature)
+	}
+
+	if err := CheckJobLease(ctx); err != nil {
+		return err
+	}
+	scannedAt := time.Now()
+	if err := s.repo.UpdateScanResult(upload.S3Key, status, signature, scannedAt); err != nil {
+		return fmt.Errorf("failed to save scan result: %w", err)
+	}
+	upload.ScanStatus, upload.ScanSignature, upload.ScannedAt = status, signature, &scannedAt
+	return scanErr
+}
+
+// RescanUploads scans uploads whose scan is pending or failed and, when rescanAfter is
+// set, clean uploads last scanned longer ago than that, working through them batchSize
+// at a time. Uploads sharing an S3 object are scanned once. The scan stops before the
+// next upload once ctx is cancelled or its job lease is lost. With scanning disabled the
+// due uploads are marked clean without fetching them.
+func (s *UploadService) RescanUploads(ctx context.Context, batchSize int, rescanAfter time.Duration) (*models.ScanReport, error) {
+	report := &models.ScanReport{StartedAt: time.Now()}
+
+	var rescanBefore *time.Time
+	if rescanAfter > 0 {
+		before := report.StartedAt.Add(-rescanAfter)
+		rescanBefore = &before
+	}
+
+	if !s.ScanningEnabled() {
+		if err := CheckJobLease(ctx); err != nil {
+			return nil, err
+		}
+		marked, err := s.repo.MarkScanDueClean(rescanBefore, report.StartedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to mark uploads clean: %w", err)
+		}
+		report.Scanned, report.Clean = int(marked), int(marked)
+		report.FinishedAt = time.Now()
+		return report, nil
+	}
+
+	scanned := make(map[string]bool)
+	var lastID uint
+	for {
+		uploads, err := s.repo.GetScanDue(rescanBefore, lastID, batchSize)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get uploads to scan: %w", err)
+		}
+
+		for i := range uploads {
+			if err := CheckJobLease(ctx); err != nil {
+				return nil, fmt.Errorf("upload scan stopped after %d uploads: %w", report.Scanned, err)
+			}
+			upload := &uploads[i]
+			lastID = upload.ID
+			if scanned[upload.S3Key] {
+				continue
+			}
+			scanned[upload.S3Key] = true
+			report.Scanned++
+
+			if err := s.rescan(ctx, upload); err != nil {
+				if errors.Is(err, ErrLeaseLost) {
+					return nil, fmt.Errorf("scan of upload %d was not recorded: %w", upload.ID, err)
+				}
+				report.Failed++
+				report.Errors = append(report.Errors, fmt.Sprintf("upload %d: %v", upload.ID, err))
+				continue
+			}
+			if upload.ScanStatus == models.UploadScanInfected {
+				report.Infected++
+			} else {
+				report.Clean++
+			}
+		}
+
+		if len(uploads) < batchSize {
+			break
+		}
+	}
+
+	report.FinishedAt = time.Now()
+	return report, nil
+}
+
+// ScanningEnabled reports whether uploads are checked by a real scanner rather than
+// marked clean unscanned
+func (s *UploadService) ScanningEnabled() bool {
+	_, noop := s.scanner.(NoopScanner)
+	return !noop
+}
+
+// MarkUnscannedClean marks uploads stored before scanning existed, or left pending, as
+// clean. It is the data migration run when scanning is disabled, so that existing files
+// are not quarantined until the first scan job.
+func (s *UploadService) MarkUnscannedClean() (int, error) {
+	marked, err := s.repo.MarkScanDueClean(nil, time.Now())
+	return int(marked), err
+}
+
+// rescan streams an upload's S3 object through the scanner
+func (s *UploadService) rescan(ctx context.Context, upload *models.Upload) error {
+	stream, err := s.s3Service.GetObject(upload.S3Key, ObjectRequest{})
+	if err != nil {
+		return fmt.Errorf("failed to fetch file: %w", err)
+	}
+	defer stream.Body.Close()
+
+	return s.applyScan(ctx, upload, stream.Body)
+}
+
+// NormalizeURLs rewrites upload URLs stored on other entities to the current public URL
+func (s *UploadService) NormalizeURLs() (int, error) {
+	return s.references.NormalizeURLs(func(upload *models.Upload) string {
+		return s.s3Service.GetFileURL(upload.S3Key)
+	})
+}
+
+// toResponse converts an upload to its response with the public URLs of its keys.
+// Quarantined uploads get no URLs.
+func (s *UploadService) toResponse(upload *models.Upload) models.UploadResponse {
+	if upload.IsQuarantined() {
+		return upload.ToResponse()
+	}
+
+	upload.URL = s.s3Service.GetFileURL(upload.S3Key)
+	if upload.PosterKey != "" {
+		upload.PosterURL = s.s3Service.GetFileURL(upload.PosterKey)
+	}
+	if upload.TranscodedKey != "" {
+		upload.TranscodedURL = s.s3Service.GetFileURL(upload.TranscodedKey)
+	}
+	return upload.ToResponse()
+}
+
+// GetDeduplicationReport returns the storage saved by content-addressed deduplication
+func (s *UploadService) GetDeduplicationReport() (*models.DeduplicationReport, error) {
+	report, err := s.repo.GetDeduplicationReport()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get deduplication report: %w", err)
+	}
+	return report, nil
+}
+
+// GetUploadReferences returns the entities referencing an upload
+func (s *UploadService) GetUploadReferences(id uint) ([]models.UploadReference, error) {
+	if _, err := s.repo.GetByID(id); err != nil {
+		return nil, fmt.Errorf("failed to get upload: %w", err)
+	}
+	return s.references.GetReferences(id)
+}
+
+// RebuildReferences rescans every entity and rebuilds the upload reference index
+func (s *UploadService) RebuildReferences() (int, error) {
+	return s.references.Rebuild()
+}
+
+// FindOrphans reports uploads older than minAge that nothing references and S3 objects
+// without an upload record. When clean is set, both are deleted.
+func (s *UploadService) FindOrphans(minAge time.Duration, clean bool) (*models.OrphanReport, error) {
+	cutoff := time.Now().Add(-minAge)
+	report := &models.OrphanReport{
+		UnreferencedUploads: []models.UploadResponse{},
+		OrphanObjects:       []models.StorageObject{},
+	}
+
+	uploads, err := s.repo.GetUnreferenced(cutoff)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get unreferenced uploads: %w", err)
+	}
+	for _, upload := range uploads {
+		report.UnreferencedUploads = append(report.UnreferencedUploads, s.toResponse(&upload))
+		report.TotalSize += upload.FileSize
+	}
+
+	keys, err := s.repo.GetAllS3Keys()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get upload keys: %w", err)
+	}
+	known := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		known[key] = true
+	}
+
+	objects, err := s.s3Service.ListObjects("uploads/")
+	if err != nil {
+		return nil, err
+	}
+	for _, obj := range objects {
+		// Skip objects that may belong to an upload still being saved
+		if known[obj.Key] || obj.LastModified.After(cutoff) {
+			continue
+		}
+		report.OrphanObjects = append(report.OrphanObjects, obj)
+		report.TotalSize += obj.Size
+	}
+
+	report.TotalSizeFormatted = utils.FormatFileSize(report.TotalSize)
+
+	if !clean {
+		return report, nil
+	}
+
+	for _, upload := range uploads {
+		if err := s.DeleteUpload(upload.ID, false); err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("upload %d: %v", upload.ID, err))
+		}
+	}
+	for _, obj := range report.OrphanObjects {
+		if err := s.s3Service.DeleteFile(obj.Key); err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("object %s: %v", obj.Key, err))
+		}
+	}
+	report.Cleaned = true
+
+	return report, nil
+}
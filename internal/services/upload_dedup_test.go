@@ -0,0 +1,59 @@
+package services
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"portfolio-be/internal/config"
+	"portfolio-be/internal/models"
+	"portfolio-be/internal/repository"
+	"portfolio-be/internal/testutil"
+)
+
+func TestDuplicateUploadsShareObjectUntilLastIsDeleted(t *testing.T) {
+	db := testutil.OpenDB(t, filepath.Join(t.TempDir(), "uploads.db"))
+	uploadRepo := repository.NewUploadRepository(db)
+	references := NewUploadReferenceService(repository.NewUploadReferenceRepository(db), uploadRepo, repository.NewResourceRepository(db))
+	quotas := NewUploadQuotaService(repository.NewUploadQuotaRepository(db), uploadRepo, repository.NewUserRepository(db), config.QuotaConfig{})
+	s3Service, bucket := newTestS3Service(t)
+	service := NewUploadService(uploadRepo, s3Service, references, nil, nil, NoopScanner{}, quotas, config.ImageConfig{})
+
+	user := &models.User{Username: "editor", Email: "editor@example.com", Password: "secret", Role: "editor"}
+	if err := db.Create(user).Error; err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+
+	content := "the same notes, uploaded twice"
+	first, err := service.StoreFile(strings.NewReader(content), "notes.txt", "text/plain", int64(len(content)), user.ID)
+	if err != nil {
+		t.Fatalf("failed to store first upload: %v", err)
+	}
+	second, err := service.StoreFile(strings.NewReader(content), "notes-copy.txt", "text/plain", int64(len(content)), user.ID)
+	if err != nil {
+		t.Fatalf("failed to store second upload: %v", err)
+	}
+
+	firstUpload, _ := uploadRepo.GetByID(first.ID)
+	secondUpload, _ := uploadRepo.GetByID(second.ID)
+	if first.ID == second.ID || firstUpload.S3Key != secondUpload.S3Key {
+		t.Fatalf("expected two records sharing one key, got %s and %s", firstUpload.S3Key, secondUpload.S3Key)
+	}
+	if bucket.count() != 1 {
+		t.Fatalf("expected one stored object, got %d", bucket.count())
+	}
+
+	if err := service.DeleteUpload(first.ID, false); err != nil {
+		t.Fatalf("failed to delete first upload: %v", err)
+	}
+	if !bucket.has(firstUpload.S3Key) {
+		t.Fatal("expected the shared object to be kept while another upload uses it")
+	}
+
+	if err := service.DeleteUpload(second.ID, false); err != nil {
+		t.Fatalf("failed to delete second upload: %v", err)
+	}
+	if bucket.has(firstUpload.S3Key) {
+		t.Error("expected the object to be deleted with its last upload")
+	}
+}
@@ -0,0 +1,270 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"portfolio-be/internal/config"
+	"portfolio-be/internal/models"
+	"portfolio-be/internal/repository"
+	"portfolio-be/pkg/utils"
+	"slices"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrQuotaExceeded is returned when an upload would exceed the total size or file count quota
+	ErrQuotaExceeded = errors.New("upload quota exceeded")
+	// ErrFileTooLarge is returned when a file exceeds the maximum single file size
+	ErrFileTooLarge = errors.New("file exceeds the maximum upload size")
+	// ErrFileClassNotAllowed is returned when a quota does not allow the file's MIME class
+	ErrFileClassNotAllowed = errors.New("file type is not allowed by the upload quota")
+	// ErrInvalidQuota is returned for quotas that do not name exactly one role or user, or
+	// that set negative limits
+	ErrInvalidQuota = errors.New("quota needs exactly one of role or user_id, non-negative limits, and allowed_classes may only contain image, video, audio, document or other")
+	// ErrQuotaNotFound is returned when a quota does not exist
+	ErrQuotaNotFound = errors.New("quota not found")
+	// ErrQuotaUserNotFound is returned when the usage of an unknown user is requested
+	ErrQuotaUserNotFound = errors.New("user not found")
+)
+
+// UploadQuotaService resolves and enforces per-role and per-user upload quotas
+type UploadQuotaService struct {
+	repo       *repository.UploadQuotaRepository
+	uploadRepo *repository.UploadRepository
+	userRepo   *repository.UserRepository
+	defaults   config.QuotaConfig
+}
+
+func NewUploadQuotaService(repo *repository.UploadQuotaRepository, uploadRepo *repository.UploadRepository, userRepo *repository.UserRepository, defaults config.QuotaConfig) *UploadQuotaService {
+	return &UploadQuotaService{
+		repo:       repo,
+		uploadRepo: uploadRepo,
+		userRepo:   userRepo,
+		defaults:   defaults,
+	}
+}
+
+// Resolve returns the quota that applies to a user along with the user's role: the
+// user's own quota, else the quota of their role, else the configured default
+func (s *UploadQuotaService) Resolve(userID uint) (*models.EffectiveQuota, string, error) {
+	role := ""
+	if userID != 0 {
+		user, err := s.userRepo.GetByID(userID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, "", ErrQuotaUserNotFound
+		}
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to get user: %w", err)
+		}
+		role = user.Role
+		if user.UserRole != nil {
+			role = user.UserRole.Name
+		}
+
+		quota, err := s.repo.GetByUser(userID)
+		if err == nil {
+			return effectiveQuota(quota, models.QuotaSourceUser), role, nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, "", fmt.Errorf("failed to get user quota: %w", err)
+		}
+	}
+
+	if role != "" {
+		quota, err := s.repo.GetByRole(role)
+		if err == nil {
+			return effectiveQuota(quota, models.QuotaSourceRole), role, nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, "", fmt.Errorf("failed to get role quota: %w", err)
+		}
+	}
+
+	classes := s.defaults.AllowedClasses
+	if classes == nil {
+		classes = []string{}
+	}
+	return &models.EffectiveQuota{
+		Source:         models.QuotaSourceDefault,
+		MaxTotalBytes:  s.defaults.MaxTotalBytes,
+		MaxFiles:       s.defaults.MaxFiles,
+		MaxFileSize:    s.defaults.MaxFileSize,
+		AllowedClasses: classes,
+	}, role, nil
+}
+
+func effectiveQuota(quota *models.UploadQuota, source string) *models.EffectiveQuota {
+	return &models.EffectiveQuota{
+		Source:         source,
+		MaxTotalBytes:  quota.MaxTotalBytes,
+		MaxFiles:       quota.MaxFiles,
+		MaxFileSize:    quota.MaxFileSize,
+		AllowedClasses: quota.Classes(),
+	}
+}
+
+// Check reports whether a user may upload a file of the given size and type
+func (s *UploadQuotaService) Check(userID uint, size int64, contentType string) error {
+	quota, _, err := s.Resolve(userID)
+	if err != nil {
+		return err
+	}
+
+	class := models.MimeClass(contentType)
+	if len(quota.AllowedClasses) > 0 && !slices.Contains(quota.AllowedClasses, class) {
+		return fmt.Errorf("%w: %s files are not allowed", ErrFileClassNotAllowed, class)
+	}
+	if quota.MaxFileSize > 0 && size > quota.MaxFileSize {
+		return fmt.Errorf("%w of %s", ErrFileTooLarge, utils.FormatFileSize(quota.MaxFileSize))
+	}
+
+	if userID == 0 || (quota.MaxFiles == 0 && quota.MaxTotalBytes == 0) {
+		return nil
+	}
+	files, bytes, err := s.uploadRepo.GetUsageByUser(userID)
+	if err != nil {
+		return fmt.Errorf("failed to get upload usage: %w", err)
+	}
+	if quota.MaxFiles > 0 && files+1 > quota.MaxFiles {
+		return fmt.Errorf("%w: limit of %d files reached", ErrQuotaExceeded, quota.MaxFiles)
+	}
+	if quota.MaxTotalBytes > 0 && bytes+size > quota.MaxTotalBytes {
+		return fmt.Errorf("%w: %s of %s used", ErrQuotaExceeded, utils.FormatFileSize(bytes), utils.FormatFileSize(quota.MaxTotalBytes))
+	}
+	return nil
+}
+
+// Confirm re-checks the file count and total size limits once an upload is saved,
+// counting the user's uploads up to and including it. Uploads that passed Check at the
+// same time are admitted in the order they were saved and the ones past the limit are
+// rejected, so parallel uploads cannot exceed the quota together.
+func (s *UploadQuotaService) Confirm(userID, uploadID uint) error {
+	if userID == 0 {
+		return nil
+	}
+	quota, _, err := s.Resolve(userID)
+	if err != nil {
+		return err
+	}
+	if quota.MaxFiles == 0 && quota.MaxTotalBytes == 0 {
+		return nil
+	}
+
+	files, bytes, err := s.uploadRepo.GetUsageByUserThrough(userID, uploadID)
+	if err != nil {
+		return fmt.Errorf("failed to get upload usage: %w", err)
+	}
+	if quota.MaxFiles > 0 && files > quota.MaxFiles {
+		return fmt.Errorf("%w: limit of %d files reached", ErrQuotaExceeded, quota.MaxFiles)
+	}
+	if quota.MaxTotalBytes > 0 && bytes > quota.MaxTotalBytes {
+		return fmt.Errorf("%w: %s of %s used", ErrQuotaExceeded, utils.FormatFileSize(bytes), utils.FormatFileSize(quota.MaxTotalBytes))
+	}
+	return nil
+}
+
+// GetUsage returns a user's upload statistics against their quota
+func (s *UploadQuotaService) GetUsage(userID uint) (*models.UploadUsage, error) {
+	quota, role, err := s.Resolve(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	summary, err := s.uploadRepo.GetUploadSummary(&userID, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get upload summary: %w", err)
+	}
+
+	usage := &models.UploadUsage{
+		UserID: userID,
+		Role:   role,
+		Quota:  *quota,
+		Usage:  *summary,
+	}
+	if quota.MaxTotalBytes > 0 {
+		remaining := max(quota.MaxTotalBytes-summary.TotalSize, 0)
+		usage.RemainingBytes = &remaining
+	}
+	if quota.MaxFiles > 0 {
+		remaining := max(quota.MaxFiles-summary.TotalFiles, 0)
+		usage.RemainingFiles = &remaining
+	}
+	return usage, nil
+}
+
+// GetQuotas returns all role and user quotas
+func (s *UploadQuotaService) GetQuotas() ([]models.UploadQuotaResponse, error) {
+	quotas, err := s.repo.GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get quotas: %w", err)
+	}
+
+	responses := make([]models.UploadQuotaResponse, len(quotas))
+	for i, quota := range quotas {
+		responses[i] = quota.ToResponse()
+	}
+	return responses, nil
+}
+
+// SaveQuota creates the quota of a role or user, replacing any existing one
+func (s *UploadQuotaService) SaveQuota(req *models.UploadQuotaRequest) (*models.UploadQuotaResponse, error) {
+	req.Role = strings.TrimSpace(req.Role)
+	if (req.Role == "") == (req.UserID == nil) {
+		return nil, ErrInvalidQuota
+	}
+	if req.MaxTotalBytes < 0 || req.MaxFiles < 0 || req.MaxFileSize < 0 {
+		return nil, fmt.Errorf("%w: limits may not be negative", ErrInvalidQuota)
+	}
+
+	classes := make([]string, 0, len(req.AllowedClasses))
+	for _, class := range req.AllowedClasses {
+		class = strings.ToLower(strings.TrimSpace(class))
+		if !slices.Contains(models.MimeClasses, class) {
+			return nil, ErrInvalidQuota
+		}
+		if !slices.Contains(classes, class) {
+			classes = append(classes, class)
+		}
+	}
+
+	var quota *models.UploadQuota
+	var err error
+	if req.UserID != nil {
+		if _, userErr := s.userRepo.GetByID(*req.UserID); userErr != nil {
+			return nil, fmt.Errorf("%w: user %d not found", ErrInvalidQuota, *req.UserID)
+		}
+		quota, err = s.repo.GetByUser(*req.UserID)
+	} else {
+		quota, err = s.repo.GetByRole(req.Role)
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		quota = &models.UploadQuota{Role: req.Role, UserID: req.UserID}
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get quota: %w", err)
+	}
+
+	quota.MaxTotalBytes = req.MaxTotalBytes
+	quota.MaxFiles = req.MaxFiles
+	quota.MaxFileSize = req.MaxFileSize
+	quota.AllowedClasses = strings.Join(classes, ",")
+
+	if err := s.repo.Save(quota); err != nil {
+		return nil, fmt.Errorf("failed to save quota: %w", err)
+	}
+
+	response := quota.ToResponse()
+	return &response, nil
+}
+
+// DeleteQuota removes a role or user quota
+func (s *UploadQuotaService) DeleteQuota(id uint) error {
+	if _, err := s.repo.GetByID(id); err != nil {
+		return ErrQuotaNotFound
+	}
+	if err := s.repo.Delete(id); err != nil {
+		return fmt.Errorf("failed to delete quota: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package services
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"portfolio-be/internal/config"
+	"portfolio-be/internal/models"
+	"portfolio-be/internal/repository"
+	"portfolio-be/internal/testutil"
+)
+
+func TestParallelUploadsStayWithinQuota(t *testing.T) {
+	db := testutil.OpenDB(t, filepath.Join(t.TempDir(), "uploads.db"))
+	uploadRepo := repository.NewUploadRepository(db)
+	quotas := NewUploadQuotaService(repository.NewUploadQuotaRepository(db), uploadRepo, repository.NewUserRepository(db),
+		config.QuotaConfig{MaxFiles: 2, MaxTotalBytes: 1000})
+
+	user := &models.User{Username: "editor", Email: "editor@example.com", Password: "secret", Role: "editor"}
+	if err := db.Create(user).Error; err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+
+	// Three uploads pass the check before any of them is saved
+	for i := 0; i < 3; i++ {
+		if err := quotas.Check(user.ID, 100, "image/png"); err != nil {
+			t.Fatalf("expected upload %d to pass the check, got %v", i, err)
+		}
+	}
+	var saved []*models.Upload
+	for i := 0; i < 3; i++ {
+		upload := &models.Upload{FileName: "a.png", OriginalName: "a.png", S3Key: "uploads/a.png", FileSize: 100, IsActive: true, UploadedBy: &user.ID}
+		if err := uploadRepo.Create(upload); err != nil {
+			t.Fatalf("failed to create upload: %v", err)
+		}
+		saved = append(saved, upload)
+	}
+
+	// Only the first two saved fit the quota, whatever order they are confirmed in
+	for _, i := range []int{2, 0, 1} {
+		err := quotas.Confirm(user.ID, saved[i].ID)
+		if i < 2 && err != nil {
+			t.Errorf("expected upload %d to be admitted, got %v", i, err)
+		}
+		if i == 2 && !errors.Is(err, ErrQuotaExceeded) {
+			t.Errorf("expected upload %d to exceed the quota, got %v", i, err)
+		}
+	}
+
+	if _, err := quotas.SaveQuota(&models.UploadQuotaRequest{Role: "editor", MaxFiles: -1}); !errors.Is(err, ErrInvalidQuota) {
+		t.Errorf("expected a negative limit to be rejected, got %v", err)
+	}
+	if _, err := quotas.GetUsage(user.ID + 1); !errors.Is(err, ErrQuotaUserNotFound) {
+		t.Errorf("expected the usage of an unknown user to be not found, got %v", err)
+	}
+}
@@ -0,0 +1,234 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"net/url"
+	"path"
+	"portfolio-be/internal/models"
+	"portfolio-be/internal/repository"
+)
+
+// UploadReferenceService maintains the index of entities that reference uploads
+type UploadReferenceService struct {
+	repo         *repository.UploadReferenceRepository
+	uploadRepo   *repository.UploadRepository
+	resourceRepo *repository.ResourceRepository
+}
+
+func NewUploadReferenceService(repo *repository.UploadReferenceRepository, uploadRepo *repository.UploadRepository, resourceRepo *repository.ResourceRepository) *UploadReferenceService {
+	return &UploadReferenceService{
+		repo:         repo,
+		uploadRepo:   uploadRepo,
+		resourceRepo: resourceRepo,
+	}
+}
+
+// SyncEntity re-indexes the upload URLs held by an entity, keyed by column name. A
+// field that still points at the same S3 object keeps the upload it was indexed with.
+func (s *UploadReferenceService) SyncEntity(entityType string, entityID uint, fields map[string]string) error {
+	existing, err := s.repo.GetByEntity(entityType, entityID)
+	if err != nil {
+		return fmt.Errorf("failed to get upload references: %w", err)
+	}
+	previous := make(map[string]uint, len(existing))
+	for _, ref := range existing {
+		previous[ref.Field] = ref.UploadID
+	}
+
+	var refs []models.UploadReference
+	for field, fieldURL := range fields {
+		upload := s.resolveURL(fieldURL, previous[field])
+		if upload == nil {
+			continue
+		}
+		refs = append(refs, models.UploadReference{
+			UploadID:   upload.ID,
+			EntityType: entityType,
+			EntityID:   entityID,
+			Field:      field,
+		})
+	}
+
+	if err := s.repo.ReplaceForEntity(entityType, entityID, refs); err != nil {
+		return fmt.Errorf("failed to index upload references: %w", err)
+	}
+	return nil
+}
+
+// SyncResource indexes the upload attached to a resource
+func (s *UploadReferenceService) SyncResource(resourceID, uploadID uint) error {
+	refs := []models.UploadReference{{
+		UploadID:   uploadID,
+		EntityType: models.ReferenceEntityResource,
+		EntityID:   resourceID,
+		Field:      "upload_id",
+	}}
+
+	if err := s.repo.ReplaceForEntity(models.ReferenceEntityResource, resourceID, refs); err != nil {
+		return fmt.Errorf("failed to index upload references: %w", err)
+	}
+	return nil
+}
+
+// RemoveEntity drops every reference held by a deleted entity
+func (s *UploadReferenceService) RemoveEntity(entityType string, entityID uint) error {
+	if err := s.repo.DeleteByEntity(entityType, entityID); err != nil {
+		return fmt.Errorf("failed to remove upload references: %w", err)
+	}
+	return nil
+}
+
+// GetReferences returns every entity referencing an upload
+func (s *UploadReferenceService) GetReferences(uploadID uint) ([]models.UploadReference, error) {
+	refs, err := s.repo.GetByUploadID(uploadID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get upload references: %w", err)
+	}
+	return refs, nil
+}
+
+// IsReferenced reports whether any entity references an upload
+func (s *UploadReferenceService) IsReferenced(uploadID uint) (bool, error) {
+	count, err := s.repo.CountByUploadID(uploadID)
+	if err != nil {
+		return false, fmt.Errorf("failed to count upload references: %w", err)
+	}
+	return count > 0, nil
+}
+
+// DetachReference removes a reference from its entity: resources are deleted,
+// URL fields on other entities are cleared
+func (s *UploadReferenceService) DetachReference(ref models.UploadReference) error {
+	if ref.EntityType == models.ReferenceEntityResource {
+		if err := s.resourceRepo.Delete(ref.EntityID); err != nil {
+			return fmt.Errorf("failed to delete resource %d: %w", ref.EntityID, err)
+		}
+	} else if err := s.repo.ClearEntityField(ref.EntityType, ref.EntityID, ref.Field); err != nil {
+		return fmt.Errorf("failed to clear %s %d: %w", ref.EntityType, ref.EntityID, err)
+	}
+
+	return s.repo.DeleteByID(ref.ID)
+}
+
+// Rebuild scans all entities and recreates the reference index, returning the number of
+// references. Fields that still point at the same S3 object keep their indexed upload.
+func (s *UploadReferenceService) Rebuild() (int, error) {
+	existing, err := s.repo.GetAll()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get upload references: %w", err)
+	}
+	previous := make(map[string]uint, len(existing))
+	for _, ref := range existing {
+		previous[referenceKey(ref.EntityType, ref.EntityID, ref.Field)] = ref.UploadID
+	}
+
+	var refs []models.UploadReference
+
+	resources, err := s.repo.ListResourceUploads()
+	if err != nil {
+		return 0, fmt.Errorf("failed to list resources: %w", err)
+	}
+	for _, resource := range resources {
+		refs = append(refs, models.UploadReference{
+			UploadID:   resource.UploadID,
+			EntityType: models.ReferenceEntityResource,
+			EntityID:   resource.ID,
+			Field:      "upload_id",
+		})
+	}
+
+	urls, err := s.repo.ListEntityURLs()
+	if err != nil {
+		return 0, fmt.Errorf("failed to list entity URLs: %w", err)
+	}
+	for _, entityURL := range urls {
+		upload := s.resolveURL(entityURL.URL, previous[referenceKey(entityURL.EntityType, entityURL.EntityID, entityURL.Field)])
+		if upload == nil {
+			continue
+		}
+		refs = append(refs, models.UploadReference{
+			UploadID:   upload.ID,
+			EntityType: entityURL.EntityType,
+			EntityID:   entityURL.EntityID,
+			Field:      entityURL.Field,
+		})
+	}
+
+	if err := s.repo.ReplaceAll(refs); err != nil {
+		return 0, fmt.Errorf("failed to rebuild upload references: %w", err)
+	}
+
+	log.Printf("Rebuilt upload reference index with %d references", len(refs))
+	return len(refs), nil
+}
+
+// NormalizeURLs rewrites entity fields pointing at uploads to the URL returned by
+// canonical, returning the number of fields changed
+func (s *UploadReferenceService) NormalizeURLs(canonical func(upload *models.Upload) string) (int, error) {
+	urls, err := s.repo.ListEntityURLs()
+	if err != nil {
+		return 0, fmt.Errorf("failed to list entity URLs: %w", err)
+	}
+
+	updated := 0
+	for _, entityURL := range urls {
+		upload := s.resolveURL(entityURL.URL, 0)
+		if upload == nil {
+			continue
+		}
+
+		canonicalURL := canonical(upload)
+		if canonicalURL == entityURL.URL {
+			continue
+		}
+		if err := s.repo.UpdateEntityField(entityURL.EntityType, entityURL.EntityID, entityURL.Field, canonicalURL); err != nil {
+			return updated, fmt.Errorf("failed to update %s %d: %w", entityURL.EntityType, entityURL.EntityID, err)
+		}
+		updated++
+	}
+
+	return updated, nil
+}
+
+// referenceKey identifies an entity field in the reference index
+func referenceKey(entityType string, entityID uint, field string) string {
+	return fmt.Sprintf("%s/%d/%s", entityType, entityID, field)
+}
+
+// uploadKeyFromURL returns the S3 key of an upload URL, or "" for URLs that do not
+// point at an upload. Upload keys are "uploads/" and a file name, whether the URL is a
+// public, endpoint or presigned one.
+func uploadKeyFromURL(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	dir, name := path.Split(parsed.Path)
+	if name == "" || path.Base(dir) != "uploads" {
+		return ""
+	}
+	return "uploads/" + name
+}
+
+// resolveURL finds the upload a URL points at, ignoring external URLs. Deduplicated
+// uploads share an S3 object and so a URL: the field's previously indexed upload is
+// kept when it is one of them, otherwise the most recent upload is taken, being the
+// one just handed to whoever attaches the URL.
+func (s *UploadReferenceService) resolveURL(rawURL string, previousID uint) *models.Upload {
+	key := uploadKeyFromURL(rawURL)
+	if key == "" {
+		return nil
+	}
+
+	uploads, err := s.uploadRepo.GetAllByS3Key(key)
+	if err != nil || len(uploads) == 0 {
+		return nil
+	}
+	for i := range uploads {
+		if uploads[i].ID == previousID {
+			return &uploads[i]
+		}
+	}
+	return &uploads[len(uploads)-1]
+}
@@ -0,0 +1,131 @@
+package services
+
+import (
+	"path/filepath"
+	"testing"
+
+	"portfolio-be/internal/models"
+	"portfolio-be/internal/repository"
+	"portfolio-be/internal/testutil"
+)
+
+func TestUploadReferencesFollowDeduplicatedUploads(t *testing.T) {
+	db := testutil.OpenDB(t, filepath.Join(t.TempDir(), "uploads.db"))
+	uploadRepo := repository.NewUploadRepository(db)
+	refRepo := repository.NewUploadReferenceRepository(db)
+	service := NewUploadReferenceService(refRepo, uploadRepo, repository.NewResourceRepository(db))
+
+	createUpload := func(key string) *models.Upload {
+		t.Helper()
+		upload := &models.Upload{FileName: filepath.Base(key), OriginalName: "photo.jpg", S3Key: key, IsActive: true}
+		if err := uploadRepo.Create(upload); err != nil {
+			t.Fatalf("failed to create upload: %v", err)
+		}
+		return upload
+	}
+	referencedBy := func(entityID uint) uint {
+		t.Helper()
+		refs, err := refRepo.GetByEntity(models.ReferenceEntityProject, entityID)
+		if err != nil {
+			t.Fatalf("failed to get references: %v", err)
+		}
+		if len(refs) != 1 {
+			t.Fatalf("expected project %d to hold one reference, got %d", entityID, len(refs))
+		}
+		return refs[0].UploadID
+	}
+
+	publicURL := "https://cdn.example.com/uploads/ab.jpg"
+	presignedURL := "http://localhost:9000/portfolio/uploads/ab.jpg?X-Amz-Signature=abc"
+	for i, image := range []string{publicURL, presignedURL} {
+		project := &models.Project{ID: uint(i + 1), Name: "Project", Image: image}
+		if err := db.Create(project).Error; err != nil {
+			t.Fatalf("failed to create project: %v", err)
+		}
+	}
+
+	// A key that the deduplicated key ends with must not be confused with it
+	createUpload("uploads/b.jpg")
+	first := createUpload("uploads/ab.jpg")
+	if err := service.SyncEntity(models.ReferenceEntityProject, 1, map[string]string{"image": publicURL}); err != nil {
+		t.Fatalf("failed to sync project 1: %v", err)
+	}
+
+	// The same content uploaded again shares the S3 object and so the URL
+	second := createUpload("uploads/ab.jpg")
+	if err := service.SyncEntity(models.ReferenceEntityProject, 2, map[string]string{"image": presignedURL}); err != nil {
+		t.Fatalf("failed to sync project 2: %v", err)
+	}
+	if err := service.SyncEntity(models.ReferenceEntityProject, 1, map[string]string{"image": publicURL}); err != nil {
+		t.Fatalf("failed to resync project 1: %v", err)
+	}
+
+	if got := referencedBy(1); got != first.ID {
+		t.Errorf("expected project 1 to keep upload %d, got %d", first.ID, got)
+	}
+	if got := referencedBy(2); got != second.ID {
+		t.Errorf("expected project 2 to reference the upload it was given, %d, got %d", second.ID, got)
+	}
+
+	// Rebuilding the index keeps the same attribution
+	if _, err := service.Rebuild(); err != nil {
+		t.Fatalf("failed to rebuild references: %v", err)
+	}
+	if got := referencedBy(1); got != first.ID {
+		t.Errorf("expected project 1 to keep upload %d after a rebuild, got %d", first.ID, got)
+	}
+	if got := referencedBy(2); got != second.ID {
+		t.Errorf("expected project 2 to keep upload %d after a rebuild, got %d", second.ID, got)
+	}
+
+	// External URLs and other paths are not uploads
+	for _, external := range []string{"https://example.com/images/ab.jpg", "https://example.com/ab.jpg", ""} {
+		if key := uploadKeyFromURL(external); key != "" {
+			t.Errorf("expected %q not to resolve, got %q", external, key)
+		}
+	}
+}
+
+func TestDetachedResourceLeavesNoCollectionItemsOrTags(t *testing.T) {
+	db := testutil.OpenDB(t, filepath.Join(t.TempDir(), "uploads.db"))
+	uploadRepo := repository.NewUploadRepository(db)
+	refRepo := repository.NewUploadReferenceRepository(db)
+	service := NewUploadReferenceService(refRepo, uploadRepo, repository.NewResourceRepository(db))
+
+	upload := &models.Upload{FileName: "a.jpg", OriginalName: "photo.jpg", S3Key: "uploads/a.jpg", IsActive: true}
+	if err := uploadRepo.Create(upload); err != nil {
+		t.Fatalf("failed to create upload: %v", err)
+	}
+	resource := &models.Resource{Name: "Photo", Type: models.ResourceTypeImage, UploadID: upload.ID}
+	if err := db.Create(resource).Error; err != nil {
+		t.Fatalf("failed to create resource: %v", err)
+	}
+	collection := &models.Collection{Name: "Gallery"}
+	if err := db.Create(collection).Error; err != nil {
+		t.Fatalf("failed to create collection: %v", err)
+	}
+	if err := db.Create(&models.CollectionItem{CollectionID: collection.ID, ResourceID: resource.ID}).Error; err != nil {
+		t.Fatalf("failed to create collection item: %v", err)
+	}
+	if err := db.Create(&models.Tagging{TagID: 1, EntityType: models.ReferenceEntityResource, EntityID: resource.ID}).Error; err != nil {
+		t.Fatalf("failed to create tagging: %v", err)
+	}
+	if err := service.SyncResource(resource.ID, upload.ID); err != nil {
+		t.Fatalf("failed to sync resource: %v", err)
+	}
+
+	refs, err := refRepo.GetByEntity(models.ReferenceEntityResource, resource.ID)
+	if err != nil || len(refs) != 1 {
+		t.Fatalf("expected the resource to hold one reference, got %d: %v", len(refs), err)
+	}
+	if err := service.DetachReference(refs[0]); err != nil {
+		t.Fatalf("failed to detach reference: %v", err)
+	}
+
+	var items, taggings int64
+	db.Model(&models.CollectionItem{}).Where("resource_id = ?", resource.ID).Count(&items)
+	db.Model(&models.Tagging{}).Where("entity_type = ? AND entity_id = ?", models.ReferenceEntityResource, resource.ID).Count(&taggings)
+	if items != 0 || taggings != 0 {
+		t.Fatalf("expected the collection items and tags to go with the resource, got %d and %d", items, taggings)
+	}
+}
@@ -0,0 +1,82 @@
+package services
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"portfolio-be/internal/config"
+	"portfolio-be/internal/models"
+	"portfolio-be/internal/repository"
+	"portfolio-be/internal/testutil"
+)
+
+func TestCleanupDryRunCountsEachSharedObjectOnce(t *testing.T) {
+	db := testutil.OpenDB(t, filepath.Join(t.TempDir(), "uploads.db"))
+	uploadRepo := repository.NewUploadRepository(db)
+	references := NewUploadReferenceService(repository.NewUploadReferenceRepository(db), uploadRepo, repository.NewResourceRepository(db))
+	service := NewUploadService(uploadRepo, nil, references, nil, nil, nil, nil, config.ImageConfig{})
+
+	expired := time.Now().Add(-48 * time.Hour)
+	later := time.Now().Add(48 * time.Hour)
+	for _, upload := range []models.Upload{
+		// Every upload of the shared object expired, so it is freed once
+		{S3Key: "uploads/shared.jpg", FileSize: 100, ExpiresAt: &expired},
+		{S3Key: "uploads/shared.jpg", FileSize: 100, ExpiresAt: &expired},
+		{S3Key: "uploads/shared.jpg", FileSize: 100, ExpiresAt: &expired},
+		// One upload of this object is still live, so it is kept
+		{S3Key: "uploads/kept.jpg", FileSize: 20, ExpiresAt: &expired},
+		{S3Key: "uploads/kept.jpg", FileSize: 20, ExpiresAt: &later},
+		{S3Key: "uploads/own.jpg", FileSize: 5, ExpiresAt: &expired},
+	} {
+		upload.FileName = filepath.Base(upload.S3Key)
+		upload.OriginalName = upload.FileName
+		upload.IsActive = true
+		if err := uploadRepo.Create(&upload); err != nil {
+			t.Fatalf("failed to create upload: %v", err)
+		}
+	}
+
+	report, err := service.CleanupExpiredUploads(context.Background(), time.Hour, 2, true)
+	if err != nil {
+		t.Fatalf("failed to run cleanup: %v", err)
+	}
+	if report.Deleted != 5 {
+		t.Errorf("expected 5 uploads to be deleted, got %d", report.Deleted)
+	}
+	if report.BytesFreed != 105 {
+		t.Errorf("expected 105 bytes to be freed, got %d", report.BytesFreed)
+	}
+}
+
+func TestRescanWithoutScannerMarksUploadsCleanUnfetched(t *testing.T) {
+	db := testutil.OpenDB(t, filepath.Join(t.TempDir(), "uploads.db"))
+	uploadRepo := repository.NewUploadRepository(db)
+	// No S3 service: fetching an object would panic
+	service := NewUploadService(uploadRepo, nil, nil, nil, nil, NoopScanner{}, nil, config.ImageConfig{})
+
+	for _, key := range []string{"uploads/a.pdf", "uploads/b.pdf", "uploads/c.pdf"} {
+		upload := models.Upload{FileName: filepath.Base(key), OriginalName: filepath.Base(key), S3Key: key, IsActive: true}
+		if err := uploadRepo.Create(&upload); err != nil {
+			t.Fatalf("failed to create upload: %v", err)
+		}
+		if upload.ScanStatus != models.UploadScanPending {
+			t.Fatalf("expected new upload to be pending, got %q", upload.ScanStatus)
+		}
+	}
+
+	report, err := service.RescanUploads(context.Background(), 2, 0)
+	if err != nil {
+		t.Fatalf("failed to rescan uploads: %v", err)
+	}
+	if report.Clean != 3 {
+		t.Errorf("expected 3 uploads marked clean, got %d", report.Clean)
+	}
+
+	var quarantined int64
+	db.Model(&models.Upload{}).Where("scan_status <> ? OR scanned_at IS NULL", models.UploadScanClean).Count(&quarantined)
+	if quarantined != 0 {
+		t.Errorf("expected no quarantined uploads, got %d", quarantined)
+	}
+}
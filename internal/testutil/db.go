@@ -0,0 +1,31 @@
+// Package testutil holds helpers shared by the tests of several packages
+package testutil
+
+import (
+	"testing"
+
+	"portfolio-be/internal/database"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// OpenDB opens and migrates a SQLite database at path, closing it when the test ends.
+// Each call opens its own connection, so opening one file twice acts like a replica.
+func OpenDB(t testing.TB, path string) *gorm.DB {
+	t.Helper()
+	db, err := database.InitSQLite(path)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	db.Logger = logger.Discard
+	if err := database.Migrate(db); err != nil {
+		t.Fatalf("failed to migrate database: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return db
+}